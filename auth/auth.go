@@ -1,42 +1,76 @@
 package auth
 
 import (
+	"backend/models"
+	services_mfa "backend/services/mfa"
+	services_password_resets "backend/services/password_resets"
+	services_refresh_tokens "backend/services/refresh_tokens"
 	services_users "backend/services/users"
 	"backend/utils"
-	"log"
 	"net/http"
 	"net/mail"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 )
 
-var JwtKey = []byte(os.Getenv("JWT_SECRET_KEY")) // 環境変数から読み込む
-
-func init() {
-	if len(JwtKey) == 0 {
-		log.Fatal("JWT_SECRET_KEY is not set in the environment")
-	}
-}
-
 // ユーザー情報のペイロード
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Email    string `json:"email"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
+	Purpose  string `json:"purpose,omitempty"` // 用途が限定されたトークンの場合に設定する(例: mfa_pending)
+	// 発行時刻(Unixミリ秒)。iatは秒単位のため、同じ秒に行った失効の判定に使用する。
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
 	jwt.StandardClaims
+
+	// APIキーで認証した場合に設定する。トークンには含めない。
+	APIKeyID string   `json:"-"`
+	Scopes   []string `json:"-"`
+}
+
+// APIキーで認証したかどうかを返す
+func (c *Claims) IsAPIKey() bool {
+	return c.APIKeyID != ""
+}
+
+// 指定した権限を持つかどうかを判定する
+// APIキーの場合は、ロールの権限のうちキーのスコープに含まれるものに限定する。
+func (c *Claims) HasPermission(permission string) bool {
+	if !models.HasPermission(c.Role, permission) {
+		return false
+	}
+	if !c.IsAPIKey() {
+		return true
+	}
+	for _, scope := range c.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
 }
 
 type AuthHandler struct {
-	UserService services_users.UserService
+	UserService          services_users.UserService
+	RefreshTokenService  services_refresh_tokens.RefreshTokenService
+	PasswordResetService services_password_resets.PasswordResetService
+	MFAService           services_mfa.MFAService
+	RevocationStore      RevocationStore
+	LoginLimiter         LoginLimiter
 }
 
 // コンストラクタ
-func NewAuthHandler(userService services_users.UserService) *AuthHandler {
+func NewAuthHandler(userService services_users.UserService, refreshTokenService services_refresh_tokens.RefreshTokenService, passwordResetService services_password_resets.PasswordResetService, mfaService services_mfa.MFAService, revocationStore RevocationStore, loginLimiter LoginLimiter) *AuthHandler {
 	return &AuthHandler{
-		UserService: userService,
+		UserService:          userService,
+		RefreshTokenService:  refreshTokenService,
+		PasswordResetService: passwordResetService,
+		MFAService:           mfaService,
+		RevocationStore:      revocationStore,
+		LoginLimiter:         loginLimiter,
 	}
 }
 
@@ -75,45 +109,62 @@ func (h *AuthHandler) Login(c echo.Context) error {
 	}
 	utils.LogInfo(c, "Email and password are valid")
 
+	// 失敗が続いているアカウントまたはIPからの試行を拒否
+	if retryAfter := LoginRetryAfter(c, h.LoginLimiter, reqBody.Email); retryAfter > 0 {
+		utils.LogError(c, "Login attempt rejected due to lockout")
+		return TooManyLoginAttempts(c, retryAfter)
+	}
+
 	// サービス層からユーザーデータを取得
 	user, err := h.UserService.FetchUserByEmailAndPassword(reqBody.Email, reqBody.Password)
 	if err != nil {
 		utils.LogError(c, "Error fetching user: "+err.Error())
-		return c.JSON(http.StatusNotFound, map[string]string{
-			"error": "User not found",
+		if err.Error() != "user not found" {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to log in",
+			})
+		}
+
+		// メールアドレスの登録有無が分からないよう、パスワード誤りと同じレスポンスを返す
+		RecordLoginFailure(c, h.LoginLimiter, reqBody.Email)
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Invalid email or password",
 		})
 	}
 
 	// 認証成功
+	RecordLoginSuccess(c, h.LoginLimiter, reqBody.Email)
 	utils.LogInfo(c, "User authenticated successfully:"+user.Email)
 
-	// JWTトークンの作成
-	expirationTime := time.Now().Add(1 * time.Hour) // トークンの有効期限を1時間に設定
-	claims := &Claims{
-		UserID:   user.ID,
-		Email:    user.Email, // 取得したユーザー情報を使う
-		Username: user.Name,
-		StandardClaims: jwt.StandardClaims{
-			ExpiresAt: expirationTime.Unix(),
-		},
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(JwtKey)
+	// 二要素認証が有効な場合は、コードの確認を待つトークンのみを発行し、セッションは発行しない
+	mfaEnabled, err := h.isMFAEnabled(user.ID)
 	if err != nil {
-		utils.LogError(c, "Could not create JWT token: "+err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Could not create token"})
+		utils.LogError(c, "Failed to check MFA settings: "+err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to log in",
+		})
 	}
-	utils.LogInfo(c, "JWT token created successfully")
+	if mfaEnabled {
+		mfaToken, expiresAt, err := issueMFAPendingToken(user)
+		if err != nil {
+			utils.LogError(c, "Could not create MFA token: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Could not create token"})
+		}
 
-	// HTTP-onlyクッキーにトークンをセット
-	cookie := new(http.Cookie)
-	cookie.Name = "token"
-	cookie.Value = tokenString
-	cookie.Expires = expirationTime
-	cookie.HttpOnly = true
-	c.SetCookie(cookie)
+		utils.LogInfo(c, "MFA required for user: "+user.ID)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"message":      "MFA required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_at":   expiresAt.UTC(),
+		})
+	}
+
+	// アクセストークンとリフレッシュトークンを発行してクッキーにセット
+	if err := h.issueSession(c, user); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Could not create token"})
+	}
 
-	utils.LogInfo(c, "JWT token set in HTTP-only cookie")
 	return c.JSON(http.StatusOK, map[string]string{"message": "Login successful"})
 }
 
@@ -121,27 +172,28 @@ func (h *AuthHandler) Login(c echo.Context) error {
 func (h *AuthHandler) CheckAuth(c echo.Context) error {
 	utils.LogInfo(c, "Checking authentication...")
 
-	// クッキーからJWTトークンを取得
-	cookie, err := c.Cookie("token")
+	// クッキーまたはAuthorizationヘッダーからJWTトークンを取得
+	tokenString, err := extractToken(c)
 	if err != nil {
-		utils.LogError(c, "Token not found in cookies")
+		utils.LogError(c, "Token not found: "+err.Error())
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Token not found"})
 	}
-	tokenString := cookie.Value
-
-	claims := &Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return JwtKey, nil
-	})
 
+	claims, err := ParseToken(tokenString)
 	if err != nil {
 		utils.LogError(c, "Failed to parse token: "+err.Error())
 		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token"})
 	}
 
-	if !token.Valid {
-		utils.LogError(c, "Invalid token")
-		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Invalid token"})
+	// 失効済みのトークンを拒否
+	revoked, err := isTokenRevoked(h.RevocationStore, claims)
+	if err != nil {
+		utils.LogError(c, "Failed to check token revocation: "+err.Error())
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"message": "Authentication service unavailable"})
+	}
+	if revoked {
+		utils.LogError(c, "Token has been revoked")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"message": "Token revoked"})
 	}
 
 	// 認証成功
@@ -151,21 +203,263 @@ func (h *AuthHandler) CheckAuth(c echo.Context) error {
 		"user_id":  claims.UserID,
 		"username": claims.Username,
 		"email":    claims.Email,
+		"role":     claims.Role,
 	})
 }
 
+// トークン更新エンドポイント
+// リフレッシュトークンをローテーションし、新しいアクセストークンを発行する。
+// 使用済みのリフレッシュトークンが提示された場合は、その系列全体が失効する。
+func (h *AuthHandler) Refresh(c echo.Context) error {
+	utils.LogInfo(c, "Refreshing token...")
+
+	// クッキーからリフレッシュトークンを取得
+	cookie, err := c.Cookie(refreshTokenCookieName)
+	if err != nil || cookie.Value == "" {
+		utils.LogError(c, "Refresh token not found in cookies")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Refresh token not found"})
+	}
+
+	// リフレッシュトークンをローテーション
+	issued, err := h.RefreshTokenService.RotateRefreshToken(cookie.Value)
+	if err != nil {
+		switch err.Error() {
+		case "invalid refresh token", "refresh token expired", "refresh token reused":
+			utils.LogError(c, "Refresh token rejected: "+err.Error())
+			clearSessionCookies(c)
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid refresh token"})
+		default:
+			utils.LogError(c, "Failed to rotate refresh token: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to refresh token"})
+		}
+	}
+
+	// アクセストークンに含めるユーザー情報を取得
+	user, err := h.UserService.FetchUserById(issued.UserId)
+	if err != nil || user == nil {
+		utils.LogError(c, "User not found for refresh token")
+		clearSessionCookies(c)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid refresh token"})
+	}
+
+	// 新しいアクセストークンを発行
+	if err := setAccessToken(c, user); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not create token"})
+	}
+	setRefreshToken(c, issued)
+
+	utils.LogInfo(c, "Token refreshed successfully")
+	return c.JSON(http.StatusOK, map[string]string{"message": "Token refreshed"})
+}
+
 // ログアウトエンドポイント
 func (h *AuthHandler) Logout(c echo.Context) error {
 	utils.LogInfo(c, "Logging out...")
 
+	// 現在のアクセストークンを有効期限まで失効させる
+	if tokenString, err := extractToken(c); err == nil && h.RevocationStore != nil {
+		if claims, err := ParseToken(tokenString); err == nil && claims.Id != "" {
+			if err := h.RevocationStore.RevokeToken(claims.Id, time.Unix(claims.ExpiresAt, 0)); err != nil {
+				utils.LogError(c, "Failed to revoke access token: "+err.Error())
+			}
+		}
+	}
+
+	// リフレッシュトークンの系列を失効させる
+	if cookie, err := c.Cookie(refreshTokenCookieName); err == nil && cookie.Value != "" {
+		if err := h.RefreshTokenService.RevokeRefreshToken(cookie.Value); err != nil {
+			utils.LogError(c, "Failed to revoke refresh token: "+err.Error())
+		}
+	}
+
 	// クッキーを削除するために、空のトークンと過去の有効期限を設定
-	cookie := new(http.Cookie)
-	cookie.Name = "token"
-	cookie.Value = ""
-	cookie.Expires = time.Unix(0, 0) // 有効期限を過去に設定して削除
-	cookie.HttpOnly = true
-	c.SetCookie(cookie)
+	clearSessionCookies(c)
 
 	utils.LogInfo(c, "User logged out and token removed from cookie")
 	return c.JSON(http.StatusOK, map[string]string{"message": "Logout successful"})
 }
+
+// 全端末ログアウトエンドポイント
+// 現在時刻より前に発行されたすべてのトークンを失効させる。JWTミドルウェアの後に登録する。
+func (h *AuthHandler) LogoutAll(c echo.Context) error {
+	utils.LogInfo(c, "Logging out from all devices...")
+
+	claims, ok := GetClaims(c)
+	if !ok {
+		utils.LogError(c, "Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	if err := h.revokeAllSessions(c, claims.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke sessions"})
+	}
+
+	clearSessionCookies(c)
+
+	utils.LogInfo(c, "User logged out from all devices")
+	return c.JSON(http.StatusOK, map[string]string{"message": "Logged out from all devices"})
+}
+
+// パスワード変更エンドポイント
+// 変更後は既存のセッションをすべて失効させ、現在の端末には新しいセッションを発行する。
+// JWTミドルウェアの後に登録する。
+func (h *AuthHandler) ChangePassword(c echo.Context) error {
+	utils.LogInfo(c, "Changing password...")
+
+	claims, ok := GetClaims(c)
+	if !ok {
+		utils.LogError(c, "Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	// JSONのリクエストボディから現在のパスワードと新しいパスワードを取得
+	type RequestBody struct {
+		CurrentPassword string `json:"current_password"`
+		NewPassword     string `json:"new_password"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		utils.LogError(c, "Failed to bind request body: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// パスワードを変更
+	err := h.UserService.ChangePassword(claims.UserID, reqBody.CurrentPassword, reqBody.NewPassword)
+	if err != nil {
+		switch err.Error() {
+		case "current password and new password are required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Current password and new password are required",
+			})
+		case "invalid current password":
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid current password",
+			})
+		case "user not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "User not found",
+			})
+		default:
+			utils.LogError(c, "Failed to change password: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to change password",
+			})
+		}
+	}
+
+	// 既存のセッションをすべて失効させる
+	if err := h.revokeAllSessions(c, claims.UserID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke sessions"})
+	}
+
+	// 現在の端末には新しいセッションを発行
+	user, err := h.UserService.FetchUserById(claims.UserID)
+	if err != nil || user == nil {
+		utils.LogError(c, "User not found after password change")
+		clearSessionCookies(c)
+		return c.JSON(http.StatusOK, map[string]string{"message": "Password changed successfully"})
+	}
+	if err := h.issueSession(c, user); err != nil {
+		clearSessionCookies(c)
+	}
+
+	utils.LogInfo(c, "Password changed successfully")
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password changed successfully"})
+}
+
+// パスワードリセット要求エンドポイント
+// 登録済みのメールアドレスにリセット用のリンクを送信する。
+// メールアドレスの登録有無が分からないよう、常に同じレスポンスを返す。
+func (h *AuthHandler) ForgotPassword(c echo.Context) error {
+	utils.LogInfo(c, "Requesting password reset...")
+
+	// JSONのリクエストボディからemailを取得
+	type RequestBody struct {
+		Email string `json:"email"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		utils.LogError(c, "Failed to bind request body: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	err := h.PasswordResetService.RequestPasswordReset(reqBody.Email)
+	if err != nil {
+		switch err.Error() {
+		case "email is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Email is required",
+			})
+		case "invalid email format":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid email format",
+			})
+		default:
+			utils.LogError(c, "Failed to request password reset: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to request password reset",
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "If the email is registered, a password reset link has been sent",
+	})
+}
+
+// パスワードリセットエンドポイント
+// リセットトークンを検証してパスワードを変更し、既存のセッションをすべて失効させる。
+func (h *AuthHandler) ResetPassword(c echo.Context) error {
+	utils.LogInfo(c, "Resetting password...")
+
+	// JSONのリクエストボディからトークンと新しいパスワードを取得
+	type RequestBody struct {
+		Token       string `json:"token"`
+		NewPassword string `json:"new_password"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		utils.LogError(c, "Failed to bind request body: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	userId, err := h.PasswordResetService.ResetPassword(reqBody.Token, reqBody.NewPassword)
+	if err != nil {
+		switch err.Error() {
+		case "token and new password are required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Token and new password are required",
+			})
+		case "invalid or expired reset token":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid or expired reset token",
+			})
+		default:
+			utils.LogError(c, "Failed to reset password: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to reset password",
+			})
+		}
+	}
+
+	// 既存のセッションをすべて失効させる
+	if err := h.revokeAllSessions(c, userId); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke sessions"})
+	}
+	clearSessionCookies(c)
+
+	utils.LogInfo(c, "Password reset successfully")
+	return c.JSON(http.StatusOK, map[string]string{"message": "Password has been reset"})
+}
@@ -2,24 +2,32 @@ package auth
 
 import (
 	"backend/models"
+	services_password_resets "backend/services/password_resets"
+	services_refresh_tokens "backend/services/refresh_tokens"
 	services_users "backend/services/users"
 	"bytes"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
-	"github.com/joho/godotenv"
+	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
 )
 
+// テスト用の署名鍵
+var testSecret = []byte("test-secret")
+
 func TestMain(m *testing.M) {
-	// .envファイルの読み込み
-	err := godotenv.Load("../.env.test")
+	// テスト用のキーリングを設定
+	testKeyring, err := NewKeyring([]*SigningKey{NewHMACKey("test", testSecret)}, "test")
 	if err != nil {
-		panic("Error loading ../.env.test file")
+		panic("Error creating test keyring: " + err.Error())
 	}
+	SetKeyring(testKeyring)
 
 	// テストを実行
 	code := m.Run()
@@ -37,31 +45,520 @@ func TestLogin(t *testing.T) {
 	c := e.NewContext(req, rec)
 
 	mockUserService := new(services_users.MockUserService)
-	handler := NewAuthHandler(mockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, nil, NewMemoryRevocationStore(), nil)
 
 	// Mockの設定
-	mockUserService.On("FetchUserByEmailAndPassword", "test@example.com", "password123").Return(&models.UserData{ID: "user1", Email: "test@example.com", Name: "Test User"}, nil)
+	mockUserService.On("FetchUserByEmailAndPassword", "test@example.com", "password123").Return(&models.UserData{ID: "user1", Email: "test@example.com", Name: "Test User", Role: models.RoleStaff}, nil)
+	mockRefreshTokenService.On("IssueRefreshToken", "user1").Return(&models.IssuedRefreshToken{Token: "refresh-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
 
 	// テスト実行
 	if assert.NoError(t, handler.Login(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Contains(t, rec.Body.String(), "Login successful")
+
+		// アクセストークンとリフレッシュトークンのクッキーが設定されていることを確認
+		cookies := responseCookies(rec)
+		assert.NotEmpty(t, cookies["token"])
+		assert.Equal(t, "refresh-token", cookies["refresh_token"])
+
+		// クッキーに設定した属性が付与されていることを確認
+		for _, cookie := range rec.Result().Cookies() {
+			assert.True(t, cookie.HttpOnly)
+			assert.True(t, cookie.Secure)
+			assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+			assert.NotEmpty(t, cookie.Path)
+		}
+
+		// アクセストークンにロールが含まれていることを確認
+		claims, err := ParseToken(cookies["token"])
+		if assert.NoError(t, err) {
+			assert.Equal(t, models.RoleStaff, claims.Role)
+		}
+	}
+
+	mockUserService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestLogin_InvalidCredentials(t *testing.T) {
+	e := echo.New()
+	reqBody := `{"email":"unknown@example.com", "password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBufferString(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockUserService := new(services_users.MockUserService)
+	handler := NewAuthHandler(mockUserService, new(services_refresh_tokens.MockRefreshTokenService), nil, nil, NewMemoryRevocationStore(), NewMemoryLoginLimiter(DefaultLoginLimiterConfig()))
+
+	// Mockの設定
+	mockUserService.On("FetchUserByEmailAndPassword", "unknown@example.com", "password123").Return(nil, errors.New("user not found"))
+
+	// テスト実行
+	// ユーザーの存在有無が分からないよう、404ではなく401を返すことを確認
+	if assert.NoError(t, handler.Login(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Invalid email or password")
+		assert.NotContains(t, rec.Body.String(), "not found")
+	}
+
+	mockUserService.AssertExpectations(t)
+}
+
+func TestLogin_LockedOut(t *testing.T) {
+	config := DefaultLoginLimiterConfig()
+	config.MaxAccountFailures = 2
+	limiter := NewMemoryLoginLimiter(config)
+
+	mockUserService := new(services_users.MockUserService)
+	handler := NewAuthHandler(mockUserService, new(services_refresh_tokens.MockRefreshTokenService), nil, nil, NewMemoryRevocationStore(), limiter)
+
+	// Mockの設定
+	// 許容回数までの失敗のみサービスが呼び出される
+	mockUserService.On("FetchUserByEmailAndPassword", "test@example.com", "wrong-password").Return(nil, errors.New("user not found")).Times(2)
+
+	login := func() *httptest.ResponseRecorder {
+		e := echo.New()
+		reqBody := `{"email":"test@example.com", "password":"wrong-password"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBufferString(reqBody))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		assert.NoError(t, handler.Login(e.NewContext(req, rec)))
+		return rec
+	}
+
+	// テスト実行
+	assert.Equal(t, http.StatusUnauthorized, login().Code)
+	assert.Equal(t, http.StatusUnauthorized, login().Code)
+
+	// ロック中はパスワードを照合せずに429を返す
+	rec := login()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+
+	mockUserService.AssertExpectations(t)
+}
+
+func TestRefresh(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "old-token"})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockUserService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, nil, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	mockRefreshTokenService.On("RotateRefreshToken", "old-token").Return(&models.IssuedRefreshToken{Token: "new-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+	mockUserService.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Email: "test@example.com", Name: "Test User"}, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.Refresh(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Token refreshed")
+
+		// 新しいトークンがクッキーに設定されていることを確認
+		cookies := responseCookies(rec)
+		assert.NotEmpty(t, cookies["token"])
+		assert.Equal(t, "new-token", cookies["refresh_token"])
 	}
 
 	mockUserService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestRefresh_InvalidCases(t *testing.T) {
+	cases := []struct {
+		name       string
+		cookie     string
+		serviceErr error
+		wantCode   int
+		wantBody   string
+	}{
+		{"missing cookie", "", nil, http.StatusUnauthorized, "Refresh token not found"},
+		{"reused token", "used-token", errors.New("refresh token reused"), http.StatusUnauthorized, "Invalid refresh token"},
+		{"expired token", "expired-token", errors.New("refresh token expired"), http.StatusUnauthorized, "Invalid refresh token"},
+		{"unknown token", "unknown-token", errors.New("invalid refresh token"), http.StatusUnauthorized, "Invalid refresh token"},
+		{"service error", "token", errors.New("failed to rotate refresh token"), http.StatusInternalServerError, "Failed to refresh token"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/refresh", nil)
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: tc.cookie})
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+			handler := NewAuthHandler(new(services_users.MockUserService), mockRefreshTokenService, nil, nil, NewMemoryRevocationStore(), nil)
+
+			if tc.serviceErr != nil {
+				mockRefreshTokenService.On("RotateRefreshToken", tc.cookie).Return(nil, tc.serviceErr)
+			}
+
+			// テスト実行
+			if assert.NoError(t, handler.Refresh(c)) {
+				assert.Equal(t, tc.wantCode, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.wantBody)
+			}
+
+			mockRefreshTokenService.AssertExpectations(t)
+		})
+	}
 }
 
 func TestLogout(t *testing.T) {
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "refresh-token"})
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
-	handler := NewAuthHandler(new(services_users.MockUserService))
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	handler := NewAuthHandler(new(services_users.MockUserService), mockRefreshTokenService, nil, nil, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	mockRefreshTokenService.On("RevokeRefreshToken", "refresh-token").Return(nil)
 
 	// テスト実行
 	if assert.NoError(t, handler.Logout(c)) {
 		assert.Equal(t, http.StatusOK, rec.Code)
 		assert.Contains(t, rec.Body.String(), "Logout successful")
+
+		// クッキーが削除されていることを確認
+		cookies := responseCookies(rec)
+		assert.Contains(t, cookies, "token")
+		assert.Contains(t, cookies, "refresh_token")
+		assert.Empty(t, cookies["token"])
+		assert.Empty(t, cookies["refresh_token"])
+	}
+
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+// レスポンスに設定されたクッキーを名前と値のマップで返す
+func responseCookies(rec *httptest.ResponseRecorder) map[string]string {
+	cookies := map[string]string{}
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie.Value
+	}
+	return cookies
+}
+
+func TestLogout_RevokesAccessToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	tokenString, claims := newSessionToken(t, "jti-1", time.Now())
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/logout", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: tokenString})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, nil, store, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.Logout(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// ログアウトしたアクセストークンが失効していることを確認
+		revoked, err := store.IsRevoked(claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	_, claims := newSessionToken(t, "jti-1", time.Now().Add(-time.Minute))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/logout/all", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(ClaimsContextKey, claims)
+
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	handler := NewAuthHandler(new(services_users.MockUserService), mockRefreshTokenService, nil, nil, store, nil)
+
+	// Mockの設定
+	mockRefreshTokenService.On("RevokeAllRefreshTokens", "user1").Return(nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.LogoutAll(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Logged out from all devices")
+
+		// 既存のアクセストークンが失効していることを確認
+		revoked, err := store.IsRevoked(claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		cookies := responseCookies(rec)
+		assert.Empty(t, cookies["token"])
+		assert.Empty(t, cookies["refresh_token"])
+	}
+
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestLogoutAll_Unauthorized(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/logout/all", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, nil, NewMemoryRevocationStore(), nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.LogoutAll(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	_, claims := newSessionToken(t, "jti-1", time.Now().Add(-time.Minute))
+
+	e := echo.New()
+	reqBody := `{"current_password":"password123", "new_password":"new-password"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/password/change", bytes.NewBufferString(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(ClaimsContextKey, claims)
+
+	mockUserService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, nil, store, nil)
+
+	// Mockの設定
+	mockUserService.On("ChangePassword", "user1", "password123", "new-password").Return(nil)
+	mockRefreshTokenService.On("RevokeAllRefreshTokens", "user1").Return(nil)
+	mockUserService.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Email: "test@example.com", Name: "Test User"}, nil)
+	mockRefreshTokenService.On("IssueRefreshToken", "user1").Return(&models.IssuedRefreshToken{Token: "new-refresh-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.ChangePassword(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Password changed successfully")
+
+		// 変更前のアクセストークンが失効していることを確認
+		revoked, err := store.IsRevoked(claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+
+		// 現在の端末に発行された新しいアクセストークンは有効であることを確認
+		cookies := responseCookies(rec)
+		assert.Equal(t, "new-refresh-token", cookies["refresh_token"])
+		newClaims, err := ParseToken(cookies["token"])
+		if assert.NoError(t, err) {
+			revoked, err = store.IsRevoked(newClaims)
+			assert.NoError(t, err)
+			assert.False(t, revoked)
+		}
+	}
+
+	mockUserService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestChangePassword_InvalidCases(t *testing.T) {
+	cases := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{"missing fields", errors.New("current password and new password are required"), http.StatusBadRequest, "Current password and new password are required"},
+		{"wrong password", errors.New("invalid current password"), http.StatusUnauthorized, "Invalid current password"},
+		{"user not found", errors.New("user not found"), http.StatusNotFound, "User not found"},
+		{"service error", errors.New("failed to change password"), http.StatusInternalServerError, "Failed to change password"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, claims := newSessionToken(t, "jti-1", time.Now())
+
+			e := echo.New()
+			reqBody := `{"current_password":"password123", "new_password":"new-password"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/password/change", bytes.NewBufferString(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.Set(ClaimsContextKey, claims)
+
+			mockUserService := new(services_users.MockUserService)
+			mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+			handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, nil, NewMemoryRevocationStore(), nil)
+
+			// Mockの設定
+			mockUserService.On("ChangePassword", "user1", "password123", "new-password").Return(tc.serviceErr)
+
+			// テスト実行
+			if assert.NoError(t, handler.ChangePassword(c)) {
+				assert.Equal(t, tc.wantStatus, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.wantBody)
+			}
+
+			// パスワード変更に失敗した場合はセッションを失効させない
+			mockUserService.AssertExpectations(t)
+			mockRefreshTokenService.AssertNotCalled(t, "RevokeAllRefreshTokens", "user1")
+		})
+	}
+}
+
+func TestCheckAuth_RevokedToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	tokenString, _ := newSessionToken(t, "jti-1", time.Now())
+	store.RevokeToken("jti-1", time.Now().Add(time.Hour))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/check", nil)
+	req.AddCookie(&http.Cookie{Name: "token", Value: tokenString})
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, nil, store, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.CheckAuth(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Token revoked")
+	}
+}
+
+// jtiと発行時刻を指定してテスト用のアクセストークンを生成する
+func newSessionToken(t *testing.T, jti string, issuedAt time.Time) (string, *Claims) {
+	claims := &Claims{
+		UserID: "user1",
+		Email:  "test@example.com",
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: issuedAt.Add(AccessTokenTTL).Unix(),
+		},
+	}
+	tokenString, err := SignToken(claims)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return tokenString, claims
+}
+
+func TestForgotPassword(t *testing.T) {
+	cases := []struct {
+		name       string
+		email      string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{"registered or not", "test@example.com", nil, http.StatusOK, "If the email is registered"},
+		{"missing email", "", errors.New("email is required"), http.StatusBadRequest, "Email is required"},
+		{"invalid email", "invalid", errors.New("invalid email format"), http.StatusBadRequest, "Invalid email format"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			reqBody := `{"email":"` + tc.email + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/password/forgot", bytes.NewBufferString(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockPasswordResetService := new(services_password_resets.MockPasswordResetService)
+			handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), mockPasswordResetService, nil, NewMemoryRevocationStore(), nil)
+
+			// Mockの設定
+			mockPasswordResetService.On("RequestPasswordReset", tc.email).Return(tc.serviceErr)
+
+			// テスト実行
+			if assert.NoError(t, handler.ForgotPassword(c)) {
+				assert.Equal(t, tc.wantStatus, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.wantBody)
+			}
+
+			mockPasswordResetService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	_, claims := newSessionToken(t, "jti-1", time.Now().Add(-time.Minute))
+
+	e := echo.New()
+	reqBody := `{"token":"reset-token", "new_password":"new-password"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/password/reset", bytes.NewBufferString(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	mockPasswordResetService := new(services_password_resets.MockPasswordResetService)
+	handler := NewAuthHandler(new(services_users.MockUserService), mockRefreshTokenService, mockPasswordResetService, nil, store, nil)
+
+	// Mockの設定
+	mockPasswordResetService.On("ResetPassword", "reset-token", "new-password").Return("user1", nil)
+	mockRefreshTokenService.On("RevokeAllRefreshTokens", "user1").Return(nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.ResetPassword(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Password has been reset")
+
+		// リセット前に発行されたアクセストークンが失効していることを確認
+		revoked, err := store.IsRevoked(claims)
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	}
+
+	mockPasswordResetService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestResetPassword_InvalidCases(t *testing.T) {
+	cases := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{"missing fields", errors.New("token and new password are required"), http.StatusBadRequest, "Token and new password are required"},
+		{"invalid token", errors.New("invalid or expired reset token"), http.StatusBadRequest, "Invalid or expired reset token"},
+		{"internal error", errors.New("failed to reset password"), http.StatusInternalServerError, "Failed to reset password"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			reqBody := `{"token":"reset-token", "new_password":"new-password"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/password/reset", bytes.NewBufferString(reqBody))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			mockPasswordResetService := new(services_password_resets.MockPasswordResetService)
+			handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), mockPasswordResetService, nil, NewMemoryRevocationStore(), nil)
+
+			// Mockの設定
+			mockPasswordResetService.On("ResetPassword", "reset-token", "new-password").Return("", tc.serviceErr)
+
+			// テスト実行
+			if assert.NoError(t, handler.ResetPassword(c)) {
+				assert.Equal(t, tc.wantStatus, rec.Code)
+				assert.Contains(t, rec.Body.String(), tc.wantBody)
+			}
+
+			mockPasswordResetService.AssertExpectations(t)
+		})
 	}
 }
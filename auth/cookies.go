@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"backend/utils"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// セッションで使用するクッキーの属性
+// フロントエンドとAPIの配置(同一サイトか別サイトか、HTTPSか)に応じて環境ごとに設定する。
+type CookieConfig struct {
+	Secure   bool          // HTTPSでのみ送信する
+	SameSite http.SameSite // クロスサイトのリクエストでの送信可否
+	Domain   string        // 空の場合はAPIのホストのみ
+}
+
+// デフォルトの設定を返す。
+// HTTPSかつフロントエンドとAPIが同一サイトに配置されていることを前提とする。
+func DefaultCookieConfig() CookieConfig {
+	return CookieConfig{
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定の項目はデフォルト値を使用する。
+func LoadCookieConfig() (CookieConfig, error) {
+	config := DefaultCookieConfig()
+	config.Secure = utils.GetEnvBool("COOKIE_SECURE", config.Secure)
+	config.Domain = os.Getenv("COOKIE_DOMAIN")
+
+	switch strings.ToLower(os.Getenv("COOKIE_SAMESITE")) {
+	case "":
+	case "lax":
+		config.SameSite = http.SameSiteLaxMode
+	case "strict":
+		config.SameSite = http.SameSiteStrictMode
+	case "none":
+		config.SameSite = http.SameSiteNoneMode
+	default:
+		return config, errors.New("COOKIE_SAMESITE must be one of lax, strict or none")
+	}
+
+	// ブラウザはSecureでないSameSite=Noneのクッキーを拒否する
+	if config.SameSite == http.SameSiteNoneMode && !config.Secure {
+		return config, errors.New("COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+	}
+	return config, nil
+}
+
+// アプリケーション全体で使用するクッキーの属性
+var cookieConfig = DefaultCookieConfig()
+
+// クッキーの属性を設定する
+func SetCookieConfig(config CookieConfig) {
+	cookieConfig = config
+}
+
+// 環境変数からクッキーの属性を読み込み、アプリケーション全体で使用する設定とする
+func InitCookieConfig() error {
+	config, err := LoadCookieConfig()
+	if err != nil {
+		return err
+	}
+	SetCookieConfig(config)
+	return nil
+}
+
+// 設定した属性を持つHTTP-onlyクッキーを生成する
+func newCookie(name, value, path string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   cookieConfig.Domain,
+		Expires:  expires,
+		Secure:   cookieConfig.Secure,
+		HttpOnly: true,
+		SameSite: cookieConfig.SameSite,
+	}
+}
@@ -0,0 +1,58 @@
+package auth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadCookieConfig(t *testing.T) {
+	// 未設定の場合はデフォルト値
+	config, err := LoadCookieConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultCookieConfig(), config)
+
+	// 別サイトに配置したフロントエンドから送信する場合
+	t.Setenv("COOKIE_SAMESITE", "None")
+	t.Setenv("COOKIE_DOMAIN", "api.example.com")
+	config, err = LoadCookieConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteNoneMode, config.SameSite)
+	assert.True(t, config.Secure)
+	assert.Equal(t, "api.example.com", config.Domain)
+
+	// SameSite=NoneはSecureが必須
+	t.Setenv("COOKIE_SECURE", "false")
+	_, err = LoadCookieConfig()
+	assert.EqualError(t, err, "COOKIE_SAMESITE=none requires COOKIE_SECURE=true")
+
+	// ローカル開発環境
+	t.Setenv("COOKIE_SAMESITE", "strict")
+	config, err = LoadCookieConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, http.SameSiteStrictMode, config.SameSite)
+	assert.False(t, config.Secure)
+
+	// 不正な値
+	t.Setenv("COOKIE_SAMESITE", "always")
+	_, err = LoadCookieConfig()
+	assert.Error(t, err)
+}
+
+func TestNewCookie(t *testing.T) {
+	defer SetCookieConfig(DefaultCookieConfig())
+	SetCookieConfig(CookieConfig{Secure: true, SameSite: http.SameSiteNoneMode, Domain: "api.example.com"})
+
+	expires := time.Now().Add(time.Hour)
+	cookie := newCookie(accessTokenCookieName, "value", accessTokenCookiePath, expires)
+
+	assert.Equal(t, "token", cookie.Name)
+	assert.Equal(t, "/", cookie.Path)
+	assert.Equal(t, "api.example.com", cookie.Domain)
+	assert.Equal(t, expires, cookie.Expires)
+	assert.True(t, cookie.Secure)
+	assert.True(t, cookie.HttpOnly)
+	assert.Equal(t, http.SameSiteNoneMode, cookie.SameSite)
+}
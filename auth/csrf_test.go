@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// CSRF対策ミドルウェアを通してハンドラーを実行する
+func runCSRFMiddleware(req *http.Request) (*httptest.ResponseRecorder, bool) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	called := false
+	handler := CSRFWithConfig(CSRFConfig{
+		AllowedOrigins: []string{"https://app.example.com/", " http://localhost:3000"},
+	})(func(c echo.Context) error {
+		called = true
+		return c.String(http.StatusOK, "ok")
+	})
+	handler(c)
+
+	return rec, called
+}
+
+func TestCSRF(t *testing.T) {
+	cases := []struct {
+		name       string
+		method     string
+		origin     string
+		referer    string
+		cookie     bool
+		wantStatus int
+	}{
+		{"safe method from other origin", http.MethodGet, "https://evil.example.com", "", true, http.StatusOK},
+		{"allowed origin", http.MethodPost, "https://app.example.com", "", true, http.StatusOK},
+		{"allowed origin with different case", http.MethodPost, "HTTPS://APP.EXAMPLE.COM", "", true, http.StatusOK},
+		{"allowed local origin", http.MethodDelete, "http://localhost:3000", "", true, http.StatusOK},
+		{"same origin", http.MethodPost, "http://example.com", "", true, http.StatusOK},
+		{"other origin", http.MethodPost, "https://evil.example.com", "", true, http.StatusForbidden},
+		{"other origin without cookie", http.MethodPost, "https://evil.example.com", "", false, http.StatusForbidden},
+		{"allowed origin with different port", http.MethodPost, "https://app.example.com:8443", "", true, http.StatusForbidden},
+		{"null origin", http.MethodPost, "null", "", true, http.StatusForbidden},
+		{"allowed referer", http.MethodPut, "", "https://app.example.com/reservations?id=1", true, http.StatusOK},
+		{"other referer", http.MethodPut, "", "https://evil.example.com/app.example.com", true, http.StatusForbidden},
+		{"no origin with cookie", http.MethodPost, "", "", true, http.StatusForbidden},
+		{"no origin without cookie", http.MethodPost, "", "", false, http.StatusOK},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/reservation", nil)
+			if tc.origin != "" {
+				req.Header.Set(echo.HeaderOrigin, tc.origin)
+			}
+			if tc.referer != "" {
+				req.Header.Set("Referer", tc.referer)
+			}
+			if tc.cookie {
+				req.AddCookie(&http.Cookie{Name: "token", Value: "token"})
+			}
+
+			rec, called := runCSRFMiddleware(req)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantStatus == http.StatusOK, called)
+		})
+	}
+}
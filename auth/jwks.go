@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"net/http"
+	"sort"
+
+	"github.com/labstack/echo/v4"
+)
+
+// JSON Web Key
+// 公開鍵の情報のみを含む。
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	N         string `json:"n,omitempty"`   // RSAのモジュラス
+	E         string `json:"e,omitempty"`   // RSAの公開指数
+	Curve     string `json:"crv,omitempty"` // OKP・ECの曲線
+	X         string `json:"x,omitempty"`   // OKPの公開鍵、ECのx座標
+	Y         string `json:"y,omitempty"`   // ECのy座標
+}
+
+// JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// 検証に使用できる公開鍵をJWKSとして返す
+// 他のサービスが共通鍵を共有せずにトークンを検証できるようにする。
+func (k *Keyring) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	for _, key := range k.publicKeys() {
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.Algorithm}
+
+		switch publicKey := key.VerifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	// 出力を安定させるためkidで並べ替える
+	sort.Slice(set.Keys, func(i, j int) bool {
+		return set.Keys[i].KeyID < set.Keys[j].KeyID
+	})
+
+	return set
+}
+
+// JWKSエンドポイント
+// アプリケーションのキーリングの公開鍵を返す。
+func JWKSHandler(c echo.Context) error {
+	if keyring == nil {
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Signing keys are not configured",
+		})
+	}
+
+	c.Response().Header().Set("Cache-Control", "public, max-age=300")
+	return c.JSON(http.StatusOK, keyring.JWKS())
+}
@@ -0,0 +1,365 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// サポートする署名アルゴリズム
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// JWTの署名鍵
+// kidで識別し、退役後もトークンの最大有効期間が過ぎるまでは検証に使用する。
+type SigningKey struct {
+	ID        string      // kid
+	Algorithm string      // HS256, RS256, EdDSA
+	SignKey   interface{} // 署名用の鍵([]byte, *rsa.PrivateKey, ed25519.PrivateKey)。検証専用の鍵はnil。
+	VerifyKey interface{} // 検証用の鍵([]byte, *rsa.PublicKey, ed25519.PublicKey)
+	RetiredAt time.Time   // 退役日時。ゼロ値の場合は退役していない。
+}
+
+// 署名アルゴリズムに対応するjwt.SigningMethodを返す
+func (k *SigningKey) method() jwt.SigningMethod {
+	switch k.Algorithm {
+	case AlgorithmHS256:
+		return jwt.SigningMethodHS256
+	case AlgorithmRS256:
+		return jwt.SigningMethodRS256
+	case AlgorithmEdDSA:
+		return jwt.SigningMethodEdDSA
+	}
+	return nil
+}
+
+// 指定時刻に検証に使用できるかどうかを判定する
+// 退役した鍵は、退役前に発行されたトークンが期限切れになるまで検証に使用する。
+func (k *SigningKey) verifiableAt(now time.Time) bool {
+	return k.RetiredAt.IsZero() || now.Before(k.RetiredAt.Add(AccessTokenTTL))
+}
+
+// HS256の署名鍵を生成する
+func NewHMACKey(kid string, secret []byte) *SigningKey {
+	return &SigningKey{ID: kid, Algorithm: AlgorithmHS256, SignKey: secret, VerifyKey: secret}
+}
+
+// RS256の署名鍵を生成する
+func NewRSAKey(kid string, privateKey *rsa.PrivateKey) *SigningKey {
+	return &SigningKey{ID: kid, Algorithm: AlgorithmRS256, SignKey: privateKey, VerifyKey: &privateKey.PublicKey}
+}
+
+// EdDSA(Ed25519)の署名鍵を生成する
+func NewEd25519Key(kid string, privateKey ed25519.PrivateKey) *SigningKey {
+	return &SigningKey{ID: kid, Algorithm: AlgorithmEdDSA, SignKey: privateKey, VerifyKey: privateKey.Public()}
+}
+
+// 複数の署名鍵を保持するキーリング
+// 有効な鍵で署名し、kidに対応する鍵で検証する。
+type Keyring struct {
+	mutex  sync.RWMutex
+	keys   map[string]*SigningKey
+	active string
+}
+
+// コンストラクタ
+// activeKidが空の場合は、退役していない最初の署名可能な鍵を使用する。
+func NewKeyring(keys []*SigningKey, activeKid string) (*Keyring, error) {
+	keyring := &Keyring{keys: make(map[string]*SigningKey)}
+
+	for _, key := range keys {
+		if key.ID == "" {
+			return nil, errors.New("kid is required")
+		}
+		if key.method() == nil {
+			return nil, fmt.Errorf("unsupported algorithm for key %s: %s", key.ID, key.Algorithm)
+		}
+		if key.VerifyKey == nil {
+			return nil, fmt.Errorf("verification key is required for key %s", key.ID)
+		}
+		if _, exists := keyring.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate kid: %s", key.ID)
+		}
+		keyring.keys[key.ID] = key
+
+		if activeKid == "" && key.SignKey != nil && key.RetiredAt.IsZero() {
+			activeKid = key.ID
+		}
+	}
+
+	active, ok := keyring.keys[activeKid]
+	if !ok {
+		return nil, errors.New("no active signing key")
+	}
+	if active.SignKey == nil || !active.RetiredAt.IsZero() {
+		return nil, fmt.Errorf("key %s cannot be used for signing", activeKid)
+	}
+	keyring.active = activeKid
+
+	return keyring, nil
+}
+
+// 有効な鍵のkidを返す
+func (k *Keyring) ActiveKeyID() string {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+	return k.active
+}
+
+// 新しい鍵を有効にし、それまでの鍵を退役させる
+// 退役した鍵は、発行済みのトークンが期限切れになるまで検証に使用する。
+func (k *Keyring) Rotate(key *SigningKey) error {
+	if key.ID == "" || key.SignKey == nil || key.VerifyKey == nil || key.method() == nil {
+		return errors.New("invalid signing key")
+	}
+
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	if _, exists := k.keys[key.ID]; exists {
+		return fmt.Errorf("duplicate kid: %s", key.ID)
+	}
+
+	if previous, ok := k.keys[k.active]; ok {
+		previous.RetiredAt = time.Now()
+	}
+	k.keys[key.ID] = key
+	k.active = key.ID
+
+	// 検証期間を過ぎた鍵を削除
+	now := time.Now()
+	for kid, existing := range k.keys {
+		if !existing.verifiableAt(now) {
+			delete(k.keys, kid)
+		}
+	}
+
+	return nil
+}
+
+// 有効な鍵でトークンに署名する
+// ヘッダーには署名に使用した鍵のkidを設定する。
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	k.mutex.RLock()
+	key := k.keys[k.active]
+	k.mutex.RUnlock()
+
+	if key == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	token := jwt.NewWithClaims(key.method(), claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.SignKey)
+}
+
+// トークンの検証に使用する鍵を返す
+// kidに対応する鍵のアルゴリズムとトークンのアルゴリズムが一致しない場合は、改ざんとみなしてエラーを返す。
+// kidを持たないトークン(キーリング導入前に発行されたもの)は有効な鍵で検証する。
+func (k *Keyring) Keyfunc(token *jwt.Token) (interface{}, error) {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		kid = k.active
+	}
+
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+	if !key.verifiableAt(time.Now()) {
+		return nil, fmt.Errorf("signing key has expired: %s", kid)
+	}
+	// アルゴリズムの差し替えによる改ざんを防ぐ
+	if token.Method != key.method() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+
+	return key.VerifyKey, nil
+}
+
+// 検証に使用できる公開鍵の一覧を返す
+// 共通鍵(HS256)は公開しない。
+func (k *Keyring) publicKeys() []*SigningKey {
+	k.mutex.RLock()
+	defer k.mutex.RUnlock()
+
+	now := time.Now()
+	var keys []*SigningKey
+	for _, key := range k.keys {
+		if key.Algorithm == AlgorithmHS256 || !key.verifiableAt(now) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// アプリケーション全体で使用するキーリング
+var keyring *Keyring
+
+// キーリングを設定する
+func SetKeyring(k *Keyring) {
+	keyring = k
+}
+
+// 環境変数からキーリングを読み込み、アプリケーション全体で使用するキーリングとして設定する
+func InitKeyring() error {
+	k, err := LoadKeyring()
+	if err != nil {
+		return err
+	}
+	SetKeyring(k)
+	return nil
+}
+
+// アプリケーションのキーリングでトークンに署名する
+func SignToken(claims jwt.Claims) (string, error) {
+	if keyring == nil {
+		return "", errors.New("keyring is not initialized")
+	}
+	return keyring.Sign(claims)
+}
+
+// 環境変数で指定する署名鍵の定義
+type keyConfig struct {
+	ID             string    `json:"kid"`
+	Algorithm      string    `json:"alg"`
+	Secret         string    `json:"secret"`           // HS256の共通鍵
+	PrivateKey     string    `json:"private_key"`      // PEM形式の秘密鍵
+	PrivateKeyFile string    `json:"private_key_file"` // PEM形式の秘密鍵ファイルのパス
+	PublicKey      string    `json:"public_key"`       // PEM形式の公開鍵(検証専用の鍵)
+	PublicKeyFile  string    `json:"public_key_file"`  // PEM形式の公開鍵ファイルのパス(検証専用の鍵)
+	RetiredAt      time.Time `json:"retired_at"`       // 退役日時(RFC3339)
+}
+
+// 環境変数からキーリングを読み込む
+// JWT_KEYSにJSON配列で鍵を定義し、JWT_ACTIVE_KIDで署名に使用する鍵を指定する。
+// JWT_KEYSがない場合は、JWT_SECRET_KEYをHS256の鍵として使用する。
+func LoadKeyring() (*Keyring, error) {
+	if raw := os.Getenv("JWT_KEYS"); raw != "" {
+		var configs []keyConfig
+		if err := json.Unmarshal([]byte(raw), &configs); err != nil {
+			return nil, fmt.Errorf("failed to parse JWT_KEYS: %w", err)
+		}
+
+		keys := make([]*SigningKey, 0, len(configs))
+		for _, config := range configs {
+			key, err := config.signingKey()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		return NewKeyring(keys, os.Getenv("JWT_ACTIVE_KID"))
+	}
+
+	secret := os.Getenv("JWT_SECRET_KEY")
+	if secret == "" {
+		return nil, errors.New("JWT_KEYS or JWT_SECRET_KEY must be set")
+	}
+	kid := os.Getenv("JWT_SECRET_KEY_ID")
+	if kid == "" {
+		kid = "default"
+	}
+	return NewKeyring([]*SigningKey{NewHMACKey(kid, []byte(secret))}, kid)
+}
+
+// 定義から署名鍵を生成する
+func (c keyConfig) signingKey() (*SigningKey, error) {
+	if c.ID == "" {
+		return nil, errors.New("kid is required in JWT_KEYS")
+	}
+
+	key := &SigningKey{ID: c.ID, Algorithm: c.Algorithm, RetiredAt: c.RetiredAt}
+
+	switch c.Algorithm {
+	case AlgorithmHS256:
+		if c.Secret == "" {
+			return nil, fmt.Errorf("secret is required for key %s", c.ID)
+		}
+		key.SignKey = []byte(c.Secret)
+		key.VerifyKey = []byte(c.Secret)
+
+	case AlgorithmRS256, AlgorithmEdDSA:
+		privatePEM, err := readPEM(c.PrivateKey, c.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key for key %s: %w", c.ID, err)
+		}
+		publicPEM, err := readPEM(c.PublicKey, c.PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key for key %s: %w", c.ID, err)
+		}
+
+		switch {
+		case privatePEM != nil:
+			key.SignKey, key.VerifyKey, err = parsePrivateKey(c.Algorithm, privatePEM)
+		case publicPEM != nil:
+			key.VerifyKey, err = parsePublicKey(c.Algorithm, publicPEM)
+		default:
+			err = errors.New("private_key or public_key is required")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", c.ID, err)
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported algorithm for key %s: %s", c.ID, c.Algorithm)
+	}
+
+	return key, nil
+}
+
+// PEMを文字列またはファイルから読み込む
+func readPEM(inline, path string) ([]byte, error) {
+	if inline != "" {
+		return []byte(inline), nil
+	}
+	if path != "" {
+		return os.ReadFile(path)
+	}
+	return nil, nil
+}
+
+// PEM形式の秘密鍵を読み込み、署名用と検証用の鍵を返す
+func parsePrivateKey(algorithm string, data []byte) (interface{}, interface{}, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return privateKey, &privateKey.PublicKey, nil
+	case AlgorithmEdDSA:
+		privateKey, err := jwt.ParseEdPrivateKeyFromPEM(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		return privateKey, privateKey.(crypto.Signer).Public(), nil
+	}
+	return nil, nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+}
+
+// PEM形式の公開鍵を読み込む
+func parsePublicKey(algorithm string, data []byte) (interface{}, error) {
+	switch algorithm {
+	case AlgorithmRS256:
+		return jwt.ParseRSAPublicKeyFromPEM(data)
+	case AlgorithmEdDSA:
+		return jwt.ParseEdPublicKeyFromPEM(data)
+	}
+	return nil, fmt.Errorf("unsupported algorithm: %s", algorithm)
+}
@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のClaimsを生成
+func newKeyringClaims() *Claims {
+	return &Claims{
+		UserID: "user1",
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: time.Now().Add(time.Hour).Unix(),
+		},
+	}
+}
+
+// キーリングで署名し、同じキーリングで検証する
+func signAndParse(t *testing.T, signer, verifier *Keyring) (*jwt.Token, error) {
+	tokenString, err := signer.Sign(newKeyringClaims())
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return jwt.ParseWithClaims(tokenString, &Claims{}, verifier.Keyfunc)
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate RSA key: %v", err)
+	}
+	return privateKey
+}
+
+func newEd25519Key(t *testing.T) ed25519.PrivateKey {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate Ed25519 key: %v", err)
+	}
+	return privateKey
+}
+
+func TestKeyring_SignAndVerify(t *testing.T) {
+	cases := map[string]*SigningKey{
+		"HS256": NewHMACKey("hs", []byte("secret")),
+		"RS256": NewRSAKey("rs", newRSAKey(t)),
+		"EdDSA": NewEd25519Key("ed", newEd25519Key(t)),
+	}
+
+	for name, key := range cases {
+		t.Run(name, func(t *testing.T) {
+			keyring, err := NewKeyring([]*SigningKey{key}, "")
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			token, err := signAndParse(t, keyring, keyring)
+			if assert.NoError(t, err) {
+				assert.True(t, token.Valid)
+				assert.Equal(t, key.ID, token.Header["kid"])
+				assert.Equal(t, name, token.Header["alg"])
+			}
+		})
+	}
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	keyring, _ := NewKeyring([]*SigningKey{NewHMACKey("old", []byte("old-secret"))}, "old")
+
+	// ローテーション前に発行したトークン
+	oldToken, _ := keyring.Sign(newKeyringClaims())
+
+	// 新しい鍵に切り替え
+	assert.NoError(t, keyring.Rotate(NewEd25519Key("new", newEd25519Key(t))))
+	assert.Equal(t, "new", keyring.ActiveKeyID())
+
+	// 新しいトークンは新しい鍵で署名される
+	token, err := signAndParse(t, keyring, keyring)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "new", token.Header["kid"])
+	}
+
+	// 退役した鍵で署名されたトークンも検証できる
+	_, err = jwt.ParseWithClaims(oldToken, &Claims{}, keyring.Keyfunc)
+	assert.NoError(t, err)
+
+	// 同じkidの鍵は追加できない
+	assert.Error(t, keyring.Rotate(NewHMACKey("new", []byte("another-secret"))))
+}
+
+func TestKeyring_RetiredKeyAgesOut(t *testing.T) {
+	oldKey := NewHMACKey("old", []byte("old-secret"))
+	newKey := NewHMACKey("new", []byte("new-secret"))
+
+	signer, _ := NewKeyring([]*SigningKey{oldKey}, "old")
+	oldToken, _ := signer.Sign(newKeyringClaims())
+
+	// 最大有効期間内に退役した鍵は検証に使用できる
+	oldKey.RetiredAt = time.Now().Add(-AccessTokenTTL / 2)
+	verifier, err := NewKeyring([]*SigningKey{oldKey, newKey}, "new")
+	if assert.NoError(t, err) {
+		_, err = jwt.ParseWithClaims(oldToken, &Claims{}, verifier.Keyfunc)
+		assert.NoError(t, err)
+	}
+
+	// 最大有効期間を過ぎた鍵は検証に使用しない
+	oldKey.RetiredAt = time.Now().Add(-AccessTokenTTL - time.Minute)
+	_, err = jwt.ParseWithClaims(oldToken, &Claims{}, verifier.Keyfunc)
+	assert.Error(t, err)
+}
+
+func TestKeyring_RejectsInvalidTokens(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	keyring, _ := NewKeyring([]*SigningKey{NewRSAKey("rs", rsaKey)}, "rs")
+
+	// 未知のkid
+	other, _ := NewKeyring([]*SigningKey{NewRSAKey("unknown", newRSAKey(t))}, "unknown")
+	_, err := signAndParse(t, other, keyring)
+	assert.Error(t, err)
+
+	// 公開鍵を共通鍵として使用したHS256トークン(アルゴリズムの差し替え)
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, &rsaKey.PublicKey)})
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, newKeyringClaims())
+	forged.Header["kid"] = "rs"
+	forgedString, _ := forged.SignedString(publicPEM)
+	_, err = jwt.ParseWithClaims(forgedString, &Claims{}, keyring.Keyfunc)
+	assert.Error(t, err)
+}
+
+func TestNewKeyring_InvalidCases(t *testing.T) {
+	secret := []byte("secret")
+
+	// 鍵がない
+	_, err := NewKeyring(nil, "")
+	assert.Error(t, err)
+
+	// 重複したkid
+	_, err = NewKeyring([]*SigningKey{NewHMACKey("a", secret), NewHMACKey("a", secret)}, "a")
+	assert.Error(t, err)
+
+	// 存在しないkidを指定
+	_, err = NewKeyring([]*SigningKey{NewHMACKey("a", secret)}, "b")
+	assert.Error(t, err)
+
+	// 退役した鍵を有効な鍵に指定
+	retired := NewHMACKey("a", secret)
+	retired.RetiredAt = time.Now()
+	_, err = NewKeyring([]*SigningKey{retired}, "a")
+	assert.Error(t, err)
+
+	// 未対応のアルゴリズム
+	_, err = NewKeyring([]*SigningKey{{ID: "a", Algorithm: "none", SignKey: secret, VerifyKey: secret}}, "a")
+	assert.Error(t, err)
+}
+
+func TestKeyring_JWKS(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	edKey := newEd25519Key(t)
+	keyring, _ := NewKeyring([]*SigningKey{
+		NewHMACKey("hs", []byte("secret")),
+		NewRSAKey("rs", rsaKey),
+		NewEd25519Key("ed", edKey),
+	}, "rs")
+
+	set := keyring.JWKS()
+
+	// 共通鍵は公開しない
+	if assert.Len(t, set.Keys, 2) {
+		ed, rs := set.Keys[0], set.Keys[1]
+
+		assert.Equal(t, "ed", ed.KeyID)
+		assert.Equal(t, "OKP", ed.KeyType)
+		assert.Equal(t, "Ed25519", ed.Curve)
+		assert.Equal(t, "EdDSA", ed.Algorithm)
+		assert.NotEmpty(t, ed.X)
+
+		assert.Equal(t, "rs", rs.KeyID)
+		assert.Equal(t, "RSA", rs.KeyType)
+		assert.Equal(t, "RS256", rs.Algorithm)
+		assert.Equal(t, "AQAB", rs.E)
+		assert.NotEmpty(t, rs.N)
+	}
+
+	// 秘密鍵の情報が含まれていないことを確認
+	body, _ := json.Marshal(set)
+	assert.NotContains(t, string(body), "secret")
+	assert.NotContains(t, string(body), `"d"`)
+}
+
+func TestJWKSHandler(t *testing.T) {
+	// テスト終了後にテスト用のキーリングに戻す
+	original := keyring
+	defer SetKeyring(original)
+
+	rsKeyring, _ := NewKeyring([]*SigningKey{NewRSAKey("rs", newRSAKey(t))}, "rs")
+	SetKeyring(rsKeyring)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil), rec)
+
+	if assert.NoError(t, JWKSHandler(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), `"kid":"rs"`)
+		assert.NotEmpty(t, rec.Header().Get("Cache-Control"))
+	}
+}
+
+func TestLoadKeyring(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	edKey := newEd25519Key(t)
+	edPublicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: mustMarshalPKIX(t, edKey.Public())})
+
+	keys, _ := json.Marshal([]map[string]string{
+		{"kid": "2024-09", "alg": "HS256", "secret": "old-secret", "retired_at": time.Now().Format(time.RFC3339)},
+		{"kid": "2024-10", "alg": "RS256", "private_key": string(privatePEM)},
+		{"kid": "partner", "alg": "EdDSA", "public_key": string(edPublicPEM)},
+	})
+	t.Setenv("JWT_KEYS", string(keys))
+	t.Setenv("JWT_ACTIVE_KID", "")
+
+	keyring, err := LoadKeyring()
+	if assert.NoError(t, err) {
+		// 退役していない最初の署名可能な鍵が有効になる
+		assert.Equal(t, "2024-10", keyring.ActiveKeyID())
+
+		token, err := signAndParse(t, keyring, keyring)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "RS256", token.Header["alg"])
+		}
+
+		// 検証専用の鍵も公開される
+		assert.Len(t, keyring.JWKS().Keys, 2)
+	}
+
+	// 検証専用の鍵は署名に使用できない
+	t.Setenv("JWT_ACTIVE_KID", "partner")
+	_, err = LoadKeyring()
+	assert.Error(t, err)
+}
+
+func TestLoadKeyring_LegacySecret(t *testing.T) {
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("JWT_SECRET_KEY", "legacy-secret")
+	t.Setenv("JWT_SECRET_KEY_ID", "")
+
+	keyring, err := LoadKeyring()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "default", keyring.ActiveKeyID())
+
+		// kidを持たない既存のトークンも検証できる
+		legacy, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, newKeyringClaims()).SignedString([]byte("legacy-secret"))
+		_, err = jwt.ParseWithClaims(legacy, &Claims{}, keyring.Keyfunc)
+		assert.NoError(t, err)
+	}
+}
+
+func TestLoadKeyring_InvalidCases(t *testing.T) {
+	cases := map[string]string{
+		"invalid json":      "not-json",
+		"missing kid":       `[{"alg":"HS256","secret":"secret"}]`,
+		"missing secret":    `[{"kid":"a","alg":"HS256"}]`,
+		"missing pem":       `[{"kid":"a","alg":"RS256"}]`,
+		"invalid pem":       `[{"kid":"a","alg":"EdDSA","private_key":"not-a-pem"}]`,
+		"unsupported alg":   `[{"kid":"a","alg":"ES256","secret":"secret"}]`,
+		"missing key files": `[{"kid":"a","alg":"RS256","private_key_file":"/nonexistent/key.pem"}]`,
+	}
+
+	for name, raw := range cases {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("JWT_KEYS", raw)
+
+			_, err := LoadKeyring()
+			assert.Error(t, err)
+		})
+	}
+
+	// 鍵が設定されていない場合はエラーを返す(プロセスは終了しない)
+	t.Setenv("JWT_KEYS", "")
+	t.Setenv("JWT_SECRET_KEY", "")
+	_, err := LoadKeyring()
+	if assert.Error(t, err) {
+		assert.True(t, strings.Contains(err.Error(), "JWT_SECRET_KEY"))
+	}
+}
+
+func mustMarshalPKIX(t *testing.T, publicKey interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal public key: %v", err)
+	}
+	return der
+}
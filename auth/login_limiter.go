@@ -0,0 +1,394 @@
+package auth
+
+import (
+	"backend/utils"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+)
+
+// ロックの対象
+const (
+	LockoutScopeAccount = "account"
+	LockoutScopeIP      = "ip"
+)
+
+// ログイン試行の制限に関する設定
+type LoginLimiterConfig struct {
+	MaxAccountFailures int           // アカウント単位の許容失敗回数
+	MaxIPFailures      int           // IP単位の許容失敗回数
+	FailureWindow      time.Duration // 失敗回数を数える期間
+	BaseLockout        time.Duration // 最初のロック期間。ロックが繰り返されるたびに倍になる。
+	MaxLockout         time.Duration // ロック期間の上限
+	LockoutResetAfter  time.Duration // ロック回数をリセットするまでの期間
+}
+
+// デフォルトの設定を返す。
+func DefaultLoginLimiterConfig() LoginLimiterConfig {
+	return LoginLimiterConfig{
+		MaxAccountFailures: 5,
+		MaxIPFailures:      20,
+		FailureWindow:      15 * time.Minute,
+		BaseLockout:        time.Minute,
+		MaxLockout:         time.Hour,
+		LockoutResetAfter:  24 * time.Hour,
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定の項目はデフォルト値を使用する。
+func LoadLoginLimiterConfig() LoginLimiterConfig {
+	config := DefaultLoginLimiterConfig()
+	config.MaxAccountFailures = utils.GetEnvInt("LOGIN_MAX_ACCOUNT_FAILURES", config.MaxAccountFailures)
+	config.MaxIPFailures = utils.GetEnvInt("LOGIN_MAX_IP_FAILURES", config.MaxIPFailures)
+	config.FailureWindow = utils.GetEnvDuration("LOGIN_FAILURE_WINDOW", config.FailureWindow)
+	config.BaseLockout = utils.GetEnvDuration("LOGIN_BASE_LOCKOUT", config.BaseLockout)
+	config.MaxLockout = utils.GetEnvDuration("LOGIN_MAX_LOCKOUT", config.MaxLockout)
+	config.LockoutResetAfter = utils.GetEnvDuration("LOGIN_LOCKOUT_RESET_AFTER", config.LockoutResetAfter)
+	return config
+}
+
+// 許容失敗回数を返す
+func (c LoginLimiterConfig) maxFailures(scope string) int64 {
+	if scope == LockoutScopeIP {
+		return int64(c.MaxIPFailures)
+	}
+	return int64(c.MaxAccountFailures)
+}
+
+// ロック回数に応じたロック期間を返す
+// 1回目はBaseLockout、以降は倍々に延長し、MaxLockoutを上限とする。
+func (c LoginLimiterConfig) lockoutDuration(strikes int64) time.Duration {
+	duration := c.BaseLockout
+	for i := int64(1); i < strikes && duration < c.MaxLockout; i++ {
+		duration *= 2
+	}
+	if c.MaxLockout > 0 && duration > c.MaxLockout {
+		duration = c.MaxLockout
+	}
+	return duration
+}
+
+// 発動したロックの情報
+type Lockout struct {
+	Scope      string        // account または ip
+	Identifier string        // アカウントの場合はメールアドレスのハッシュ値、IPの場合はIPアドレス
+	Strikes    int64         // 期間内のロック回数
+	Duration   time.Duration // ロック期間
+}
+
+// ログイン試行の失敗をアカウント単位とIP単位で記録し、一定回数を超えた場合にロックする
+// メールアドレスの登録有無に関わらず同じように数えるため、ロックの有無からアカウントの存在は分からない。
+type LoginLimiter interface {
+	// ロック中の場合は解除までの残り時間を返す。ロックされていない場合は0を返す。
+	RetryAfter(email, ip string) (time.Duration, error)
+	// 失敗を記録し、ロックが発動した場合はその情報を返す
+	RecordFailure(email, ip string) ([]Lockout, error)
+	// 成功時にアカウントの失敗回数とロック回数をリセットする
+	RecordSuccess(email string) error
+}
+
+// ログイン試行の対象
+type loginTarget struct {
+	scope      string
+	identifier string
+}
+
+// メールアドレスとIPアドレスから対象の一覧を返す
+// メールアドレスはキーに個人情報を残さないようハッシュ化する。
+func loginTargets(email, ip string) []loginTarget {
+	var targets []loginTarget
+	if account := accountIdentifier(email); account != "" {
+		targets = append(targets, loginTarget{scope: LockoutScopeAccount, identifier: account})
+	}
+	if ip != "" {
+		targets = append(targets, loginTarget{scope: LockoutScopeIP, identifier: ip})
+	}
+	return targets
+}
+
+// メールアドレスを正規化してハッシュ化する
+func accountIdentifier(email string) string {
+	normalized := strings.ToLower(strings.TrimSpace(email))
+	if normalized == "" {
+		return ""
+	}
+	return utils.HashToken(normalized)
+}
+
+// Redisを使用したLoginLimiterの実装
+// 複数インスタンス間で失敗回数とロック状態を共有する。
+type RedisLoginLimiter struct {
+	Client *redis.Client
+	Prefix string // キーの接頭辞
+	Config LoginLimiterConfig
+}
+
+func NewRedisLoginLimiter(client *redis.Client, config LoginLimiterConfig) LoginLimiter {
+	return &RedisLoginLimiter{
+		Client: client,
+		Prefix: "auth:login:",
+		Config: config,
+	}
+}
+
+func (l *RedisLoginLimiter) failuresKey(target loginTarget) string {
+	return l.Prefix + "failures:" + target.scope + ":" + target.identifier
+}
+
+func (l *RedisLoginLimiter) lockKey(target loginTarget) string {
+	return l.Prefix + "lock:" + target.scope + ":" + target.identifier
+}
+
+func (l *RedisLoginLimiter) strikesKey(target loginTarget) string {
+	return l.Prefix + "strikes:" + target.scope + ":" + target.identifier
+}
+
+// ロック中の場合は解除までの残り時間を返す
+// アカウントとIPの両方がロックされている場合は長い方を返す。
+func (l *RedisLoginLimiter) RetryAfter(email, ip string) (time.Duration, error) {
+	ctx := context.Background()
+
+	var retryAfter time.Duration
+	for _, target := range loginTargets(email, ip) {
+		ttl, err := l.Client.PTTL(ctx, l.lockKey(target)).Result()
+		if err != nil {
+			log.Printf("Failed to check login lock in Redis: %v", err)
+			return 0, err
+		}
+		if ttl > retryAfter {
+			retryAfter = ttl
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// 失敗を記録し、許容回数に達した対象をロックする
+func (l *RedisLoginLimiter) RecordFailure(email, ip string) ([]Lockout, error) {
+	ctx := context.Background()
+
+	var lockouts []Lockout
+	for _, target := range loginTargets(email, ip) {
+		failures, err := l.Client.Incr(ctx, l.failuresKey(target)).Result()
+		if err != nil {
+			log.Printf("Failed to record login failure in Redis: %v", err)
+			return lockouts, err
+		}
+		// 最初の失敗から一定期間で失敗回数をリセットする
+		if failures == 1 {
+			if err := l.Client.Expire(ctx, l.failuresKey(target), l.Config.FailureWindow).Err(); err != nil {
+				log.Printf("Failed to set login failure expiry in Redis: %v", err)
+				return lockouts, err
+			}
+		}
+
+		if failures < l.Config.maxFailures(target.scope) {
+			continue
+		}
+
+		// 許容回数に達したため、ロック回数に応じた期間ロックする
+		strikes, err := l.Client.Incr(ctx, l.strikesKey(target)).Result()
+		if err != nil {
+			log.Printf("Failed to record login lockout in Redis: %v", err)
+			return lockouts, err
+		}
+		duration := l.Config.lockoutDuration(strikes)
+
+		_, err = l.Client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Expire(ctx, l.strikesKey(target), l.Config.LockoutResetAfter)
+			pipe.Set(ctx, l.lockKey(target), strikes, duration)
+			pipe.Del(ctx, l.failuresKey(target))
+			return nil
+		})
+		if err != nil {
+			log.Printf("Failed to lock login in Redis: %v", err)
+			return lockouts, err
+		}
+
+		lockouts = append(lockouts, Lockout{
+			Scope:      target.scope,
+			Identifier: target.identifier,
+			Strikes:    strikes,
+			Duration:   duration,
+		})
+	}
+
+	return lockouts, nil
+}
+
+// 成功時にアカウントの失敗回数とロック回数をリセットする
+// IP単位の失敗回数は、攻撃者が自身のアカウントでログインしてリセットできないよう維持する。
+func (l *RedisLoginLimiter) RecordSuccess(email string) error {
+	for _, target := range loginTargets(email, "") {
+		err := l.Client.Del(context.Background(), l.failuresKey(target), l.strikesKey(target)).Err()
+		if err != nil {
+			log.Printf("Failed to reset login failures in Redis: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+// メモリ上で失敗回数とロック状態を管理するLoginLimiterの実装
+// 単一インスタンスでの開発やテストで使用する。
+type MemoryLoginLimiter struct {
+	mutex    sync.Mutex
+	config   LoginLimiterConfig
+	failures map[loginTarget]*memoryCounter
+	strikes  map[loginTarget]*memoryCounter
+	locks    map[loginTarget]time.Time // 対象 -> ロック解除時刻
+	now      func() time.Time
+}
+
+// 有効期限付きのカウンター
+type memoryCounter struct {
+	count     int64
+	expiresAt time.Time
+}
+
+func NewMemoryLoginLimiter(config LoginLimiterConfig) LoginLimiter {
+	return &MemoryLoginLimiter{
+		config:   config,
+		failures: make(map[loginTarget]*memoryCounter),
+		strikes:  make(map[loginTarget]*memoryCounter),
+		locks:    make(map[loginTarget]time.Time),
+		now:      time.Now,
+	}
+}
+
+// ロック中の場合は解除までの残り時間を返す
+func (l *MemoryLoginLimiter) RetryAfter(email, ip string) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	var retryAfter time.Duration
+	for _, target := range loginTargets(email, ip) {
+		if remaining := l.locks[target].Sub(now); remaining > retryAfter {
+			retryAfter = remaining
+		}
+	}
+
+	return retryAfter, nil
+}
+
+// 失敗を記録し、許容回数に達した対象をロックする
+func (l *MemoryLoginLimiter) RecordFailure(email, ip string) ([]Lockout, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	var lockouts []Lockout
+	for _, target := range loginTargets(email, ip) {
+		failures := incrementCounter(l.failures, target, now, l.config.FailureWindow)
+		if failures < l.config.maxFailures(target.scope) {
+			continue
+		}
+
+		strikes := incrementCounter(l.strikes, target, now, l.config.LockoutResetAfter)
+		l.strikes[target].expiresAt = now.Add(l.config.LockoutResetAfter)
+		duration := l.config.lockoutDuration(strikes)
+
+		l.locks[target] = now.Add(duration)
+		delete(l.failures, target)
+
+		lockouts = append(lockouts, Lockout{
+			Scope:      target.scope,
+			Identifier: target.identifier,
+			Strikes:    strikes,
+			Duration:   duration,
+		})
+	}
+
+	return lockouts, nil
+}
+
+// 成功時にアカウントの失敗回数とロック回数をリセットする
+func (l *MemoryLoginLimiter) RecordSuccess(email string) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, target := range loginTargets(email, "") {
+		delete(l.failures, target)
+		delete(l.strikes, target)
+	}
+	return nil
+}
+
+// カウンターを1増やして新しい値を返す
+// 期限切れの場合は0から数え直し、最初の値から期間を設定する。
+func incrementCounter(counters map[loginTarget]*memoryCounter, target loginTarget, now time.Time, window time.Duration) int64 {
+	counter, ok := counters[target]
+	if !ok || !now.Before(counter.expiresAt) {
+		counter = &memoryCounter{expiresAt: now.Add(window)}
+		counters[target] = counter
+	}
+	counter.count++
+	return counter.count
+}
+
+// ロック中のログイン試行であれば、解除までの残り時間を返す
+// ストアに接続できない場合は、ログインそのものを止めないよう制限なしとして扱う。
+func LoginRetryAfter(c echo.Context, limiter LoginLimiter, email string) time.Duration {
+	if limiter == nil {
+		return 0
+	}
+
+	retryAfter, err := limiter.RetryAfter(email, c.RealIP())
+	if err != nil {
+		utils.LogError(c, "Failed to check login lock: "+err.Error())
+		return 0
+	}
+	return retryAfter
+}
+
+// ロック中のログイン試行に429を返す
+// アカウントの存在有無に関わらず同じレスポンスを返す。
+func TooManyLoginAttempts(c echo.Context, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+	return c.JSON(http.StatusTooManyRequests, map[string]string{
+		"error": "Too many login attempts. Please try again later",
+	})
+}
+
+// ログインの失敗を記録し、ロックが発動した場合は監査ログに残す
+func RecordLoginFailure(c echo.Context, limiter LoginLimiter, email string) {
+	if limiter == nil {
+		return
+	}
+
+	ip := c.RealIP()
+	lockouts, err := limiter.RecordFailure(email, ip)
+	if err != nil {
+		utils.LogError(c, "Failed to record login failure: "+err.Error())
+	}
+
+	for _, lockout := range lockouts {
+		utils.LogAudit(c, fmt.Sprintf(
+			"event=login_lockout scope=%s identifier=%s ip=%s strikes=%d duration=%s",
+			lockout.Scope, lockout.Identifier, ip, lockout.Strikes, lockout.Duration,
+		))
+	}
+}
+
+// ログインの成功を記録し、アカウントの失敗回数をリセットする
+func RecordLoginSuccess(c echo.Context, limiter LoginLimiter, email string) {
+	if limiter == nil {
+		return
+	}
+
+	if err := limiter.RecordSuccess(email); err != nil {
+		utils.LogError(c, "Failed to reset login failures: "+err.Error())
+	}
+}
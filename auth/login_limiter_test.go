@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用の設定
+var testLoginLimiterConfig = LoginLimiterConfig{
+	MaxAccountFailures: 3,
+	MaxIPFailures:      5,
+	FailureWindow:      10 * time.Minute,
+	BaseLockout:        time.Minute,
+	MaxLockout:         4 * time.Minute,
+	LockoutResetAfter:  time.Hour,
+}
+
+// 指定回数だけ失敗を記録し、最後の記録で発動したロックを返す
+func recordFailures(t *testing.T, limiter LoginLimiter, email, ip string, count int) []Lockout {
+	var lockouts []Lockout
+	for i := 0; i < count; i++ {
+		var err error
+		lockouts, err = limiter.RecordFailure(email, ip)
+		assert.NoError(t, err)
+	}
+	return lockouts
+}
+
+// 各実装に共通するロックの振る舞いを検証する
+func testLoginLimiter(t *testing.T, limiter LoginLimiter, advance func(time.Duration)) {
+	// 許容回数に達するまではロックされない
+	lockouts := recordFailures(t, limiter, "user@example.com", "192.0.2.1", 2)
+	assert.Empty(t, lockouts)
+	retryAfter, err := limiter.RetryAfter("user@example.com", "192.0.2.1")
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	// 許容回数に達するとアカウントがロックされる
+	lockouts = recordFailures(t, limiter, "user@example.com", "192.0.2.1", 1)
+	if assert.Len(t, lockouts, 1) {
+		assert.Equal(t, LockoutScopeAccount, lockouts[0].Scope)
+		assert.Equal(t, int64(1), lockouts[0].Strikes)
+		assert.Equal(t, time.Minute, lockouts[0].Duration)
+	}
+
+	// メールアドレスの大文字小文字や前後の空白に関わらず同じアカウントとして扱う
+	retryAfter, err = limiter.RetryAfter(" USER@example.com", "198.51.100.1")
+	assert.NoError(t, err)
+	assert.True(t, retryAfter > 0 && retryAfter <= time.Minute)
+
+	// 他のアカウントには影響しない
+	retryAfter, err = limiter.RetryAfter("other@example.com", "198.51.100.1")
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	// ロック期間が過ぎると解除される
+	advance(time.Minute + time.Second)
+	retryAfter, err = limiter.RetryAfter("user@example.com", "198.51.100.1")
+	assert.NoError(t, err)
+	assert.Zero(t, retryAfter)
+
+	// ロックが繰り返されるたびにロック期間が倍になり、上限で止まる
+	// IP単位のロックが混ざらないようIPアドレスは指定しない
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		lockouts = recordFailures(t, limiter, "user@example.com", "", 3)
+		if assert.Len(t, lockouts, 1) {
+			assert.Equal(t, want, lockouts[0].Duration)
+		}
+		advance(want + time.Second)
+	}
+
+	// ログインに成功するとロック回数がリセットされる
+	assert.NoError(t, limiter.RecordSuccess("user@example.com"))
+	lockouts = recordFailures(t, limiter, "user@example.com", "", 3)
+	if assert.Len(t, lockouts, 1) {
+		assert.Equal(t, time.Minute, lockouts[0].Duration)
+	}
+
+	// 同じIPから複数のアカウントに対して失敗が続くとIPがロックされる
+	var ipLockout *Lockout
+	for _, email := range []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"} {
+		for _, lockout := range recordFailures(t, limiter, email, "203.0.113.1", 1) {
+			if lockout.Scope == LockoutScopeIP {
+				lockout := lockout
+				ipLockout = &lockout
+			}
+		}
+	}
+	if assert.NotNil(t, ipLockout) {
+		assert.Equal(t, "203.0.113.1", ipLockout.Identifier)
+	}
+	retryAfter, err = limiter.RetryAfter("new@example.com", "203.0.113.1")
+	assert.NoError(t, err)
+	assert.True(t, retryAfter > 0)
+
+	// 失敗回数は期間が過ぎるとリセットされる
+	recordFailures(t, limiter, "window@example.com", "", 2)
+	advance(10*time.Minute + time.Second)
+	lockouts = recordFailures(t, limiter, "window@example.com", "", 1)
+	assert.Empty(t, lockouts)
+}
+
+func TestMemoryLoginLimiter(t *testing.T) {
+	limiter := NewMemoryLoginLimiter(testLoginLimiterConfig).(*MemoryLoginLimiter)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	testLoginLimiter(t, limiter, func(d time.Duration) { now = now.Add(d) })
+}
+
+func TestRedisLoginLimiter(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	testLoginLimiter(t, NewRedisLoginLimiter(client, testLoginLimiterConfig), server.FastForward)
+
+	// キーにメールアドレスが含まれないことを確認
+	for _, key := range server.Keys() {
+		assert.NotContains(t, key, "@")
+	}
+}
+
+func TestRedisLoginLimiter_Unavailable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+	limiter := NewRedisLoginLimiter(client, testLoginLimiterConfig)
+
+	// Redisに接続できない場合はエラーを返す
+	server.Close()
+	_, err := limiter.RetryAfter("user@example.com", "192.0.2.1")
+	assert.Error(t, err)
+	_, err = limiter.RecordFailure("user@example.com", "192.0.2.1")
+	assert.Error(t, err)
+
+	// ハンドラー向けの関数ではログインを止めない
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/login", nil), httptest.NewRecorder())
+	assert.Zero(t, LoginRetryAfter(c, limiter, "user@example.com"))
+}
+
+func TestTooManyLoginAttempts(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/login", nil), rec)
+
+	// テスト実行
+	if assert.NoError(t, TooManyLoginAttempts(c, 1500*time.Millisecond)) {
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "2", rec.Header().Get("Retry-After"))
+		assert.Contains(t, rec.Body.String(), "Too many login attempts")
+	}
+}
+
+func TestRecordLoginFailure_AuditLog(t *testing.T) {
+	limiter := NewMemoryLoginLimiter(testLoginLimiterConfig)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/login", nil)
+	req.Header.Set(echo.HeaderXRealIP, "192.0.2.1")
+	c := e.NewContext(req, httptest.NewRecorder())
+
+	// ログ出力をキャプチャするためのバッファを作成
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	// ロックが発動するまでは監査ログを出力しない
+	RecordLoginFailure(c, limiter, "user@example.com")
+	RecordLoginFailure(c, limiter, "user@example.com")
+	assert.NotContains(t, buf.String(), "AUDIT")
+
+	RecordLoginFailure(c, limiter, "user@example.com")
+	output := buf.String()
+	assert.Contains(t, output, "AUDIT")
+	assert.Contains(t, output, "event=login_lockout scope=account")
+	assert.Contains(t, output, "ip=192.0.2.1")
+	assert.NotContains(t, output, "user@example.com")
+
+	// 以降のログインはロックされる
+	assert.True(t, LoginRetryAfter(c, limiter, "user@example.com") > 0)
+
+	// 制限が設定されていない場合は何もしない
+	RecordLoginFailure(c, nil, "user@example.com")
+	RecordLoginSuccess(c, nil, "user@example.com")
+	assert.Zero(t, LoginRetryAfter(c, nil, "user@example.com"))
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// 二要素認証のコード確認待ちを表すトークンの用途
+	TokenPurposeMFAPending = "mfa_pending"
+
+	// 二要素認証のコード確認待ちトークンの有効期間
+	MFAPendingTokenTTL = 5 * time.Minute
+)
+
+// 二要素認証のコード確認待ちトークンを発行する
+// パスワードの確認が済んだことのみを表し、アクセストークンとしては使用できない。
+func issueMFAPendingToken(user *models.UserData) (string, time.Time, error) {
+	tokenId, err := generateTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(MFAPendingTokenTTL)
+	claims := &Claims{
+		UserID:     user.ID,
+		Email:      user.Email,
+		Purpose:    TokenPurposeMFAPending,
+		IssuedAtMs: now.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenId,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	tokenString, err := SignToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
+
+// 二要素認証のコード確認待ちトークンを検証し、Claimsを返す。
+func ParseMFAPendingToken(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.Purpose != TokenPurposeMFAPending {
+		return nil, errors.New("unexpected token purpose")
+	}
+	return claims, nil
+}
+
+// 二要素認証のコード入力の試行回数を数えるためのキー
+// ログイン試行の制限と同じ仕組みで、ユーザー単位に制限する。
+func mfaLimiterKey(userId string) string {
+	return "mfa:" + userId
+}
+
+// 二要素認証が有効かどうかを返す
+func (h *AuthHandler) isMFAEnabled(userId string) (bool, error) {
+	if h.MFAService == nil {
+		return false, nil
+	}
+	return h.MFAService.IsEnabled(userId)
+}
+
+// 二要素認証の2段階目のログインエンドポイント
+// パスワード確認後に発行されたトークンとTOTPのコード(またはリカバリーコード)を検証し、セッションを発行する。
+func (h *AuthHandler) LoginMFA(c echo.Context) error {
+	utils.LogInfo(c, "Verifying MFA code...")
+
+	// JSONのリクエストボディからトークンとコードを取得
+	type RequestBody struct {
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		utils.LogError(c, "Failed to bind request body: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// バリデーション：トークンとコードが空でないことを確認
+	if reqBody.MFAToken == "" || reqBody.Code == "" {
+		utils.LogError(c, "MFA token and code are required")
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "MFA token and code are required",
+		})
+	}
+
+	claims, err := ParseMFAPendingToken(reqBody.MFAToken)
+	if err != nil {
+		utils.LogError(c, "Failed to parse MFA token: "+err.Error())
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired MFA token"})
+	}
+
+	// 使用済みのトークンを拒否
+	revoked, err := isTokenRevoked(h.RevocationStore, claims)
+	if err != nil {
+		utils.LogError(c, "Failed to check token revocation: "+err.Error())
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Authentication service unavailable"})
+	}
+	if revoked {
+		utils.LogError(c, "MFA token has been used")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired MFA token"})
+	}
+
+	// コードの総当たりを防ぐため、失敗が続いている場合は拒否
+	limiterKey := mfaLimiterKey(claims.UserID)
+	if retryAfter := LoginRetryAfter(c, h.LoginLimiter, limiterKey); retryAfter > 0 {
+		utils.LogError(c, "MFA attempt rejected due to lockout")
+		return TooManyLoginAttempts(c, retryAfter)
+	}
+
+	// 同じトークンで同時に要求された場合に複数のセッションを発行しないよう、コードの確認前に使用済みにする
+	if h.RevocationStore != nil {
+		consumed, err := h.RevocationStore.ConsumeToken(claims.Id, time.Unix(claims.ExpiresAt, 0))
+		if err != nil {
+			utils.LogError(c, "Failed to consume MFA token: "+err.Error())
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Authentication service unavailable"})
+		}
+		if !consumed {
+			utils.LogError(c, "MFA token has been used")
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired MFA token"})
+		}
+	}
+
+	err = h.MFAService.Verify(claims.UserID, reqBody.Code)
+	if err != nil {
+		// セッションを発行していないため、コードを入力し直せるようトークンを戻す
+		if h.RevocationStore != nil {
+			if restoreErr := h.RevocationStore.RestoreToken(claims.Id); restoreErr != nil {
+				utils.LogError(c, "Failed to restore MFA token: "+restoreErr.Error())
+			}
+		}
+
+		switch err.Error() {
+		case "invalid code":
+			RecordLoginFailure(c, h.LoginLimiter, limiterKey)
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid code"})
+		case "mfa not enabled":
+			// トークンの発行後に二要素認証が無効にされた場合は、ログインからやり直す
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired MFA token"})
+		default:
+			utils.LogError(c, "Failed to verify MFA code: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify code"})
+		}
+	}
+	RecordLoginSuccess(c, h.LoginLimiter, limiterKey)
+
+	// ロールなどの最新のユーザー情報でセッションを発行する
+	user, err := h.UserService.FetchUserById(claims.UserID)
+	if err != nil || user == nil {
+		utils.LogError(c, "User not found for MFA token")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid or expired MFA token"})
+	}
+
+	if err := h.issueSession(c, user); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Could not create token"})
+	}
+
+	utils.LogInfo(c, "User authenticated with MFA successfully:"+user.Email)
+	return c.JSON(http.StatusOK, map[string]string{"message": "Login successful"})
+}
+
+// 二要素認証の登録開始エンドポイント
+// 認証アプリに登録するためのシークレットとQRコードを返す。
+func (h *AuthHandler) EnrollMFA(c echo.Context) error {
+	utils.LogInfo(c, "Enrolling MFA...")
+
+	claims, ok := GetClaims(c)
+	if !ok {
+		utils.LogError(c, "Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	enrollment, err := h.MFAService.Enroll(claims.UserID, claims.Email)
+	if err != nil {
+		switch err.Error() {
+		case "mfa already enabled":
+			return c.JSON(http.StatusConflict, map[string]string{"error": "MFA is already enabled"})
+		default:
+			utils.LogError(c, "Failed to enroll MFA: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to enroll MFA"})
+		}
+	}
+
+	utils.LogInfo(c, "MFA enrollment started")
+	return c.JSON(http.StatusOK, enrollment)
+}
+
+// 二要素認証の登録完了エンドポイント
+// 認証アプリが生成したコードを確認して二要素認証を有効にし、リカバリーコードを返す。
+func (h *AuthHandler) ConfirmMFA(c echo.Context) error {
+	utils.LogInfo(c, "Confirming MFA...")
+
+	claims, ok := GetClaims(c)
+	if !ok {
+		utils.LogError(c, "Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	// JSONのリクエストボディからコードを取得
+	type RequestBody struct {
+		Code string `json:"code"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		utils.LogError(c, "Failed to bind request body: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	recoveryCodes, err := h.MFAService.Confirm(claims.UserID, reqBody.Code)
+	if err != nil {
+		switch err.Error() {
+		case "code is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Code is required"})
+		case "invalid code":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid code"})
+		case "mfa not enrolled":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "MFA enrollment has not been started"})
+		case "mfa already enabled":
+			return c.JSON(http.StatusConflict, map[string]string{"error": "MFA is already enabled"})
+		default:
+			utils.LogError(c, "Failed to confirm MFA: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to confirm MFA"})
+		}
+	}
+
+	utils.LogInfo(c, "MFA enabled successfully")
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "MFA enabled",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// 二要素認証の無効化エンドポイント
+// 現在のコードまたはリカバリーコードを確認した上で無効にする。
+func (h *AuthHandler) DisableMFA(c echo.Context) error {
+	utils.LogInfo(c, "Disabling MFA...")
+
+	claims, ok := GetClaims(c)
+	if !ok {
+		utils.LogError(c, "Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	// JSONのリクエストボディからコードを取得
+	type RequestBody struct {
+		Code string `json:"code"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		utils.LogError(c, "Failed to bind request body: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// コードの総当たりを防ぐため、失敗が続いている場合は拒否
+	limiterKey := mfaLimiterKey(claims.UserID)
+	if retryAfter := LoginRetryAfter(c, h.LoginLimiter, limiterKey); retryAfter > 0 {
+		utils.LogError(c, "MFA attempt rejected due to lockout")
+		return TooManyLoginAttempts(c, retryAfter)
+	}
+
+	err := h.MFAService.Disable(claims.UserID, reqBody.Code)
+	if err != nil {
+		switch err.Error() {
+		case "code is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Code is required"})
+		case "invalid code":
+			RecordLoginFailure(c, h.LoginLimiter, limiterKey)
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid code"})
+		case "mfa not enabled":
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "MFA is not enabled"})
+		default:
+			utils.LogError(c, "Failed to disable MFA: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to disable MFA"})
+		}
+	}
+	RecordLoginSuccess(c, h.LoginLimiter, limiterKey)
+
+	utils.LogInfo(c, "MFA disabled successfully")
+	return c.JSON(http.StatusOK, map[string]string{"message": "MFA disabled"})
+}
@@ -0,0 +1,310 @@
+package auth
+
+import (
+	"backend/models"
+	services_mfa "backend/services/mfa"
+	services_refresh_tokens "backend/services/refresh_tokens"
+	services_users "backend/services/users"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// 2段階目のログインリクエストを作成する
+func newLoginMFAContext(mfaToken, code string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	body, _ := json.Marshal(map[string]string{"mfa_token": mfaToken, "code": code})
+	req := httptest.NewRequest(http.MethodPost, "/api/login/mfa", bytes.NewBuffer(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	return e.NewContext(req, rec), rec
+}
+
+func TestLogin_MFARequired(t *testing.T) {
+	e := echo.New()
+	reqBody := `{"email":"staff@example.com", "password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/login", bytes.NewBufferString(reqBody))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	mockUserService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	mockUserService.On("FetchUserByEmailAndPassword", "staff@example.com", "password123").Return(&models.UserData{ID: "user1", Email: "staff@example.com", Role: models.RoleStaff}, nil)
+	mockMFAService.On("IsEnabled", "user1").Return(true, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.Login(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		// セッションのクッキーは発行されないことを確認
+		cookies := responseCookies(rec)
+		assert.Empty(t, cookies["token"])
+		assert.Empty(t, cookies["refresh_token"])
+
+		var res map[string]interface{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		assert.Equal(t, true, res["mfa_required"])
+
+		// 確認待ちトークンはアクセストークンとして使用できないことを確認
+		mfaToken, _ := res["mfa_token"].(string)
+		_, err := ParseToken(mfaToken)
+		assert.Error(t, err)
+
+		claims, err := ParseMFAPendingToken(mfaToken)
+		if assert.NoError(t, err) {
+			assert.Equal(t, "user1", claims.UserID)
+		}
+	}
+
+	mockUserService.AssertExpectations(t)
+	mockMFAService.AssertExpectations(t)
+	mockRefreshTokenService.AssertNotCalled(t, "IssueRefreshToken", "user1")
+}
+
+func TestLoginMFA(t *testing.T) {
+	user := &models.UserData{ID: "user1", Email: "staff@example.com", Name: "Staff", Role: models.RoleStaff}
+	mfaToken, _, err := issueMFAPendingToken(user)
+	assert.NoError(t, err)
+
+	store := NewMemoryRevocationStore()
+	mockUserService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, mockMFAService, store, nil)
+
+	// Mockの設定
+	mockMFAService.On("Verify", "user1", "123456").Return(nil)
+	mockUserService.On("FetchUserById", "user1").Return(user, nil)
+	mockRefreshTokenService.On("IssueRefreshToken", "user1").Return(&models.IssuedRefreshToken{Token: "refresh-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	// テスト実行
+	c, rec := newLoginMFAContext(mfaToken, "123456")
+	if assert.NoError(t, handler.LoginMFA(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "Login successful")
+
+		cookies := responseCookies(rec)
+		assert.NotEmpty(t, cookies["token"])
+		assert.Equal(t, "refresh-token", cookies["refresh_token"])
+	}
+
+	// 同じ確認待ちトークンは再利用できないことを確認
+	c, rec = newLoginMFAContext(mfaToken, "123456")
+	if assert.NoError(t, handler.LoginMFA(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	mockMFAService.AssertNumberOfCalls(t, "Verify", 1)
+	mockUserService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestLoginMFA_ConcurrentRequests(t *testing.T) {
+	user := &models.UserData{ID: "user1", Email: "staff@example.com", Role: models.RoleStaff}
+	mfaToken, _, err := issueMFAPendingToken(user)
+	assert.NoError(t, err)
+
+	mockUserService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	// コードの確認中に同じトークンの要求が届くよう、確認に時間がかかるようにする
+	mockMFAService.On("Verify", "user1", "123456").After(50 * time.Millisecond).Return(nil)
+	mockUserService.On("FetchUserById", "user1").Return(user, nil)
+	mockRefreshTokenService.On("IssueRefreshToken", "user1").Return(&models.IssuedRefreshToken{Token: "refresh-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	// テスト実行
+	const requests = 5
+	codes := make([]int, requests)
+	var wg sync.WaitGroup
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, rec := newLoginMFAContext(mfaToken, "123456")
+			assert.NoError(t, handler.LoginMFA(c))
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+
+	// セッションは1つだけ発行される
+	succeeded := 0
+	for _, code := range codes {
+		if code == http.StatusOK {
+			succeeded++
+		} else {
+			assert.Equal(t, http.StatusUnauthorized, code)
+		}
+	}
+	assert.Equal(t, 1, succeeded)
+	mockMFAService.AssertNumberOfCalls(t, "Verify", 1)
+	mockRefreshTokenService.AssertNumberOfCalls(t, "IssueRefreshToken", 1)
+}
+
+func TestLoginMFA_RetryAfterInvalidCode(t *testing.T) {
+	user := &models.UserData{ID: "user1", Email: "staff@example.com", Role: models.RoleStaff}
+	mfaToken, _, err := issueMFAPendingToken(user)
+	assert.NoError(t, err)
+
+	mockUserService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(mockUserService, mockRefreshTokenService, nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	mockMFAService.On("Verify", "user1", "000000").Return(errors.New("invalid code"))
+	mockMFAService.On("Verify", "user1", "123456").Return(nil)
+	mockUserService.On("FetchUserById", "user1").Return(user, nil)
+	mockRefreshTokenService.On("IssueRefreshToken", "user1").Return(&models.IssuedRefreshToken{Token: "refresh-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	// コードを間違えた場合は、同じトークンで入力し直せる
+	c, rec := newLoginMFAContext(mfaToken, "000000")
+	if assert.NoError(t, handler.LoginMFA(c)) {
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+	c, rec = newLoginMFAContext(mfaToken, "123456")
+	if assert.NoError(t, handler.LoginMFA(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
+
+func TestLoginMFA_InvalidCases(t *testing.T) {
+	user := &models.UserData{ID: "user1", Email: "staff@example.com", Role: models.RoleStaff}
+	mfaToken, _, err := issueMFAPendingToken(user)
+	assert.NoError(t, err)
+
+	// アクセストークンは確認待ちトークンとして使用できない
+	accessToken, err := SignToken(&Claims{UserID: "user1", Role: models.RoleStaff})
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		name         string
+		mfaToken     string
+		code         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"missing code", mfaToken, "", nil, http.StatusBadRequest},
+		{"access token", accessToken, "123456", nil, http.StatusUnauthorized},
+		{"invalid code", mfaToken, "000000", errors.New("invalid code"), http.StatusUnauthorized},
+		{"mfa disabled", mfaToken, "123456", errors.New("mfa not enabled"), http.StatusUnauthorized},
+		{"service error", mfaToken, "123456", errors.New("failed to verify mfa"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockMFAService := new(services_mfa.MockMFAService)
+			handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+			if tc.serviceErr != nil {
+				mockMFAService.On("Verify", "user1", tc.code).Return(tc.serviceErr)
+			}
+
+			// テスト実行
+			c, rec := newLoginMFAContext(tc.mfaToken, tc.code)
+			if assert.NoError(t, handler.LoginMFA(c)) {
+				assert.Equal(t, tc.expectedCode, rec.Code)
+				assert.Empty(t, responseCookies(rec)["token"])
+			}
+
+			mockMFAService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoginMFA_LockedOut(t *testing.T) {
+	config := DefaultLoginLimiterConfig()
+	config.MaxAccountFailures = 2
+	limiter := NewMemoryLoginLimiter(config)
+
+	user := &models.UserData{ID: "user1", Email: "staff@example.com", Role: models.RoleStaff}
+	mfaToken, _, err := issueMFAPendingToken(user)
+	assert.NoError(t, err)
+
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, mockMFAService, NewMemoryRevocationStore(), limiter)
+
+	// Mockの設定
+	// 許容回数までの失敗のみサービスが呼び出される
+	mockMFAService.On("Verify", "user1", "000000").Return(errors.New("invalid code")).Times(config.MaxAccountFailures)
+
+	for i := 0; i < config.MaxAccountFailures; i++ {
+		c, rec := newLoginMFAContext(mfaToken, "000000")
+		if assert.NoError(t, handler.LoginMFA(c)) {
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		}
+	}
+
+	// テスト実行
+	c, rec := newLoginMFAContext(mfaToken, "000000")
+	if assert.NoError(t, handler.LoginMFA(c)) {
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	}
+
+	mockMFAService.AssertExpectations(t)
+}
+
+func TestEnrollMFA(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/mfa/enroll", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(ClaimsContextKey, &Claims{UserID: "user1", Email: "staff@example.com", Role: models.RoleStaff})
+
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	mockMFAService.On("Enroll", "user1", "staff@example.com").Return(&models.MFAEnrollment{
+		Secret:          "SECRET",
+		ProvisioningURI: "otpauth://totp/Test:staff@example.com?secret=SECRET",
+		QRCode:          "data:image/png;base64,AAAA",
+	}, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.EnrollMFA(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "otpauth://totp/")
+	}
+
+	mockMFAService.AssertExpectations(t)
+}
+
+func TestConfirmMFA(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/mfa/confirm", bytes.NewBufferString(`{"code":"123456"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set(ClaimsContextKey, &Claims{UserID: "user1", Email: "staff@example.com", Role: models.RoleStaff})
+
+	mockMFAService := new(services_mfa.MockMFAService)
+	handler := NewAuthHandler(new(services_users.MockUserService), new(services_refresh_tokens.MockRefreshTokenService), nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+	// Mockの設定
+	mockMFAService.On("Confirm", "user1", "123456").Return([]string{"abcde-fghij"}, nil)
+
+	// テスト実行
+	if assert.NoError(t, handler.ConfirmMFA(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "abcde-fghij")
+	}
+
+	mockMFAService.AssertExpectations(t)
+}
@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"backend/models"
+	services_api_keys "backend/services/api_keys"
+	"backend/utils"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+// echo.Contextに認証済みのClaimsを格納するキー
+const ClaimsContextKey = "auth_claims"
+
+// APIキーを指定するヘッダー
+const APIKeyHeader = "X-API-Key"
+
+// JWT認証ミドルウェアの設定
+type JWTConfig struct {
+	// ミドルウェアをスキップするかどうかを判定する
+	Skipper middleware.Skipper
+	// トークンの失効状態を確認するストア。nilの場合は確認しない。
+	RevocationStore RevocationStore
+	// APIキーを検証するサービス。nilの場合はAPIキーでの認証を受け付けない。
+	APIKeyService services_api_keys.APIKeyService
+}
+
+// JWT認証ミドルウェアのデフォルト設定
+var DefaultJWTConfig = JWTConfig{
+	Skipper: middleware.DefaultSkipper,
+}
+
+// JWT認証ミドルウェア
+// クッキーまたはAuthorizationヘッダーのトークンを検証し、Claimsをコンテキストに格納する。
+// APIキーが設定されている場合は、X-API-Keyヘッダーのキーでも認証できる。
+func JWT() echo.MiddlewareFunc {
+	return JWTWithConfig(DefaultJWTConfig)
+}
+
+// 設定を指定してJWT認証ミドルウェアを生成する
+func JWTWithConfig(config JWTConfig) echo.MiddlewareFunc {
+	if config.Skipper == nil {
+		config.Skipper = DefaultJWTConfig.Skipper
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			if config.Skipper(c) {
+				return next(c)
+			}
+
+			// APIキーでの認証
+			if key := c.Request().Header.Get(APIKeyHeader); key != "" && config.APIKeyService != nil {
+				claims, err := authenticateAPIKey(config.APIKeyService, key)
+				if err != nil {
+					utils.LogError(c, "API key authentication failed: "+err.Error())
+					if err.Error() == "invalid api key" {
+						return c.JSON(http.StatusUnauthorized, map[string]string{
+							"error": "Invalid API key",
+						})
+					}
+					return c.JSON(http.StatusServiceUnavailable, map[string]string{
+						"error": "Authentication service unavailable",
+					})
+				}
+
+				c.Set(ClaimsContextKey, claims)
+				return next(c)
+			}
+
+			// リクエストからトークンを取得
+			tokenString, err := extractToken(c)
+			if err != nil {
+				utils.LogError(c, "Token not found: "+err.Error())
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+
+			// トークンを検証
+			claims, err := ParseToken(tokenString)
+			if err != nil {
+				utils.LogError(c, "Invalid token: "+err.Error())
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid token",
+				})
+			}
+
+			// 失効済みのトークンを拒否
+			revoked, err := isTokenRevoked(config.RevocationStore, claims)
+			if err != nil {
+				utils.LogError(c, "Failed to check token revocation: "+err.Error())
+				return c.JSON(http.StatusServiceUnavailable, map[string]string{
+					"error": "Authentication service unavailable",
+				})
+			}
+			if revoked {
+				utils.LogError(c, "Token has been revoked")
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Token revoked",
+				})
+			}
+
+			c.Set(ClaimsContextKey, claims)
+			return next(c)
+		}
+	}
+}
+
+// コンテキストから認証済みのClaimsを取得する。
+// JWTミドルウェアを通過していない場合はfalseを返す。
+func GetClaims(c echo.Context) (*Claims, bool) {
+	claims, ok := c.Get(ClaimsContextKey).(*Claims)
+	return claims, ok && claims != nil
+}
+
+// APIキーを検証し、所有するユーザーの現在のロールとキーのスコープを持つClaimsを返す
+func authenticateAPIKey(service services_api_keys.APIKeyService, key string) (*Claims, error) {
+	apiKey, user, err := service.Authenticate(key)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &Claims{
+		UserID:   user.ID,
+		Email:    user.Email,
+		Username: user.Name,
+		Role:     user.Role,
+		APIKeyID: apiKey.ID,
+		Scopes:   apiKey.Scopes,
+	}
+	if claims.Role == "" {
+		claims.Role = models.RoleCustomer
+	}
+	return claims, nil
+}
+
+// ストアが設定されている場合に、トークンが失効しているかを確認する
+// 確認できない場合は安全側に倒してエラーを返す。
+func isTokenRevoked(store RevocationStore, claims *Claims) (bool, error) {
+	if store == nil {
+		return false, nil
+	}
+	return store.IsRevoked(claims)
+}
+
+// JWTアクセストークンを検証し、Claimsを返す。
+// kidに対応する鍵がない場合や、署名アルゴリズムが鍵と一致しない場合、有効期限切れの場合はエラーを返す。
+func ParseToken(tokenString string) (*Claims, error) {
+	claims, err := parseClaims(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	// 二要素認証待ちのトークンなど、用途が限定されたトークンはアクセストークンとして扱わない
+	if claims.Purpose != "" {
+		return nil, errors.New("unexpected token purpose")
+	}
+
+	// ロールを含まないトークンは最小権限のcustomerとして扱う
+	if claims.Role == "" {
+		claims.Role = models.RoleCustomer
+	}
+
+	return claims, nil
+}
+
+// JWTトークンの署名と有効期限を検証し、Claimsを返す。
+func parseClaims(tokenString string) (*Claims, error) {
+	if keyring == nil {
+		return nil, errors.New("keyring is not initialized")
+	}
+
+	claims := &Claims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, keyring.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+
+	return claims, nil
+}
+
+// リクエストからJWTトークンを取得する。
+// Authorizationヘッダー(Bearer)を優先し、なければtokenクッキーを使用する。
+func extractToken(c echo.Context) (string, error) {
+	if header := c.Request().Header.Get(echo.HeaderAuthorization); header != "" {
+		scheme, tokenString, found := strings.Cut(header, " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(tokenString) == "" {
+			return "", errors.New("malformed authorization header")
+		}
+		return strings.TrimSpace(tokenString), nil
+	}
+
+	cookie, err := c.Cookie("token")
+	if err != nil || cookie.Value == "" {
+		return "", errors.New("token not found in cookies or authorization header")
+	}
+	return cookie.Value, nil
+}
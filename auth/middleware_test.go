@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"backend/models"
+	services_api_keys "backend/services/api_keys"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のトークンを生成
+func newTestToken(t *testing.T, method jwt.SigningMethod, key interface{}, expiresAt time.Time) string {
+	token := jwt.NewWithClaims(method, &Claims{
+		UserID: "user1",
+		Email:  "test@example.com",
+		StandardClaims: jwt.StandardClaims{
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	tokenString, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+// ミドルウェアを通してハンドラーを実行し、レスポンスとClaimsを返す
+func runJWTMiddleware(req *http.Request) (*httptest.ResponseRecorder, *Claims) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	var got *Claims
+	handler := JWT()(func(c echo.Context) error {
+		got, _ = GetClaims(c)
+		return c.String(http.StatusOK, "ok")
+	})
+	handler(c)
+
+	return rec, got
+}
+
+func TestJWT_Cookie(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.AddCookie(&http.Cookie{
+		Name:  "token",
+		Value: newTestToken(t, jwt.SigningMethodHS256, testSecret, time.Now().Add(time.Hour)),
+	})
+
+	rec, claims := runJWTMiddleware(req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, claims) {
+		assert.Equal(t, "user1", claims.UserID)
+		assert.Equal(t, "test@example.com", claims.Email)
+	}
+}
+
+func TestJWT_BearerHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+newTestToken(t, jwt.SigningMethodHS256, testSecret, time.Now().Add(time.Hour)))
+
+	rec, claims := runJWTMiddleware(req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	if assert.NotNil(t, claims) {
+		assert.Equal(t, "user1", claims.UserID)
+	}
+}
+
+func TestJWT_Unauthorized(t *testing.T) {
+	// 1. トークンがない場合
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	rec, claims := runJWTMiddleware(req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Unauthorized")
+	assert.Nil(t, claims)
+
+	// 2. Authorizationヘッダーの形式が不正な場合
+	req = httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Basic dXNlcjpwYXNz")
+	rec, _ = runJWTMiddleware(req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Unauthorized")
+}
+
+func TestJWT_InvalidToken(t *testing.T) {
+	cases := map[string]string{
+		"expired":        newTestToken(t, jwt.SigningMethodHS256, testSecret, time.Now().Add(-time.Minute)),
+		"wrong key":      newTestToken(t, jwt.SigningMethodHS256, []byte("another-secret"), time.Now().Add(time.Hour)),
+		"none algorithm": newTestToken(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, time.Now().Add(time.Hour)),
+		"malformed":      "not-a-jwt",
+	}
+
+	for name, tokenString := range cases {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+			req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokenString)
+
+			rec, claims := runJWTMiddleware(req)
+
+			assert.Equal(t, http.StatusUnauthorized, rec.Code)
+			assert.Contains(t, rec.Body.String(), "Invalid token")
+			assert.Nil(t, claims)
+		})
+	}
+}
+
+func TestGetClaims_WithoutMiddleware(t *testing.T) {
+	e := echo.New()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), httptest.NewRecorder())
+
+	claims, ok := GetClaims(c)
+	assert.False(t, ok)
+	assert.Nil(t, claims)
+}
+
+func TestJWT_RevokedToken(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	tokenString, _ := newSessionToken(t, "jti-1", time.Now())
+	store.RevokeToken("jti-1", time.Now().Add(time.Hour))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set(echo.HeaderAuthorization, "Bearer "+tokenString)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	called := false
+	handler := JWTWithConfig(JWTConfig{RevocationStore: store})(func(c echo.Context) error {
+		called = true
+		return c.String(http.StatusOK, "ok")
+	})
+	handler(c)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Token revoked")
+	assert.False(t, called)
+}
+
+func TestJWT_APIKey(t *testing.T) {
+	apiKeyService := new(services_api_keys.MockAPIKeyService)
+	apiKeyService.On("Authenticate", "bnk_valid.secret").Return(
+		&models.APIKeyData{ID: "key1", UserId: "kiosk1", Scopes: []string{models.PermissionReservationsWrite}},
+		&models.UserData{ID: "kiosk1", Email: "kiosk@example.com", Role: models.RoleStaff},
+		nil,
+	)
+	apiKeyService.On("Authenticate", "bnk_invalid.secret").Return(nil, nil, errors.New("invalid api key"))
+	apiKeyService.On("Authenticate", "bnk_error.secret").Return(nil, nil, errors.New("failed to authenticate api key"))
+
+	cases := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{"valid key", "bnk_valid.secret", http.StatusOK},
+		{"invalid key", "bnk_invalid.secret", http.StatusUnauthorized},
+		{"service error", "bnk_error.secret", http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/reservations", nil)
+			req.Header.Set(APIKeyHeader, tc.key)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var got *Claims
+			handler := JWTWithConfig(JWTConfig{APIKeyService: apiKeyService})(func(c echo.Context) error {
+				got, _ = GetClaims(c)
+				return c.String(http.StatusOK, "ok")
+			})
+			handler(c)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantStatus == http.StatusOK && assert.NotNil(t, got) {
+				assert.Equal(t, "kiosk1", got.UserID)
+				assert.Equal(t, models.RoleStaff, got.Role)
+				assert.True(t, got.IsAPIKey())
+				assert.True(t, got.HasPermission(models.PermissionReservationsWrite))
+				assert.False(t, got.HasPermission(models.PermissionReservationsManage))
+			}
+		})
+	}
+}
+
+func TestJWT_APIKeyDisabled(t *testing.T) {
+	// APIキーサービスが設定されていない場合は、APIキーのみのリクエストを拒否する
+	req := httptest.NewRequest(http.MethodGet, "/api/reservations", nil)
+	req.Header.Set(APIKeyHeader, "bnk_valid.secret")
+
+	rec, claims := runJWTMiddleware(req)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Nil(t, claims)
+}
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"backend/models"
+	services_identities "backend/services/identities"
+	"backend/utils"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// 認可要求の状態を保持するクッキー名
+	oidcStateCookieName = "oidc_state"
+	// 認可要求の状態を保持するクッキーのパス
+	oidcStateCookiePath = "/api/auth/oidc"
+	// 認可要求からコールバックまでの有効期間
+	OIDCStateTTL = 10 * time.Minute
+
+	// 認可要求の状態を表すトークンの用途
+	TokenPurposeOIDCState = "oidc_state"
+)
+
+// 認可要求の状態
+// サーバーに保存せず、署名したトークンとしてクッキーに保持する。
+// アクセストークンとして扱われないよう、用途を設定する。
+type oidcStateClaims struct {
+	Purpose      string `json:"purpose"`
+	Provider     string `json:"provider"`
+	State        string `json:"state"`
+	Nonce        string `json:"nonce"`
+	CodeVerifier string `json:"code_verifier"`
+	jwt.StandardClaims
+}
+
+// OIDCによるソーシャルログインのハンドラー
+// セッションの発行と二要素認証の確認はAuthHandlerと共通の処理を使用する。
+type OIDCHandler struct {
+	AuthHandler     *AuthHandler
+	IdentityService services_identities.IdentityService
+	Providers       map[string]*OIDCProvider
+	RedirectURL     string // ログイン後に遷移するフロントエンドのURL
+}
+
+// コンストラクタ
+func NewOIDCHandler(authHandler *AuthHandler, identityService services_identities.IdentityService, providers map[string]*OIDCProvider, redirectURL string) *OIDCHandler {
+	return &OIDCHandler{
+		AuthHandler:     authHandler,
+		IdentityService: identityService,
+		Providers:       providers,
+		RedirectURL:     redirectURL,
+	}
+}
+
+// ソーシャルログインの開始エンドポイント
+// state, nonce, PKCEのcode_verifierを生成してクッキーに保持し、プロバイダーの認可画面にリダイレクトする。
+func (h *OIDCHandler) Login(c echo.Context) error {
+	utils.LogInfo(c, "Starting OIDC login...")
+
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		utils.LogError(c, "Unknown OIDC provider: "+c.Param("provider"))
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Unknown provider"})
+	}
+
+	state, err := newOIDCState(provider.Config.Name)
+	if err != nil {
+		utils.LogError(c, "Failed to generate OIDC state: "+err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to start login"})
+	}
+
+	authURL, err := provider.AuthCodeURL(state.State, state.Nonce, state.CodeVerifier)
+	if err != nil {
+		utils.LogError(c, "Failed to build authorization URL: "+err.Error())
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Provider is unavailable"})
+	}
+
+	if err := setOIDCStateCookie(c, state); err != nil {
+		utils.LogError(c, "Failed to sign OIDC state: "+err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to start login"})
+	}
+
+	utils.LogInfo(c, "Redirecting to OIDC provider: "+provider.Config.Name)
+	return c.Redirect(http.StatusFound, authURL)
+}
+
+// ソーシャルログインのコールバックエンドポイント
+// stateとnonceを検証してIDトークンを取得し、紐付くユーザーのセッションを発行する。
+func (h *OIDCHandler) Callback(c echo.Context) error {
+	utils.LogInfo(c, "Handling OIDC callback...")
+
+	provider, ok := h.Providers[c.Param("provider")]
+	if !ok {
+		utils.LogError(c, "Unknown OIDC provider: "+c.Param("provider"))
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Unknown provider"})
+	}
+
+	// 認可要求の状態は一度しか使用できない
+	state, err := readOIDCStateCookie(c)
+	clearOIDCStateCookie(c)
+	if err != nil {
+		utils.LogError(c, "Invalid OIDC state cookie: "+err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired login request"})
+	}
+	if state.Provider != provider.Config.Name || subtle.ConstantTimeCompare([]byte(state.State), []byte(c.QueryParam("state"))) != 1 {
+		utils.LogError(c, "OIDC state mismatch")
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid or expired login request"})
+	}
+
+	// ユーザーが認可を拒否した場合など
+	if errorCode := c.QueryParam("error"); errorCode != "" {
+		utils.LogError(c, "OIDC provider returned error: "+errorCode)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authorization was denied"})
+	}
+
+	code := c.QueryParam("code")
+	if code == "" {
+		utils.LogError(c, "Authorization code not found")
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Authorization code is required"})
+	}
+
+	claims, err := provider.Exchange(code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		utils.LogError(c, "Failed to exchange authorization code: "+err.Error())
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Failed to authenticate with provider"})
+	}
+
+	user, err := h.IdentityService.ResolveUser(&models.ExternalIdentity{
+		Provider:      provider.Config.Name,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.IsEmailVerified(),
+		Name:          claims.Name,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "email not verified", "invalid email format":
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "A verified email address is required"})
+		case "account email not verified":
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Verify your email address before linking this account"})
+		default:
+			utils.LogError(c, "Failed to resolve user: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log in"})
+		}
+	}
+	utils.LogInfo(c, "User authenticated with OIDC successfully:"+user.Email)
+
+	// 二要素認証が有効な場合は、パスワードでのログインと同様にコードの確認を待つ
+	mfaEnabled, err := h.AuthHandler.isMFAEnabled(user.ID)
+	if err != nil {
+		utils.LogError(c, "Failed to check MFA settings: "+err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to log in"})
+	}
+	if mfaEnabled {
+		mfaToken, _, err := issueMFAPendingToken(user)
+		if err != nil {
+			utils.LogError(c, "Could not create MFA token: "+err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Could not create token"})
+		}
+
+		// サーバーのログやRefererに残らないよう、フラグメントで渡す
+		utils.LogInfo(c, "MFA required for user: "+user.ID)
+		return c.Redirect(http.StatusFound, h.RedirectURL+"#"+url.Values{"mfa_token": {mfaToken}}.Encode())
+	}
+
+	if err := h.AuthHandler.issueSession(c, user); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"message": "Could not create token"})
+	}
+
+	return c.Redirect(http.StatusFound, h.RedirectURL)
+}
+
+// 認可要求の状態を生成する
+func newOIDCState(provider string) (*oidcStateClaims, error) {
+	state, err := utils.GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := utils.GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+	// RFC 7636で43文字以上が求められる
+	codeVerifier, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &oidcStateClaims{
+		Purpose:      TokenPurposeOIDCState,
+		Provider:     provider,
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: codeVerifier,
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(OIDCStateTTL).Unix(),
+		},
+	}, nil
+}
+
+// 認可要求の状態に署名し、HTTP-onlyクッキーにセットする
+// プロバイダーからのリダイレクトでも送信されるよう、設定にかかわらずSameSite=Laxとする。
+func setOIDCStateCookie(c echo.Context, state *oidcStateClaims) error {
+	tokenString, err := SignToken(state)
+	if err != nil {
+		return err
+	}
+
+	cookie := newCookie(oidcStateCookieName, tokenString, oidcStateCookiePath, time.Unix(state.ExpiresAt, 0))
+	cookie.SameSite = http.SameSiteLaxMode
+	c.SetCookie(cookie)
+	return nil
+}
+
+// クッキーから認可要求の状態を読み込み、署名と有効期限を検証する
+func readOIDCStateCookie(c echo.Context) (*oidcStateClaims, error) {
+	cookie, err := c.Cookie(oidcStateCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil, errors.New("state cookie not found")
+	}
+	if keyring == nil {
+		return nil, errors.New("keyring is not initialized")
+	}
+
+	state := &oidcStateClaims{}
+	token, err := jwt.ParseWithClaims(cookie.Value, state, keyring.Keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid || state.Purpose != TokenPurposeOIDCState || state.State == "" || state.Nonce == "" || state.CodeVerifier == "" {
+		return nil, errors.New("invalid state cookie")
+	}
+	return state, nil
+}
+
+// 認可要求の状態を保持するクッキーを削除する
+func clearOIDCStateCookie(c echo.Context) {
+	cookie := newCookie(oidcStateCookieName, "", oidcStateCookiePath, time.Unix(0, 0)) // 有効期限を過去に設定して削除
+	cookie.SameSite = http.SameSiteLaxMode
+	c.SetCookie(cookie)
+}
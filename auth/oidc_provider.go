@@ -0,0 +1,493 @@
+package auth
+
+import (
+	"backend/utils"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const (
+	// JWKSを再取得するまでの最短間隔
+	// 未知のkidを含むトークンが大量に送られても、プロバイダーに問い合わせが集中しないようにする。
+	oidcKeysRefreshInterval = time.Minute
+	// IDトークンのiatと有効期限の検証で許容する時刻のずれ
+	oidcClockSkew = time.Minute
+)
+
+// OIDCプロバイダーの設定
+type OIDCProviderConfig struct {
+	Name         string   // プロバイダー名(google, lineなど)。URLとアカウントの紐付けに使用する。
+	IssuerURL    string   // 発行者のURL。/.well-known/openid-configuration から各エンドポイントを取得する。
+	ClientID     string   // クライアントID
+	ClientSecret string   // クライアントシークレット
+	RedirectURL  string   // 認可後のコールバックURL
+	Scopes       []string // 要求するスコープ
+	// email_verifiedを返さないプロバイダーで、メールアドレスを確認済みとして扱うかどうか
+	TrustEmail bool
+}
+
+// ディスカバリードキュメントのうち、使用する項目
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// トークンエンドポイントのレスポンス
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+}
+
+// IDトークンのaudクレーム
+// 文字列と配列のどちらの形式も受け付ける。
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+func (a oidcAudience) contains(value string) bool {
+	for _, aud := range a {
+		if aud == value {
+			return true
+		}
+	}
+	return false
+}
+
+// IDトークンのクレーム
+type OIDCIDTokenClaims struct {
+	Issuer          string       `json:"iss"`
+	Subject         string       `json:"sub"`
+	Audience        oidcAudience `json:"aud"`
+	AuthorizedParty string       `json:"azp,omitempty"`
+	ExpiresAt       int64        `json:"exp"`
+	IssuedAt        int64        `json:"iat"`
+	Nonce           string       `json:"nonce"`
+	Email           string       `json:"email"`
+	EmailVerified   interface{}  `json:"email_verified"` // プロバイダーによって真偽値または文字列で返される
+	Name            string       `json:"name"`
+}
+
+// 署名以外の検証はVerifyIDTokenで行う
+func (c *OIDCIDTokenClaims) Valid() error {
+	return nil
+}
+
+// メールアドレスが確認済みかどうかを返す
+func (c *OIDCIDTokenClaims) IsEmailVerified() bool {
+	switch verified := c.EmailVerified.(type) {
+	case bool:
+		return verified
+	case string:
+		return strings.EqualFold(verified, "true")
+	}
+	return false
+}
+
+// OIDCのRelying Party
+// 認可コードフロー(PKCE)でIDトークンを取得し、署名とクレームを検証する。
+type OIDCProvider struct {
+	Config     OIDCProviderConfig
+	HTTPClient *http.Client
+
+	mutex         sync.Mutex
+	discovery     *oidcDiscovery
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// OIDCプロバイダーを生成する
+// エンドポイントは初回の使用時に取得するため、起動時にプロバイダーへ接続できなくてもよい。
+func NewOIDCProvider(config OIDCProviderConfig, httpClient *http.Client) *OIDCProvider {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+	config.IssuerURL = strings.TrimSuffix(config.IssuerURL, "/")
+
+	return &OIDCProvider{Config: config, HTTPClient: httpClient}
+}
+
+// 認可エンドポイントのURLを返す
+// codeVerifierからS256のcode_challengeを生成して付与する。
+func (p *OIDCProvider) AuthCodeURL(state, nonce, codeVerifier string) (string, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return "", err
+	}
+
+	authURL, err := url.Parse(discovery.AuthorizationEndpoint)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := sha256.Sum256([]byte(codeVerifier))
+	query := authURL.Query()
+	query.Set("response_type", "code")
+	query.Set("client_id", p.Config.ClientID)
+	query.Set("redirect_uri", p.Config.RedirectURL)
+	query.Set("scope", strings.Join(p.Config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	query.Set("code_challenge_method", "S256")
+	authURL.RawQuery = query.Encode()
+
+	return authURL.String(), nil
+}
+
+// 認可コードをトークンと交換し、検証済みのIDトークンのクレームを返す
+func (p *OIDCProvider) Exchange(code, codeVerifier, nonce string) (*OIDCIDTokenClaims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.Config.RedirectURL)
+	form.Set("client_id", p.Config.ClientID)
+	form.Set("code_verifier", codeVerifier)
+	if p.Config.ClientSecret != "" {
+		form.Set("client_secret", p.Config.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tokenResponse oidcTokenResponse
+	status, err := p.doJSON(req, &tokenResponse)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned %d: %s", status, tokenResponse.Error)
+	}
+	if tokenResponse.IDToken == "" {
+		return nil, errors.New("id_token not found in token response")
+	}
+
+	return p.VerifyIDToken(tokenResponse.IDToken, nonce)
+}
+
+// IDトークンの署名とクレームを検証する
+// iss, aud, azp, exp, iatに加え、認可要求時のnonceと一致することを確認する。
+func (p *OIDCProvider) VerifyIDToken(rawIDToken, nonce string) (*OIDCIDTokenClaims, error) {
+	discovery, err := p.discover()
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &OIDCIDTokenClaims{}
+	token, err := jwt.ParseWithClaims(rawIDToken, claims, p.keyfunc)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, errors.New("invalid id token")
+	}
+
+	now := time.Now()
+	if claims.Issuer != discovery.Issuer {
+		return nil, fmt.Errorf("unexpected issuer: %s", claims.Issuer)
+	}
+	if !claims.Audience.contains(p.Config.ClientID) {
+		return nil, errors.New("id token is not issued for this client")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.Config.ClientID {
+		return nil, errors.New("unexpected authorized party")
+	}
+	if claims.ExpiresAt == 0 || now.After(time.Unix(claims.ExpiresAt, 0).Add(oidcClockSkew)) {
+		return nil, errors.New("id token expired")
+	}
+	if claims.IssuedAt == 0 || now.Add(oidcClockSkew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return nil, errors.New("id token issued in the future")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("sub not found in id token")
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, errors.New("nonce mismatch")
+	}
+
+	if p.Config.TrustEmail && claims.Email != "" && claims.EmailVerified == nil {
+		claims.EmailVerified = true
+	}
+
+	return claims, nil
+}
+
+// IDトークンの検証に使用する鍵を返す
+// 非対称鍵はJWKSから取得し、HS256の場合はクライアントシークレットを使用する。
+func (p *OIDCProvider) keyfunc(token *jwt.Token) (interface{}, error) {
+	switch token.Method.(type) {
+	case *jwt.SigningMethodHMAC:
+		// OIDCの仕様では、HS256のIDトークンはクライアントシークレットで署名される
+		if token.Method != jwt.SigningMethodHS256 || p.Config.ClientSecret == "" {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(p.Config.ClientSecret), nil
+
+	case *jwt.SigningMethodRSA, *jwt.SigningMethodECDSA:
+		kid, _ := token.Header["kid"].(string)
+		key, err := p.publicKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// アルゴリズムの差し替えによる改ざんを防ぐ
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+		case *ecdsa.PublicKey:
+			if _, ok := token.Method.(*jwt.SigningMethodECDSA); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+		}
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+}
+
+// kidに対応する公開鍵を返す
+// 見つからない場合は、鍵のローテーションに追従するためJWKSを再取得する。
+func (p *OIDCProvider) publicKey(kid string) (interface{}, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	if !p.keysFetchedAt.IsZero() && time.Since(p.keysFetchedAt) < oidcKeysRefreshInterval {
+		return nil, fmt.Errorf("unknown kid: %s", kid)
+	}
+
+	if err := p.fetchKeys(); err != nil {
+		return nil, err
+	}
+	if key := p.lookupKey(kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown kid: %s", kid)
+}
+
+// 取得済みの鍵からkidに対応する鍵を探す
+// kidが指定されていない場合は、鍵が1つだけのときに限りその鍵を使用する。
+func (p *OIDCProvider) lookupKey(kid string) interface{} {
+	if kid == "" {
+		if len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key
+			}
+		}
+		return nil
+	}
+	return p.keys[kid]
+}
+
+// JWKSを取得する。呼び出し元でmutexをロックすること。
+func (p *OIDCProvider) fetchKeys() error {
+	if p.discovery == nil {
+		return errors.New("provider is not discovered")
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.discovery.JWKSURI, nil)
+	if err != nil {
+		return err
+	}
+
+	var set JWKSet
+	status, err := p.doJSON(req, &set)
+	if err != nil {
+		return err
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("jwks endpoint returned %d", status)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// 未対応の鍵は無視する
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	p.keys = keys
+	p.keysFetchedAt = time.Now()
+	return nil
+}
+
+// ディスカバリードキュメントを取得する
+// 一度取得した内容はプロセスの終了まで使用する。
+func (p *OIDCProvider) discover() (*oidcDiscovery, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, p.Config.IssuerURL+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovery oidcDiscovery
+	status, err := p.doJSON(req, &discovery)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("discovery endpoint returned %d", status)
+	}
+
+	// 別の発行者のドキュメントを返すプロバイダーは信頼しない
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Config.IssuerURL {
+		return nil, fmt.Errorf("issuer mismatch: %s", discovery.Issuer)
+	}
+	if discovery.AuthorizationEndpoint == "" || discovery.TokenEndpoint == "" || discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing required endpoints")
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// リクエストを送信し、JSONのレスポンスをデコードしてステータスコードを返す
+func (p *OIDCProvider) doJSON(req *http.Request, v interface{}) (int, error) {
+	res, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return res.StatusCode, err
+	}
+	if err := json.Unmarshal(body, v); err != nil && res.StatusCode == http.StatusOK {
+		return res.StatusCode, err
+	}
+	return res.StatusCode, nil
+}
+
+// JWKから公開鍵を生成する
+func (k JWK) publicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve: %s", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported key type: %s", k.KeyType)
+}
+
+// 環境変数からOIDCプロバイダーを読み込む
+// OIDC_PROVIDERSにカンマ区切りでプロバイダー名を指定し、
+// OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID, OIDC_<NAME>_CLIENT_SECRET, OIDC_<NAME>_REDIRECT_URLで各設定を行う。
+func LoadOIDCProviders() (map[string]*OIDCProvider, error) {
+	providers := map[string]*OIDCProvider{}
+
+	for _, name := range strings.Split(os.Getenv("OIDC_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		config := OIDCProviderConfig{
+			Name:         name,
+			IssuerURL:    os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+			Scopes:       strings.Fields(strings.ReplaceAll(os.Getenv(prefix+"SCOPES"), ",", " ")),
+			TrustEmail:   utils.GetEnvBool(prefix+"TRUST_EMAIL", false),
+		}
+		if config.IssuerURL == "" || config.ClientID == "" || config.RedirectURL == "" {
+			return nil, fmt.Errorf("%sISSUER, %sCLIENT_ID and %sREDIRECT_URL must be set", prefix, prefix, prefix)
+		}
+
+		providers[name] = NewOIDCProvider(config, nil)
+	}
+
+	return providers, nil
+}
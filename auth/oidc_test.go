@@ -0,0 +1,344 @@
+package auth
+
+import (
+	"backend/models"
+	services_identities "backend/services/identities"
+	services_mfa "backend/services/mfa"
+	services_refresh_tokens "backend/services/refresh_tokens"
+	services_users "backend/services/users"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// テスト用のOIDCプロバイダー
+// ディスカバリー、JWKS、トークンエンドポイントを提供し、PKCEのcode_verifierを検証する。
+type mockOIDCServer struct {
+	*httptest.Server
+	key           *rsa.PrivateKey
+	codeChallenge string                            // 認可要求で受け取ったcode_challenge
+	idTokenClaims func(jwt.MapClaims) jwt.MapClaims // 発行するIDトークンのクレームを変更する
+	nonce         string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	m := &mockOIDCServer{key: key}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 m.URL,
+			"authorization_endpoint": m.URL + "/authorize",
+			"token_endpoint":         m.URL + "/token",
+			"jwks_uri":               m.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(JWKSet{Keys: []JWK{{
+			KeyType:   "RSA",
+			KeyID:     "mock",
+			Use:       "sig",
+			Algorithm: AlgorithmRS256,
+			N:         base64.RawURLEncoding.EncodeToString(key.PublicKey.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.PublicKey.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+		if r.PostForm.Get("code") != "auth-code" || base64.RawURLEncoding.EncodeToString(sum[:]) != m.codeChallenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		now := time.Now()
+		claims := jwt.MapClaims{
+			"iss":            m.URL,
+			"sub":            "google-sub-1",
+			"aud":            []string{"client-id"},
+			"exp":            now.Add(time.Hour).Unix(),
+			"iat":            now.Unix(),
+			"nonce":          m.nonce,
+			"email":          "test@example.com",
+			"email_verified": true,
+			"name":           "Test User",
+		}
+		if m.idTokenClaims != nil {
+			claims = m.idTokenClaims(claims)
+		}
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = "mock"
+		idToken, _ := token.SignedString(key)
+
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": idToken})
+	})
+	m.Server = httptest.NewServer(mux)
+	t.Cleanup(m.Close)
+	return m
+}
+
+// テスト用のハンドラーとモックを生成
+func newTestOIDCHandler(t *testing.T, server *mockOIDCServer) (*OIDCHandler, *services_identities.MockIdentityService, *services_mfa.MockMFAService, *services_refresh_tokens.MockRefreshTokenService) {
+	mockIdentityService := new(services_identities.MockIdentityService)
+	mockMFAService := new(services_mfa.MockMFAService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	authHandler := NewAuthHandler(new(services_users.MockUserService), mockRefreshTokenService, nil, mockMFAService, NewMemoryRevocationStore(), nil)
+
+	provider := NewOIDCProvider(OIDCProviderConfig{
+		Name:         "google",
+		IssuerURL:    server.URL,
+		ClientID:     "client-id",
+		ClientSecret: "client-secret",
+		RedirectURL:  "http://localhost:8080/api/auth/oidc/google/callback",
+	}, server.Client())
+
+	handler := NewOIDCHandler(authHandler, mockIdentityService, map[string]*OIDCProvider{"google": provider}, "http://localhost:3000/")
+	return handler, mockIdentityService, mockMFAService, mockRefreshTokenService
+}
+
+// ログインを開始し、認可要求のパラメータと状態のクッキーを返す
+func startOIDCLogin(t *testing.T, handler *OIDCHandler, server *mockOIDCServer) (url.Values, *http.Cookie) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/google/login", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("provider")
+	c.SetParamValues("google")
+
+	assert.NoError(t, handler.Login(c))
+	assert.Equal(t, http.StatusFound, rec.Code)
+
+	location, err := url.Parse(rec.Header().Get(echo.HeaderLocation))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(location.String(), server.URL+"/authorize"))
+
+	query := location.Query()
+	server.codeChallenge = query.Get("code_challenge")
+	server.nonce = query.Get("nonce")
+
+	var stateCookie *http.Cookie
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == oidcStateCookieName {
+			stateCookie = cookie
+		}
+	}
+	return query, stateCookie
+}
+
+// コールバックのリクエストを作成する
+func newOIDCCallbackContext(provider, rawQuery string, stateCookie *http.Cookie) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oidc/"+provider+"/callback?"+rawQuery, nil)
+	if stateCookie != nil {
+		req.AddCookie(stateCookie)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("provider")
+	c.SetParamValues(provider)
+	return c, rec
+}
+
+func TestOIDCLogin(t *testing.T) {
+	server := newMockOIDCServer(t)
+	handler, _, _, _ := newTestOIDCHandler(t, server)
+
+	// テスト実行
+	query, stateCookie := startOIDCLogin(t, handler, server)
+
+	// 認可コードフローとPKCEのパラメータが付与されていることを確認
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "client-id", query.Get("client_id"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.NotEmpty(t, query.Get("state"))
+	assert.NotEmpty(t, query.Get("nonce"))
+	assert.NotEmpty(t, query.Get("code_challenge"))
+
+	// 状態のクッキーはアクセストークンとして使用できないことを確認
+	if assert.NotNil(t, stateCookie) {
+		assert.True(t, stateCookie.HttpOnly)
+		_, err := ParseToken(stateCookie.Value)
+		assert.Error(t, err)
+	}
+}
+
+func TestOIDCCallback(t *testing.T) {
+	server := newMockOIDCServer(t)
+	handler, mockIdentityService, mockMFAService, mockRefreshTokenService := newTestOIDCHandler(t, server)
+	query, stateCookie := startOIDCLogin(t, handler, server)
+
+	// Mockの設定
+	expectedIdentity := &models.ExternalIdentity{Provider: "google", Subject: "google-sub-1", Email: "test@example.com", EmailVerified: true, Name: "Test User"}
+	mockIdentityService.On("ResolveUser", expectedIdentity).Return(&models.UserData{ID: "user1", Email: "test@example.com", Role: models.RoleCustomer}, nil)
+	mockMFAService.On("IsEnabled", "user1").Return(false, nil)
+	mockRefreshTokenService.On("IssueRefreshToken", "user1").Return(&models.IssuedRefreshToken{Token: "refresh-token", UserId: "user1", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	// テスト実行
+	c, rec := newOIDCCallbackContext("google", url.Values{"code": {"auth-code"}, "state": {query.Get("state")}}.Encode(), stateCookie)
+	if assert.NoError(t, handler.Callback(c)) {
+		assert.Equal(t, http.StatusFound, rec.Code)
+		assert.Equal(t, "http://localhost:3000/", rec.Header().Get(echo.HeaderLocation))
+
+		cookies := responseCookies(rec)
+		assert.NotEmpty(t, cookies["token"])
+		assert.Equal(t, "refresh-token", cookies["refresh_token"])
+		// 状態のクッキーは削除される
+		assert.Empty(t, cookies[oidcStateCookieName])
+	}
+
+	mockIdentityService.AssertExpectations(t)
+	mockMFAService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestOIDCCallback_MFARequired(t *testing.T) {
+	server := newMockOIDCServer(t)
+	handler, mockIdentityService, mockMFAService, mockRefreshTokenService := newTestOIDCHandler(t, server)
+	query, stateCookie := startOIDCLogin(t, handler, server)
+
+	// Mockの設定
+	mockIdentityService.On("ResolveUser", mock.Anything).Return(&models.UserData{ID: "user1", Email: "test@example.com", Role: models.RoleStaff}, nil)
+	mockMFAService.On("IsEnabled", "user1").Return(true, nil)
+
+	// テスト実行
+	c, rec := newOIDCCallbackContext("google", url.Values{"code": {"auth-code"}, "state": {query.Get("state")}}.Encode(), stateCookie)
+	if assert.NoError(t, handler.Callback(c)) {
+		assert.Equal(t, http.StatusFound, rec.Code)
+
+		// セッションは発行せず、確認待ちトークンをフラグメントで渡すことを確認
+		location, err := url.Parse(rec.Header().Get(echo.HeaderLocation))
+		assert.NoError(t, err)
+		fragment, err := url.ParseQuery(location.Fragment)
+		assert.NoError(t, err)
+		_, err = ParseMFAPendingToken(fragment.Get("mfa_token"))
+		assert.NoError(t, err)
+		assert.Empty(t, responseCookies(rec)["token"])
+	}
+
+	mockRefreshTokenService.AssertNotCalled(t, "IssueRefreshToken", "user1")
+}
+
+func TestOIDCCallback_InvalidCases(t *testing.T) {
+	testCases := []struct {
+		name          string
+		provider      string
+		query         func(state string) url.Values
+		withCookie    bool
+		idTokenClaims func(jwt.MapClaims) jwt.MapClaims
+		expectedCode  int
+	}{
+		{
+			name:         "unknown provider",
+			provider:     "unknown",
+			query:        func(state string) url.Values { return url.Values{"code": {"auth-code"}, "state": {state}} },
+			withCookie:   true,
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			name:         "missing state cookie",
+			provider:     "google",
+			query:        func(state string) url.Values { return url.Values{"code": {"auth-code"}, "state": {state}} },
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "state mismatch",
+			provider:     "google",
+			query:        func(state string) url.Values { return url.Values{"code": {"auth-code"}, "state": {"forged"}} },
+			withCookie:   true,
+			expectedCode: http.StatusBadRequest,
+		},
+		{
+			name:         "authorization denied",
+			provider:     "google",
+			query:        func(state string) url.Values { return url.Values{"error": {"access_denied"}, "state": {state}} },
+			withCookie:   true,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:         "invalid code",
+			provider:     "google",
+			query:        func(state string) url.Values { return url.Values{"code": {"other-code"}, "state": {state}} },
+			withCookie:   true,
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "nonce mismatch",
+			provider:   "google",
+			query:      func(state string) url.Values { return url.Values{"code": {"auth-code"}, "state": {state}} },
+			withCookie: true,
+			idTokenClaims: func(claims jwt.MapClaims) jwt.MapClaims {
+				claims["nonce"] = "replayed"
+				return claims
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "wrong audience",
+			provider:   "google",
+			query:      func(state string) url.Values { return url.Values{"code": {"auth-code"}, "state": {state}} },
+			withCookie: true,
+			idTokenClaims: func(claims jwt.MapClaims) jwt.MapClaims {
+				claims["aud"] = "other-client"
+				return claims
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "expired id token",
+			provider:   "google",
+			query:      func(state string) url.Values { return url.Values{"code": {"auth-code"}, "state": {state}} },
+			withCookie: true,
+			idTokenClaims: func(claims jwt.MapClaims) jwt.MapClaims {
+				claims["exp"] = time.Now().Add(-time.Hour).Unix()
+				return claims
+			},
+			expectedCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := newMockOIDCServer(t)
+			server.idTokenClaims = tc.idTokenClaims
+			handler, mockIdentityService, _, _ := newTestOIDCHandler(t, server)
+			query, stateCookie := startOIDCLogin(t, handler, server)
+			if !tc.withCookie {
+				stateCookie = nil
+			}
+
+			// テスト実行
+			c, rec := newOIDCCallbackContext(tc.provider, tc.query(query.Get("state")).Encode(), stateCookie)
+			if assert.NoError(t, handler.Callback(c)) {
+				assert.Equal(t, tc.expectedCode, rec.Code)
+				assert.Empty(t, responseCookies(rec)["token"])
+			}
+
+			mockIdentityService.AssertNotCalled(t, "ResolveUser", mock.Anything)
+		})
+	}
+}
+
+func TestOIDCIDTokenClaims_IsEmailVerified(t *testing.T) {
+	assert.True(t, (&OIDCIDTokenClaims{EmailVerified: true}).IsEmailVerified())
+	assert.True(t, (&OIDCIDTokenClaims{EmailVerified: "true"}).IsEmailVerified())
+	assert.False(t, (&OIDCIDTokenClaims{EmailVerified: false}).IsEmailVerified())
+	assert.False(t, (&OIDCIDTokenClaims{}).IsEmailVerified())
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"backend/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// 権限チェックミドルウェア
+// 指定したすべての権限を持つユーザーのみ後続のハンドラーを実行する。JWTミドルウェアの後に登録する。
+func RequirePermission(permissions ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := GetClaims(c)
+			if !ok {
+				utils.LogError(c, "Claims not found in context")
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+
+			for _, permission := range permissions {
+				if !claims.HasPermission(permission) {
+					utils.LogError(c, "Permission denied: "+permission+" for role "+claims.Role)
+					return c.JSON(http.StatusForbidden, map[string]string{
+						"error": "Forbidden",
+					})
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// コンテキストのユーザーが指定した権限を持つかどうかを判定する
+// ハンドラー内で参照範囲を切り替える場合に使用する。
+func HasPermission(c echo.Context, permission string) bool {
+	claims, ok := GetClaims(c)
+	if !ok {
+		return false
+	}
+	return claims.HasPermission(permission)
+}
+
+// セッション(クッキーまたはアクセストークン)での認証を必須とするミドルウェア
+// ログアウトやパスワード変更、APIキーの管理など、本人の操作に限るエンドポイントでAPIキーを拒否する。
+// JWTミドルウェアの後に登録する。
+func RequireSession() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			claims, ok := GetClaims(c)
+			if !ok {
+				utils.LogError(c, "Claims not found in context")
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+			if claims.IsAPIKey() {
+				utils.LogError(c, "API key is not allowed for this endpoint")
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error": "API keys are not allowed for this endpoint",
+				})
+			}
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"backend/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// 権限チェックミドルウェアを通してハンドラーを実行する
+func runRequirePermission(claims *Claims, permissions ...string) (*httptest.ResponseRecorder, bool) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/users", nil), rec)
+	if claims != nil {
+		c.Set(ClaimsContextKey, claims)
+	}
+
+	called := false
+	handler := RequirePermission(permissions...)(func(c echo.Context) error {
+		called = true
+		return c.String(http.StatusOK, "ok")
+	})
+	handler(c)
+
+	return rec, called
+}
+
+func TestRequirePermission(t *testing.T) {
+	cases := []struct {
+		name       string
+		role       string
+		permission string
+		wantStatus int
+	}{
+		{"customer reads own reservations", models.RoleCustomer, models.PermissionReservationsRead, http.StatusOK},
+		{"customer cannot manage reservations", models.RoleCustomer, models.PermissionReservationsManage, http.StatusForbidden},
+		{"customer cannot manage users", models.RoleCustomer, models.PermissionUsersManage, http.StatusForbidden},
+		{"staff manages reservations", models.RoleStaff, models.PermissionReservationsManage, http.StatusOK},
+		{"staff manages notifications", models.RoleStaff, models.PermissionNotificationsManage, http.StatusOK},
+		{"staff cannot manage users", models.RoleStaff, models.PermissionUsersManage, http.StatusForbidden},
+		{"admin manages users", models.RoleAdmin, models.PermissionUsersManage, http.StatusOK},
+		{"customer cannot manage mfa", models.RoleCustomer, models.PermissionMFAManage, http.StatusForbidden},
+		{"staff manages mfa", models.RoleStaff, models.PermissionMFAManage, http.StatusOK},
+		{"staff cannot manage api keys", models.RoleStaff, models.PermissionAPIKeysManage, http.StatusForbidden},
+		{"admin manages api keys", models.RoleAdmin, models.PermissionAPIKeysManage, http.StatusOK},
+		{"staff cannot manage calendar", models.RoleStaff, models.PermissionCalendarManage, http.StatusForbidden},
+		{"admin manages calendar", models.RoleAdmin, models.PermissionCalendarManage, http.StatusOK},
+		{"unknown role", "owner", models.PermissionReservationsRead, http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec, called := runRequirePermission(&Claims{UserID: "user1", Role: tc.role}, tc.permission)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Equal(t, tc.wantStatus == http.StatusOK, called)
+		})
+	}
+}
+
+func TestRequirePermission_APIKeyScopes(t *testing.T) {
+	// APIキーはロールが持つ権限のうち、スコープに含まれるもののみ使用できる
+	claims := &Claims{UserID: "kiosk1", Role: models.RoleStaff, APIKeyID: "key1", Scopes: []string{models.PermissionReservationsWrite}}
+
+	rec, called := runRequirePermission(claims, models.PermissionReservationsWrite)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, called)
+
+	rec, called = runRequirePermission(claims, models.PermissionReservationsManage)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+
+	// スコープに含まれていても、ロールが持たない権限は使用できない
+	claims.Role = models.RoleCustomer
+	claims.Scopes = []string{models.PermissionReservationsManage}
+	rec, called = runRequirePermission(claims, models.PermissionReservationsManage)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.False(t, called)
+}
+
+func TestRequireSession(t *testing.T) {
+	cases := []struct {
+		name       string
+		claims     *Claims
+		wantStatus int
+	}{
+		{"session", &Claims{UserID: "user1", Role: models.RoleAdmin}, http.StatusOK},
+		{"api key", &Claims{UserID: "user1", Role: models.RoleAdmin, APIKeyID: "key1", Scopes: []string{models.PermissionAPIKeysManage}}, http.StatusForbidden},
+		{"no claims", nil, http.StatusUnauthorized},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/logout/all", nil), rec)
+			if tc.claims != nil {
+				c.Set(ClaimsContextKey, tc.claims)
+			}
+
+			handler := RequireSession()(func(c echo.Context) error {
+				return c.String(http.StatusOK, "ok")
+			})
+			handler(c)
+
+			assert.Equal(t, tc.wantStatus, rec.Code)
+		})
+	}
+}
+
+func TestRequirePermission_Unauthorized(t *testing.T) {
+	rec, called := runRequirePermission(nil, models.PermissionReservationsRead)
+
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.False(t, called)
+}
+
+func TestParseToken_DefaultRole(t *testing.T) {
+	// ロールを含まないトークンはcustomerとして扱う
+	tokenString, _ := newSessionToken(t, "jti-1", time.Now())
+
+	claims, err := ParseToken(tokenString)
+	if assert.NoError(t, err) {
+		assert.Equal(t, models.RoleCustomer, claims.Role)
+	}
+}
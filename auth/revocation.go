@@ -0,0 +1,245 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// アクセストークンの失効状態を管理するストア
+// 個別のトークンはjtiで、ユーザー単位の失効は基準時刻で管理する。
+type RevocationStore interface {
+	// 指定したjtiのトークンを有効期限まで失効させる
+	RevokeToken(jti string, expiresAt time.Time) error
+	// 一度だけ使用できるトークンを使用済みにする。既に使用済みの場合はfalseを返す
+	ConsumeToken(jti string, expiresAt time.Time) (bool, error)
+	// 使用済みにしたトークンを、処理に失敗した場合に再び使用できるようにする
+	RestoreToken(jti string) error
+	// 指定したユーザーに対して、基準時刻より前に発行されたトークンをすべて失効させる
+	RevokeAllForUser(userId string, before time.Time) error
+	// トークンが失効しているかどうかを判定する
+	IsRevoked(claims *Claims) (bool, error)
+}
+
+// トークンが失効しているかを判定する
+// ユーザー単位の基準時刻(Unixミリ秒)が設定されている場合は、それより前に発行されたトークンを失効とみなす。
+// iat_msを持たない以前のトークンはiatの秒の先頭で発行されたものとして扱い、同じ秒に発行されたトークンも失効させる。
+func isRevokedBy(claims *Claims, tokenRevoked bool, userRevokedBefore int64) bool {
+	if tokenRevoked {
+		return true
+	}
+	issuedAt := claims.IssuedAtMs
+	if issuedAt == 0 {
+		issuedAt = claims.IssuedAt * 1000
+	}
+	return userRevokedBefore > 0 && issuedAt < userRevokedBefore
+}
+
+// Redisを使用したRevocationStoreの実装
+// 複数インスタンス間で失効状態を共有する。
+type RedisRevocationStore struct {
+	Client   *redis.Client
+	Prefix   string        // キーの接頭辞
+	TokenTTL time.Duration // アクセストークンの最大有効期間。ユーザー単位の失効情報の保持期間に使用する。
+}
+
+func NewRedisRevocationStore(client *redis.Client, tokenTTL time.Duration) RevocationStore {
+	return &RedisRevocationStore{
+		Client:   client,
+		Prefix:   "auth:revoked:",
+		TokenTTL: tokenTTL,
+	}
+}
+
+func (s *RedisRevocationStore) tokenKey(jti string) string {
+	return s.Prefix + "jti:" + jti
+}
+
+func (s *RedisRevocationStore) userKey(userId string) string {
+	return s.Prefix + "user:" + userId
+}
+
+// 指定したjtiのトークンを失効させる。
+// キーはトークンの有効期限で自動的に削除される。
+func (s *RedisRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return errors.New("jti is required")
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		// 既に有効期限切れのトークンは失効させる必要がない
+		return nil
+	}
+
+	if err := s.Client.Set(context.Background(), s.tokenKey(jti), 1, ttl).Err(); err != nil {
+		log.Printf("Failed to revoke token in Redis: %v", err)
+		return err
+	}
+
+	log.Printf("Token revoked: %s", jti)
+	return nil
+}
+
+// 一度だけ使用できるトークンを使用済みにする。
+// SETNXで判定と記録を同時に行うため、同じトークンで同時に要求された場合も1つだけが成功する。
+func (s *RedisRevocationStore) ConsumeToken(jti string, expiresAt time.Time) (bool, error) {
+	if jti == "" {
+		return false, errors.New("jti is required")
+	}
+
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return false, nil
+	}
+
+	consumed, err := s.Client.SetNX(context.Background(), s.tokenKey(jti), 1, ttl).Result()
+	if err != nil {
+		log.Printf("Failed to consume token in Redis: %v", err)
+		return false, err
+	}
+	return consumed, nil
+}
+
+// 使用済みにしたトークンを再び使用できるようにする
+func (s *RedisRevocationStore) RestoreToken(jti string) error {
+	if err := s.Client.Del(context.Background(), s.tokenKey(jti)).Err(); err != nil {
+		log.Printf("Failed to restore token in Redis: %v", err)
+		return err
+	}
+	return nil
+}
+
+// 指定したユーザーの基準時刻より前のトークンをすべて失効させる。
+// 基準時刻より前に発行されたトークンはTokenTTL経過後にすべて期限切れとなるため、その期間だけ保持する。
+func (s *RedisRevocationStore) RevokeAllForUser(userId string, before time.Time) error {
+	if userId == "" {
+		return errors.New("userId is required")
+	}
+
+	err := s.Client.Set(context.Background(), s.userKey(userId), before.UnixMilli(), s.TokenTTL).Err()
+	if err != nil {
+		log.Printf("Failed to revoke user tokens in Redis: %v", err)
+		return err
+	}
+
+	log.Printf("All tokens revoked for user: %s", userId)
+	return nil
+}
+
+// トークンが失効しているかどうかを判定する
+func (s *RedisRevocationStore) IsRevoked(claims *Claims) (bool, error) {
+	keys := []string{s.userKey(claims.UserID)}
+	if claims.Id != "" {
+		keys = append(keys, s.tokenKey(claims.Id))
+	}
+
+	values, err := s.Client.MGet(context.Background(), keys...).Result()
+	if err != nil {
+		log.Printf("Failed to check token revocation in Redis: %v", err)
+		return false, err
+	}
+
+	var userRevokedBefore int64
+	if value, ok := values[0].(string); ok {
+		userRevokedBefore, _ = strconv.ParseInt(value, 10, 64)
+	}
+	tokenRevoked := len(values) > 1 && values[1] != nil
+
+	return isRevokedBy(claims, tokenRevoked, userRevokedBefore), nil
+}
+
+// メモリ上で失効状態を管理するRevocationStoreの実装
+// 単一インスタンスでの開発やテストで使用する。
+type MemoryRevocationStore struct {
+	mutex  sync.Mutex
+	tokens map[string]time.Time // jti -> 有効期限
+	users  map[string]int64     // ユーザーID -> 基準時刻(Unixミリ秒)
+}
+
+func NewMemoryRevocationStore() RevocationStore {
+	return &MemoryRevocationStore{
+		tokens: make(map[string]time.Time),
+		users:  make(map[string]int64),
+	}
+}
+
+// 指定したjtiのトークンを失効させる
+func (s *MemoryRevocationStore) RevokeToken(jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return errors.New("jti is required")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	// 期限切れのエントリを掃除
+	now := time.Now()
+	for key, expiry := range s.tokens {
+		if now.After(expiry) {
+			delete(s.tokens, key)
+		}
+	}
+
+	s.tokens[jti] = expiresAt
+	return nil
+}
+
+// 一度だけ使用できるトークンを使用済みにする
+func (s *MemoryRevocationStore) ConsumeToken(jti string, expiresAt time.Time) (bool, error) {
+	if jti == "" {
+		return false, errors.New("jti is required")
+	}
+	if !time.Now().Before(expiresAt) {
+		return false, nil
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if expiry, ok := s.tokens[jti]; ok && time.Now().Before(expiry) {
+		return false, nil
+	}
+	s.tokens[jti] = expiresAt
+	return true, nil
+}
+
+// 使用済みにしたトークンを再び使用できるようにする
+func (s *MemoryRevocationStore) RestoreToken(jti string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.tokens, jti)
+	return nil
+}
+
+// 指定したユーザーの基準時刻より前のトークンをすべて失効させる
+func (s *MemoryRevocationStore) RevokeAllForUser(userId string, before time.Time) error {
+	if userId == "" {
+		return errors.New("userId is required")
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.users[userId] = before.UnixMilli()
+	return nil
+}
+
+// トークンが失効しているかどうかを判定する
+func (s *MemoryRevocationStore) IsRevoked(claims *Claims) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	expiry, tokenRevoked := s.tokens[claims.Id]
+	if tokenRevoked && time.Now().After(expiry) {
+		tokenRevoked = false
+	}
+
+	return isRevokedBy(claims, tokenRevoked, s.users[claims.UserID]), nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/golang-jwt/jwt"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のClaimsを生成
+func newRevocationClaims(userId, jti string, issuedAt time.Time) *Claims {
+	return &Claims{
+		UserID:     userId,
+		IssuedAtMs: issuedAt.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			IssuedAt:  issuedAt.Unix(),
+			ExpiresAt: issuedAt.Add(time.Hour).Unix(),
+		},
+	}
+}
+
+// 各ストアに共通する失効の振る舞いを検証する
+func testRevocationStore(t *testing.T, store RevocationStore) {
+	// 同じ秒の前後に発行したトークンを区別するため、秒の途中の時刻を基準にする
+	now := time.Now().Truncate(time.Second).Add(500 * time.Millisecond)
+	oldToken := newRevocationClaims("user1", "jti-old", now.Add(-time.Minute))
+	otherToken := newRevocationClaims("user1", "jti-other", now.Add(-time.Minute))
+	otherUser := newRevocationClaims("user2", "jti-user2", now.Add(-time.Minute))
+
+	// 失効前はすべて有効
+	revoked, err := store.IsRevoked(oldToken)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// jti単位の失効
+	assert.NoError(t, store.RevokeToken("jti-old", now.Add(time.Hour)))
+	revoked, err = store.IsRevoked(oldToken)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+	revoked, err = store.IsRevoked(otherToken)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// ユーザー単位の失効
+	assert.NoError(t, store.RevokeAllForUser("user1", now))
+	revoked, err = store.IsRevoked(otherToken)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 他のユーザーには影響しない
+	revoked, err = store.IsRevoked(otherUser)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// 基準時刻と同じ秒でも、基準時刻より前に発行したトークンは失効する
+	sameSecond := newRevocationClaims("user1", "jti-same-second", now.Add(-100*time.Millisecond))
+	revoked, err = store.IsRevoked(sameSecond)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// iat_msを持たない以前のトークンは、同じ秒に発行されたものも失効する
+	legacy := newRevocationClaims("user1", "jti-legacy", now.Add(100*time.Millisecond))
+	legacy.IssuedAtMs = 0
+	revoked, err = store.IsRevoked(legacy)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 基準時刻以降に発行し直したトークンは有効
+	reissued := newRevocationClaims("user1", "jti-new", now)
+	revoked, err = store.IsRevoked(reissued)
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// 一度だけ使用できるトークン
+	consumed, err := store.ConsumeToken("jti-once", now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+	consumed, err = store.ConsumeToken("jti-once", now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.False(t, consumed)
+
+	// 戻したトークンは再び使用できる
+	assert.NoError(t, store.RestoreToken("jti-once"))
+	consumed, err = store.ConsumeToken("jti-once", now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, consumed)
+
+	// 入力値のバリデーション
+	_, err = store.ConsumeToken("", now.Add(time.Hour))
+	assert.Error(t, err)
+	assert.Error(t, store.RevokeToken("", now.Add(time.Hour)))
+	assert.Error(t, store.RevokeAllForUser("", now))
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	testRevocationStore(t, NewMemoryRevocationStore())
+}
+
+func TestRedisRevocationStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	store := NewRedisRevocationStore(client, time.Hour)
+	testRevocationStore(t, store)
+
+	// 失効情報にはTTLが設定されている
+	assert.True(t, mr.TTL("auth:revoked:jti:jti-old") > 0)
+	assert.Equal(t, time.Hour, mr.TTL("auth:revoked:user:user1"))
+
+	// 有効期限を過ぎると失効情報は削除される
+	mr.FastForward(2 * time.Hour)
+	revoked, err := store.IsRevoked(newRevocationClaims("user1", "jti-old", time.Now().Add(-time.Minute)))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestRedisRevocationStore_Unavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisRevocationStore(client, time.Hour)
+
+	// Redisに接続できない場合はエラーを返す
+	mr.Close()
+	_, err := store.IsRevoked(newRevocationClaims("user1", "jti-1", time.Now()))
+	assert.Error(t, err)
+}
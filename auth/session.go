@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"backend/models"
+	services_refresh_tokens "backend/services/refresh_tokens"
+	"backend/utils"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// アクセストークンを保持するクッキー名
+	accessTokenCookieName = "token"
+	// リフレッシュトークンを保持するクッキー名
+	refreshTokenCookieName = "refresh_token"
+	// アクセストークンを保持するクッキーのパス
+	accessTokenCookiePath = "/"
+	// リフレッシュトークンを保持するクッキーのパス
+	refreshTokenCookiePath = "/api"
+	// アクセストークンの有効期限
+	AccessTokenTTL = 1 * time.Hour
+)
+
+// アクセストークンとリフレッシュトークンを発行し、クッキーにセットする
+func (h *AuthHandler) issueSession(c echo.Context, user *models.UserData) error {
+	if err := setAccessToken(c, user); err != nil {
+		return err
+	}
+
+	issued, err := h.RefreshTokenService.IssueRefreshToken(user.ID)
+	if err != nil {
+		utils.LogError(c, "Could not issue refresh token: "+err.Error())
+		return err
+	}
+	setRefreshToken(c, issued)
+
+	return nil
+}
+
+// ユーザー情報からJWTアクセストークンを作成し、HTTP-onlyクッキーにセットする
+func setAccessToken(c echo.Context, user *models.UserData) error {
+	// 失効管理に使用するトークンID
+	tokenId, err := generateTokenID()
+	if err != nil {
+		utils.LogError(c, "Could not generate token ID: "+err.Error())
+		return err
+	}
+
+	// JWTトークンの作成
+	now := time.Now()
+	expirationTime := now.Add(AccessTokenTTL)
+	claims := &Claims{
+		UserID:     user.ID,
+		Email:      user.Email, // 取得したユーザー情報を使う
+		Username:   user.Name,
+		Role:       user.Role,
+		IssuedAtMs: now.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenId,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expirationTime.Unix(),
+		},
+	}
+	tokenString, err := SignToken(claims)
+	if err != nil {
+		utils.LogError(c, "Could not create JWT token: "+err.Error())
+		return err
+	}
+	utils.LogInfo(c, "JWT token created successfully")
+
+	// HTTP-onlyクッキーにトークンをセット
+	c.SetCookie(newCookie(accessTokenCookieName, tokenString, accessTokenCookiePath, expirationTime))
+
+	utils.LogInfo(c, "JWT token set in HTTP-only cookie")
+	return nil
+}
+
+// リフレッシュトークンをHTTP-onlyクッキーにセットする
+func setRefreshToken(c echo.Context, issued *models.IssuedRefreshToken) {
+	c.SetCookie(newCookie(refreshTokenCookieName, issued.Token, refreshTokenCookiePath, issued.ExpiresAt))
+
+	utils.LogInfo(c, "Refresh token set in HTTP-only cookie")
+}
+
+// アクセストークンとリフレッシュトークンのクッキーを削除する
+func clearSessionCookies(c echo.Context) {
+	// 有効期限を過去に設定して削除
+	c.SetCookie(newCookie(accessTokenCookieName, "", accessTokenCookiePath, time.Unix(0, 0)))
+	c.SetCookie(newCookie(refreshTokenCookieName, "", refreshTokenCookiePath, time.Unix(0, 0)))
+}
+
+// すべてのセッションを失効させる
+// 発行済みのアクセストークンとリフレッシュトークンの両方が対象。
+func (h *AuthHandler) revokeAllSessions(c echo.Context, userId string) error {
+	if err := RevokeUserSessions(h.RevocationStore, h.RefreshTokenService, userId); err != nil {
+		utils.LogError(c, err.Error())
+		return err
+	}
+
+	utils.LogInfo(c, "All sessions revoked for user: "+userId)
+	return nil
+}
+
+// 指定したユーザーの発行済みのアクセストークンとリフレッシュトークンをすべて失効させる
+// ロールの変更など、認証以外の処理で既存のセッションを無効にする場合にも使用する。
+func RevokeUserSessions(store RevocationStore, refreshTokenService services_refresh_tokens.RefreshTokenService, userId string) error {
+	if store != nil {
+		if err := store.RevokeAllForUser(userId, time.Now()); err != nil {
+			return fmt.Errorf("failed to revoke access tokens: %w", err)
+		}
+	}
+
+	if err := refreshTokenService.RevokeAllRefreshTokens(userId); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// トークンIDとして使用するランダムな文字列を生成する
+func generateTokenID() (string, error) {
+	return utils.GenerateRandomString(16)
+}
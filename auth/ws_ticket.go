@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// WebSocket接続用のチケットを表すトークンの用途
+	TokenPurposeWSTicket = "ws_ticket"
+
+	// WebSocket接続用のチケットの有効期間
+	WSTicketTTL = 30 * time.Second
+
+	// WebSocket接続時にチケットを指定するクエリパラメータ
+	WSTicketQueryParam = "ticket"
+)
+
+// WebSocket接続用のチケット
+// 発行元のアクセストークンの情報を保持し、接続後はそのセッションとして扱う。
+type wsTicketClaims struct {
+	Purpose           string `json:"purpose"`
+	UserID            string `json:"user_id"`
+	Email             string `json:"email"`
+	Username          string `json:"username"`
+	Role              string `json:"role"`
+	SessionID         string `json:"sid"`
+	SessionIssuedAt   int64  `json:"session_iat"`
+	SessionIssuedAtMs int64  `json:"session_iat_ms,omitempty"`
+	SessionExpiresAt  int64  `json:"session_exp"`
+	IssuedAtMs        int64  `json:"iat_ms,omitempty"`
+	jwt.StandardClaims
+}
+
+// WebSocket接続用のチケット発行エンドポイント
+// クッキーを送信できないクライアント向けに、一度だけ使用できる短命なチケットを発行する。
+func (h *AuthHandler) IssueWSTicket(c echo.Context) error {
+	utils.LogInfo(c, "Issuing WebSocket ticket...")
+
+	claims, ok := GetClaims(c)
+	if !ok {
+		utils.LogError(c, "Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	ticket, expiresAt, err := issueWSTicket(claims)
+	if err != nil {
+		utils.LogError(c, "Could not create WebSocket ticket: "+err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Could not create ticket"})
+	}
+
+	utils.LogInfo(c, "WebSocket ticket issued for user: "+claims.UserID)
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"ticket":     ticket,
+		"expires_at": expiresAt,
+	})
+}
+
+// WebSocket接続用のチケットを発行する
+func issueWSTicket(session *Claims) (string, time.Time, error) {
+	tokenId, err := generateTokenID()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(WSTicketTTL)
+	// セッションの有効期限を超えて接続できないようにする
+	if session.ExpiresAt > 0 && expiresAt.Unix() > session.ExpiresAt {
+		expiresAt = time.Unix(session.ExpiresAt, 0)
+	}
+
+	claims := &wsTicketClaims{
+		Purpose:           TokenPurposeWSTicket,
+		UserID:            session.UserID,
+		Email:             session.Email,
+		Username:          session.Username,
+		Role:              session.Role,
+		SessionID:         session.Id,
+		SessionIssuedAt:   session.IssuedAt,
+		SessionIssuedAtMs: session.IssuedAtMs,
+		SessionExpiresAt:  session.ExpiresAt,
+		IssuedAtMs:        now.UnixMilli(),
+		StandardClaims: jwt.StandardClaims{
+			Id:        tokenId,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+
+	tokenString, err := SignToken(claims)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return tokenString, expiresAt, nil
+}
+
+// WebSocket接続用のチケットを検証し、発行元のセッションのClaimsを返す。
+func parseWSTicket(tokenString string) (*wsTicketClaims, *Claims, error) {
+	if keyring == nil {
+		return nil, nil, errors.New("keyring is not initialized")
+	}
+
+	ticket := &wsTicketClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, ticket, keyring.Keyfunc)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !token.Valid || ticket.Purpose != TokenPurposeWSTicket || ticket.UserID == "" || ticket.Id == "" {
+		return nil, nil, errors.New("invalid ticket")
+	}
+
+	session := &Claims{
+		UserID:     ticket.UserID,
+		Email:      ticket.Email,
+		Username:   ticket.Username,
+		Role:       ticket.Role,
+		IssuedAtMs: ticket.SessionIssuedAtMs,
+		StandardClaims: jwt.StandardClaims{
+			Id:        ticket.SessionID,
+			IssuedAt:  ticket.SessionIssuedAt,
+			ExpiresAt: ticket.SessionExpiresAt,
+		},
+	}
+	return ticket, session, nil
+}
+
+// WebSocket接続のリクエストを認証し、接続するユーザーのClaimsを返す。
+// ticketクエリパラメータのチケット、またはクッキー(Authorizationヘッダー)のアクセストークンを受け付ける。
+// チケットは一度使用すると失効させる。
+func AuthenticateWebSocket(c echo.Context, store RevocationStore) (*Claims, error) {
+	if ticketString := c.QueryParam(WSTicketQueryParam); ticketString != "" {
+		ticket, claims, err := parseWSTicket(ticketString)
+		if err != nil {
+			return nil, err
+		}
+
+		// 使用済みのチケット、およびユーザー単位で失効させた後のチケットを拒否する
+		ticketClaims := &Claims{UserID: ticket.UserID, IssuedAtMs: ticket.IssuedAtMs, StandardClaims: ticket.StandardClaims}
+		revoked, err := isTokenRevoked(store, ticketClaims)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, errors.New("ticket already used")
+		}
+		// 同じチケットによる同時の接続を1つだけ受け付けるよう、原子的に使用済みにする
+		if store != nil {
+			consumed, err := store.ConsumeToken(ticket.Id, time.Unix(ticket.ExpiresAt, 0))
+			if err != nil {
+				return nil, err
+			}
+			if !consumed {
+				return nil, errors.New("ticket already used")
+			}
+		}
+
+		return checkWSSession(store, claims)
+	}
+
+	tokenString, err := extractToken(c)
+	if err != nil {
+		return nil, err
+	}
+	claims, err := ParseToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	return checkWSSession(store, claims)
+}
+
+// WebSocket接続中のセッションが有効かどうかを確認する
+// 接続を維持している間、有効期限切れや失効を検出するために使用する。
+func CheckWebSocketSession(store RevocationStore, claims *Claims) error {
+	_, err := checkWSSession(store, claims)
+	return err
+}
+
+// セッションの有効期限と失効状態を確認する
+func checkWSSession(store RevocationStore, claims *Claims) (*Claims, error) {
+	if claims.ExpiresAt > 0 && time.Now().Unix() >= claims.ExpiresAt {
+		return nil, errors.New("session expired")
+	}
+
+	revoked, err := isTokenRevoked(store, claims)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.New("session revoked")
+	}
+
+	if claims.Role == "" {
+		claims.Role = models.RoleCustomer
+	}
+	return claims, nil
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"backend/models"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のセッションのClaims
+func newTestSession(expiresAt time.Time) *Claims {
+	return &Claims{
+		UserID: "user1",
+		Email:  "test@example.com",
+		Role:   models.RoleStaff,
+		StandardClaims: jwt.StandardClaims{
+			Id:        "session1",
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	}
+}
+
+// WebSocket接続のリクエストを生成する
+func newWSContext(target string, cookie *http.Cookie) echo.Context {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	return e.NewContext(req, httptest.NewRecorder())
+}
+
+func TestIssueWSTicket(t *testing.T) {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodPost, "/api/ws/ticket", nil), rec)
+	c.Set(ClaimsContextKey, newTestSession(time.Now().Add(time.Hour)))
+
+	handler := &AuthHandler{}
+	if assert.NoError(t, handler.IssueWSTicket(c)) {
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Contains(t, rec.Body.String(), "ticket")
+	}
+}
+
+func TestAuthenticateWebSocket_Ticket(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	session := newTestSession(time.Now().Add(time.Hour))
+
+	ticket, expiresAt, err := issueWSTicket(session)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.WithinDuration(t, time.Now().Add(WSTicketTTL), expiresAt, time.Second)
+
+	// チケットはアクセストークンとして使用できない
+	_, err = ParseToken(ticket)
+	assert.Error(t, err)
+
+	// チケットで認証すると、発行元のセッションとして扱われる
+	claims, err := AuthenticateWebSocket(newWSContext("/ws?ticket="+ticket, nil), store)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "user1", claims.UserID)
+		assert.Equal(t, models.RoleStaff, claims.Role)
+		assert.Equal(t, "session1", claims.Id)
+		assert.Equal(t, session.ExpiresAt, claims.ExpiresAt)
+	}
+
+	// チケットは一度しか使用できない
+	_, err = AuthenticateWebSocket(newWSContext("/ws?ticket="+ticket, nil), store)
+	assert.EqualError(t, err, "ticket already used")
+}
+
+func TestAuthenticateWebSocket_TicketConcurrentUse(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	session := newTestSession(time.Now().Add(time.Hour))
+
+	ticket, _, err := issueWSTicket(session)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// 同じチケットで同時に接続した場合も、1つの接続のみ認証する
+	const attempts = 5
+	var wg sync.WaitGroup
+	results := make(chan error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := AuthenticateWebSocket(newWSContext("/ws?ticket="+ticket, nil), store)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	succeeded := 0
+	for err := range results {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		assert.EqualError(t, err, "ticket already used")
+	}
+	assert.Equal(t, 1, succeeded)
+}
+
+func TestAuthenticateWebSocket_TicketFromRevokedSession(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	session := newTestSession(time.Now().Add(time.Hour))
+
+	ticket, _, err := issueWSTicket(session)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// チケットの発行後にログアウトした場合
+	assert.NoError(t, store.RevokeToken(session.Id, time.Unix(session.ExpiresAt, 0)))
+
+	_, err = AuthenticateWebSocket(newWSContext("/ws?ticket="+ticket, nil), store)
+	assert.EqualError(t, err, "session revoked")
+}
+
+func TestAuthenticateWebSocket_Cookie(t *testing.T) {
+	tokenString := newTestToken(t, jwt.SigningMethodHS256, testSecret, time.Now().Add(time.Hour))
+
+	claims, err := AuthenticateWebSocket(newWSContext("/ws", &http.Cookie{Name: "token", Value: tokenString}), NewMemoryRevocationStore())
+	if assert.NoError(t, err) {
+		assert.Equal(t, "user1", claims.UserID)
+		assert.Equal(t, models.RoleCustomer, claims.Role)
+	}
+}
+
+func TestAuthenticateWebSocket_Invalid(t *testing.T) {
+	mfaToken, _, err := issueMFAPendingToken(&models.UserData{ID: "user1", Email: "test@example.com"})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	cases := map[string]echo.Context{
+		"no credentials": newWSContext("/ws", nil),
+		"expired cookie": newWSContext("/ws", &http.Cookie{Name: "token", Value: newTestToken(t, jwt.SigningMethodHS256, testSecret, time.Now().Add(-time.Minute))}),
+		"invalid ticket": newWSContext("/ws?ticket=invalid", nil),
+		"mfa token":      newWSContext("/ws?ticket="+mfaToken, nil),
+	}
+
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := AuthenticateWebSocket(c, NewMemoryRevocationStore())
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCheckWebSocketSession(t *testing.T) {
+	store := NewMemoryRevocationStore()
+
+	assert.NoError(t, CheckWebSocketSession(store, newTestSession(time.Now().Add(time.Hour))))
+	assert.EqualError(t, CheckWebSocketSession(store, newTestSession(time.Now().Add(-time.Second))), "session expired")
+
+	// すべてのセッションを失効させた場合
+	session := newTestSession(time.Now().Add(time.Hour))
+	session.IssuedAt = time.Now().Add(-time.Minute).Unix()
+	assert.NoError(t, store.RevokeAllForUser("user1", time.Now()))
+	assert.EqualError(t, CheckWebSocketSession(store, session), "session revoked")
+}
@@ -0,0 +1,93 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// メッセージブローカーの種類
+const (
+	TypeMemory = "memory" // 単一インスタンスの開発環境やテスト向け
+	TypeRedis  = "redis"  // Redis Pub/Sub
+	TypeNATS   = "nats"   // NATS
+)
+
+var (
+	errBrokerClosed      = errors.New("broker is closed")
+	errChannelRequired   = errors.New("channel is required")
+	errUnknownBrokerType = errors.New("unknown broker type")
+)
+
+// ブローカーから受信したメッセージ
+type Message struct {
+	Channel string
+	Payload []byte
+}
+
+// インスタンス間でメッセージを中継するブローカー
+// 通知をパブリッシュしたインスタンスに限らず、サブスクライブしているすべてのインスタンスが受信する。
+type Broker interface {
+	// 指定したチャンネルにメッセージをパブリッシュする
+	Publish(ctx context.Context, channel string, payload []byte) error
+	// 指定したチャンネルをサブスクライブする
+	Subscribe(ctx context.Context, channels ...string) (Subscription, error)
+	// ブローカーをクローズする。サブスクリプションもすべてクローズされる。
+	Close() error
+}
+
+// チャンネルのサブスクリプション
+type Subscription interface {
+	// 受信したメッセージのチャンネル。サブスクリプションがクローズされるとクローズされる。
+	Messages() <-chan *Message
+	// サブスクリプションをクローズする
+	Close() error
+}
+
+// ブローカーの設定
+type Config struct {
+	Type    string // memory, redis, nats のいずれか
+	NATSURL string // NATSの接続先
+}
+
+// デフォルトの設定を返す。
+func DefaultConfig() Config {
+	return Config{
+		Type:    TypeRedis,
+		NATSURL: "nats://127.0.0.1:4222",
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定の項目はデフォルト値を使用する。
+func LoadConfig() Config {
+	config := DefaultConfig()
+	if brokerType := strings.ToLower(strings.TrimSpace(os.Getenv("BROKER_TYPE"))); brokerType != "" {
+		config.Type = brokerType
+	}
+	if natsURL := os.Getenv("NATS_URL"); natsURL != "" {
+		config.NATSURL = natsURL
+	}
+	return config
+}
+
+// 設定に応じたブローカーを生成する
+// Redisを使用する場合は、アプリケーションで共有しているクライアントを指定する。
+func New(config Config, redisClient *redis.Client) (Broker, error) {
+	switch config.Type {
+	case TypeMemory:
+		return NewMemoryBroker(), nil
+	case TypeRedis:
+		if redisClient == nil {
+			return nil, errors.New("redis client is required")
+		}
+		return NewRedisBroker(redisClient), nil
+	case TypeNATS:
+		return NewNATSBroker(config.NATSURL)
+	default:
+		return nil, errUnknownBrokerType
+	}
+}
@@ -0,0 +1,156 @@
+package broker
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	natsserver "github.com/nats-io/nats-server/v2/test"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// メッセージを受信する
+func receive(t *testing.T, subscription Subscription) *Message {
+	select {
+	case message, ok := <-subscription.Messages():
+		if !ok {
+			t.Fatal("Subscription closed")
+		}
+		return message
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+	return nil
+}
+
+// サブスクリプションのチャンネルがクローズされることを確認する
+func assertClosed(t *testing.T, subscription Subscription) {
+	select {
+	case _, ok := <-subscription.Messages():
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("Subscription was not closed")
+	}
+}
+
+// ブローカーの実装に共通する挙動を確認する
+func testBroker(t *testing.T, broker Broker) {
+	ctx := context.Background()
+
+	subscription, err := broker.Subscribe(ctx, "channel1", "channel2")
+	if !assert.NoError(t, err) {
+		return
+	}
+	other, err := broker.Subscribe(ctx, "channel2")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	// サブスクライブしていないチャンネルのメッセージは受信しない
+	assert.NoError(t, broker.Publish(ctx, "channel3", []byte("ignored")))
+	assert.NoError(t, broker.Publish(ctx, "channel1", []byte("hello")))
+	assert.NoError(t, broker.Publish(ctx, "channel2", []byte("world")))
+
+	assert.Equal(t, &Message{Channel: "channel1", Payload: []byte("hello")}, receive(t, subscription))
+	assert.Equal(t, &Message{Channel: "channel2", Payload: []byte("world")}, receive(t, subscription))
+	// 同じチャンネルをサブスクライブしているすべてのサブスクリプションが受信する
+	assert.Equal(t, &Message{Channel: "channel2", Payload: []byte("world")}, receive(t, other))
+
+	assert.Equal(t, errChannelRequired, broker.Publish(ctx, "", []byte("hello")))
+	_, err = broker.Subscribe(ctx)
+	assert.Equal(t, errChannelRequired, err)
+
+	// 個別にクローズしたサブスクリプションは受信を終了する
+	assert.NoError(t, other.Close())
+	assertClosed(t, other)
+
+	// ブローカーのクローズで残りのサブスクリプションも受信を終了する
+	assert.NoError(t, broker.Close())
+	assertClosed(t, subscription)
+}
+
+func TestMemoryBroker(t *testing.T) {
+	broker := NewMemoryBroker()
+	testBroker(t, broker)
+
+	assert.Equal(t, errBrokerClosed, broker.Publish(context.Background(), "channel1", []byte("hello")))
+}
+
+func TestRedisBroker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	broker := NewRedisBroker(client)
+	testBroker(t, broker)
+
+	_, err := broker.Subscribe(context.Background(), "channel1")
+	assert.Equal(t, errBrokerClosed, err)
+}
+
+func TestNATSBroker(t *testing.T) {
+	server := natsserver.RunRandClientPortServer()
+	defer server.Shutdown()
+
+	broker, err := NewNATSBroker(server.ClientURL())
+	if !assert.NoError(t, err) {
+		return
+	}
+	testBroker(t, broker)
+
+	// 送信待ちのメッセージを送信した後に接続がクローズされる
+	assert.Eventually(t, broker.Conn.IsClosed, time.Second, 10*time.Millisecond)
+	assert.Equal(t, errBrokerClosed, broker.Publish(context.Background(), "channel1", []byte("hello")))
+	_, err = broker.Subscribe(context.Background(), "channel1")
+	assert.Equal(t, errBrokerClosed, err)
+}
+
+func TestNATSBroker_BetweenConnections(t *testing.T) {
+	server := natsserver.RunRandClientPortServer()
+	defer server.Shutdown()
+
+	// 別のインスタンスから送信したメッセージも受信する
+	publisher, err := NewNATSBroker(server.ClientURL())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer publisher.Close()
+	subscriber, err := NewNATSBroker(server.ClientURL())
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer subscriber.Close()
+
+	subscription, err := subscriber.Subscribe(context.Background(), "channel1")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, publisher.Publish(context.Background(), "channel1", []byte("hello")))
+	assert.Equal(t, &Message{Channel: "channel1", Payload: []byte("hello")}, receive(t, subscription))
+}
+
+func TestLoadConfig(t *testing.T) {
+	os.Setenv("BROKER_TYPE", " NATS ")
+	os.Setenv("NATS_URL", "nats://nats:4222")
+	defer os.Unsetenv("BROKER_TYPE")
+	defer os.Unsetenv("NATS_URL")
+
+	config := LoadConfig()
+	assert.Equal(t, TypeNATS, config.Type)
+	assert.Equal(t, "nats://nats:4222", config.NATSURL)
+}
+
+func TestNew(t *testing.T) {
+	broker, err := New(Config{Type: TypeMemory}, nil)
+	assert.NoError(t, err)
+	assert.IsType(t, &MemoryBroker{}, broker)
+
+	_, err = New(Config{Type: TypeRedis}, nil)
+	assert.EqualError(t, err, "redis client is required")
+
+	_, err = New(Config{Type: "kafka"}, nil)
+	assert.Equal(t, errUnknownBrokerType, err)
+}
@@ -0,0 +1,114 @@
+package broker
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+// サブスクリプションごとの受信バッファのサイズ
+const memoryBufferSize = 64
+
+// プロセス内でメッセージを中継するBrokerの実装
+// インスタンス間では共有されないため、テストや単一インスタンスの開発環境で使用する。
+type MemoryBroker struct {
+	mu            sync.Mutex
+	subscriptions map[*memorySubscription]struct{}
+	closed        bool
+}
+
+func NewMemoryBroker() *MemoryBroker {
+	return &MemoryBroker{
+		subscriptions: make(map[*memorySubscription]struct{}),
+	}
+}
+
+// サブスクライブしているチャンネルにメッセージを配信する
+// 受信バッファに空きのないサブスクリプションには配信せず、パブリッシュ側を待たせない。
+func (b *MemoryBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if channel == "" {
+		return errChannelRequired
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return errBrokerClosed
+	}
+
+	for subscription := range b.subscriptions {
+		if _, ok := subscription.channels[channel]; !ok {
+			continue
+		}
+		// 受信側で書き換えられても影響しないよう、サブスクリプションごとに複製する
+		message := &Message{Channel: channel, Payload: append([]byte(nil), payload...)}
+		select {
+		case subscription.messages <- message:
+		default:
+			log.Printf("Dropping message for slow subscriber on channel: %s", channel)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errChannelRequired
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errBrokerClosed
+	}
+
+	subscription := &memorySubscription{
+		broker:   b,
+		channels: make(map[string]struct{}, len(channels)),
+		messages: make(chan *Message, memoryBufferSize),
+	}
+	for _, channel := range channels {
+		subscription.channels[channel] = struct{}{}
+	}
+	b.subscriptions[subscription] = struct{}{}
+	return subscription, nil
+}
+
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+
+	for subscription := range b.subscriptions {
+		close(subscription.messages)
+	}
+	b.subscriptions = nil
+	return nil
+}
+
+// MemoryBrokerのサブスクリプション
+type memorySubscription struct {
+	broker   *MemoryBroker
+	channels map[string]struct{}
+	messages chan *Message
+}
+
+func (s *memorySubscription) Messages() <-chan *Message {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+
+	// ブローカーのクローズ時にすでにクローズされている場合は何もしない
+	if _, ok := s.broker.subscriptions[s]; !ok {
+		return nil
+	}
+	delete(s.broker.subscriptions, s)
+	close(s.messages)
+	return nil
+}
@@ -0,0 +1,173 @@
+package broker
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// サブスクリプションごとの受信バッファのサイズ
+// 超過した分はNATSのクライアントがスローコンシューマーとして破棄する。
+const natsBufferSize = 256
+
+// 期限のないコンテキストでサブスクライブした場合に、サーバーの応答を待つ時間
+const natsFlushTimeout = 5 * time.Second
+
+// NATSを使用したBrokerの実装
+// チャンネル名をそのままサブジェクトとして使用する。
+type NATSBroker struct {
+	Conn *nats.Conn
+
+	mu            sync.Mutex
+	subscriptions map[*natsSubscription]struct{}
+}
+
+// NATSに接続してBrokerを生成する
+// 接続が切れた場合は、クローズされるまで再接続を試みる。
+func NewNATSBroker(url string) (*NATSBroker, error) {
+	conn, err := nats.Connect(url,
+		nats.Name("backend"),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			if err != nil {
+				log.Printf("Disconnected from NATS: %v", err)
+			}
+		}),
+		nats.ReconnectHandler(func(conn *nats.Conn) {
+			log.Printf("Reconnected to NATS: %s", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &NATSBroker{
+		Conn:          conn,
+		subscriptions: make(map[*natsSubscription]struct{}),
+	}, nil
+}
+
+func (b *NATSBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if channel == "" {
+		return errChannelRequired
+	}
+	if b.Conn.IsClosed() {
+		return errBrokerClosed
+	}
+	return b.Conn.Publish(channel, payload)
+}
+
+func (b *NATSBroker) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errChannelRequired
+	}
+	if b.Conn.IsClosed() {
+		return nil, errBrokerClosed
+	}
+
+	subscription := &natsSubscription{
+		broker:   b,
+		received: make(chan *nats.Msg, natsBufferSize),
+		messages: make(chan *Message),
+		done:     make(chan struct{}),
+	}
+	for _, channel := range channels {
+		sub, err := b.Conn.ChanSubscribe(channel, subscription.received)
+		if err != nil {
+			subscription.Close()
+			return nil, err
+		}
+		subscription.subs = append(subscription.subs, sub)
+	}
+
+	// サブスクライブがサーバーに届いてから返す
+	// NATSのクライアントは期限のないコンテキストを受け付けないため、既定の待ち時間を設定する
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, natsFlushTimeout)
+		defer cancel()
+	}
+	if err := b.Conn.FlushWithContext(ctx); err != nil {
+		subscription.Close()
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.subscriptions[subscription] = struct{}{}
+	b.mu.Unlock()
+
+	go subscription.forward()
+	return subscription, nil
+}
+
+// サブスクリプションをすべてクローズし、送信待ちのメッセージを送信してから接続をクローズする
+func (b *NATSBroker) Close() error {
+	b.mu.Lock()
+	subscriptions := b.subscriptions
+	b.subscriptions = make(map[*natsSubscription]struct{})
+	b.mu.Unlock()
+
+	for subscription := range subscriptions {
+		subscription.close()
+	}
+
+	if err := b.Conn.Drain(); err != nil {
+		b.Conn.Close()
+		return err
+	}
+	return nil
+}
+
+// NATSBrokerのサブスクリプション
+type natsSubscription struct {
+	broker   *NATSBroker
+	subs     []*nats.Subscription
+	received chan *nats.Msg
+	messages chan *Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+// NATSから受信したメッセージを中継する
+func (s *natsSubscription) forward() {
+	defer close(s.messages)
+	for {
+		select {
+		case msg := <-s.received:
+			select {
+			case s.messages <- &Message{Channel: msg.Subject, Payload: msg.Data}:
+			case <-s.done:
+				return
+			}
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *natsSubscription) Messages() <-chan *Message {
+	return s.messages
+}
+
+func (s *natsSubscription) Close() error {
+	s.broker.mu.Lock()
+	delete(s.broker.subscriptions, s)
+	s.broker.mu.Unlock()
+	return s.close()
+}
+
+// サブスクライブを解除して受信の中継を止める
+func (s *natsSubscription) close() error {
+	var err error
+	s.once.Do(func() {
+		for _, sub := range s.subs {
+			if unsubscribeErr := sub.Unsubscribe(); unsubscribeErr != nil && err == nil {
+				err = unsubscribeErr
+			}
+		}
+		close(s.done)
+	})
+	return err
+}
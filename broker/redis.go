@@ -0,0 +1,118 @@
+package broker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Redis Pub/Subを使用したBrokerの実装
+// クライアントはアプリケーションで共有しているものを使用し、クローズはcacheパッケージに任せる。
+type RedisBroker struct {
+	Client *redis.Client
+
+	mu            sync.Mutex
+	subscriptions map[*redisSubscription]struct{}
+	closed        bool
+}
+
+func NewRedisBroker(client *redis.Client) *RedisBroker {
+	return &RedisBroker{
+		Client:        client,
+		subscriptions: make(map[*redisSubscription]struct{}),
+	}
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, channel string, payload []byte) error {
+	if channel == "" {
+		return errChannelRequired
+	}
+	return b.Client.Publish(ctx, channel, payload).Err()
+}
+
+// サブスクライブの完了を確認してから返す
+func (b *RedisBroker) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	if len(channels) == 0 {
+		return nil, errChannelRequired
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errBrokerClosed
+	}
+
+	pubsub := b.Client.Subscribe(ctx, channels...)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	subscription := &redisSubscription{
+		broker:   b,
+		pubsub:   pubsub,
+		messages: make(chan *Message),
+		done:     make(chan struct{}),
+	}
+	go subscription.forward()
+	b.subscriptions[subscription] = struct{}{}
+	return subscription, nil
+}
+
+// サブスクリプションをすべてクローズする
+func (b *RedisBroker) Close() error {
+	b.mu.Lock()
+	b.closed = true
+	subscriptions := b.subscriptions
+	b.subscriptions = make(map[*redisSubscription]struct{})
+	b.mu.Unlock()
+
+	for subscription := range subscriptions {
+		subscription.close()
+	}
+	return nil
+}
+
+// RedisBrokerのサブスクリプション
+type redisSubscription struct {
+	broker   *RedisBroker
+	pubsub   *redis.PubSub
+	messages chan *Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+// Redisから受信したメッセージを中継する
+// 接続が切れた場合はgo-redisが再接続し、PubSubがクローズされるまで受信を続ける。
+func (s *redisSubscription) forward() {
+	defer close(s.messages)
+	for msg := range s.pubsub.Channel() {
+		select {
+		case s.messages <- &Message{Channel: msg.Channel, Payload: []byte(msg.Payload)}:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *redisSubscription) Messages() <-chan *Message {
+	return s.messages
+}
+
+func (s *redisSubscription) Close() error {
+	s.broker.mu.Lock()
+	delete(s.broker.subscriptions, s)
+	s.broker.mu.Unlock()
+	return s.close()
+}
+
+// 受信の中継を止めてPubSubをクローズする
+func (s *redisSubscription) close() error {
+	var err error
+	s.once.Do(func() {
+		close(s.done)
+		err = s.pubsub.Close()
+	})
+	return err
+}
@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	// Redisとのやり取りに使用するグローバルなコンテキスト。
+	Ctx = context.Background()
+	// Redisクライアントです。セッション失効やレート制限などの共有状態に使用。
+	Client *redis.Client
+)
+
+// Redisの接続を初期化
+// Redisの接続先を環境変数から取得し、クライアントを作成する。
+// 成功時にはnilを返し、接続に失敗した場合はエラーメッセージを返す。
+func InitRedis() error {
+	log.Println("Initializing Redis client...")
+
+	Client = redis.NewClient(&redis.Options{
+		Addr: os.Getenv("REDIS_URL"),
+	})
+
+	// 接続の確認
+	log.Println("Pinging Redis...")
+	if err := Client.Ping(Ctx).Err(); err != nil {
+		log.Printf("Unable to ping Redis: %v", err)
+		return fmt.Errorf("unable to ping Redis: %v", err)
+	}
+
+	log.Println("Connected to Redis successfully")
+	return nil
+}
+
+// Redisクライアントをクローズ。
+// この関数はアプリケーションのシャットダウン時に呼び出されることを想定する。
+func CloseRedis() {
+	if Client != nil {
+		if err := Client.Close(); err != nil {
+			log.Printf("Failed to close Redis client: %v", err)
+			return
+		}
+		log.Println("Redis client closed")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers_api_keys
+
+import (
+	services_api_keys "backend/services/api_keys"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+type APIKeyHandler struct {
+	APIKeyService services_api_keys.APIKeyService
+}
+
+// コンストラクタ
+func NewAPIKeyHandler(apiKeyService services_api_keys.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		APIKeyService: apiKeyService,
+	}
+}
+
+// APIキーの一覧を取得するハンドラー
+// クエリパラメータuser_idを指定した場合は、そのユーザーのキーのみを返す。
+func (h *APIKeyHandler) GetAPIKeys(c echo.Context) error {
+	log.Println("Fetching API keys...")
+
+	keys, err := h.APIKeyService.FetchAPIKeys(c.QueryParam("user_id"))
+	if err != nil {
+		log.Printf("Error fetching API keys: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch API keys",
+		})
+	}
+
+	log.Println("Fetched API keys successfully")
+	return c.JSON(http.StatusOK, keys)
+}
+
+// APIキーを発行するハンドラー
+// キー本体はこのレスポンスでのみ返す。
+func (h *APIKeyHandler) CreateAPIKey(c echo.Context) error {
+	log.Println("Creating API key...")
+
+	// リクエストボディからデータを取得
+	type RequestBody struct {
+		UserId    string     `json:"user_id"`
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	issued, err := h.APIKeyService.CreateAPIKey(reqBody.UserId, reqBody.Name, reqBody.Scopes, reqBody.ExpiresAt)
+	if err != nil {
+		switch err.Error() {
+		case "userId and name are required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "User ID and name are required",
+			})
+		case "scopes are required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Scopes are required",
+			})
+		case "invalid scope":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid scope",
+			})
+		case "invalid expiration":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Expiration must be in the future",
+			})
+		case "scope not allowed for user role":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Scope is not allowed for the user's role",
+			})
+		case "user not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "User not found",
+			})
+		default:
+			log.Printf("Failed to create API key: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to create API key",
+			})
+		}
+	}
+
+	log.Println("API key created successfully")
+	return c.JSON(http.StatusCreated, issued)
+}
+
+// APIキーを失効させるハンドラー
+func (h *APIKeyHandler) RevokeAPIKey(c echo.Context) error {
+	log.Println("Revoking API key...")
+
+	// パスパラメータからidを取得
+	id := c.Param("id")
+
+	err := h.APIKeyService.RevokeAPIKey(id)
+	if err != nil {
+		switch err.Error() {
+		case "id is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Id is required",
+			})
+		case "api key not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "API key not found",
+			})
+		default:
+			log.Printf("Failed to revoke API key: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to revoke API key",
+			})
+		}
+	}
+
+	log.Println("API key revoked successfully")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "API key revoked successfully",
+	})
+}
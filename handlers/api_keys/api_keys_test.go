@@ -0,0 +1,150 @@
+package handlers_api_keys
+
+import (
+	"backend/models"
+	services_api_keys "backend/services/api_keys"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler_GetAPIKeys(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/api-keys?user_id=kiosk1", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスをインスタンス化
+	mockService := new(services_api_keys.MockAPIKeyService)
+	handler := NewAPIKeyHandler(mockService)
+
+	// モックの挙動を設定
+	mockService.On("FetchAPIKeys", "kiosk1").Return([]models.APIKeyData{
+		{ID: "key1", UserId: "kiosk1", Name: "Store tablet", Prefix: "bnk_abcdefgh", KeyHash: "hash"},
+	}, nil)
+
+	// ハンドラーを実行
+	handler.GetAPIKeys(c)
+
+	// ステータスコードとレスポンス内容を確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Store tablet")
+	// ハッシュ値はレスポンスに含めない
+	assert.NotContains(t, rec.Body.String(), "hash")
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_CreateAPIKey(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	body := `{"user_id":"kiosk1", "name":"Store tablet", "scopes":["reservations:write"]}`
+	req := httptest.NewRequest(http.MethodPost, "/api/api-keys", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスをインスタンス化
+	mockService := new(services_api_keys.MockAPIKeyService)
+	handler := NewAPIKeyHandler(mockService)
+
+	// モックの挙動を設定
+	mockService.On("CreateAPIKey", "kiosk1", "Store tablet", []string{"reservations:write"}, (*time.Time)(nil)).Return(&models.IssuedAPIKey{
+		Key:    "bnk_abcdefgh.secret",
+		APIKey: models.APIKeyData{ID: "key1", UserId: "kiosk1", Name: "Store tablet"},
+	}, nil)
+
+	// ハンドラーを実行
+	handler.CreateAPIKey(c)
+
+	// ステータスコードとレスポンス内容を確認
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bnk_abcdefgh.secret")
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_CreateAPIKey_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"invalid scope", errors.New("invalid scope"), http.StatusBadRequest},
+		{"scope beyond role", errors.New("scope not allowed for user role"), http.StatusBadRequest},
+		{"user not found", errors.New("user not found"), http.StatusNotFound},
+		{"server error", errors.New("failed to create api key"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			body := `{"user_id":"kiosk1", "name":"Store tablet", "scopes":["users:manage"]}`
+			req := httptest.NewRequest(http.MethodPost, "/api/api-keys", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// モックサービスをインスタンス化
+			mockService := new(services_api_keys.MockAPIKeyService)
+			handler := NewAPIKeyHandler(mockService)
+
+			// モックの挙動を設定
+			mockService.On("CreateAPIKey", "kiosk1", "Store tablet", []string{"users:manage"}, (*time.Time)(nil)).Return(nil, tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.CreateAPIKey(c)
+
+			// ステータスコードを確認
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestHandler_RevokeAPIKey(t *testing.T) {
+	testCases := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"success", nil, http.StatusOK},
+		{"not found", errors.New("api key not found"), http.StatusNotFound},
+		{"server error", errors.New("failed to revoke api key"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/api/api-keys/key1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("key1")
+
+			// モックサービスをインスタンス化
+			mockService := new(services_api_keys.MockAPIKeyService)
+			handler := NewAPIKeyHandler(mockService)
+
+			// モックの挙動を設定
+			mockService.On("RevokeAPIKey", "key1").Return(tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.RevokeAPIKey(c)
+
+			// ステータスコードを確認
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package handlers_business_calendar
+
+import (
+	"backend/models"
+	services_business_calendar "backend/services/business_calendar"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type BusinessCalendarHandler struct {
+	BusinessCalendarService services_business_calendar.BusinessCalendarService
+}
+
+// コンストラクタ
+func NewBusinessCalendarHandler(businessCalendarService services_business_calendar.BusinessCalendarService) *BusinessCalendarHandler {
+	return &BusinessCalendarHandler{
+		BusinessCalendarService: businessCalendarService,
+	}
+}
+
+// 曜日ごとの営業時間を取得するハンドラー
+func (h *BusinessCalendarHandler) GetBusinessHours(c echo.Context) error {
+	log.Println("Fetching business hours...")
+
+	hours, err := h.BusinessCalendarService.FetchBusinessHours()
+	if err != nil {
+		log.Printf("Error fetching business hours: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch business hours",
+		})
+	}
+
+	log.Println("Fetched business hours successfully")
+	return c.JSON(http.StatusOK, hours)
+}
+
+// 曜日ごとの営業時間を置き換えるハンドラー
+// 指定しなかった曜日は休業日となる。hoursが未指定または空の場合は400を返す。
+func (h *BusinessCalendarHandler) UpdateBusinessHours(c echo.Context) error {
+	log.Println("Updating business hours...")
+
+	// リクエストボディからデータを取得
+	type RequestBody struct {
+		Hours []models.BusinessHoursData `json:"hours"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	hours, err := h.BusinessCalendarService.UpdateBusinessHours(reqBody.Hours)
+	if err != nil {
+		if status, message, ok := validationError(err); ok {
+			return c.JSON(status, map[string]string{"error": message})
+		}
+		log.Printf("Failed to update business hours: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to update business hours",
+		})
+	}
+
+	log.Println("Business hours updated successfully")
+	return c.JSON(http.StatusOK, hours)
+}
+
+// 通常と異なる営業時間の設定の一覧を取得するハンドラー
+// クエリパラメータfrom, toで期間を指定できる。
+func (h *BusinessCalendarHandler) GetHoursOverrides(c echo.Context) error {
+	log.Println("Fetching hours overrides...")
+
+	overrides, err := h.BusinessCalendarService.FetchHoursOverrides(c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		if status, message, ok := validationError(err); ok {
+			return c.JSON(status, map[string]string{"error": message})
+		}
+		log.Printf("Error fetching hours overrides: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch hours overrides",
+		})
+	}
+
+	log.Println("Fetched hours overrides successfully")
+	return c.JSON(http.StatusOK, overrides)
+}
+
+// 祝日などで通常と異なる営業時間とする日を追加するハンドラー
+func (h *BusinessCalendarHandler) CreateHoursOverride(c echo.Context) error {
+	log.Println("Creating hours override...")
+
+	// リクエストボディからデータを取得
+	type RequestBody struct {
+		Date      string `json:"date"`
+		OpenTime  string `json:"open_time"`
+		CloseTime string `json:"close_time"`
+		Reason    string `json:"reason"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	override, err := h.BusinessCalendarService.CreateHoursOverride(reqBody.Date, reqBody.OpenTime, reqBody.CloseTime, reqBody.Reason)
+	if err != nil {
+		if status, message, ok := validationError(err); ok {
+			return c.JSON(status, map[string]string{"error": message})
+		}
+		log.Printf("Failed to create hours override: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create hours override",
+		})
+	}
+
+	log.Println("Hours override created successfully")
+	return c.JSON(http.StatusCreated, override)
+}
+
+// 通常と異なる営業時間の設定を削除するハンドラー
+func (h *BusinessCalendarHandler) DeleteHoursOverride(c echo.Context) error {
+	log.Println("Deleting hours override...")
+
+	err := h.BusinessCalendarService.DeleteHoursOverride(c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "id is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Id is required",
+			})
+		case "hours override not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Hours override not found",
+			})
+		default:
+			log.Printf("Failed to delete hours override: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to delete hours override",
+			})
+		}
+	}
+
+	log.Println("Hours override deleted successfully")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Hours override deleted successfully",
+	})
+}
+
+// 休業期間の一覧を取得するハンドラー
+// クエリパラメータfrom, toで期間を指定できる。
+func (h *BusinessCalendarHandler) GetClosures(c echo.Context) error {
+	log.Println("Fetching closures...")
+
+	closures, err := h.BusinessCalendarService.FetchClosures(c.QueryParam("from"), c.QueryParam("to"))
+	if err != nil {
+		if status, message, ok := validationError(err); ok {
+			return c.JSON(status, map[string]string{"error": message})
+		}
+		log.Printf("Error fetching closures: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to fetch closures",
+		})
+	}
+
+	log.Println("Fetched closures successfully")
+	return c.JSON(http.StatusOK, closures)
+}
+
+// 臨時休業や予約を受け付けない期間を追加するハンドラー
+func (h *BusinessCalendarHandler) CreateClosure(c echo.Context) error {
+	log.Println("Creating closure...")
+
+	// リクエストボディからデータを取得
+	type RequestBody struct {
+		StartDate string `json:"start_date"`
+		EndDate   string `json:"end_date"`
+		Reason    string `json:"reason"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	closure, err := h.BusinessCalendarService.CreateClosure(reqBody.StartDate, reqBody.EndDate, reqBody.Reason)
+	if err != nil {
+		if status, message, ok := validationError(err); ok {
+			return c.JSON(status, map[string]string{"error": message})
+		}
+		log.Printf("Failed to create closure: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Failed to create closure",
+		})
+	}
+
+	log.Println("Closure created successfully")
+	return c.JSON(http.StatusCreated, closure)
+}
+
+// 休業期間を削除するハンドラー
+func (h *BusinessCalendarHandler) DeleteClosure(c echo.Context) error {
+	log.Println("Deleting closure...")
+
+	err := h.BusinessCalendarService.DeleteClosure(c.Param("id"))
+	if err != nil {
+		switch err.Error() {
+		case "id is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Id is required",
+			})
+		case "closure not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Closure not found",
+			})
+		default:
+			log.Printf("Failed to delete closure: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to delete closure",
+			})
+		}
+	}
+
+	log.Println("Closure deleted successfully")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Closure deleted successfully",
+	})
+}
+
+// 入力の検証エラーを、レスポンスのステータスコードとメッセージに変換する
+// 検証エラーでない場合はfalseを返す。
+func validationError(err error) (int, string, bool) {
+	switch err.Error() {
+	case "business hours are required":
+		return http.StatusBadRequest, "At least one business hours entry is required", true
+	case "invalid weekday":
+		return http.StatusBadRequest, "Weekday must be between 0 (Sunday) and 6 (Saturday)", true
+	case "invalid time format. Use 'HH:MM'":
+		return http.StatusBadRequest, "Invalid time format. Use 'HH:MM'", true
+	case "open time must be before close time":
+		return http.StatusBadRequest, "Open time must be before close time", true
+	case "overlapping business hours":
+		return http.StatusConflict, "Business hours overlap", true
+	case "invalid date format. Use 'YYYY-MM-DD'":
+		return http.StatusBadRequest, "Invalid date format. Use 'YYYY-MM-DD'", true
+	case "invalid date range":
+		return http.StatusBadRequest, "End date must not be before start date", true
+	case "start date and end date are required":
+		return http.StatusBadRequest, "Start date and end date are required", true
+	}
+	return 0, "", false
+}
@@ -0,0 +1,242 @@
+package handlers_business_calendar
+
+import (
+	"backend/models"
+	services_business_calendar "backend/services/business_calendar"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_GetBusinessHours(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/calendar/hours", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスをインスタンス化
+	mockService := new(services_business_calendar.MockBusinessCalendarService)
+	handler := NewBusinessCalendarHandler(mockService)
+
+	// モックの挙動を設定
+	mockService.On("FetchBusinessHours").Return([]models.BusinessHoursData{
+		{ID: "hours1", Weekday: 1, OpenTime: "11:00", CloseTime: "22:00"},
+	}, nil)
+
+	// ハンドラーを実行
+	handler.GetBusinessHours(c)
+
+	// ステータスコードとレスポンス内容を確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"open_time":"11:00"`)
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_UpdateBusinessHours(t *testing.T) {
+	testCases := []struct {
+		name         string
+		body         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"success", `{"hours":[{"weekday":1,"open_time":"11:00","close_time":"22:00"}]}`, nil, http.StatusOK},
+		{"invalid body", `{"hours":"monday"}`, nil, http.StatusBadRequest},
+		{"invalid weekday", `{"hours":[{"weekday":9,"open_time":"11:00","close_time":"22:00"}]}`, errors.New("invalid weekday"), http.StatusBadRequest},
+		{"overlapping", `{"hours":[{"weekday":1,"open_time":"11:00","close_time":"22:00"}]}`, errors.New("overlapping business hours"), http.StatusConflict},
+		{"empty hours", `{"hours":[]}`, errors.New("business hours are required"), http.StatusBadRequest},
+		{"missing hours", `{}`, errors.New("business hours are required"), http.StatusBadRequest},
+		{"misspelled key", `{"hour":[{"weekday":1,"open_time":"11:00","close_time":"22:00"}]}`, errors.New("business hours are required"), http.StatusBadRequest},
+		{"server error", `{"hours":[{"weekday":1,"open_time":"11:00","close_time":"22:00"}]}`, errors.New("failed to update business hours"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodPut, "/api/calendar/hours", strings.NewReader(tc.body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// モックサービスをインスタンス化
+			mockService := new(services_business_calendar.MockBusinessCalendarService)
+			handler := NewBusinessCalendarHandler(mockService)
+
+			// モックの挙動を設定
+			mockService.On("UpdateBusinessHours", mock.Anything).Return([]models.BusinessHoursData{}, tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.UpdateBusinessHours(c)
+
+			// ステータスコードを確認
+			assert.Equal(t, tc.expectedCode, rec.Code)
+		})
+	}
+}
+
+func TestHandler_CreateHoursOverride(t *testing.T) {
+	testCases := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"success", nil, http.StatusCreated},
+		{"invalid date", errors.New("invalid date format. Use 'YYYY-MM-DD'"), http.StatusBadRequest},
+		{"close before open", errors.New("open time must be before close time"), http.StatusBadRequest},
+		{"server error", errors.New("failed to create hours override"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			body := `{"date":"2024-12-24","open_time":"17:00","close_time":"23:00","reason":"Christmas Eve"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/calendar/overrides", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// モックサービスをインスタンス化
+			mockService := new(services_business_calendar.MockBusinessCalendarService)
+			handler := NewBusinessCalendarHandler(mockService)
+
+			// モックの挙動を設定
+			if tc.serviceErr != nil {
+				mockService.On("CreateHoursOverride", "2024-12-24", "17:00", "23:00", "Christmas Eve").Return(nil, tc.serviceErr)
+			} else {
+				mockService.On("CreateHoursOverride", "2024-12-24", "17:00", "23:00", "Christmas Eve").Return(&models.HoursOverrideData{ID: "override1", Date: "2024-12-24"}, nil)
+			}
+
+			// ハンドラーを実行
+			handler.CreateHoursOverride(c)
+
+			// ステータスコードを確認
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_GetClosures(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/calendar/closures?from=2024-12-01&to=2024-12-31", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスをインスタンス化
+	mockService := new(services_business_calendar.MockBusinessCalendarService)
+	handler := NewBusinessCalendarHandler(mockService)
+
+	// モックの挙動を設定
+	mockService.On("FetchClosures", "2024-12-01", "2024-12-31").Return([]models.ClosureData{
+		{ID: "closure1", StartDate: "2024-12-31", EndDate: "2025-01-03", Reason: "New Year holidays"},
+	}, nil)
+
+	// ハンドラーを実行
+	handler.GetClosures(c)
+
+	// ステータスコードとレスポンス内容を確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "New Year holidays")
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_CreateClosure(t *testing.T) {
+	testCases := []struct {
+		name         string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"success", nil, http.StatusCreated},
+		{"end before start", errors.New("invalid date range"), http.StatusBadRequest},
+		{"missing dates", errors.New("start date and end date are required"), http.StatusBadRequest},
+		{"server error", errors.New("failed to create closure"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			body := `{"start_date":"2024-12-31","end_date":"2025-01-03","reason":"New Year holidays"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/calendar/closures", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// モックサービスをインスタンス化
+			mockService := new(services_business_calendar.MockBusinessCalendarService)
+			handler := NewBusinessCalendarHandler(mockService)
+
+			// モックの挙動を設定
+			if tc.serviceErr != nil {
+				mockService.On("CreateClosure", "2024-12-31", "2025-01-03", "New Year holidays").Return(nil, tc.serviceErr)
+			} else {
+				mockService.On("CreateClosure", "2024-12-31", "2025-01-03", "New Year holidays").Return(&models.ClosureData{ID: "closure1"}, nil)
+			}
+
+			// ハンドラーを実行
+			handler.CreateClosure(c)
+
+			// ステータスコードを確認
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_DeleteCalendarEntries(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		serviceErr   error
+		expectedCode int
+	}{
+		{"override deleted", "DeleteHoursOverride", nil, http.StatusOK},
+		{"override not found", "DeleteHoursOverride", errors.New("hours override not found"), http.StatusNotFound},
+		{"closure deleted", "DeleteClosure", nil, http.StatusOK},
+		{"closure not found", "DeleteClosure", errors.New("closure not found"), http.StatusNotFound},
+		{"closure server error", "DeleteClosure", errors.New("failed to delete closure"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodDelete, "/api/calendar/entry1", nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("entry1")
+
+			// モックサービスをインスタンス化
+			mockService := new(services_business_calendar.MockBusinessCalendarService)
+			handler := NewBusinessCalendarHandler(mockService)
+
+			// モックの挙動を設定
+			mockService.On(tc.method, "entry1").Return(tc.serviceErr)
+
+			// ハンドラーを実行
+			if tc.method == "DeleteHoursOverride" {
+				handler.DeleteHoursOverride(c)
+			} else {
+				handler.DeleteClosure(c)
+			}
+
+			// ステータスコードを確認
+			assert.Equal(t, tc.expectedCode, rec.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package handlers_notifications
 
 import (
+	"backend/auth"
+	"backend/models"
 	services_notifications "backend/services/notifications"
 	"log"
 	"net/http"
@@ -19,13 +21,29 @@ func NewNotificationHandler(notificationService services_notifications.Notificat
 	}
 }
 
-// 全通知情報を取得し、JSON形式で返すハンドラー
+// 通知情報一覧を取得し、JSON形式で返すハンドラー
+// 全通知の管理権限を持つユーザーには全通知を、それ以外のユーザーには自分宛ての通知のみを返す。
 // 通知情報取得に失敗した場合、500エラーを返す。
 func (h *NotificationHandler) GetNotifications(c echo.Context) error {
 	log.Println("Fetching notifications...")
 
-	// サービス層で予約情報一覧を取得
-	notifications, err := h.NotificationService.FetchNotifications()
+	// JWTミドルウェアで検証済みのClaimsを取得
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		log.Printf("Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	// サービス層で通知情報一覧を取得
+	var notifications []models.NotificationData
+	var err error
+	if auth.HasPermission(c, models.PermissionNotificationsManage) {
+		notifications, err = h.NotificationService.FetchNotifications()
+	} else {
+		notifications, err = h.NotificationService.FetchNotificationsByUserId(claims.UserID)
+	}
 	if err != nil {
 		log.Printf("Error fetching notifications from Supabase: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -1,6 +1,7 @@
 package handlers_notifications
 
 import (
+	"backend/auth"
 	"backend/models"
 	services_notifications "backend/services/notifications"
 	"errors"
@@ -20,6 +21,9 @@ func TestHandler_GetNotifications(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
+	// 全通知を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
 	// モックサービスのインスタンス化
 	mockNotificationService := new(services_notifications.MockNotificationService)
 	// NotificationHandlerのインスタンス化
@@ -51,6 +55,9 @@ func TestHandler_GetNotifications_DatabaseError(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
+	// 全通知を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
 	// モックサービスのインスタンス化
 	mockNotificationService := new(services_notifications.MockNotificationService)
 	// NotificationHandlerのインスタンス化
@@ -77,6 +84,9 @@ func TestHandler_GetNotifications_NoDatas(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
+	// 全通知を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
 	// モックサービスのインスタンス化
 	mockNotificationService := new(services_notifications.MockNotificationService)
 	// NotificationHandlerのインスタンス化
@@ -269,3 +279,55 @@ func TestHandler_AddNotification_ServerError(t *testing.T) {
 	// モックが期待通りに呼び出されたかを確認
 	mockNotificationService.AssertExpectations(t)
 }
+
+func TestHandler_GetNotifications_Customer(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/notifications", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// 一般の予約客として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
+	// モックサービスのインスタンス化
+	mockNotificationService := new(services_notifications.MockNotificationService)
+	// NotificationHandlerのインスタンス化
+	handler := NewNotificationHandler(mockNotificationService)
+
+	// モックデータの設定
+	mockNotifications := []models.NotificationData{
+		{ID: "1", UserId: "user1", Message: "New reservation confirmed"},
+	}
+	mockNotificationService.On("FetchNotificationsByUserId", "user1").Return(mockNotifications, nil)
+
+	// ハンドラーを実行
+	handler.GetNotifications(c)
+
+	// 自分宛ての通知のみが返されることを確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "New reservation confirmed")
+
+	// 全通知の取得は呼び出されないことを確認
+	mockNotificationService.AssertExpectations(t)
+	mockNotificationService.AssertNotCalled(t, "FetchNotifications")
+}
+
+func TestHandler_GetNotifications_Unauthorized(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/notifications", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスのインスタンス化
+	mockNotificationService := new(services_notifications.MockNotificationService)
+	handler := NewNotificationHandler(mockNotificationService)
+
+	// ハンドラーを実行
+	handler.GetNotifications(c)
+
+	// ステータスコードの確認
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockNotificationService.AssertNotCalled(t, "FetchNotifications")
+}
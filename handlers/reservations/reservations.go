@@ -2,14 +2,16 @@ package handlers_reservations
 
 import (
 	"backend/auth"
+	"backend/models"
 	services_notifications "backend/services/notifications"
 	services_reservations "backend/services/reservations"
 	services_users "backend/services/users"
 	"backend/websocket"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 )
 
@@ -17,24 +19,42 @@ type ReservationHandler struct {
 	UserService         services_users.UserService
 	ReservationService  services_reservations.ReservationService
 	NotificationService services_notifications.NotificationService
+	Publisher           websocket.Publisher
 }
 
 // コンストラクタ
-func NewReservationHandler(userService services_users.UserService, reservationService services_reservations.ReservationService, notificationService services_notifications.NotificationService) *ReservationHandler {
+func NewReservationHandler(userService services_users.UserService, reservationService services_reservations.ReservationService, notificationService services_notifications.NotificationService, publisher websocket.Publisher) *ReservationHandler {
 	return &ReservationHandler{
 		UserService:         userService,
 		ReservationService:  reservationService,
 		NotificationService: notificationService,
+		Publisher:           publisher,
 	}
 }
 
-// 全予約情報を取得し、JSON形式で返すハンドラー
+// 予約情報一覧を取得し、JSON形式で返すハンドラー
+// 全予約の管理権限を持つユーザーには全予約を、それ以外のユーザーには自分の予約のみを返す。
 // 予約情報取得に失敗した場合、500エラーを返す。
 func (h *ReservationHandler) GetReservations(c echo.Context) error {
 	log.Println("Fetching reservations...")
 
+	// JWTミドルウェアで検証済みのClaimsを取得
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		log.Printf("Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
 	// サービス層で予約情報一覧を取得
-	reservations, err := h.ReservationService.FetchReservations()
+	var reservations []models.ReservationData
+	var err error
+	if auth.HasPermission(c, models.PermissionReservationsManage) {
+		reservations, err = h.ReservationService.FetchReservations()
+	} else {
+		reservations, err = h.ReservationService.FetchReservationsByUserId(claims.UserID)
+	}
 	if err != nil {
 		log.Printf("Error fetching reservations from Supabase: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{
@@ -46,62 +66,151 @@ func (h *ReservationHandler) GetReservations(c echo.Context) error {
 	return c.JSON(http.StatusOK, reservations)
 }
 
-// パスパラメータで指定されたユーザーIDで予約情報を取得する。
-// データベースに該当予約情報がいない場合、404エラーを返す。
-func (h *ReservationHandler) GetReservationByUserId(c echo.Context) error {
-	log.Println("Fetching reservation by userId...")
+// パスパラメータで指定されたユーザーの予約情報一覧を取得する。
+// user_idにmeを指定した場合は、自分の予約を返す。
+// クエリパラメータで期間(period)、ステータス(status)、日付の範囲(from, to)を絞り込み、
+// limitとcursorでページ単位に取得する。
+func (h *ReservationHandler) GetReservationsByUserId(c echo.Context) error {
+	log.Println("Fetching reservations by userId...")
+
+	// 全予約の管理権限がない場合は、自分の予約のみ参照できる
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		log.Printf("Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
 
 	// パスパラメータからuserIdを取得
 	userId := c.Param("user_id")
+	if userId == "me" {
+		userId = claims.UserID
+	}
+	if claims.UserID != userId && !auth.HasPermission(c, models.PermissionReservationsManage) {
+		log.Printf("User %s is not allowed to access reservations of %s", claims.UserID, userId)
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Forbidden",
+		})
+	}
 
-	// サービス層からユーザーデータを取得
-	reservation, err := h.ReservationService.FetchReservationByUserId(userId)
+	// クエリパラメータから検索条件を取得
+	// ステータスはカンマ区切り、または複数回指定できる
+	params := models.ReservationSearchParams{
+		Period: c.QueryParam("period"),
+		From:   c.QueryParam("from"),
+		To:     c.QueryParam("to"),
+		Cursor: c.QueryParam("cursor"),
+	}
+	for _, value := range c.QueryParams()["status"] {
+		for _, status := range strings.Split(value, ",") {
+			if status = strings.TrimSpace(status); status != "" {
+				params.Statuses = append(params.Statuses, status)
+			}
+		}
+	}
+	if limit := c.QueryParam("limit"); limit != "" {
+		var err error
+		params.Limit, err = strconv.Atoi(limit)
+		if err != nil || params.Limit <= 0 {
+			log.Printf("Invalid limit: %s", limit)
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid limit",
+			})
+		}
+	}
+
+	// サービス層から予約情報一覧を取得
+	page, err := h.ReservationService.SearchReservationsByUserId(userId, params)
 	if err != nil {
 		switch err.Error() {
 		case "userId is required":
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "UserId is required",
 			})
-		case "reservation not found":
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "Reservation not found",
+		case "invalid limit":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid limit",
+			})
+		case "invalid reservation status":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid reservation status",
+			})
+		case "invalid date range":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid date range. Use 'YYYY-MM-DD'",
+			})
+		case "invalid period":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid period. Use 'upcoming' or 'past'",
+			})
+		case "invalid cursor":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid cursor",
 			})
 		default:
-			log.Printf("Failed to not reservation: %v", err)
+			log.Printf("Failed to fetch reservations: %v", err)
 			return c.JSON(http.StatusInternalServerError, map[string]string{
-				"error": "Failed to not reservation",
+				"error": "Failed to fetch reservations",
 			})
 		}
 	}
 
-	log.Println("Fetched reservation successfully")
-	return c.JSON(http.StatusOK, reservation)
+	log.Println("Fetched reservations successfully")
+	return c.JSON(http.StatusOK, page)
 }
 
-// 新しい予約情報を追加するハンドラー
-func (h *ReservationHandler) AddReservation(c echo.Context) error {
-	log.Println("Creating new reservation...")
+// 指定した日付と人数で予約できる枠を取得するハンドラー
+// クエリパラメータで日付(date)と人数(party_size)を指定する。
+func (h *ReservationHandler) GetAvailability(c echo.Context) error {
+	log.Println("Fetching availability...")
 
-	// クッキーからJWTトークンを取得
-	cookie, err := c.Cookie("token")
-	if err != nil {
-		log.Printf("Token not found in cookies")
-		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Unauthorized",
+	partySize, err := strconv.Atoi(c.QueryParam("party_size"))
+	if err != nil || partySize <= 0 {
+		log.Printf("Invalid party size: %s", c.QueryParam("party_size"))
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid party size",
 		})
 	}
-	tokenString := cookie.Value
 
-	// JWTトークンを解析してユーザーIDを取得
-	claims := &auth.Claims{}
-	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		return auth.JwtKey, nil
-	})
+	// サービス層から予約できる枠を取得
+	availability, err := h.ReservationService.FetchAvailability(c.QueryParam("date"), partySize)
+	if err != nil {
+		switch err.Error() {
+		case "invalid date format. Use 'YYYY-MM-DD'":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid date format. Use 'YYYY-MM-DD'",
+			})
+		case "invalid party size":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid party size",
+			})
+		case "party size exceeds maximum":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Party size exceeds the maximum",
+			})
+		default:
+			log.Printf("Failed to fetch availability: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to fetch availability",
+			})
+		}
+	}
+
+	log.Println("Fetched availability successfully")
+	return c.JSON(http.StatusOK, availability)
+}
+
+// 新しい予約情報を追加するハンドラー
+func (h *ReservationHandler) AddReservation(c echo.Context) error {
+	log.Println("Creating new reservation...")
 
-	if err != nil || !token.Valid {
-		log.Printf("Invalid token: %v", err)
+	// JWTミドルウェアで検証済みのClaimsを取得
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		log.Printf("Claims not found in context")
 		return c.JSON(http.StatusUnauthorized, map[string]string{
-			"error": "Invalid token",
+			"error": "Unauthorized",
 		})
 	}
 
@@ -113,10 +222,10 @@ func (h *ReservationHandler) AddReservation(c echo.Context) error {
 		ReservationDate string `json:"reservation_date"` // 予約日
 		NumPeople       int    `json:"num_people"`       // 人数
 		SpecialRequest  string `json:"special_request"`  // 特別リクエスト
-		Status          string `json:"status"`           // ステータス
 	}
 
 	// リクエストボディをバインド
+	// ステータスはpendingで作成し、クライアントからは指定できない
 	var reqBody RequestBody
 	if err := c.Bind(&reqBody); err != nil {
 		log.Printf("Failed to bind request body: %v", err)
@@ -126,16 +235,40 @@ func (h *ReservationHandler) AddReservation(c echo.Context) error {
 	}
 
 	// 予約を作成する
-	reservationId, err := h.ReservationService.CreateReservation(userID, reqBody.ReservationDate, reqBody.NumPeople, reqBody.SpecialRequest, reqBody.Status)
+	reservationId, err := h.ReservationService.CreateReservation(userID, reqBody.ReservationDate, reqBody.NumPeople, reqBody.SpecialRequest)
 	if err != nil {
 		switch err.Error() {
 		case "userID, reservation date, and num_people are required":
 			return c.JSON(http.StatusBadRequest, map[string]string{
 				"error": "UserID, reservation date, and num_people are required",
 			})
-		case "invalid reservation date format. Use 'YYYY-MM-DD HH:MM:SS'":
+		case "invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'",
+			})
+		case "party size exceeds maximum":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Party size exceeds the maximum",
+			})
+		case "reservation date is not on a slot boundary":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Reservation date must be at the start of a time slot",
+			})
+		case "slot is full":
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "No seats available for the selected time slot",
+			})
+		case "closed on reservation date":
 			return c.JSON(http.StatusBadRequest, map[string]string{
-				"error": "Invalid reservation date format. Use 'YYYY-MM-DD HH:MM:SS'",
+				"error": "The restaurant is closed on the selected date",
+			})
+		case "outside business hours":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Reservation time is outside business hours",
+			})
+		case "email not verified":
+			return c.JSON(http.StatusForbidden, map[string]string{
+				"error": "Email address is not verified",
 			})
 		case "user not found":
 			return c.JSON(http.StatusNotFound, map[string]string{
@@ -166,14 +299,250 @@ func (h *ReservationHandler) AddReservation(c echo.Context) error {
 	}
 	log.Println("Notification created successfully")
 
-	// Redisに通知メッセージをパブリッシュ
-	err = websocket.PublishToRedis("reservation-notifications", notificationMessage)
+	// ブローカーに通知メッセージをパブリッシュ
+	// 予約したユーザー本人と店舗スタッフにのみ配信する
+	recipient := websocket.StaffRecipient()
+	recipient.UserID = userID
+	err = h.Publisher.Publish(websocket.ChannelReservationNotifications, websocket.Message{
+		Type:      "reservation_notification",
+		Content:   notificationMessage,
+		Recipient: recipient,
+	})
 	if err != nil {
-		log.Printf("Failed to publish notification to Redis: %v", err)
+		log.Printf("Failed to publish notification: %v", err)
+	} else {
+		log.Println("Published reservation notification successfully")
 	}
-	log.Println("Published reservation notification to Redis successfully")
 
 	return c.JSON(http.StatusCreated, map[string]string{
 		"message": "Reservation created successfully",
 	})
 }
+
+// 予約の内容やステータスを変更するハンドラー
+// 日時・人数・リクエストは予約したユーザー本人と店舗スタッフが変更できる。
+// ステータスは店舗スタッフのみ変更でき、予約したユーザー本人はキャンセルのみ行える。
+func (h *ReservationHandler) UpdateReservation(c echo.Context) error {
+	log.Println("Updating reservation...")
+
+	// JWTミドルウェアで検証済みのClaimsを取得
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		log.Printf("Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	// リクエストボディからデータを取得
+	// 日時・人数・リクエストとステータスのいずれか、または両方を指定する
+	// 日時・人数・リクエストは指定した項目のみ変更する
+	type RequestBody struct {
+		ReservationDate *string `json:"reservation_date"` // 予約日
+		NumPeople       *int    `json:"num_people"`       // 人数
+		SpecialRequest  *string `json:"special_request"`  // 特別リクエスト
+		Status          string  `json:"status"`           // 遷移先のステータス
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+	updateDetails := reqBody.ReservationDate != nil || reqBody.NumPeople != nil || reqBody.SpecialRequest != nil
+	if !updateDetails && reqBody.Status == "" {
+		log.Println("No changes specified")
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "No changes specified",
+		})
+	}
+
+	reservation, status, message := h.fetchReservationForUser(claims, c.Param("id"), auth.HasPermission(c, models.PermissionReservationsManage))
+	if reservation == nil {
+		return c.JSON(status, map[string]string{"error": message})
+	}
+
+	// 店舗スタッフ以外はキャンセル以外のステータスに変更できない
+	if reqBody.Status != "" && reqBody.Status != models.ReservationStatusCancelled && !auth.HasPermission(c, models.PermissionReservationsManage) {
+		log.Printf("User %s is not allowed to change reservation status to %s", claims.UserID, reqBody.Status)
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Forbidden",
+		})
+	}
+
+	// 日時・人数・リクエストだけが保存されないよう、変更前にステータスを遷移できるか確認する
+	transitionStatus := reqBody.Status != "" && reqBody.Status != reservation.Status
+	if transitionStatus {
+		if !models.IsValidReservationStatus(reqBody.Status) {
+			log.Printf("Invalid reservation status: %s", reqBody.Status)
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid reservation status",
+			})
+		}
+		if !models.CanTransitionReservationStatus(reservation.Status, reqBody.Status) {
+			log.Printf("Invalid status transition for reservation %s: %s -> %s", reservation.ID, reservation.Status, reqBody.Status)
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Reservation cannot be changed to " + reqBody.Status,
+			})
+		}
+	}
+
+	// 日時・人数・リクエストを変更する
+	if updateDetails {
+		var err error
+		reservation, err = h.ReservationService.UpdateReservation(reservation.ID, reqBody.ReservationDate, reqBody.NumPeople, reqBody.SpecialRequest)
+		if err != nil {
+			switch err.Error() {
+			case "id, reservation date, and num_people are required":
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Reservation date must not be empty and num_people must be positive",
+				})
+			case "invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'":
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'",
+				})
+			case "reservation not found":
+				return c.JSON(http.StatusNotFound, map[string]string{
+					"error": "Reservation not found",
+				})
+			case "reservation cannot be modified":
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "Reservation can no longer be modified",
+				})
+			case "party size exceeds maximum":
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Party size exceeds the maximum",
+				})
+			case "reservation date is not on a slot boundary":
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Reservation date must be at the start of a time slot",
+				})
+			case "slot is full":
+				return c.JSON(http.StatusConflict, map[string]string{
+					"error": "No seats available for the selected time slot",
+				})
+			case "closed on reservation date":
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "The restaurant is closed on the selected date",
+				})
+			case "outside business hours":
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Reservation time is outside business hours",
+				})
+			default:
+				log.Printf("Failed to update reservation: %v", err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to update reservation",
+				})
+			}
+		}
+		log.Println("Reservation updated successfully")
+	}
+
+	// ステータスを遷移させる
+	if transitionStatus {
+		return h.changeStatus(c, reservation.ID, reqBody.Status)
+	}
+
+	return c.JSON(http.StatusOK, reservation)
+}
+
+// 予約をキャンセルするハンドラー
+// 予約したユーザー本人と店舗スタッフがキャンセルできる。
+func (h *ReservationHandler) CancelReservation(c echo.Context) error {
+	log.Println("Cancelling reservation...")
+
+	// JWTミドルウェアで検証済みのClaimsを取得
+	claims, ok := auth.GetClaims(c)
+	if !ok {
+		log.Printf("Claims not found in context")
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+
+	reservation, status, message := h.fetchReservationForUser(claims, c.Param("id"), auth.HasPermission(c, models.PermissionReservationsManage))
+	if reservation == nil {
+		return c.JSON(status, map[string]string{"error": message})
+	}
+
+	return h.changeStatus(c, reservation.ID, models.ReservationStatusCancelled)
+}
+
+// 予約を取得し、ユーザーが操作できるかを確認する
+// 操作できない場合はnilと、レスポンスのステータスコードとエラーメッセージを返す。
+func (h *ReservationHandler) fetchReservationForUser(claims *auth.Claims, id string, canManage bool) (*models.ReservationData, int, string) {
+	reservation, err := h.ReservationService.FetchReservationById(id)
+	if err != nil {
+		if err.Error() == "reservation not found" {
+			return nil, http.StatusNotFound, "Reservation not found"
+		}
+		log.Printf("Failed to fetch reservation: %v", err)
+		return nil, http.StatusInternalServerError, "Failed to fetch reservation"
+	}
+
+	// 全予約の管理権限がない場合は、自分の予約のみ操作できる
+	// 他のユーザーの予約の存在が分からないよう、見つからない場合と同じレスポンスを返す
+	if reservation.UserId != claims.UserID && !canManage {
+		log.Printf("User %s is not allowed to access reservation %s", claims.UserID, id)
+		return nil, http.StatusNotFound, "Reservation not found"
+	}
+	return reservation, 0, ""
+}
+
+// 予約のステータスを遷移させ、予約したユーザーに通知する
+func (h *ReservationHandler) changeStatus(c echo.Context, id, status string) error {
+	reservation, err := h.ReservationService.ChangeReservationStatus(id, status)
+	if err != nil {
+		switch err.Error() {
+		case "invalid reservation status":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid reservation status",
+			})
+		case "reservation not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "Reservation not found",
+			})
+		case "invalid status transition":
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Reservation cannot be changed to " + status,
+			})
+		case "reservation status conflict":
+			return c.JSON(http.StatusConflict, map[string]string{
+				"error": "Reservation was updated by another request. Please reload and try again",
+			})
+		default:
+			log.Printf("Failed to change reservation status: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to change reservation status",
+			})
+		}
+	}
+	log.Printf("Reservation %s changed to %s", reservation.ID, reservation.Status)
+
+	h.notifyStatusChange(reservation)
+	return c.JSON(http.StatusOK, reservation)
+}
+
+// 予約のステータスの変更を予約したユーザーに通知する
+// ステータスは変更済みのため、通知に失敗した場合もエラーにはしない。
+func (h *ReservationHandler) notifyStatusChange(reservation *models.ReservationData) {
+	notificationMessage := "Reservation for " + reservation.LocalReservationDate().Format("2006-01-02 15:04") + " is now " + reservation.Status
+	if err := h.NotificationService.CreateNotification(reservation.UserId, reservation.ID, notificationMessage); err != nil {
+		log.Printf("Error creating notification: %v", err)
+	}
+
+	err := h.Publisher.Publish(websocket.ChannelReservationNotifications, websocket.Message{
+		Type:      "reservation_notification",
+		Content:   notificationMessage,
+		Recipient: websocket.UserRecipient(reservation.UserId),
+	})
+	if err != nil {
+		log.Printf("Failed to publish notification: %v", err)
+		return
+	}
+	log.Println("Published reservation status notification successfully")
+}
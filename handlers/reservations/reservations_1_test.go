@@ -1,6 +1,7 @@
 package handlers_reservations
 
 import (
+	"backend/auth"
 	"backend/models"
 	services_reservations "backend/services/reservations"
 	"errors"
@@ -22,9 +23,12 @@ func TestHandler_GetReservations(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
+	// 全予約を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックデータの設定
 	reservationDate1, _ := time.Parse(time.RFC3339, "2024-10-01T18:00:00Z")
@@ -57,9 +61,12 @@ func TestHandler_GetReservations_ServiceError(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
+	// 全予約を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックデータの設定
 
@@ -83,9 +90,12 @@ func TestHandler_GetReservations_NoData(t *testing.T) {
 	rec := httptest.NewRecorder()
 	c := e.NewContext(req, rec)
 
+	// 全予約を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックデータの設定
 
@@ -102,7 +112,7 @@ func TestHandler_GetReservations_NoData(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
-func TestHandler_GetReservationByUserId(t *testing.T) {
+func TestHandler_GetReservationsByUserId(t *testing.T) {
 	// Echoのセットアップ
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/reservations/:user_id", nil)
@@ -113,31 +123,37 @@ func TestHandler_GetReservationByUserId(t *testing.T) {
 	c.SetParamNames("user_id")
 	c.SetParamValues("user1")
 
+	// 本人として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックの挙動を設定
-	mockReservation := &models.ReservationData{
-		ID:             "reservation1",
-		UserId:         "user1",
-		SpecialRequest: "Reservation 1",
-		CreatedAt:      time.Now(),
+	nextCursor := "next"
+	mockPage := &models.ReservationPage{
+		Items: []models.ReservationData{
+			{ID: "reservation1", UserId: "user1", SpecialRequest: "Reservation 1", CreatedAt: time.Now()},
+		},
+		NextCursor: &nextCursor,
 	}
-	mockService.On("FetchReservationByUserId", "user1").Return(mockReservation, nil)
+	mockService.On("SearchReservationsByUserId", "user1", models.ReservationSearchParams{}).Return(mockPage, nil)
 
 	// ハンドラーを実行
-	handler.GetReservationByUserId(c)
+	handler.GetReservationsByUserId(c)
 
 	// ステータスコードとレスポンス内容を確認
 	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"items":[`)
 	assert.Contains(t, rec.Body.String(), "Reservation 1")
+	assert.Contains(t, rec.Body.String(), `"next_cursor":"next"`)
 
 	// モックが期待通りに呼び出されたか確認
 	mockService.AssertExpectations(t)
 }
 
-func TestHandler_GetReservationByUserId_ValidationError(t *testing.T) {
+func TestHandler_GetReservationsByUserId_ValidationError(t *testing.T) {
 	// Echoのセットアップ
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/reservations/:user_id", nil)
@@ -148,15 +164,18 @@ func TestHandler_GetReservationByUserId_ValidationError(t *testing.T) {
 	c.SetParamNames("user_id")
 	c.SetParamValues("user1")
 
+	// 本人として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックの挙動を設定
-	mockService.On("FetchReservationByUserId", mock.Anything).Return(nil, errors.New("userId is required"))
+	mockService.On("SearchReservationsByUserId", mock.Anything, mock.Anything).Return(nil, errors.New("userId is required"))
 
 	// ハンドラーを実行
-	handler.GetReservationByUserId(c)
+	handler.GetReservationsByUserId(c)
 
 	// ステータスコードとレスポンス内容を確認
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
@@ -166,7 +185,7 @@ func TestHandler_GetReservationByUserId_ValidationError(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
-func TestHandler_GetReservationByUserId_NoReservationData(t *testing.T) {
+func TestHandler_GetReservationsByUserId_NoReservations(t *testing.T) {
 	// Echoのセットアップ
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/reservations/:user_id", nil)
@@ -177,25 +196,28 @@ func TestHandler_GetReservationByUserId_NoReservationData(t *testing.T) {
 	c.SetParamNames("user_id")
 	c.SetParamValues("user1")
 
+	// 本人として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックの挙動を設定
-	mockService.On("FetchReservationByUserId", mock.Anything).Return(nil, errors.New("reservation not found"))
+	mockService.On("SearchReservationsByUserId", mock.Anything, mock.Anything).Return(&models.ReservationPage{Items: []models.ReservationData{}}, nil)
 
 	// ハンドラーを実行
-	handler.GetReservationByUserId(c)
+	handler.GetReservationsByUserId(c)
 
-	// ステータスコードとレスポンス内容を確認
-	assert.Equal(t, http.StatusNotFound, rec.Code)
-	assert.Contains(t, rec.Body.String(), "Reservation not found")
+	// 予約がない場合も空の一覧を返す
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"items":[],"next_cursor":null}`, rec.Body.String())
 
 	// モックが期待通りに呼び出されたか確認
 	mockService.AssertExpectations(t)
 }
 
-func TestHandler_GetReservationByUserId_ServerError(t *testing.T) {
+func TestHandler_GetReservationsByUserId_ServerError(t *testing.T) {
 	// Echoのセットアップ
 	e := echo.New()
 	req := httptest.NewRequest(http.MethodGet, "/api/reservations/:user_id", nil)
@@ -206,20 +228,211 @@ func TestHandler_GetReservationByUserId_ServerError(t *testing.T) {
 	c.SetParamNames("user_id")
 	c.SetParamValues("user1")
 
+	// 本人として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
 	// モックサービスをインスタンス化
 	mockService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockService, nil)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
 
 	// モックの挙動を設定
-	mockService.On("FetchReservationByUserId", mock.Anything).Return(nil, errors.New("server error"))
+	mockService.On("SearchReservationsByUserId", mock.Anything, mock.Anything).Return(nil, errors.New("server error"))
 
 	// ハンドラーを実行
-	handler.GetReservationByUserId(c)
+	handler.GetReservationsByUserId(c)
 
 	// ステータスコードとレスポンス内容を確認
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
-	assert.Contains(t, rec.Body.String(), "Failed to not reservation")
+	assert.Contains(t, rec.Body.String(), "Failed to fetch reservations")
 
 	// モックが期待通りに呼び出されたか確認
 	mockService.AssertExpectations(t)
 }
+
+func TestHandler_GetReservations_Customer(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/reservations", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// 一般の予約客として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
+	// モックサービスをインスタンス化
+	mockService := new(services_reservations.MockReservationService)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
+
+	// モックデータの設定
+	mockReservations := []models.ReservationData{
+		{ID: "1", UserId: "user1", NumPeople: 2, SpecialRequest: "Own reservation", Status: "pending"},
+	}
+	mockService.On("FetchReservationsByUserId", "user1").Return(mockReservations, nil)
+
+	// ハンドラーを実行
+	handler.GetReservations(c)
+
+	// 自分の予約のみが返されることを確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Own reservation")
+
+	// 全予約の取得は呼び出されないことを確認
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "FetchReservations")
+}
+
+func TestHandler_GetReservations_Unauthorized(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/reservations", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスをインスタンス化
+	mockService := new(services_reservations.MockReservationService)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
+
+	// ハンドラーを実行
+	handler.GetReservations(c)
+
+	// ステータスコードの確認
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	mockService.AssertNotCalled(t, "FetchReservations")
+}
+
+func TestHandler_GetReservationsByUserId_Forbidden(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/reservations/:user_id", nil)
+	rec := httptest.NewRecorder()
+
+	// コンテキストに他のユーザーのIDを設定
+	c := e.NewContext(req, rec)
+	c.SetParamNames("user_id")
+	c.SetParamValues("user2")
+
+	// 一般の予約客として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
+	// モックサービスをインスタンス化
+	mockService := new(services_reservations.MockReservationService)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
+
+	// ハンドラーを実行
+	handler.GetReservationsByUserId(c)
+
+	// 他のユーザーの予約は参照できないことを確認
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Forbidden")
+	mockService.AssertNotCalled(t, "SearchReservationsByUserId", "user2", mock.Anything)
+}
+
+func TestHandler_GetReservationsByUserId_Staff(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/reservations/:user_id", nil)
+	rec := httptest.NewRecorder()
+
+	// コンテキストに他のユーザーのIDを設定
+	c := e.NewContext(req, rec)
+	c.SetParamNames("user_id")
+	c.SetParamValues("user2")
+
+	// 全予約を管理できるスタッフとして認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "staff1", Role: models.RoleStaff})
+
+	// モックサービスをインスタンス化
+	mockService := new(services_reservations.MockReservationService)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
+
+	// モックの挙動を設定
+	mockService.On("SearchReservationsByUserId", "user2", models.ReservationSearchParams{}).Return(&models.ReservationPage{Items: []models.ReservationData{{ID: "reservation2", UserId: "user2"}}}, nil)
+
+	// ハンドラーを実行
+	handler.GetReservationsByUserId(c)
+
+	// スタッフは他のユーザーの予約も参照できることを確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "reservation2")
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetReservationsByUserId_Filters(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/api/reservations/me?period=upcoming&status=pending,confirmed&status=seated&from=2024-10-01&to=2024-10-31&limit=10&cursor=abc", nil)
+	rec := httptest.NewRecorder()
+
+	// meを指定した場合は自分の予約を取得する
+	c := e.NewContext(req, rec)
+	c.SetParamNames("user_id")
+	c.SetParamValues("me")
+
+	// 本人として認証済みの状態にする
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
+	// モックサービスをインスタンス化
+	mockService := new(services_reservations.MockReservationService)
+	handler := NewReservationHandler(nil, mockService, nil, nil)
+
+	// クエリパラメータが検索条件として渡されることを確認
+	mockService.On("SearchReservationsByUserId", "user1", models.ReservationSearchParams{
+		Period:   models.ReservationPeriodUpcoming,
+		Statuses: []string{models.ReservationStatusPending, models.ReservationStatusConfirmed, models.ReservationStatusSeated},
+		From:     "2024-10-01",
+		To:       "2024-10-31",
+		Cursor:   "abc",
+		Limit:    10,
+	}).Return(&models.ReservationPage{Items: []models.ReservationData{}}, nil)
+
+	// ハンドラーを実行
+	handler.GetReservationsByUserId(c)
+
+	// ステータスコードを確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestHandler_GetReservationsByUserId_InvalidQuery(t *testing.T) {
+	testCases := []struct {
+		name        string
+		query       string
+		serviceErr  error
+		expectedErr string
+	}{
+		{"invalid limit", "?limit=ten", nil, "Invalid limit"},
+		{"zero limit", "?limit=0", nil, "Invalid limit"},
+		{"invalid period", "?period=today", errors.New("invalid period"), "Invalid period"},
+		{"invalid status", "?status=reserved", errors.New("invalid reservation status"), "Invalid reservation status"},
+		{"invalid date range", "?from=2024-10-31&to=2024-10-01", errors.New("invalid date range"), "Invalid date range"},
+		{"invalid cursor", "?cursor=abc", errors.New("invalid cursor"), "Invalid cursor"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/reservations/user1"+tc.query, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("user_id")
+			c.SetParamValues("user1")
+			c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: "user1", Role: models.RoleCustomer})
+
+			// モックサービスをインスタンス化
+			mockService := new(services_reservations.MockReservationService)
+			handler := NewReservationHandler(nil, mockService, nil, nil)
+			mockService.On("SearchReservationsByUserId", "user1", mock.Anything).Return(nil, tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.GetReservationsByUserId(c)
+
+			// ステータスコードとレスポンス内容を確認
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.expectedErr)
+			if tc.serviceErr == nil {
+				mockService.AssertNotCalled(t, "SearchReservationsByUserId", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
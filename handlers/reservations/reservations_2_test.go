@@ -2,11 +2,14 @@ package handlers_reservations
 
 import (
 	"backend/auth"
+	"backend/models"
 	services_notifications "backend/services/notifications"
 	services_reservations "backend/services/reservations"
+	"backend/websocket"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
@@ -16,6 +19,21 @@ import (
 	"github.com/stretchr/testify/mock"
 )
 
+// テスト用の署名鍵
+var testSecret = []byte("test-secret")
+
+func TestMain(m *testing.M) {
+	// テスト用のキーリングを設定
+	testKeyring, err := auth.NewKeyring([]*auth.SigningKey{auth.NewHMACKey("test", testSecret)}, "test")
+	if err != nil {
+		panic("Error creating test keyring: " + err.Error())
+	}
+	auth.SetKeyring(testKeyring)
+
+	// テストを実行
+	os.Exit(m.Run())
+}
+
 func TestHandler_AddReservation(t *testing.T) {
 	// Echoのセットアップ
 	e := echo.New()
@@ -28,13 +46,14 @@ func TestHandler_AddReservation(t *testing.T) {
 	// モックサービスをインスタンス化
 	mockReservationService := new(services_reservations.MockReservationService)
 	mockNotificationService := new(services_notifications.MockNotificationService)
-	handler := NewReservationHandler(nil, mockReservationService, mockNotificationService)
+	mockPublisher := new(websocket.MockPublisher)
+	handler := NewReservationHandler(nil, mockReservationService, mockNotificationService, mockPublisher)
 
 	// JWTトークンのモックを作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
 		UserID: "user1",
 	})
-	tokenString, _ := token.SignedString(auth.JwtKey) // 実際のキーを使用してトークンを生成
+	tokenString, _ := token.SignedString(testSecret) // テスト用のキーを使用してトークンを生成
 
 	cookie := &http.Cookie{
 		Name:  "token",
@@ -43,11 +62,18 @@ func TestHandler_AddReservation(t *testing.T) {
 	req.AddCookie(cookie)
 
 	// モックデータの設定
-	mockReservationService.On("CreateReservation", "user1", "2024-10-01 18:00:00", 2, "Window seat", "confirmed").Return("reservationId", nil)
+	mockReservationService.On("CreateReservation", "user1", "2024-10-01 18:00:00", 2, "Window seat").Return("reservationId", nil)
 	mockNotificationService.On("CreateNotification", "user1", "reservationId", "New reservation created for user user1").Return(nil)
+	// 予約したユーザー本人と店舗スタッフに通知をパブリッシュする
+	mockPublisher.On("Publish", websocket.ChannelReservationNotifications, mock.MatchedBy(func(message websocket.Message) bool {
+		return message.Content == "New reservation created for user user1" &&
+			message.Recipient.Includes("user1", models.RoleCustomer) &&
+			message.Recipient.Includes("staff1", models.RoleStaff) &&
+			!message.Recipient.Includes("user2", models.RoleCustomer)
+	})).Return(nil)
 
-	// ハンドラーを実行
-	handler.AddReservation(c)
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
 
 	// ステータスコードの確認
 	assert.Equal(t, http.StatusCreated, rec.Code)
@@ -58,6 +84,7 @@ func TestHandler_AddReservation(t *testing.T) {
 	// モックが期待通りに呼び出されたかを確認
 	mockReservationService.AssertExpectations(t)
 	mockNotificationService.AssertExpectations(t)
+	mockPublisher.AssertExpectations(t)
 }
 
 func TestHandler_AddReservation_ValidationError(t *testing.T) {
@@ -71,13 +98,13 @@ func TestHandler_AddReservation_ValidationError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockReservationService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockReservationService, nil)
+	handler := NewReservationHandler(nil, mockReservationService, nil, nil)
 
 	// JWTトークンのモックを作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
 		UserID: "user1",
 	})
-	tokenString, _ := token.SignedString(auth.JwtKey)
+	tokenString, _ := token.SignedString(testSecret)
 
 	cookie := &http.Cookie{
 		Name:  "token",
@@ -86,11 +113,11 @@ func TestHandler_AddReservation_ValidationError(t *testing.T) {
 	req.AddCookie(cookie)
 
 	// 予約作成時にモックを設定（通常はここでエラーが返るが、ユーザーが存在しないため不要）
-	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return("", errors.New("userID, reservation date, and num_people are required"))
 
-	// ハンドラーを実行
-	handler.AddReservation(c)
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
 
 	// ステータスコードの確認
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
@@ -113,13 +140,13 @@ func TestHandler_AddReservation_ValidationDateFormat(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockReservationService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockReservationService, nil)
+	handler := NewReservationHandler(nil, mockReservationService, nil, nil)
 
 	// JWTトークンのモックを作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
 		UserID: "user1",
 	})
-	tokenString, _ := token.SignedString(auth.JwtKey)
+	tokenString, _ := token.SignedString(testSecret)
 
 	cookie := &http.Cookie{
 		Name:  "token",
@@ -128,17 +155,17 @@ func TestHandler_AddReservation_ValidationDateFormat(t *testing.T) {
 	req.AddCookie(cookie)
 
 	// 予約作成時にモックを設定（通常はここでエラーが返るが、ユーザーが存在しないため不要）
-	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
-		Return("", errors.New("invalid reservation date format. Use 'YYYY-MM-DD HH:MM:SS'"))
+	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("", errors.New("invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'"))
 
-	// ハンドラーを実行
-	handler.AddReservation(c)
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
 
 	// ステータスコードの確認
 	assert.Equal(t, http.StatusBadRequest, rec.Code)
 
 	// レスポンス内容の確認
-	assert.Contains(t, rec.Body.String(), "Invalid reservation date format. Use 'YYYY-MM-DD HH:MM:SS'")
+	assert.Contains(t, rec.Body.String(), "Invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'")
 
 	// モックが期待通りに呼び出されたかを確認
 	mockReservationService.AssertExpectations(t)
@@ -155,13 +182,13 @@ func TestHandler_AddReservation_UserNotFound(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockReservationService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockReservationService, nil)
+	handler := NewReservationHandler(nil, mockReservationService, nil, nil)
 
 	// JWTトークンのモックを作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
 		UserID: "user1",
 	})
-	tokenString, _ := token.SignedString(auth.JwtKey)
+	tokenString, _ := token.SignedString(testSecret)
 
 	cookie := &http.Cookie{
 		Name:  "token",
@@ -170,11 +197,11 @@ func TestHandler_AddReservation_UserNotFound(t *testing.T) {
 	req.AddCookie(cookie)
 
 	// 予約作成時にモックを設定（通常はここでエラーが返るが、ユーザーが存在しないため不要）
-	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return("", errors.New("user not found")) // ここではエラーが発生することはないが、あくまで安全のため
 
-	// ハンドラーを実行
-	handler.AddReservation(c)
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
 
 	// ステータスコードの確認
 	assert.Equal(t, http.StatusNotFound, rec.Code)
@@ -186,6 +213,48 @@ func TestHandler_AddReservation_UserNotFound(t *testing.T) {
 	mockReservationService.AssertExpectations(t)
 }
 
+func TestHandler_AddReservation_EmailNotVerified(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	body := `{"reservation_date":"2024-10-01 18:00:00", "num_people":2, "special_request":"Window seat", "status":"confirmed"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/reservation", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// モックサービスをインスタンス化
+	mockReservationService := new(services_reservations.MockReservationService)
+	handler := NewReservationHandler(nil, mockReservationService, nil, nil)
+
+	// JWTトークンのモックを作成
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
+		UserID: "user1",
+	})
+	tokenString, _ := token.SignedString(testSecret)
+
+	cookie := &http.Cookie{
+		Name:  "token",
+		Value: tokenString,
+	}
+	req.AddCookie(cookie)
+
+	// メールアドレスが未確認のため予約が拒否される
+	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return("", errors.New("email not verified"))
+
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
+
+	// ステータスコードの確認
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// レスポンス内容の確認
+	assert.Contains(t, rec.Body.String(), "Email address is not verified")
+
+	// モックが期待通りに呼び出されたかを確認
+	mockReservationService.AssertExpectations(t)
+}
+
 func TestHandler_AddReservation_CreateError(t *testing.T) {
 	// Echoのセットアップ
 	e := echo.New()
@@ -197,13 +266,13 @@ func TestHandler_AddReservation_CreateError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockReservationService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockReservationService, nil)
+	handler := NewReservationHandler(nil, mockReservationService, nil, nil)
 
 	// JWTトークンのモックを作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
 		UserID: "user1",
 	})
-	tokenString, _ := token.SignedString(auth.JwtKey)
+	tokenString, _ := token.SignedString(testSecret)
 
 	cookie := &http.Cookie{
 		Name:  "token",
@@ -212,11 +281,11 @@ func TestHandler_AddReservation_CreateError(t *testing.T) {
 	req.AddCookie(cookie)
 
 	// 予約作成時にモックを設定（通常はここでエラーが返るが、ユーザーが存在しないため不要）
-	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return("", errors.New("failed to create reservation"))
 
-	// ハンドラーを実行
-	handler.AddReservation(c)
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
 
 	// ステータスコードの確認
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
@@ -239,13 +308,13 @@ func TestHandler_AddReservation_ServerError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockReservationService := new(services_reservations.MockReservationService)
-	handler := NewReservationHandler(nil, mockReservationService, nil)
+	handler := NewReservationHandler(nil, mockReservationService, nil, nil)
 
 	// JWTトークンのモックを作成
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &auth.Claims{
 		UserID: "user1",
 	})
-	tokenString, _ := token.SignedString(auth.JwtKey)
+	tokenString, _ := token.SignedString(testSecret)
 
 	cookie := &http.Cookie{
 		Name:  "token",
@@ -254,11 +323,11 @@ func TestHandler_AddReservation_ServerError(t *testing.T) {
 	req.AddCookie(cookie)
 
 	// 予約作成時にモックを設定（通常はここでエラーが返るが、ユーザーが存在しないため不要）
-	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+	mockReservationService.On("CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 		Return("", errors.New("server error"))
 
-	// ハンドラーを実行
-	handler.AddReservation(c)
+	// JWTミドルウェアを通してハンドラーを実行
+	auth.JWT()(handler.AddReservation)(c)
 
 	// ステータスコードの確認
 	assert.Equal(t, http.StatusInternalServerError, rec.Code)
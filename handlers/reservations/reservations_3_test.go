@@ -0,0 +1,266 @@
+package handlers_reservations
+
+import (
+	"backend/auth"
+	"backend/models"
+	services_notifications "backend/services/notifications"
+	services_reservations "backend/services/reservations"
+	"backend/websocket"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// テスト用のハンドラーとモック
+type testHandler struct {
+	handler             *ReservationHandler
+	reservationService  *services_reservations.MockReservationService
+	notificationService *services_notifications.MockNotificationService
+	publisher           *websocket.MockPublisher
+}
+
+func newTestHandler() *testHandler {
+	reservationService := new(services_reservations.MockReservationService)
+	notificationService := new(services_notifications.MockNotificationService)
+	publisher := new(websocket.MockPublisher)
+	return &testHandler{
+		handler:             NewReservationHandler(nil, reservationService, notificationService, publisher),
+		reservationService:  reservationService,
+		notificationService: notificationService,
+		publisher:           publisher,
+	}
+}
+
+// 認証済みのユーザーとしてリクエストのコンテキストを作成
+func newReservationContext(method, path, body, userId, role string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("reservation1")
+	c.Set(auth.ClaimsContextKey, &auth.Claims{UserID: userId, Role: role})
+	return c, rec
+}
+
+// 予約したユーザーへの通知を期待する
+func (h *testHandler) expectOwnerNotification(message string) {
+	h.notificationService.On("CreateNotification", "user1", "reservation1", message).Return(nil)
+	h.publisher.On("Publish", websocket.ChannelReservationNotifications, websocket.Message{
+		Type:      "reservation_notification",
+		Content:   message,
+		Recipient: websocket.UserRecipient("user1"),
+	}).Return(nil)
+}
+
+var testReservationDate = time.Date(2024, 10, 1, 18, 0, 0, 0, time.UTC)
+
+// テスト用に値へのポインタを返す
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestHandler_UpdateReservation_Details(t *testing.T) {
+	h := newTestHandler()
+	c, rec := newReservationContext(http.MethodPut, "/api/reservations/reservation1", `{"reservation_date":"2024-10-02 19:00:00","num_people":3}`, "user1", models.RoleCustomer)
+
+	// モックデータの設定
+	// 指定しなかったリクエストはnilとして渡し、変更しない
+	h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", Status: models.ReservationStatusPending}, nil)
+	h.reservationService.On("UpdateReservation", "reservation1", ptr("2024-10-02 19:00:00"), ptr(3), (*string)(nil)).Return(&models.ReservationData{ID: "reservation1", UserId: "user1", NumPeople: 3, Status: models.ReservationStatusPending}, nil)
+
+	// ハンドラーを実行
+	assert.NoError(t, h.handler.UpdateReservation(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"num_people":3`)
+
+	// ステータスを変更していないため通知しない
+	h.reservationService.AssertNotCalled(t, "ChangeReservationStatus", mock.Anything, mock.Anything)
+	h.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+	h.reservationService.AssertExpectations(t)
+}
+
+func TestHandler_UpdateReservation_SpecialRequestOnly(t *testing.T) {
+	h := newTestHandler()
+	c, rec := newReservationContext(http.MethodPut, "/api/reservations/reservation1", `{"special_request":"Birthday cake"}`, "user1", models.RoleCustomer)
+
+	// モックデータの設定
+	// 日時と人数は指定していないため、nilとして渡し、変更しない
+	h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", Status: models.ReservationStatusPending}, nil)
+	h.reservationService.On("UpdateReservation", "reservation1", (*string)(nil), (*int)(nil), ptr("Birthday cake")).Return(&models.ReservationData{ID: "reservation1", UserId: "user1", NumPeople: 2, SpecialRequest: "Birthday cake", Status: models.ReservationStatusPending}, nil)
+
+	// ハンドラーを実行
+	assert.NoError(t, h.handler.UpdateReservation(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"special_request":"Birthday cake"`)
+
+	h.reservationService.AssertExpectations(t)
+}
+
+func TestHandler_UpdateReservation_StatusByStaff(t *testing.T) {
+	h := newTestHandler()
+	c, rec := newReservationContext(http.MethodPut, "/api/reservations/reservation1", `{"status":"confirmed"}`, "staff1", models.RoleStaff)
+
+	// モックデータの設定
+	h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", ReservationDate: testReservationDate, Status: models.ReservationStatusPending}, nil)
+	h.reservationService.On("ChangeReservationStatus", "reservation1", models.ReservationStatusConfirmed).Return(&models.ReservationData{ID: "reservation1", UserId: "user1", ReservationDate: testReservationDate, Status: models.ReservationStatusConfirmed}, nil)
+	h.expectOwnerNotification("Reservation for 2024-10-01 18:00 is now confirmed")
+
+	// ハンドラーを実行
+	assert.NoError(t, h.handler.UpdateReservation(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"confirmed"`)
+
+	h.reservationService.AssertExpectations(t)
+	h.notificationService.AssertExpectations(t)
+	h.publisher.AssertExpectations(t)
+}
+
+func TestHandler_UpdateReservation_Forbidden(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		userId         string
+		expectedStatus int
+	}{
+		// 予約したユーザー本人でもステータスはキャンセルにしか変更できない
+		{"customer confirms", `{"status":"confirmed"}`, "user1", http.StatusForbidden},
+		// 他のユーザーの予約は存在しないものとして扱う
+		{"other user", `{"num_people":3,"reservation_date":"2024-10-02 19:00:00"}`, "user2", http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler()
+			c, rec := newReservationContext(http.MethodPut, "/api/reservations/reservation1", tc.body, tc.userId, models.RoleCustomer)
+
+			// モックデータの設定
+			h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", Status: models.ReservationStatusPending}, nil)
+
+			// ハンドラーを実行
+			assert.NoError(t, h.handler.UpdateReservation(c))
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+
+			h.reservationService.AssertNotCalled(t, "UpdateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			h.reservationService.AssertNotCalled(t, "ChangeReservationStatus", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_UpdateReservation_DetailsWithInvalidTransition(t *testing.T) {
+	h := newTestHandler()
+	c, rec := newReservationContext(http.MethodPut, "/api/reservations/reservation1", `{"num_people":3,"status":"completed"}`, "staff1", models.RoleStaff)
+
+	// モックデータの設定
+	h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", NumPeople: 2, Status: models.ReservationStatusPending}, nil)
+
+	// ハンドラーを実行
+	assert.NoError(t, h.handler.UpdateReservation(c))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	// ステータスを遷移できない場合は、人数も変更しない
+	h.reservationService.AssertNotCalled(t, "UpdateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	h.reservationService.AssertNotCalled(t, "ChangeReservationStatus", mock.Anything, mock.Anything)
+}
+
+func TestHandler_UpdateReservation_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		fetchErr       error
+		updateErr      error
+		statusErr      error
+		expectedStatus int
+	}{
+		{"no changes", `{}`, nil, nil, nil, http.StatusBadRequest},
+		{"invalid body", `{"num_people":"three"}`, nil, nil, nil, http.StatusBadRequest},
+		{"not found", `{"status":"seated"}`, errors.New("reservation not found"), nil, nil, http.StatusNotFound},
+		{"cannot be modified", `{"reservation_date":"2024-10-02 19:00:00","num_people":3}`, nil, errors.New("reservation cannot be modified"), nil, http.StatusConflict},
+		{"slot full", `{"reservation_date":"2024-10-02 19:00:00","num_people":3}`, nil, errors.New("slot is full"), nil, http.StatusConflict},
+		{"party too large", `{"reservation_date":"2024-10-02 19:00:00","num_people":12}`, nil, errors.New("party size exceeds maximum"), nil, http.StatusBadRequest},
+		{"outside business hours", `{"reservation_date":"2024-10-02 03:00:00","num_people":3}`, nil, errors.New("outside business hours"), nil, http.StatusBadRequest},
+		{"invalid status", `{"status":"arrived"}`, nil, nil, errors.New("invalid reservation status"), http.StatusBadRequest},
+		{"invalid transition", `{"status":"completed"}`, nil, nil, errors.New("invalid status transition"), http.StatusConflict},
+		{"conflict", `{"status":"seated"}`, nil, nil, errors.New("reservation status conflict"), http.StatusConflict},
+		{"database error", `{"status":"seated"}`, nil, nil, errors.New("failed to update reservation status"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler()
+			c, rec := newReservationContext(http.MethodPut, "/api/reservations/reservation1", tc.body, "staff1", models.RoleStaff)
+
+			// モックデータの設定
+			if tc.fetchErr != nil {
+				h.reservationService.On("FetchReservationById", "reservation1").Return(nil, tc.fetchErr)
+			} else {
+				h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", Status: models.ReservationStatusConfirmed}, nil)
+			}
+			h.reservationService.On("UpdateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, tc.updateErr)
+			h.reservationService.On("ChangeReservationStatus", mock.Anything, mock.Anything).Return(nil, tc.statusErr)
+
+			// ハンドラーを実行
+			assert.NoError(t, h.handler.UpdateReservation(c))
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+
+			// 変更に失敗した場合は通知しない
+			h.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestHandler_CancelReservation(t *testing.T) {
+	h := newTestHandler()
+	c, rec := newReservationContext(http.MethodPost, "/api/reservations/reservation1/cancel", "", "user1", models.RoleCustomer)
+
+	// モックデータの設定
+	h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", ReservationDate: testReservationDate, Status: models.ReservationStatusConfirmed}, nil)
+	h.reservationService.On("ChangeReservationStatus", "reservation1", models.ReservationStatusCancelled).Return(&models.ReservationData{ID: "reservation1", UserId: "user1", ReservationDate: testReservationDate, Status: models.ReservationStatusCancelled}, nil)
+	h.expectOwnerNotification("Reservation for 2024-10-01 18:00 is now cancelled")
+
+	// ハンドラーを実行
+	assert.NoError(t, h.handler.CancelReservation(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"status":"cancelled"`)
+
+	h.reservationService.AssertExpectations(t)
+	h.notificationService.AssertExpectations(t)
+	h.publisher.AssertExpectations(t)
+}
+
+func TestHandler_CancelReservation_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name           string
+		userId         string
+		statusErr      error
+		expectedStatus int
+	}{
+		{"other user", "user2", nil, http.StatusNotFound},
+		{"already completed", "user1", errors.New("invalid status transition"), http.StatusConflict},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler()
+			c, rec := newReservationContext(http.MethodPost, "/api/reservations/reservation1/cancel", "", tc.userId, models.RoleCustomer)
+
+			// モックデータの設定
+			h.reservationService.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", Status: models.ReservationStatusCompleted}, nil)
+			h.reservationService.On("ChangeReservationStatus", "reservation1", models.ReservationStatusCancelled).Return(nil, tc.statusErr)
+
+			// ハンドラーを実行
+			assert.NoError(t, h.handler.CancelReservation(c))
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+
+			h.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+		})
+	}
+}
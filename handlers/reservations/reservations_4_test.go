@@ -0,0 +1,96 @@
+package handlers_reservations
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"backend/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_GetAvailability(t *testing.T) {
+	h := newTestHandler()
+	c, rec := newReservationContext(http.MethodGet, "/api/availability?date=2024-10-02&party_size=4", "", "user1", models.RoleCustomer)
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	start := time.Date(2024, 10, 2, 18, 0, 0, 0, tokyo)
+
+	// モックデータの設定
+	h.reservationService.On("FetchAvailability", "2024-10-02", 4).Return(&models.Availability{
+		Date:      "2024-10-02",
+		TimeZone:  "Asia/Tokyo",
+		PartySize: 4,
+		Slots: []models.AvailabilitySlot{
+			{Start: start.UTC(), StartLocal: start, AvailableSeats: 12},
+		},
+	}, nil)
+
+	// ハンドラーを実行
+	assert.NoError(t, h.handler.GetAvailability(c))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"date":"2024-10-02","time_zone":"Asia/Tokyo","party_size":4,"closed":false,"slots":[{"start":"2024-10-02T09:00:00Z","start_local":"2024-10-02T18:00:00+09:00","available_seats":12}]}`, rec.Body.String())
+}
+
+func TestHandler_GetAvailability_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"missing party size", "date=2024-10-02", nil, http.StatusBadRequest},
+		{"invalid party size", "date=2024-10-02&party_size=two", nil, http.StatusBadRequest},
+		{"invalid date", "date=2024/10/02&party_size=2", errors.New("invalid date format. Use 'YYYY-MM-DD'"), http.StatusBadRequest},
+		{"party too large", "date=2024-10-02&party_size=20", errors.New("party size exceeds maximum"), http.StatusBadRequest},
+		{"database error", "date=2024-10-02&party_size=2", errors.New("failed to fetch availability"), http.StatusInternalServerError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler()
+			c, rec := newReservationContext(http.MethodGet, "/api/availability?"+tc.query, "", "user1", models.RoleCustomer)
+
+			// モックデータの設定
+			h.reservationService.On("FetchAvailability", mock.Anything, mock.Anything).Return(nil, tc.serviceErr)
+
+			// ハンドラーを実行
+			assert.NoError(t, h.handler.GetAvailability(c))
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestHandler_AddReservation_Unavailable(t *testing.T) {
+	testCases := []struct {
+		name           string
+		serviceErr     error
+		expectedStatus int
+	}{
+		{"slot full", errors.New("slot is full"), http.StatusConflict},
+		{"closed", errors.New("closed on reservation date"), http.StatusBadRequest},
+		{"outside business hours", errors.New("outside business hours"), http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := newTestHandler()
+			c, rec := newReservationContext(http.MethodPost, "/api/reservation", `{"reservation_date":"2024-10-02 19:00:00","num_people":4,"special_request":"Window seat"}`, "user1", models.RoleCustomer)
+
+			// モックデータの設定
+			h.reservationService.On("CreateReservation", "user1", "2024-10-02 19:00:00", 4, "Window seat").Return("", tc.serviceErr)
+
+			// ハンドラーを実行
+			assert.NoError(t, h.handler.AddReservation(c))
+			assert.Equal(t, tc.expectedStatus, rec.Code)
+
+			// 予約に失敗した場合は通知しない
+			h.notificationService.AssertNotCalled(t, "CreateNotification", mock.Anything, mock.Anything, mock.Anything)
+			h.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package handlers_users
 
 import (
+	"backend/auth"
+	services_email_verifications "backend/services/email_verifications"
+	services_refresh_tokens "backend/services/refresh_tokens"
 	services_users "backend/services/users"
 	"log"
 	"net/http"
@@ -9,13 +12,21 @@ import (
 )
 
 type UserHandler struct {
-	UserService services_users.UserService
+	UserService              services_users.UserService
+	EmailVerificationService services_email_verifications.EmailVerificationService
+	LoginLimiter             auth.LoginLimiter
+	RefreshTokenService      services_refresh_tokens.RefreshTokenService
+	RevocationStore          auth.RevocationStore
 }
 
 // コンストラクタ
-func NewUserHandler(userService services_users.UserService) *UserHandler {
+func NewUserHandler(userService services_users.UserService, emailVerificationService services_email_verifications.EmailVerificationService, loginLimiter auth.LoginLimiter, refreshTokenService services_refresh_tokens.RefreshTokenService, revocationStore auth.RevocationStore) *UserHandler {
 	return &UserHandler{
-		UserService: userService,
+		UserService:              userService,
+		EmailVerificationService: emailVerificationService,
+		LoginLimiter:             loginLimiter,
+		RefreshTokenService:      refreshTokenService,
+		RevocationStore:          revocationStore,
 	}
 }
 
@@ -57,6 +68,12 @@ func (h *UserHandler) GetUserByEmailAndPassword(c echo.Context) error {
 		})
 	}
 
+	// 失敗が続いているアカウントまたはIPからの試行を拒否
+	if retryAfter := auth.LoginRetryAfter(c, h.LoginLimiter, reqBody.Email); retryAfter > 0 {
+		log.Println("Login attempt rejected due to lockout")
+		return auth.TooManyLoginAttempts(c, retryAfter)
+	}
+
 	// サービス層からユーザーデータを取得
 	user, err := h.UserService.FetchUserByEmailAndPassword(reqBody.Email, reqBody.Password)
 	if err != nil {
@@ -70,8 +87,10 @@ func (h *UserHandler) GetUserByEmailAndPassword(c echo.Context) error {
 				"error": "Invalid email format",
 			})
 		case "user not found":
-			return c.JSON(http.StatusNotFound, map[string]string{
-				"error": "User not found",
+			// メールアドレスの登録有無が分からないよう、パスワード誤りと同じレスポンスを返す
+			auth.RecordLoginFailure(c, h.LoginLimiter, reqBody.Email)
+			return c.JSON(http.StatusUnauthorized, map[string]string{
+				"error": "Invalid email or password",
 			})
 		default:
 			log.Printf("Error fetching user: %v", err)
@@ -81,6 +100,8 @@ func (h *UserHandler) GetUserByEmailAndPassword(c echo.Context) error {
 		}
 	}
 
+	auth.RecordLoginSuccess(c, h.LoginLimiter, reqBody.Email)
+
 	log.Println("Fetched user successfully")
 	return c.JSON(http.StatusOK, user)
 }
@@ -134,8 +155,150 @@ func (h *UserHandler) AddUser(c echo.Context) error {
 		}
 	}
 
+	// 新規ユーザーは未確認の状態で作成されるため、確認メールを送信する
+	// 送信に失敗してもユーザー作成は成功とし、再送信エンドポイントから再試行できるようにする
+	if err := h.EmailVerificationService.SendVerification(reqBody.Email); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+
 	log.Println("User created successfully")
 	return c.JSON(http.StatusCreated, map[string]string{
 		"message": "User created successfully",
 	})
 }
+
+// メールアドレスの確認エンドポイント
+// 確認メールのリンクに含まれるトークンを検証し、メールアドレスを確認済みにする。
+func (h *UserHandler) VerifyEmail(c echo.Context) error {
+	log.Println("Verifying email...")
+
+	// クエリパラメータからトークンを取得
+	token := c.QueryParam("token")
+
+	_, err := h.EmailVerificationService.ConfirmEmail(token)
+	if err != nil {
+		switch err.Error() {
+		case "token is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Token is required",
+			})
+		case "invalid or expired verification token":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid or expired verification token",
+			})
+		default:
+			log.Printf("Failed to verify email: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to verify email",
+			})
+		}
+	}
+
+	log.Println("Email verified successfully")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "Email verified successfully",
+	})
+}
+
+// 確認メールの再送信エンドポイント
+// メールアドレスの登録有無が分からないよう、常に同じレスポンスを返す。
+func (h *UserHandler) ResendVerification(c echo.Context) error {
+	log.Println("Resending verification email...")
+
+	// リクエストボディからデータを取得
+	type RequestBody struct {
+		Email string `json:"email"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	err := h.EmailVerificationService.SendVerification(reqBody.Email)
+	if err != nil {
+		switch err.Error() {
+		case "email is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Email is required",
+			})
+		case "invalid email format":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid email format",
+			})
+		default:
+			log.Printf("Failed to resend verification email: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to resend verification email",
+			})
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "If the email is registered and not yet verified, a verification link has been sent",
+	})
+}
+
+// 指定されたユーザーのロールを変更するハンドラー
+// 管理者のみが利用できる。変更前のロールで発行済みのセッションはすべて失効させる。
+func (h *UserHandler) UpdateUserRole(c echo.Context) error {
+	log.Println("Updating user role...")
+
+	// パスパラメータからidを取得
+	id := c.Param("id")
+
+	// リクエストボディからデータを取得
+	type RequestBody struct {
+		Role string `json:"role"`
+	}
+
+	// リクエストボディをバインド
+	var reqBody RequestBody
+	if err := c.Bind(&reqBody); err != nil {
+		log.Printf("Failed to bind request body: %v", err)
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid request body",
+		})
+	}
+
+	// ロールを変更
+	err := h.UserService.UpdateUserRole(id, reqBody.Role)
+	if err != nil {
+		switch err.Error() {
+		case "id is required":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Id is required",
+			})
+		case "invalid role":
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Invalid role",
+			})
+		case "user not found":
+			return c.JSON(http.StatusNotFound, map[string]string{
+				"error": "User not found",
+			})
+		default:
+			log.Printf("Failed to update role: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": "Failed to update role",
+			})
+		}
+	}
+
+	// 発行済みのトークンには変更前のロールが含まれるため、再ログインを求める
+	if err := auth.RevokeUserSessions(h.RevocationStore, h.RefreshTokenService, id); err != nil {
+		log.Printf("Failed to revoke sessions after role change: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "User role updated, but failed to revoke existing sessions",
+		})
+	}
+
+	log.Println("User role updated successfully")
+	return c.JSON(http.StatusOK, map[string]string{
+		"message": "User role updated successfully",
+	})
+}
@@ -1,6 +1,7 @@
 package handlers_users
 
 import (
+	"backend/auth"
 	"backend/models"
 	services_users "backend/services/users"
 	"errors"
@@ -22,7 +23,7 @@ func TestHandler_GetUsers(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := &services_users.MockUserService{}
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// モックの挙動を設定
 	mockUsers := []models.UserData{
@@ -54,7 +55,7 @@ func TestHandler_GetUsers_ServiceError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := &services_users.MockUserService{}
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービスがエラーを返すようにモックの挙動を設定
 	mockService.On("FetchUsers").Return(nil, errors.New("database error"))
@@ -80,7 +81,7 @@ func TestHandler_GetUsers_NoUsers(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := &services_users.MockUserService{}
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービスがエラーを返すようにモックの挙動を設定
 	mockService.On("FetchUsers").Return([]models.UserData{}, nil)
@@ -108,7 +109,7 @@ func TestHandler_GetUserByEmailAndPassword(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// モックデータの設定
 	mockUser := &models.UserData{
@@ -140,7 +141,7 @@ func TestHandler_GetUserByEmailAndPassword_ValidationError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// モックの挙動を設定（バリデーションエラーを返す）
 	mockService.On("FetchUserByEmailAndPassword", "", "").Return(nil, errors.New("email and password are required"))
@@ -167,7 +168,7 @@ func TestHandler_GetUserByEmailAndPassword_InvalidEmailFormat(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// モックの挙動を設定（無効なメールフォーマットの場合のエラーを返す）
 	mockService.On("FetchUserByEmailAndPassword", "invalid-email", "password123").Return(nil, errors.New("invalid email format"))
@@ -194,7 +195,7 @@ func TestHandler_GetUserByEmailAndPassword_UserNotFound(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービスがユーザーが見つからないことを返すようにモックの挙動を設定
 	mockService.On("FetchUserByEmailAndPassword", "john@example.com", "password123").Return(nil, errors.New("user not found"))
@@ -203,8 +204,8 @@ func TestHandler_GetUserByEmailAndPassword_UserNotFound(t *testing.T) {
 	handler.GetUserByEmailAndPassword(c)
 
 	// ステータスコードとレスポンス内容を確認
-	assert.Equal(t, http.StatusNotFound, rec.Code)
-	assert.Contains(t, rec.Body.String(), "User not found")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Contains(t, rec.Body.String(), "Invalid email or password")
 
 	// モックが期待通りに呼び出されたかを確認
 	mockService.AssertExpectations(t)
@@ -221,7 +222,7 @@ func TestHandler_GetUserByEmailAndPassword_ServiceError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービスがエラーを返すようにモックの挙動を設定
 	mockService.On("FetchUserByEmailAndPassword", "john@example.com", "password123").Return(nil, errors.New("error fetching user"))
@@ -236,3 +237,36 @@ func TestHandler_GetUserByEmailAndPassword_ServiceError(t *testing.T) {
 	// モックが期待通りに呼び出されたかを確認
 	mockService.AssertExpectations(t)
 }
+
+func TestHandler_GetUserByEmailAndPassword_LockedOut(t *testing.T) {
+	// ログイン制限のセットアップ
+	config := auth.DefaultLoginLimiterConfig()
+	config.MaxAccountFailures = 1
+	limiter := auth.NewMemoryLoginLimiter(config)
+
+	// モックサービスをインスタンス化
+	mockService := new(services_users.MockUserService)
+	handler := NewUserHandler(mockService, nil, limiter, nil, nil)
+
+	// サービス側でのモックの挙動を設定（ロック後は呼び出されない）
+	mockService.On("FetchUserByEmailAndPassword", "john@example.com", "wrong-password").Return(nil, errors.New("user not found")).Once()
+
+	for _, wantStatus := range []int{http.StatusUnauthorized, http.StatusTooManyRequests} {
+		// Echoのセットアップ
+		e := echo.New()
+		body := `{"email":"john@example.com", "password":"wrong-password"}`
+		req := httptest.NewRequest(http.MethodPost, "/api/user", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		// ハンドラーを実行
+		handler.GetUserByEmailAndPassword(c)
+
+		// ステータスコードを確認
+		assert.Equal(t, wantStatus, rec.Code)
+	}
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+}
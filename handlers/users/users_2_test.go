@@ -1,12 +1,16 @@
 package handlers_users
 
 import (
+	"backend/auth"
+	services_email_verifications "backend/services/email_verifications"
+	services_refresh_tokens "backend/services/refresh_tokens"
 	services_users "backend/services/users"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/stretchr/testify/assert"
@@ -23,10 +27,12 @@ func TestHandler_AddUser(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	mockVerificationService := new(services_email_verifications.MockEmailVerificationService)
+	handler := NewUserHandler(mockService, mockVerificationService, nil, nil, nil)
 
 	// サービス側でのモックの挙動を設定
 	mockService.On("CreateUser", "John Doe", "john@example.com", "password123").Return(nil)
+	mockVerificationService.On("SendVerification", "john@example.com").Return(nil)
 
 	// ハンドラーを実行
 	handler.AddUser(c)
@@ -37,6 +43,7 @@ func TestHandler_AddUser(t *testing.T) {
 
 	// モックが期待通りに呼び出されたかを確認
 	mockService.AssertExpectations(t)
+	mockVerificationService.AssertExpectations(t)
 }
 
 func TestHandler_AddUser_ValidationError(t *testing.T) {
@@ -50,7 +57,7 @@ func TestHandler_AddUser_ValidationError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービス側でのモックの挙動を設定
 	mockService.On("CreateUser", "", "", "").Return(errors.New("name, email and password are required"))
@@ -77,7 +84,7 @@ func TestHandler_AddUser_InvalidEmailFormat(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービス側でのモックの挙動を設定
 	mockService.On("CreateUser", "John Doe", "invalid-email", "password123").Return(errors.New("invalid email format"))
@@ -104,7 +111,7 @@ func TestHandler_AddUser_AlreadyExists(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービス側でのモックの挙動を設定
 	mockService.On("CreateUser", "John Doe", "john@example.com", "password123").Return(errors.New("user already exists"))
@@ -131,7 +138,7 @@ func TestHandler_AddUser_ServiceError(t *testing.T) {
 
 	// モックサービスをインスタンス化
 	mockService := new(services_users.MockUserService)
-	handler := NewUserHandler(mockService)
+	handler := NewUserHandler(mockService, nil, nil, nil, nil)
 
 	// サービス側でのモックの挙動を設定
 	mockService.On("CreateUser", "John Doe", "john@example.com", "password123").Return(errors.New("failed to create user"))
@@ -146,3 +153,207 @@ func TestHandler_AddUser_ServiceError(t *testing.T) {
 	// モックが期待通りに呼び出されたかを確認
 	mockService.AssertExpectations(t)
 }
+
+func TestHandler_UpdateUserRole(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	body := `{"role":"staff"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/users/:id/role", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("user1")
+
+	// モックサービスをインスタンス化
+	mockService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	store := auth.NewMemoryRevocationStore()
+	handler := NewUserHandler(mockService, nil, nil, mockRefreshTokenService, store)
+
+	// サービス側でのモックの挙動を設定
+	mockService.On("UpdateUserRole", "user1", "staff").Return(nil)
+	mockRefreshTokenService.On("RevokeAllRefreshTokens", "user1").Return(nil)
+
+	// 変更前のロールで発行済みのアクセストークン
+	issuedAt := time.Now().Add(-time.Minute)
+	claims := &auth.Claims{UserID: "user1", IssuedAtMs: issuedAt.UnixMilli()}
+
+	// ハンドラーを実行
+	handler.UpdateUserRole(c)
+
+	// ステータスコードとレスポンス内容を確認
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "User role updated successfully")
+
+	// 発行済みのアクセストークンは失効する
+	revoked, err := store.IsRevoked(claims)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestHandler_UpdateUserRole_RevocationError(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	body := `{"role":"customer"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/users/:id/role", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("user1")
+
+	// モックサービスをインスタンス化
+	mockService := new(services_users.MockUserService)
+	mockRefreshTokenService := new(services_refresh_tokens.MockRefreshTokenService)
+	handler := NewUserHandler(mockService, nil, nil, mockRefreshTokenService, auth.NewMemoryRevocationStore())
+
+	// サービス側でのモックの挙動を設定
+	mockService.On("UpdateUserRole", "user1", "customer").Return(nil)
+	mockRefreshTokenService.On("RevokeAllRefreshTokens", "user1").Return(errors.New("connection refused"))
+
+	// ハンドラーを実行
+	handler.UpdateUserRole(c)
+
+	// セッションを失効できなかったことを返す
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "failed to revoke existing sessions")
+
+	// モックが期待通りに呼び出されたかを確認
+	mockService.AssertExpectations(t)
+	mockRefreshTokenService.AssertExpectations(t)
+}
+
+func TestHandler_UpdateUserRole_ErrorCases(t *testing.T) {
+	cases := []struct {
+		name       string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{"invalid role", errors.New("invalid role"), http.StatusBadRequest, "Invalid role"},
+		{"user not found", errors.New("user not found"), http.StatusNotFound, "User not found"},
+		{"service error", errors.New("failed to update role"), http.StatusInternalServerError, "Failed to update role"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			body := `{"role":"owner"}`
+			req := httptest.NewRequest(http.MethodPut, "/api/users/:id/role", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+			c.SetParamNames("id")
+			c.SetParamValues("user1")
+
+			// モックサービスをインスタンス化
+			mockService := new(services_users.MockUserService)
+			handler := NewUserHandler(mockService, nil, nil, nil, nil)
+
+			// サービス側でのモックの挙動を設定
+			mockService.On("UpdateUserRole", "user1", "owner").Return(tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.UpdateUserRole(c)
+
+			// ステータスコードとレスポンス内容を確認
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.wantBody)
+
+			// モックが期待通りに呼び出されたかを確認
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_VerifyEmail(t *testing.T) {
+	cases := []struct {
+		name       string
+		token      string
+		userId     string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{"success", "valid-token", "user1", nil, http.StatusOK, "Email verified successfully"},
+		{"missing token", "", "", errors.New("token is required"), http.StatusBadRequest, "Token is required"},
+		{"invalid token", "invalid-token", "", errors.New("invalid or expired verification token"), http.StatusBadRequest, "Invalid or expired verification token"},
+		{"service error", "valid-token", "", errors.New("failed to verify email"), http.StatusInternalServerError, "Failed to verify email"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/api/email/verify?token="+tc.token, nil)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// モックサービスをインスタンス化
+			mockVerificationService := new(services_email_verifications.MockEmailVerificationService)
+			handler := NewUserHandler(new(services_users.MockUserService), mockVerificationService, nil, nil, nil)
+
+			// サービス側でのモックの挙動を設定
+			mockVerificationService.On("ConfirmEmail", tc.token).Return(tc.userId, tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.VerifyEmail(c)
+
+			// ステータスコードとレスポンス内容を確認
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.wantBody)
+
+			// モックが期待通りに呼び出されたかを確認
+			mockVerificationService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandler_ResendVerification(t *testing.T) {
+	cases := []struct {
+		name       string
+		email      string
+		serviceErr error
+		wantStatus int
+		wantBody   string
+	}{
+		{"registered or not", "john@example.com", nil, http.StatusOK, "a verification link has been sent"},
+		{"missing email", "", errors.New("email is required"), http.StatusBadRequest, "Email is required"},
+		{"invalid email", "invalid-email", errors.New("invalid email format"), http.StatusBadRequest, "Invalid email format"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Echoのセットアップ
+			e := echo.New()
+			body := `{"email":"` + tc.email + `"}`
+			req := httptest.NewRequest(http.MethodPost, "/api/email/verify/resend", strings.NewReader(body))
+			req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			// モックサービスをインスタンス化
+			mockVerificationService := new(services_email_verifications.MockEmailVerificationService)
+			handler := NewUserHandler(new(services_users.MockUserService), mockVerificationService, nil, nil, nil)
+
+			// サービス側でのモックの挙動を設定
+			mockVerificationService.On("SendVerification", tc.email).Return(tc.serviceErr)
+
+			// ハンドラーを実行
+			handler.ResendVerification(c)
+
+			// ステータスコードとレスポンス内容を確認
+			assert.Equal(t, tc.wantStatus, rec.Code)
+			assert.Contains(t, rec.Body.String(), tc.wantBody)
+
+			// モックが期待通りに呼び出されたかを確認
+			mockVerificationService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+// メールを送信せずにログへ出力する
+func (s *LogSender) Send(message Message) error {
+	if message.To == "" {
+		return errors.New("recipient is required")
+	}
+
+	log.Printf("[mail] To: %s\nSubject: %s\n\n%s", message.To, message.Subject, message.Body)
+	return nil
+}
+
+// SMTPでメールを送信する
+func (s *SMTPSender) Send(message Message) error {
+	if message.To == "" {
+		return errors.New("recipient is required")
+	}
+	if s.Config.Host == "" || s.Config.From == "" {
+		return errors.New("smtp host and sender address are required")
+	}
+
+	// ヘッダーインジェクションを防ぐため改行を含む値は拒否する
+	for _, value := range []string{message.To, message.Subject, s.Config.From} {
+		if strings.ContainsAny(value, "\r\n") {
+			return errors.New("invalid mail header")
+		}
+	}
+
+	var auth smtp.Auth
+	if s.Config.Username != "" {
+		auth = smtp.PlainAuth("", s.Config.Username, s.Config.Password, s.Config.Host)
+	}
+
+	addr := s.Config.Host + ":" + strconv.Itoa(s.Config.Port)
+	if err := smtp.SendMail(addr, auth, s.Config.From, []string{message.To}, buildMessage(s.Config.From, message)); err != nil {
+		log.Printf("Failed to send mail: %v", err)
+		return err
+	}
+
+	log.Printf("Mail sent to %s", message.To)
+	return nil
+}
+
+// RFC 5322形式のメッセージを組み立てる
+func buildMessage(from string, message Message) []byte {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", message.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", message.Subject)
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(builder.String())
+}
@@ -0,0 +1,78 @@
+package mailer
+
+import (
+	"log"
+	"os"
+	"strconv"
+)
+
+// 送信方式
+const (
+	SenderLog  = "log"
+	SenderSMTP = "smtp"
+)
+
+// 送信するメール
+type Message struct {
+	To      string // 宛先メールアドレス
+	Subject string // 件名
+	Body    string // 本文(プレーンテキスト)
+}
+
+// Senderインターフェース
+type Sender interface {
+	Send(message Message) error
+}
+
+// ログに出力するだけのSender
+// ローカル開発で実際のメールを送信せずにリンクを確認するために使用する。
+type LogSender struct{}
+
+func NewLogSender() Sender {
+	return &LogSender{}
+}
+
+// SMTPの接続設定
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string // 送信元メールアドレス
+}
+
+// SMTPでメールを送信するSender
+type SMTPSender struct {
+	Config SMTPConfig
+}
+
+func NewSMTPSender(config SMTPConfig) Sender {
+	return &SMTPSender{
+		Config: config,
+	}
+}
+
+// 環境変数からSenderを生成する。
+// MAIL_SENDERがsmtpの場合はSMTPで送信し、それ以外はログに出力する。
+func LoadSender() Sender {
+	switch sender := os.Getenv("MAIL_SENDER"); sender {
+	case SenderSMTP:
+		port, err := strconv.Atoi(os.Getenv("SMTP_PORT"))
+		if err != nil {
+			port = 587
+		}
+		return NewSMTPSender(SMTPConfig{
+			Host:     os.Getenv("SMTP_HOST"),
+			Port:     port,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     os.Getenv("MAIL_FROM"),
+		})
+	case "", SenderLog:
+		log.Println("Using log-only mail sender")
+		return NewLogSender()
+	default:
+		log.Printf("Unknown MAIL_SENDER %q, using log-only mail sender", sender)
+		return NewLogSender()
+	}
+}
@@ -0,0 +1,15 @@
+package mailer
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// MockSender is a mock implementation of Sender
+type MockSender struct {
+	mock.Mock
+}
+
+func (m *MockSender) Send(message Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
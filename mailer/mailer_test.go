@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogSender_Send(t *testing.T) {
+	sender := NewLogSender()
+
+	err := sender.Send(Message{To: "test@example.com", Subject: "Subject", Body: "Body"})
+	assert.NoError(t, err)
+
+	err = sender.Send(Message{Subject: "Subject", Body: "Body"})
+	assert.Error(t, err)
+}
+
+func TestSMTPSender_Send_InvalidCases(t *testing.T) {
+	// 接続設定がない場合
+	err := NewSMTPSender(SMTPConfig{}).Send(Message{To: "test@example.com", Subject: "Subject"})
+	assert.Error(t, err)
+
+	// ヘッダーに改行が含まれる場合
+	sender := NewSMTPSender(SMTPConfig{Host: "localhost", Port: 25, From: "noreply@example.com"})
+	err = sender.Send(Message{To: "test@example.com\r\nBcc: attacker@example.com", Subject: "Subject"})
+	assert.Error(t, err)
+	assert.Equal(t, "invalid mail header", err.Error())
+}
+
+func TestBuildMessage(t *testing.T) {
+	raw := string(buildMessage("noreply@example.com", Message{To: "test@example.com", Subject: "Hello", Body: "line1\nline2"}))
+
+	assert.True(t, strings.HasPrefix(raw, "From: noreply@example.com\r\n"))
+	assert.Contains(t, raw, "To: test@example.com\r\n")
+	assert.Contains(t, raw, "Subject: Hello\r\n")
+	assert.True(t, strings.HasSuffix(raw, "\r\n\r\nline1\r\nline2"))
+}
+
+func TestLoadSender(t *testing.T) {
+	t.Setenv("MAIL_SENDER", "")
+	assert.IsType(t, &LogSender{}, LoadSender())
+
+	t.Setenv("MAIL_SENDER", "smtp")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
+	t.Setenv("SMTP_PORT", "2525")
+	t.Setenv("MAIL_FROM", "noreply@example.com")
+	sender, ok := LoadSender().(*SMTPSender)
+	if assert.True(t, ok) {
+		assert.Equal(t, "smtp.example.com", sender.Config.Host)
+		assert.Equal(t, 2525, sender.Config.Port)
+		assert.Equal(t, "noreply@example.com", sender.Config.From)
+	}
+}
@@ -2,18 +2,41 @@ package main
 
 import (
 	"backend/auth"
+	"backend/broker"
+	"backend/cache"
+	handlers_api_keys "backend/handlers/api_keys"
+	handlers_business_calendar "backend/handlers/business_calendar"
 	handlers_notifications "backend/handlers/notifications"
 	handlers_reservations "backend/handlers/reservations"
 	handlers_users "backend/handlers/users"
+	"backend/mailer"
+	"backend/models"
+	"backend/passwords"
+	repositories_api_keys "backend/repositories/api_keys"
+	repositories_business_calendar "backend/repositories/business_calendar"
+	repositories_identities "backend/repositories/identities"
+	repositories_mfa "backend/repositories/mfa"
 	repositories_notifications "backend/repositories/notifications"
+	repositories_password_resets "backend/repositories/password_resets"
+	repositories_refresh_tokens "backend/repositories/refresh_tokens"
 	repositories_reservations "backend/repositories/reservations"
 	repositories_users "backend/repositories/users"
+	services_api_keys "backend/services/api_keys"
+	services_business_calendar "backend/services/business_calendar"
+	services_email_verifications "backend/services/email_verifications"
+	services_identities "backend/services/identities"
+	services_mfa "backend/services/mfa"
 	services_notifications "backend/services/notifications"
+	services_password_resets "backend/services/password_resets"
+	services_refresh_tokens "backend/services/refresh_tokens"
 	services_reservations "backend/services/reservations"
 	services_users "backend/services/users"
 	"backend/supabase"
+	"backend/utils"
 	"backend/websocket"
+	"context"
 	"strings"
+	"time"
 
 	"log"
 	"net/http"
@@ -46,8 +69,41 @@ func main() {
 		log.Fatalf("Test query failed: %v", err)
 	}
 
+	// Redisクライアントの初期化
+	err = cache.InitRedis()
+	if err != nil {
+		log.Fatalf("Redis initialization failed: %v", err)
+	}
+
+	// 通知をインスタンス間で中継するブローカーの初期化
+	// BROKER_TYPEでmemory, redis, natsを切り替える
+	messageBroker, err := broker.New(broker.LoadConfig(), cache.Client)
+	if err != nil {
+		log.Fatalf("Broker initialization failed: %v", err)
+	}
+
+	// JWT署名鍵の読み込み
+	err = auth.InitKeyring()
+	if err != nil {
+		log.Fatalf("JWT keyring initialization failed: %v", err)
+	}
+
+	// セッションのクッキー属性の読み込み
+	err = auth.InitCookieConfig()
+	if err != nil {
+		log.Fatalf("Cookie configuration failed: %v", err)
+	}
+
 	e := echo.New()
 
+	// ログイン試行のIP単位の制限に使用するため、クライアントIPの取得方法を明示する
+	// プロキシの背後で動作する場合のみX-Forwarded-Forヘッダーを信頼する
+	if utils.GetEnvBool("TRUST_PROXY_HEADERS", false) {
+		e.IPExtractor = echo.ExtractIPFromXFFHeader()
+	} else {
+		e.IPExtractor = echo.ExtractIPDirect()
+	}
+
 	// ミドルウェアの設定
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
@@ -56,44 +112,188 @@ func main() {
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
 		AllowOrigins:     strings.Split(allowedOrigins, ","),
 		AllowMethods:     []string{echo.GET, echo.POST, echo.PUT, echo.DELETE},
-		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAuthorization},
+		AllowHeaders:     []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAuthorization, auth.APIKeyHeader},
 		AllowCredentials: true,
 	}))
+	// クッキーで認証するため、状態を変更するリクエストの送信元を検証する
+	e.Use(auth.CSRFWithConfig(auth.CSRFConfig{
+		AllowedOrigins: strings.Split(allowedOrigins, ","),
+	}))
 
 	// RepositoryとServiceとHandlerの初期化
 	userRepository := repositories_users.NewUserRepository()
 	reservationRepository := repositories_reservations.NewReservationRepository()
 	notificationRepository := repositories_notifications.NewNotificationRepository()
+	refreshTokenRepository := repositories_refresh_tokens.NewRefreshTokenRepository()
+	passwordResetRepository := repositories_password_resets.NewPasswordResetRepository()
+	mfaRepository := repositories_mfa.NewMFARepository()
+	identityRepository := repositories_identities.NewIdentityRepository()
+	apiKeyRepository := repositories_api_keys.NewAPIKeyRepository()
+	businessCalendarRepository := repositories_business_calendar.NewBusinessCalendarRepository()
 
-	userService := services_users.NewUserService(userRepository)
-	reservationService := services_reservations.NewReservationService(userRepository, reservationRepository)
+	passwordHasher := passwords.NewHasher(passwords.LoadConfig())
+	// 開発環境ではメールを送信せずログに出力する
+	mailSender := mailer.LoadSender()
+
+	passwordResetURL := os.Getenv("PASSWORD_RESET_URL")
+	if passwordResetURL == "" {
+		passwordResetURL = "http://localhost:3000/password/reset"
+	}
+
+	// 確認リンクの署名鍵。複数インスタンスや再起動後も同じリンクを検証できるよう、すべてのインスタンスで共通の値を指定する
+	emailVerificationSecret := os.Getenv("EMAIL_VERIFICATION_SECRET")
+	if emailVerificationSecret == "" {
+		log.Fatalf("EMAIL_VERIFICATION_SECRET must be set")
+	}
+	emailVerificationURL := os.Getenv("EMAIL_VERIFICATION_URL")
+	if emailVerificationURL == "" {
+		emailVerificationURL = "http://localhost:8080/api/email/verify"
+	}
+
+	// TOTPのシークレットの暗号化鍵
+	// 暗号化せずに保存するのは、開発環境でMFA_ALLOW_PLAINTEXT=trueを明示した場合のみとする
+	var mfaEncryptionKey []byte
+	if secret := os.Getenv("MFA_ENCRYPTION_KEY"); secret != "" {
+		mfaEncryptionKey = utils.DeriveKey(secret)
+	} else if utils.GetEnvBool("MFA_ALLOW_PLAINTEXT", false) {
+		log.Println("MFA_ENCRYPTION_KEY is not set, TOTP secrets will be stored unencrypted")
+	} else {
+		log.Fatalf("MFA_ENCRYPTION_KEY must be set (or MFA_ALLOW_PLAINTEXT=true for development)")
+	}
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = "Buy Notice App"
+	}
+
+	// ソーシャルログインのプロバイダー。ログイン後はフロントエンドにリダイレクトする
+	oidcProviders, err := auth.LoadOIDCProviders()
+	if err != nil {
+		log.Fatalf("OIDC provider configuration failed: %v", err)
+	}
+	oidcRedirectURL := os.Getenv("OIDC_LOGIN_REDIRECT_URL")
+	if oidcRedirectURL == "" {
+		oidcRedirectURL = "http://localhost:3000/"
+	}
+
+	// 予約日時は店舗のタイムゾーンで扱う
+	venueLocation, err := services_reservations.LoadVenueLocation()
+	if err != nil {
+		log.Fatalf("Venue time zone configuration failed: %v", err)
+	}
+
+	userService := services_users.NewUserService(userRepository, passwordHasher)
+	reservationService := services_reservations.NewReservationService(userRepository, reservationRepository, businessCalendarRepository, utils.GetEnvBool("RESERVATION_REQUIRE_VERIFIED_EMAIL", false), services_reservations.LoadCapacityConfig(), venueLocation)
 	notificationService := services_notifications.NewNotificationService(userRepository, reservationRepository, notificationRepository)
+	refreshTokenService := services_refresh_tokens.NewRefreshTokenService(refreshTokenRepository, utils.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour))
+	emailVerificationService := services_email_verifications.NewEmailVerificationService(userRepository, mailSender, []byte(emailVerificationSecret), utils.GetEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour), emailVerificationURL)
+	passwordResetService := services_password_resets.NewPasswordResetService(userRepository, passwordResetRepository, passwordHasher, mailSender, utils.GetEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute), passwordResetURL)
+	mfaService := services_mfa.NewMFAService(mfaRepository, mfaIssuer, mfaEncryptionKey)
+	identityService := services_identities.NewIdentityService(userRepository, identityRepository, passwordHasher)
+	apiKeyService := services_api_keys.NewAPIKeyService(userRepository, apiKeyRepository)
+	businessCalendarService := services_business_calendar.NewBusinessCalendarService(businessCalendarRepository)
+
+	// アクセストークンの失効状態はインスタンス間で共有する
+	revocationStore := auth.NewRedisRevocationStore(cache.Client, auth.AccessTokenTTL)
+	// ログインの失敗回数とロック状態もインスタンス間で共有する
+	loginLimiter := auth.NewRedisLoginLimiter(cache.Client, auth.LoadLoginLimiterConfig())
 
-	authHandler := auth.NewAuthHandler(userService)
-	userHandler := handlers_users.NewUserHandler(userService)
+	authHandler := auth.NewAuthHandler(userService, refreshTokenService, passwordResetService, mfaService, revocationStore, loginLimiter)
+	oidcHandler := auth.NewOIDCHandler(authHandler, identityService, oidcProviders, oidcRedirectURL)
+	userHandler := handlers_users.NewUserHandler(userService, emailVerificationService, loginLimiter, refreshTokenService, revocationStore)
 	notificationHandler := handlers_notifications.NewNotificationHandler(notificationService)
-	reservationHandler := handlers_reservations.NewReservationHandler(userService, reservationService, notificationService)
+	reservationHandler := handlers_reservations.NewReservationHandler(userService, reservationService, notificationService, websocket.NewPublisher(messageBroker))
+	apiKeyHandler := handlers_api_keys.NewAPIKeyHandler(apiKeyService)
+	businessCalendarHandler := handlers_business_calendar.NewBusinessCalendarHandler(businessCalendarService)
 
-	// APIエンドポイントの設定
-	e.GET("/api/users", userHandler.GetUsers)
+	// APIエンドポイントの設定(認証不要)
 	e.POST("/api/user", userHandler.GetUserByEmailAndPassword)
 	e.POST("/api/user/add", userHandler.AddUser)
-
-	e.GET("/api/reservations", reservationHandler.GetReservations)
-	e.GET("/api/reservations/:user_id", reservationHandler.GetReservationByUserId)
-	e.POST("/api/reservation", reservationHandler.AddReservation)
-
-	e.GET("/api/notifications", notificationHandler.GetNotifications)
-	e.POST("/api/notification", notificationHandler.AddNotification)
+	e.GET("/api/email/verify", userHandler.VerifyEmail)
+	e.POST("/api/email/verify/resend", userHandler.ResendVerification)
 
 	e.POST("/api/login", authHandler.Login)
+	// 二要素認証が有効な場合の2段階目のログイン
+	e.POST("/api/login/mfa", authHandler.LoginMFA)
 	e.GET("/api/auth/check", authHandler.CheckAuth)
+	e.POST("/api/auth/refresh", authHandler.Refresh)
 	e.POST("/api/logout", authHandler.Logout)
+	e.POST("/api/password/forgot", authHandler.ForgotPassword)
+	e.POST("/api/password/reset", authHandler.ResetPassword)
+	// ソーシャルログイン(OIDC)
+	e.GET("/api/auth/oidc/:provider/login", oidcHandler.Login)
+	e.GET("/api/auth/oidc/:provider/callback", oidcHandler.Callback)
+	// 他のサービスがトークンを検証するための公開鍵
+	e.GET("/.well-known/jwks.json", auth.JWKSHandler)
+
+	// APIエンドポイントの設定(認証必須)
+	// クッキーまたはAuthorizationヘッダーのJWTを検証し、失効済みのトークンを拒否する
+	// サーバー間連携や店頭端末はX-API-KeyヘッダーのAPIキーでも認証できる
+	api := e.Group("/api", auth.JWTWithConfig(auth.JWTConfig{
+		RevocationStore: revocationStore,
+		APIKeyService:   apiKeyService,
+	}))
+
+	// 本人の操作に限るエンドポイントはAPIキーを受け付けない
+	api.POST("/logout/all", authHandler.LogoutAll, auth.RequireSession())
+	api.POST("/password/change", authHandler.ChangePassword, auth.RequireSession())
+	api.POST("/ws/ticket", authHandler.IssueWSTicket, auth.RequireSession())
+
+	// 二要素認証の設定は予約を管理するスタッフと管理者のみ
+	api.POST("/mfa/enroll", authHandler.EnrollMFA, auth.RequireSession(), auth.RequirePermission(models.PermissionMFAManage))
+	api.POST("/mfa/confirm", authHandler.ConfirmMFA, auth.RequireSession(), auth.RequirePermission(models.PermissionMFAManage))
+	api.POST("/mfa/disable", authHandler.DisableMFA, auth.RequireSession(), auth.RequirePermission(models.PermissionMFAManage))
+
+	// APIキーの発行・失効は管理者のみ
+	api.GET("/api-keys", apiKeyHandler.GetAPIKeys, auth.RequireSession(), auth.RequirePermission(models.PermissionAPIKeysManage))
+	api.POST("/api-keys", apiKeyHandler.CreateAPIKey, auth.RequireSession(), auth.RequirePermission(models.PermissionAPIKeysManage))
+	api.DELETE("/api-keys/:id", apiKeyHandler.RevokeAPIKey, auth.RequireSession(), auth.RequirePermission(models.PermissionAPIKeysManage))
+
+	// ユーザー管理は管理者のみ
+	api.GET("/users", userHandler.GetUsers, auth.RequirePermission(models.PermissionUsersManage))
+	api.PUT("/users/:id/role", userHandler.UpdateUserRole, auth.RequirePermission(models.PermissionUsersManage))
+
+	// 予約・通知の参照範囲はロールに応じてハンドラー内で切り替える
+	api.GET("/reservations", reservationHandler.GetReservations, auth.RequirePermission(models.PermissionReservationsRead))
+	// ユーザーの予約一覧。user_idにmeを指定すると自分の予約を返す
+	api.GET("/reservations/:user_id", reservationHandler.GetReservationsByUserId, auth.RequirePermission(models.PermissionReservationsRead))
+	// 指定した日付と人数で予約できる枠
+	api.GET("/availability", reservationHandler.GetAvailability, auth.RequirePermission(models.PermissionReservationsRead))
+	api.POST("/reservation", reservationHandler.AddReservation, auth.RequirePermission(models.PermissionReservationsWrite))
+	// 予約の変更とキャンセルは予約したユーザー本人と店舗スタッフのみ。ステータスの変更は店舗スタッフのみ
+	api.PUT("/reservations/:id", reservationHandler.UpdateReservation, auth.RequirePermission(models.PermissionReservationsWrite))
+	api.POST("/reservations/:id/cancel", reservationHandler.CancelReservation, auth.RequirePermission(models.PermissionReservationsWrite))
+
+	// 営業時間と休業日は誰でも参照でき、変更は管理者のみ
+	api.GET("/calendar/hours", businessCalendarHandler.GetBusinessHours, auth.RequirePermission(models.PermissionReservationsRead))
+	api.PUT("/calendar/hours", businessCalendarHandler.UpdateBusinessHours, auth.RequirePermission(models.PermissionCalendarManage))
+	api.GET("/calendar/overrides", businessCalendarHandler.GetHoursOverrides, auth.RequirePermission(models.PermissionReservationsRead))
+	api.POST("/calendar/overrides", businessCalendarHandler.CreateHoursOverride, auth.RequirePermission(models.PermissionCalendarManage))
+	api.DELETE("/calendar/overrides/:id", businessCalendarHandler.DeleteHoursOverride, auth.RequirePermission(models.PermissionCalendarManage))
+	api.GET("/calendar/closures", businessCalendarHandler.GetClosures, auth.RequirePermission(models.PermissionReservationsRead))
+	api.POST("/calendar/closures", businessCalendarHandler.CreateClosure, auth.RequirePermission(models.PermissionCalendarManage))
+	api.DELETE("/calendar/closures/:id", businessCalendarHandler.DeleteClosure, auth.RequirePermission(models.PermissionCalendarManage))
+
+	api.GET("/notifications", notificationHandler.GetNotifications, auth.RequirePermission(models.PermissionNotificationsRead))
+	api.POST("/notification", notificationHandler.AddNotification, auth.RequirePermission(models.PermissionNotificationsManage))
 
 	// WebSocketエンドポイントの設定
+	// 接続時にクッキーのアクセストークン、または/api/ws/ticketで発行したチケットで認証する
+	websocket.SetRevocationStore(revocationStore)
+	websocket.SetBroker(messageBroker)
+	websocket.InitHub(websocket.LoadHubConfig())
+	// 再接続時にlast_event_idを指定すると、保持期間内の取りこぼした通知を再送する
+	websocket.InitStream(websocket.LoadStreamConfig(), cache.Client)
 	e.GET("/ws", websocket.HandleWebSocket)
-	// メッセージをブロードキャストするためのゴルーチン
-	go websocket.HandleMessages()
+	// WebSocketに接続できない環境向けのServer-Sent Events
+	e.GET("/api/notifications/stream", websocket.HandleSSE)
+	// 送信待ちのメッセージ数や破棄したメッセージ数などの統計は管理者のみ
+	api.GET("/ws/metrics", websocket.HandleMetrics, auth.RequireSession(), auth.RequirePermission(models.PermissionUsersManage))
+	// ブローカーからのメッセージを配信するためのゴルーチン
+	go func() {
+		if err := websocket.HandleMessages(); err != nil {
+			log.Fatalf("Failed to deliver messages from broker: %v", err)
+		}
+	}()
 
 	// ヘルスチェックエンドポイントの追加
 	e.GET("/", func(c echo.Context) error {
@@ -108,6 +308,18 @@ func main() {
 		<-quit
 		log.Println("Shutting down server...")
 
+		// WebSocketクライアントに1001 Going Awayを送信し、再接続先のタスクに移るよう促す
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), utils.GetEnvDuration("WS_SHUTDOWN_TIMEOUT", 5*time.Second))
+		if err := websocket.Shutdown(shutdownCtx); err != nil {
+			log.Printf("WebSocket shutdown failed: %v", err)
+		}
+		cancel()
+
+		// ブローカーのクローズ
+		if err := messageBroker.Close(); err != nil {
+			log.Printf("Broker close failed: %v", err)
+		}
+
 		// Echoサーバーのシャットダウン
 		if err := e.Close(); err != nil {
 			log.Printf("Echo shutdown failed: %v", err)
@@ -115,6 +327,9 @@ func main() {
 
 		// Supabaseコネクションプールのクローズ
 		supabase.ClosePool()
+
+		// Redisクライアントのクローズ
+		cache.CloseRedis()
 	}()
 
 	// サーバーの起動
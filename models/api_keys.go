@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// APIキーの情報を表すデータ構造
+// 各フィールドには、JSONおよびデータベースのタグを指定。
+type APIKeyData struct {
+	ID         string     `json:"id" db:"id"`                     // UUID型
+	UserId     string     `json:"user_id" db:"user_id"`           // 所有するユーザー(サービスアカウント)のID
+	Name       string     `json:"name" db:"name"`                 // 用途を識別する名前
+	Prefix     string     `json:"prefix" db:"prefix"`             // キーの先頭部分(一覧での識別用)
+	KeyHash    string     `json:"-" db:"key_hash"`                // キーのハッシュ値
+	Scopes     []string   `json:"scopes" db:"scopes"`             // 利用できる権限
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`     // 有効期限(無期限の場合はnil)
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"` // 最終使用日時
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`     // 失効日時
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`     // タイムスタンプ
+}
+
+// 指定時刻に使用できるかどうかを返す
+func (k *APIKeyData) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// 発行したAPIキー
+// キー本体はこのレスポンスでのみ返し、以降は取得できない。
+type IssuedAPIKey struct {
+	Key    string     `json:"key"`
+	APIKey APIKeyData `json:"api_key"`
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 曜日ごとの通常の営業時間
+// 同じ曜日に複数の時間帯(ランチとディナーなど)を設定できる。
+type BusinessHoursData struct {
+	ID        string `json:"id" db:"id"`                 // UUID型
+	Weekday   int    `json:"weekday" db:"weekday"`       // 曜日(0が日曜日)
+	OpenTime  string `json:"open_time" db:"open_time"`   // 開店時刻(HH:MM)
+	CloseTime string `json:"close_time" db:"close_time"` // 閉店時刻(HH:MM)。24:00は日付の終わりを表す
+}
+
+// 祝日などで、通常と異なる営業時間とする日
+// 同じ日に設定がある場合は、その日の曜日の営業時間を使用しない。
+type HoursOverrideData struct {
+	ID        string    `json:"id" db:"id"`                 // UUID型
+	Date      string    `json:"date" db:"date"`             // 対象の日付(YYYY-MM-DD)
+	OpenTime  string    `json:"open_time" db:"open_time"`   // 開店時刻(HH:MM)
+	CloseTime string    `json:"close_time" db:"close_time"` // 閉店時刻(HH:MM)
+	Reason    string    `json:"reason" db:"reason"`         // 祝日名などの理由
+	CreatedAt time.Time `json:"created_at" db:"created_at"` // タイムスタンプ
+}
+
+// 臨時休業や、予約を受け付けない期間
+// 営業時間の設定にかかわらず、期間中は予約できない。
+type ClosureData struct {
+	ID        string    `json:"id" db:"id"`                 // UUID型
+	StartDate string    `json:"start_date" db:"start_date"` // 開始日(YYYY-MM-DD)
+	EndDate   string    `json:"end_date" db:"end_date"`     // 終了日(YYYY-MM-DD)。この日を含む
+	Reason    string    `json:"reason" db:"reason"`         // 休業の理由
+	CreatedAt time.Time `json:"created_at" db:"created_at"` // タイムスタンプ
+}
+
+// 営業日の判定に使用する設定
+type BusinessCalendar struct {
+	Hours     []BusinessHoursData
+	Overrides []HoursOverrideData
+	Closures  []ClosureData
+}
+
+// ある日の営業時間帯
+type OpeningPeriod struct {
+	Open  time.Time
+	Close time.Time
+}
+
+// 指定した日の営業時間帯を返す。
+// 休業期間に含まれる日や、営業時間が設定されていない日は空を返す。
+// 曜日ごとの営業時間が1件も設定されていない場合は、営業時間を制限せず終日営業として扱う。
+func (c *BusinessCalendar) OpeningPeriods(date time.Time) []OpeningPeriod {
+	day := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, date.Location())
+	key := day.Format("2006-01-02")
+
+	for _, closure := range c.Closures {
+		if closure.StartDate <= key && key <= closure.EndDate {
+			return nil
+		}
+	}
+
+	periods := []OpeningPeriod{}
+	overridden := false
+	for _, override := range c.Overrides {
+		if override.Date != key {
+			continue
+		}
+		overridden = true
+		if period, ok := newOpeningPeriod(day, override.OpenTime, override.CloseTime); ok {
+			periods = append(periods, period)
+		}
+	}
+	if overridden {
+		return periods
+	}
+
+	if len(c.Hours) == 0 {
+		period, _ := newOpeningPeriod(day, "00:00", "24:00")
+		return []OpeningPeriod{period}
+	}
+
+	for _, hours := range c.Hours {
+		if hours.Weekday != int(day.Weekday()) {
+			continue
+		}
+		if period, ok := newOpeningPeriod(day, hours.OpenTime, hours.CloseTime); ok {
+			periods = append(periods, period)
+		}
+	}
+	return periods
+}
+
+// 指定した期間が、いずれかの営業時間帯に収まるかどうかを判定する
+func (c *BusinessCalendar) IsOpen(start, end time.Time) bool {
+	for _, period := range c.OpeningPeriods(start) {
+		if !start.Before(period.Open) && !end.After(period.Close) {
+			return true
+		}
+	}
+	return false
+}
+
+// HH:MM形式の時刻を、その日の0時からの経過時間に変換する
+// 24:00は日付の終わりとして扱う。
+func ParseClockTime(value string) (time.Duration, bool) {
+	parts := strings.Split(value, ":")
+	if len(parts) != 2 || len(parts[0]) != 2 || len(parts[1]) != 2 {
+		return 0, false
+	}
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, false
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil || minute < 0 || minute > 59 {
+		return 0, false
+	}
+	if hour < 0 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, false
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, true
+}
+
+// 開店時刻と閉店時刻から、指定した日の営業時間帯を作成する
+func newOpeningPeriod(day time.Time, openTime, closeTime string) (OpeningPeriod, bool) {
+	openAt, ok := ParseClockTime(openTime)
+	if !ok {
+		return OpeningPeriod{}, false
+	}
+	closeAt, ok := ParseClockTime(closeTime)
+	if !ok || closeAt <= openAt {
+		return OpeningPeriod{}, false
+	}
+	return OpeningPeriod{Open: atClockTime(day, openAt), Close: atClockTime(day, closeAt)}, true
+}
+
+// 指定した日の、0時からの経過時間で表した時刻を返す
+// 夏時間の切り替え日でも時計の時刻に合わせる。
+func atClockTime(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// 外部のIDプロバイダーのアカウントとユーザーの紐付けを表すデータ構造
+// 各フィールドには、JSONおよびデータベースのタグを指定。
+type UserIdentityData struct {
+	ID        string    `json:"id" db:"id"`                 // UUID型
+	UserId    string    `json:"user_id" db:"user_id"`       // ユーザーID
+	Provider  string    `json:"provider" db:"provider"`     // プロバイダー名(google, lineなど)
+	Subject   string    `json:"-" db:"subject"`             // プロバイダー内で一意なアカウントID(sub)
+	Email     string    `json:"email" db:"email"`           // 紐付け時のメールアドレス
+	CreatedAt time.Time `json:"created_at" db:"created_at"` // タイムスタンプ
+}
+
+// IDトークンから取得した外部アカウントの情報
+type ExternalIdentity struct {
+	Provider      string // プロバイダー名
+	Subject       string // プロバイダー内で一意なアカウントID(sub)
+	Email         string // メールアドレス
+	EmailVerified bool   // プロバイダーがメールアドレスを確認済みかどうか
+	Name          string // 表示名
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// 二要素認証の設定を表すデータ構造
+// 各フィールドには、JSONおよびデータベースのタグを指定。
+type UserMFAData struct {
+	UserId       string     `json:"user_id" db:"user_id"`           // ユーザーID
+	Secret       string     `json:"-" db:"secret"`                  // TOTPの共有シークレット(暗号化済み)
+	ConfirmedAt  *time.Time `json:"confirmed_at" db:"confirmed_at"` // 登録完了の日時(登録途中の場合はnil)
+	LastUsedStep int64      `json:"-" db:"last_used_step"`          // 最後に使用したTOTPの時間ステップ
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`     // タイムスタンプ
+	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`     // タイムスタンプ
+}
+
+// 二要素認証が有効かどうかを返す
+func (m *UserMFAData) IsEnabled() bool {
+	return m.ConfirmedAt != nil
+}
+
+// 二要素認証の登録開始時に返す情報
+// シークレットはこのレスポンスでのみ返し、以降は取得できない。
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`           // 手入力用のシークレット
+	ProvisioningURI string `json:"provisioning_uri"` // 認証アプリに登録するためのotpauth URI
+	QRCode          string `json:"qr_code"`          // provisioning_uriのQRコード(PNGのData URI)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// パスワードリセットトークンの情報を表すデータ構造
+// 各フィールドには、JSONおよびデータベースのタグを指定。
+type PasswordResetTokenData struct {
+	ID        string     `json:"id" db:"id"`                 // UUID型
+	UserId    string     `json:"user_id" db:"user_id"`       // ユーザーID
+	TokenHash string     `json:"-" db:"token_hash"`          // トークンのハッシュ値
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"` // 有効期限
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`       // 使用済みの日時
+	CreatedAt time.Time  `json:"created_at" db:"created_at"` // タイムスタンプ
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// リフレッシュトークンの情報を表すデータ構造
+// 各フィールドには、JSONおよびデータベースのタグを指定。
+type RefreshTokenData struct {
+	ID        string     `json:"id" db:"id"`                 // UUID型
+	UserId    string     `json:"user_id" db:"user_id"`       // ユーザーID
+	FamilyId  string     `json:"family_id" db:"family_id"`   // ローテーションで引き継がれる系列ID
+	TokenHash string     `json:"-" db:"token_hash"`          // トークンのハッシュ値
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"` // 有効期限
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`       // ローテーション済みの日時
+	RevokedAt *time.Time `json:"revoked_at" db:"revoked_at"` // 失効日時
+	CreatedAt time.Time  `json:"created_at" db:"created_at"` // タイムスタンプ
+}
+
+// 発行したリフレッシュトークンを表すデータ構造
+// Tokenはクライアントに渡すトークン本体で、データベースには保存しない。
+type IssuedRefreshToken struct {
+	Token     string    `json:"refresh_token"` // トークン本体
+	UserId    string    `json:"user_id"`       // ユーザーID
+	FamilyId  string    `json:"-"`             // ローテーションで引き継がれる系列ID
+	ExpiresAt time.Time `json:"expires_at"`    // 有効期限
+}
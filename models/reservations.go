@@ -1,16 +1,162 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // 予約の情報を表すデータ構造
 // 各フィールドには、JSONおよびデータベースのタグを指定。
 type ReservationData struct {
 	ID              string    `json:"id" db:"id"`                             // UUID型
 	UserId          string    `json:"user_id" db:"user_id"`                   // ユーザーID
-	ReservationDate time.Time `json:"reservation_date" db:"reservation_date"` // 予約日
+	ReservationDate time.Time `json:"reservation_date" db:"reservation_date"` // 予約日時(UTC)
+	TimeZone        string    `json:"time_zone" db:"time_zone"`               // 予約時の店舗のタイムゾーン(IANA名)
 	NumPeople       int       `json:"num_people" db:"num_people"`             // 予約人数
 	SpecialRequest  string    `json:"special_request" db:"special_request"`   // 特別なリクエスト
 	Status          string    `json:"status" db:"status"`                     // 予約ステータス
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`             // タイムスタンプ
 	UpdatedAt       time.Time `json:"updated_at" db:"updated_at"`             // タイムスタンプ
 }
+
+// 予約日時を、予約時の店舗のタイムゾーンの時刻で返す
+// タイムゾーンが不明な場合はUTCで返す。
+func (r *ReservationData) LocalReservationDate() time.Time {
+	location, err := time.LoadLocation(r.TimeZone)
+	if err != nil {
+		return r.ReservationDate.UTC()
+	}
+	return r.ReservationDate.In(location)
+}
+
+// 予約日時をUTCと店舗のタイムゾーンの時刻の両方で返すため、JSONへの変換を独自に行う
+// 夏時間の切り替えをまたいでも、クライアントは店舗の時計の時刻をそのまま表示できる。
+func (r ReservationData) MarshalJSON() ([]byte, error) {
+	type reservationData ReservationData
+	return json.Marshal(struct {
+		reservationData
+		ReservationDate      time.Time `json:"reservation_date"`
+		ReservationDateLocal time.Time `json:"reservation_date_local"`
+	}{
+		reservationData:      reservationData(r),
+		ReservationDate:      r.ReservationDate.UTC(),
+		ReservationDateLocal: r.LocalReservationDate(),
+	})
+}
+
+// 予約ステータス
+const (
+	ReservationStatusPending   = "pending"   // 受付済み(店舗の確認待ち)
+	ReservationStatusConfirmed = "confirmed" // 店舗が確定した予約
+	ReservationStatusSeated    = "seated"    // 来店して着席済み
+	ReservationStatusCompleted = "completed" // 利用完了
+	ReservationStatusCancelled = "cancelled" // キャンセル
+	ReservationStatusNoShow    = "no_show"   // 連絡なしの不来店
+)
+
+// ステータスごとに遷移できるステータス
+// completed, cancelled, no_showは終了状態で、以降は遷移できない。
+var reservationStatusTransitions = map[string][]string{
+	ReservationStatusPending:   {ReservationStatusConfirmed, ReservationStatusCancelled},
+	ReservationStatusConfirmed: {ReservationStatusSeated, ReservationStatusCancelled, ReservationStatusNoShow},
+	ReservationStatusSeated:    {ReservationStatusCompleted},
+	ReservationStatusCompleted: {},
+	ReservationStatusCancelled: {},
+	ReservationStatusNoShow:    {},
+}
+
+// 定義済みの予約ステータスかどうかを判定する
+func IsValidReservationStatus(status string) bool {
+	_, ok := reservationStatusTransitions[status]
+	return ok
+}
+
+// 予約ステータスを遷移できるかどうかを判定する
+// 同じステータスへの遷移は許可しない。
+func CanTransitionReservationStatus(from, to string) bool {
+	for _, status := range reservationStatusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
+// 予約の内容(日時、人数、リクエスト)を変更できるステータスかどうかを判定する
+// 来店後や終了状態の予約は変更できない。
+func IsReservationModifiable(status string) bool {
+	return status == ReservationStatusPending || status == ReservationStatusConfirmed
+}
+
+// 予約一覧の期間
+const (
+	ReservationPeriodUpcoming = "upcoming" // これからの予約
+	ReservationPeriodPast     = "past"     // 過去の予約
+)
+
+// ユーザーの予約一覧の検索条件
+// クエリパラメータの値をそのまま保持し、サービス層で検証する。
+type ReservationSearchParams struct {
+	Period   string   // upcoming, past のいずれか。空の場合は期間で絞り込まない
+	Statuses []string // 予約ステータス。空の場合はすべて
+	From     string   // この日以降の予約 (YYYY-MM-DD)
+	To       string   // この日までの予約 (YYYY-MM-DD、当日を含む)
+	Cursor   string   // 前のページのレスポンスのnext_cursor
+	Limit    int      // 1ページの件数。0の場合はデフォルト値
+}
+
+// リポジトリで予約一覧を取得する条件
+// 予約日時とIDの順に並べ、カーソルより後の予約を返す。
+type ReservationQuery struct {
+	UserId     string
+	Statuses   []string
+	From       *time.Time         // 予約日時の下限(この時刻を含む)
+	To         *time.Time         // 予約日時の上限(この時刻を含まない)
+	Descending bool               // trueの場合は新しい順
+	After      *ReservationCursor // 前のページの最後の予約
+	Limit      int
+}
+
+// 予約一覧のページ位置
+type ReservationCursor struct {
+	ReservationDate time.Time
+	ID              string
+}
+
+// 予約一覧の1ページ分
+// 次のページがない場合、next_cursorはnullとなる。
+type ReservationPage struct {
+	Items      []ReservationData `json:"items"`
+	NextCursor *string           `json:"next_cursor"`
+}
+
+// 席を確保している予約ステータス
+// キャンセルと不来店の予約は、枠の残席数に含めない。
+var ReservationSeatHoldingStatuses = []string{
+	ReservationStatusPending,
+	ReservationStatusConfirmed,
+	ReservationStatusSeated,
+	ReservationStatusCompleted,
+}
+
+// 予約枠ごとの予約済みの席数
+type SlotUsage struct {
+	Start time.Time // 枠の開始日時
+	Seats int       // 予約済みの席数
+}
+
+// 予約可能な枠
+type AvailabilitySlot struct {
+	Start          time.Time `json:"start"`           // 枠の開始日時(UTC)
+	StartLocal     time.Time `json:"start_local"`     // 枠の開始日時(店舗のタイムゾーン)
+	AvailableSeats int       `json:"available_seats"` // 残席数
+}
+
+// 指定した日と人数で予約可能な枠の一覧
+type Availability struct {
+	Date      string             `json:"date"`
+	TimeZone  string             `json:"time_zone"` // 店舗のタイムゾーン。dateはこのタイムゾーンの日付
+	PartySize int                `json:"party_size"`
+	Closed    bool               `json:"closed"` // 休業日の場合はtrue
+	Slots     []AvailabilitySlot `json:"slots"`
+}
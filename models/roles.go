@@ -0,0 +1,73 @@
+package models
+
+// ユーザーのロール
+const (
+	RoleCustomer = "customer" // 一般の予約客
+	RoleStaff    = "staff"    // 店舗スタッフ
+	RoleAdmin    = "admin"    // 管理者
+)
+
+// 権限
+// 自分のリソースに対する操作と、全リソースに対する管理操作を区別する。
+const (
+	PermissionReservationsRead    = "reservations:read"    // 自分の予約の参照
+	PermissionReservationsWrite   = "reservations:write"   // 自分の予約の作成
+	PermissionReservationsManage  = "reservations:manage"  // 全ユーザーの予約の管理
+	PermissionNotificationsRead   = "notifications:read"   // 自分の通知の参照
+	PermissionNotificationsManage = "notifications:manage" // 全ユーザーの通知の管理
+	PermissionUsersManage         = "users:manage"         // ユーザーの管理
+	PermissionMFAManage           = "mfa:manage"           // 自分の二要素認証の設定
+	PermissionAPIKeysManage       = "api_keys:manage"      // APIキーの発行と失効
+	PermissionCalendarManage      = "calendar:manage"      // 営業時間と休業日の管理
+)
+
+// ロールごとに付与される権限
+var rolePermissions = map[string][]string{
+	RoleCustomer: {
+		PermissionReservationsRead,
+		PermissionReservationsWrite,
+		PermissionNotificationsRead,
+	},
+	RoleStaff: {
+		PermissionReservationsRead,
+		PermissionReservationsWrite,
+		PermissionReservationsManage,
+		PermissionNotificationsRead,
+		PermissionNotificationsManage,
+		PermissionMFAManage,
+	},
+	RoleAdmin: {
+		PermissionReservationsRead,
+		PermissionReservationsWrite,
+		PermissionReservationsManage,
+		PermissionNotificationsRead,
+		PermissionNotificationsManage,
+		PermissionUsersManage,
+		PermissionMFAManage,
+		PermissionAPIKeysManage,
+		PermissionCalendarManage,
+	},
+}
+
+// 定義済みのロールかどうかを判定する
+func IsValidRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// 定義済みの権限かどうかを判定する
+// 管理者はすべての権限を持つ。
+func IsValidPermission(permission string) bool {
+	return HasPermission(RoleAdmin, permission)
+}
+
+// ロールが指定した権限を持つかどうかを判定する
+// 未定義のロールは権限を持たない。
+func HasPermission(role, permission string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -8,7 +8,15 @@ type UserData struct {
 	ID        string    `json:"id" db:"id"`                 // UUID型
 	Name      string    `json:"name" db:"name"`             // ユーザー名
 	Email     string    `json:"email" db:"email"`           // メールアドレス
-	Password  string    `json:"password" db:"password"`     // パスワード
+	Password  string    `json:"-" db:"password"`            // パスワードハッシュ(レスポンスには含めない)
+	Role      string    `json:"role" db:"role"`             // ロール(customer, staff, admin)
 	CreatedAt time.Time `json:"created_at" db:"created_at"` // タイムスタンプ
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"` // タイムスタンプ
+
+	EmailVerifiedAt *time.Time `json:"email_verified_at" db:"email_verified_at"` // メールアドレスの確認日時(未確認の場合はnil)
+}
+
+// メールアドレスが確認済みかどうかを返す
+func (u *UserData) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
@@ -0,0 +1,158 @@
+package passwords
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// パスワードを設定されたアルゴリズムでハッシュ化する。
+// 失敗した場合はエラーを返す。
+func (h *HasherImpl) Hash(password string) (string, error) {
+	if password == "" {
+		return "", errors.New("password is required")
+	}
+
+	switch h.Config.Algorithm {
+	case AlgorithmArgon2id:
+		return h.hashArgon2id(password)
+	default:
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), h.Config.BcryptCost)
+		if err != nil {
+			log.Printf("Failed to hash password with bcrypt: %v", err)
+			return "", err
+		}
+		return string(hashed), nil
+	}
+}
+
+// 保存されているハッシュとパスワードを照合する。
+// 一致した場合、現在の設定で再ハッシュすべきかどうかも返す。
+// 平文で保存されている旧データは一致すれば常に再ハッシュ対象となる。
+func (h *HasherImpl) Verify(hashedPassword, password string) (bool, bool, error) {
+	if hashedPassword == "" || password == "" {
+		return false, false, nil
+	}
+
+	switch {
+	case isBcryptHash(hashedPassword):
+		err := bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			log.Printf("Failed to compare bcrypt hash: %v", err)
+			return false, false, err
+		}
+
+		cost, err := bcrypt.Cost([]byte(hashedPassword))
+		if err != nil {
+			return true, true, nil
+		}
+		needsRehash := h.Config.Algorithm != AlgorithmBcrypt || cost < h.Config.BcryptCost
+		return true, needsRehash, nil
+
+	case strings.HasPrefix(hashedPassword, "$argon2id$"):
+		params, salt, key, err := decodeArgon2id(hashedPassword)
+		if err != nil {
+			log.Printf("Failed to decode argon2id hash: %v", err)
+			return false, false, err
+		}
+
+		computed := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		if subtle.ConstantTimeCompare(computed, key) != 1 {
+			return false, false, nil
+		}
+
+		needsRehash := h.Config.Algorithm != AlgorithmArgon2id ||
+			params.time < h.Config.Argon2Time ||
+			params.memory < h.Config.Argon2Memory ||
+			params.threads < h.Config.Argon2Threads
+		return true, needsRehash, nil
+
+	default:
+		// ハッシュ化される前に登録された平文パスワード
+		if subtle.ConstantTimeCompare([]byte(hashedPassword), []byte(password)) != 1 {
+			return false, false, nil
+		}
+		return true, true, nil
+	}
+}
+
+// argon2idでハッシュ化し、PHC文字列形式にエンコードする
+func (h *HasherImpl) hashArgon2id(password string) (string, error) {
+	salt := make([]byte, h.Config.Argon2SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		log.Printf("Failed to generate salt: %v", err)
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Config.Argon2Time, h.Config.Argon2Memory, h.Config.Argon2Threads, h.Config.Argon2KeyLen)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.Config.Argon2Memory,
+		h.Config.Argon2Time,
+		h.Config.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// bcryptのハッシュ形式かどうかを判定する
+func isBcryptHash(hashedPassword string) bool {
+	return strings.HasPrefix(hashedPassword, "$2a$") ||
+		strings.HasPrefix(hashedPassword, "$2b$") ||
+		strings.HasPrefix(hashedPassword, "$2y$")
+}
+
+// argon2idのパラメータ
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+// PHC文字列形式のargon2idハッシュをデコードする
+func decodeArgon2id(encoded string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, errors.New("invalid argon2id hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return params, nil, nil, err
+	}
+	if version != argon2.Version {
+		return params, nil, nil, errors.New("unsupported argon2 version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return params, nil, nil, err
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if len(key) == 0 {
+		return params, nil, nil, errors.New("invalid argon2id hash length")
+	}
+
+	return params, salt, key, nil
+}
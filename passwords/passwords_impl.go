@@ -0,0 +1,106 @@
+package passwords
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ハッシュアルゴリズム
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+// パスワードハッシュの設定
+// アルゴリズムとコストパラメータを保持する。
+type Config struct {
+	Algorithm     string // 新規ハッシュに使用するアルゴリズム
+	BcryptCost    int    // bcryptのコスト
+	Argon2Time    uint32 // argon2idの反復回数
+	Argon2Memory  uint32 // argon2idのメモリ使用量(KiB)
+	Argon2Threads uint8  // argon2idの並列度
+	Argon2KeyLen  uint32 // argon2idの出力長(byte)
+	Argon2SaltLen uint32 // argon2idのソルト長(byte)
+}
+
+// Hasherインターフェース
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(hashedPassword, password string) (match bool, needsRehash bool, err error)
+}
+
+// HasherImplはHasherインターフェースを実装する
+type HasherImpl struct {
+	Config Config
+}
+
+func NewHasher(config Config) Hasher {
+	return &HasherImpl{
+		Config: config,
+	}
+}
+
+// デフォルトの設定を返す。
+// argon2idのパラメータはOWASPの推奨値に合わせている。
+func DefaultConfig() Config {
+	return Config{
+		Algorithm:     AlgorithmBcrypt,
+		BcryptCost:    bcrypt.DefaultCost,
+		Argon2Time:    2,
+		Argon2Memory:  19 * 1024,
+		Argon2Threads: 1,
+		Argon2KeyLen:  32,
+		Argon2SaltLen: 16,
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定または不正な値の場合はデフォルト値を使用する。
+func LoadConfig() Config {
+	config := DefaultConfig()
+
+	switch algorithm := os.Getenv("PASSWORD_HASH_ALGORITHM"); algorithm {
+	case "":
+	case AlgorithmBcrypt, AlgorithmArgon2id:
+		config.Algorithm = algorithm
+	default:
+		log.Printf("Unknown PASSWORD_HASH_ALGORITHM %q, using %s", algorithm, config.Algorithm)
+	}
+
+	if cost, ok := lookupUint("BCRYPT_COST", 32); ok {
+		if int(cost) < bcrypt.MinCost || int(cost) > bcrypt.MaxCost {
+			log.Printf("BCRYPT_COST must be between %d and %d, using %d", bcrypt.MinCost, bcrypt.MaxCost, config.BcryptCost)
+		} else {
+			config.BcryptCost = int(cost)
+		}
+	}
+	if time, ok := lookupUint("ARGON2_TIME", 32); ok && time > 0 {
+		config.Argon2Time = uint32(time)
+	}
+	if memory, ok := lookupUint("ARGON2_MEMORY", 32); ok && memory > 0 {
+		config.Argon2Memory = uint32(memory)
+	}
+	if threads, ok := lookupUint("ARGON2_THREADS", 8); ok && threads > 0 {
+		config.Argon2Threads = uint8(threads)
+	}
+
+	return config
+}
+
+// 環境変数を符号なし整数として読み込む
+func lookupUint(key string, bitSize int) (uint64, bool) {
+	value := os.Getenv(key)
+	if value == "" {
+		return 0, false
+	}
+
+	parsed, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil {
+		log.Printf("Invalid %s: %v", key, err)
+		return 0, false
+	}
+	return parsed, true
+}
@@ -0,0 +1,124 @@
+package passwords
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// テスト用に計算コストを下げた設定
+func testConfig(algorithm string) Config {
+	config := DefaultConfig()
+	config.Algorithm = algorithm
+	config.BcryptCost = bcrypt.MinCost
+	config.Argon2Memory = 1024
+	config.Argon2Time = 1
+	return config
+}
+
+func TestHasher_Bcrypt(t *testing.T) {
+	hasher := NewHasher(testConfig(AlgorithmBcrypt))
+
+	hashed, err := hasher.Hash("password123")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$2a$"))
+	assert.NotEqual(t, "password123", hashed)
+
+	// 正しいパスワード
+	match, needsRehash, err := hasher.Verify(hashed, "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, needsRehash)
+
+	// 誤ったパスワード
+	match, _, err = hasher.Verify(hashed, "wrong-password")
+	assert.NoError(t, err)
+	assert.False(t, match)
+}
+
+func TestHasher_Argon2id(t *testing.T) {
+	hasher := NewHasher(testConfig(AlgorithmArgon2id))
+
+	hashed, err := hasher.Hash("password123")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	match, needsRehash, err := hasher.Verify(hashed, "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.False(t, needsRehash)
+
+	match, _, err = hasher.Verify(hashed, "wrong-password")
+	assert.NoError(t, err)
+	assert.False(t, match)
+
+	// 壊れたハッシュ
+	_, _, err = hasher.Verify("$argon2id$v=19$broken", "password123")
+	assert.Error(t, err)
+}
+
+func TestHasher_Hash_EmptyPassword(t *testing.T) {
+	hasher := NewHasher(testConfig(AlgorithmBcrypt))
+
+	_, err := hasher.Hash("")
+	assert.Error(t, err)
+	assert.Equal(t, "password is required", err.Error())
+}
+
+func TestHasher_Verify_NeedsRehash(t *testing.T) {
+	// 平文で保存された旧データ
+	hasher := NewHasher(testConfig(AlgorithmBcrypt))
+	match, needsRehash, err := hasher.Verify("password123", "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+
+	match, needsRehash, err = hasher.Verify("password123", "wrong-password")
+	assert.NoError(t, err)
+	assert.False(t, match)
+	assert.False(t, needsRehash)
+
+	// コストが設定値より低いbcryptハッシュ
+	lowCost, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	strongConfig := testConfig(AlgorithmBcrypt)
+	strongConfig.BcryptCost = bcrypt.MinCost + 1
+	match, needsRehash, err = NewHasher(strongConfig).Verify(string(lowCost), "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+
+	// アルゴリズムが切り替わった場合
+	match, needsRehash, err = NewHasher(testConfig(AlgorithmArgon2id)).Verify(string(lowCost), "password123")
+	assert.NoError(t, err)
+	assert.True(t, match)
+	assert.True(t, needsRehash)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "argon2id")
+	t.Setenv("BCRYPT_COST", "12")
+	t.Setenv("ARGON2_TIME", "3")
+	t.Setenv("ARGON2_MEMORY", "65536")
+	t.Setenv("ARGON2_THREADS", "4")
+
+	config := LoadConfig()
+	assert.Equal(t, AlgorithmArgon2id, config.Algorithm)
+	assert.Equal(t, 12, config.BcryptCost)
+	assert.Equal(t, uint32(3), config.Argon2Time)
+	assert.Equal(t, uint32(65536), config.Argon2Memory)
+	assert.Equal(t, uint8(4), config.Argon2Threads)
+}
+
+func TestLoadConfig_InvalidValues(t *testing.T) {
+	t.Setenv("PASSWORD_HASH_ALGORITHM", "md5")
+	t.Setenv("BCRYPT_COST", "99")
+	t.Setenv("ARGON2_TIME", "abc")
+
+	config := LoadConfig()
+	defaults := DefaultConfig()
+	assert.Equal(t, defaults.Algorithm, config.Algorithm)
+	assert.Equal(t, defaults.BcryptCost, config.BcryptCost)
+	assert.Equal(t, defaults.Argon2Time, config.Argon2Time)
+}
@@ -0,0 +1,194 @@
+package repositories_api_keys
+
+import (
+	"backend/models"
+	"backend/supabase"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// APIキーの一覧を取得する。
+// userIdが空の場合は全ユーザーのキーを返す。
+func (r *APIKeyRepositoryImpl) FetchAPIKeys(userId string) ([]models.APIKeyData, error) {
+	log.Println("Fetching API keys from Supabase...")
+
+	query := `
+        SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, revoked_at, created_at
+        FROM api_keys
+        WHERE $1 = '' OR user_id::text = $1
+        ORDER BY created_at DESC
+    `
+
+	// Supabaseからクエリを実行し、条件に一致するキーを取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query, userId)
+	if err != nil {
+		log.Printf("Failed to fetch API keys: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKeyData{}
+
+	// 結果をスキャンしてキーをリストに追加
+	for rows.Next() {
+		var key models.APIKeyData
+		err := rows.Scan(
+			&key.ID,
+			&key.UserId,
+			&key.Name,
+			&key.Prefix,
+			&key.Scopes,
+			&key.ExpiresAt,
+			&key.LastUsedAt,
+			&key.RevokedAt,
+			&key.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan API key: %v", err)
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch API keys: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d API keys", len(keys))
+	return keys, nil
+}
+
+// 指定されたハッシュ値に対応するAPIキーを取得する。
+// キーが見つからない場合、エラーを返す。
+func (r *APIKeyRepositoryImpl) FetchAPIKeyByHash(keyHash string) (*models.APIKeyData, error) {
+	log.Println("Fetching API key by hash...")
+
+	// バリデーション: ハッシュ値が空でないか確認
+	if keyHash == "" {
+		log.Printf("Key hash is required")
+		return nil, errors.New("key hash is required")
+	}
+
+	query := `
+        SELECT id, user_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at
+        FROM api_keys
+        WHERE key_hash = $1
+        LIMIT 1
+    `
+
+	// Supabaseからクエリを実行し、条件に一致するキーを取得
+	row := supabase.Pool.QueryRow(supabase.Ctx, query, keyHash)
+
+	var key models.APIKeyData
+	err := row.Scan(
+		&key.ID,
+		&key.UserId,
+		&key.Name,
+		&key.Prefix,
+		&key.KeyHash,
+		&key.Scopes,
+		&key.ExpiresAt,
+		&key.LastUsedAt,
+		&key.RevokedAt,
+		&key.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("API key not found")
+			return nil, errors.New("api key not found")
+		}
+		log.Printf("Failed to fetch API key: %v", err)
+		return nil, err
+	}
+
+	log.Printf("API key found: %s", key.ID)
+	return &key, nil
+}
+
+// 新しいAPIキーをデータベースに追加し、追加したキーの情報を返す。
+// keyHashにはキー本体のハッシュ値を渡す。
+func (r *APIKeyRepositoryImpl) CreateAPIKey(userId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*models.APIKeyData, error) {
+	log.Printf("Creating new API key for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || name == "" || prefix == "" || keyHash == "" {
+		log.Printf("UserID, name, prefix and key hash are required")
+		return nil, errors.New("userID, name, prefix and key hash are required")
+	}
+
+	query := `
+        INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW())
+        RETURNING id, created_at
+    `
+
+	key := models.APIKeyData{
+		UserId:    userId,
+		Name:      name,
+		Prefix:    prefix,
+		KeyHash:   keyHash,
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	}
+
+	// キーを挿入
+	err := supabase.Pool.QueryRow(supabase.Ctx, query, userId, name, prefix, keyHash, scopes, expiresAt).Scan(&key.ID, &key.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create API key: %v", err)
+		return nil, err
+	}
+
+	log.Println("API key created successfully")
+	return &key, nil
+}
+
+// 指定されたAPIキーを失効させる。
+// 既に失効している場合は失効日時を変更しない。
+func (r *APIKeyRepositoryImpl) RevokeAPIKey(id string) error {
+	log.Printf("Revoking API key: %s\n", id)
+
+	// バリデーション: IDが空でないか確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	query := `
+        UPDATE api_keys
+        SET revoked_at = COALESCE(revoked_at, NOW())
+        WHERE id = $1
+    `
+
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, id)
+	if err != nil {
+		log.Printf("Failed to revoke API key: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("API key not found: %s", id)
+		return errors.New("api key not found")
+	}
+
+	log.Println("API key revoked successfully")
+	return nil
+}
+
+// 指定されたAPIキーの最終使用日時を更新する。
+func (r *APIKeyRepositoryImpl) UpdateLastUsed(id string) error {
+	// バリデーション: IDが空でないか確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	_, err := supabase.Pool.Exec(supabase.Ctx, `UPDATE api_keys SET last_used_at = NOW() WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("Failed to update API key last used: %v", err)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package repositories_api_keys
+
+import (
+	"backend/models"
+	"time"
+)
+
+// APIKeyRepositoryインターフェース
+type APIKeyRepository interface {
+	FetchAPIKeys(userId string) ([]models.APIKeyData, error)
+	FetchAPIKeyByHash(keyHash string) (*models.APIKeyData, error)
+	CreateAPIKey(userId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*models.APIKeyData, error)
+	RevokeAPIKey(id string) error
+	UpdateLastUsed(id string) error
+}
+
+// APIKeyRepositoryImplはAPIKeyRepositoryインターフェースを実装する
+type APIKeyRepositoryImpl struct{}
+
+func NewAPIKeyRepository() APIKeyRepository {
+	return &APIKeyRepositoryImpl{}
+}
@@ -0,0 +1,47 @@
+package repositories_api_keys
+
+import (
+	"backend/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAPIKeyRepository is a mock implementation of APIKeyRepository
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) FetchAPIKeys(userId string) ([]models.APIKeyData, error) {
+	args := m.Called(userId)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.APIKeyData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) FetchAPIKeyByHash(keyHash string) (*models.APIKeyData, error) {
+	args := m.Called(keyHash)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.APIKeyData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(userId, name, prefix, keyHash string, scopes []string, expiresAt *time.Time) (*models.APIKeyData, error) {
+	args := m.Called(userId, name, prefix, keyHash, scopes, expiresAt)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.APIKeyData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) UpdateLastUsed(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
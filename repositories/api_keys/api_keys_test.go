@@ -0,0 +1,66 @@
+package repositories_api_keys
+
+import (
+	"backend/supabase"
+	"log"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSupabase() {
+	// 環境変数の読み込み
+	err := godotenv.Load("../../.env.test")
+	if err != nil {
+		log.Println("No ../../.env.test file found")
+	}
+
+	// テストの前にSupabaseクライアントの初期化
+	err = supabase.InitSupabase()
+	if err != nil {
+		log.Fatalf("Supabase initialization failed: %v", err)
+	}
+}
+
+func TestRepository_FetchAPIKeyByHash_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewAPIKeyRepository()
+
+	// メソッドを実行
+	_, err := repo.FetchAPIKeyByHash("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_CreateAPIKey_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewAPIKeyRepository()
+
+	// メソッドを実行
+	_, err := repo.CreateAPIKey("", "kiosk", "", "", nil, nil)
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_RevokeAPIKey_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewAPIKeyRepository()
+
+	// メソッドを実行
+	err := repo.RevokeAPIKey("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
@@ -0,0 +1,319 @@
+package repositories_business_calendar
+
+import (
+	"backend/models"
+	"backend/supabase"
+	"errors"
+	"log"
+)
+
+// 曜日ごとの営業時間をすべて取得する。
+func (r *BusinessCalendarRepositoryImpl) FetchBusinessHours() ([]models.BusinessHoursData, error) {
+	log.Println("Fetching business hours from Supabase...")
+
+	query := `
+        SELECT id, weekday, to_char(open_time, 'HH24:MI'), to_char(close_time, 'HH24:MI')
+        FROM business_hours
+        ORDER BY weekday, open_time
+    `
+
+	// Supabaseからクエリを実行し、営業時間を取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query)
+	if err != nil {
+		log.Printf("Failed to fetch business hours: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	hours := []models.BusinessHoursData{}
+	for rows.Next() {
+		var h models.BusinessHoursData
+		if err := rows.Scan(&h.ID, &h.Weekday, &h.OpenTime, &h.CloseTime); err != nil {
+			log.Printf("Failed to scan business hours: %v", err)
+			return nil, err
+		}
+		hours = append(hours, h)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch business hours: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d business hours", len(hours))
+	return hours, nil
+}
+
+// 曜日ごとの営業時間を、指定した内容にすべて置き換える。
+// 置き換えは1つのトランザクションで行い、途中で失敗した場合は元の営業時間を維持する。
+func (r *BusinessCalendarRepositoryImpl) ReplaceBusinessHours(hours []models.BusinessHoursData) ([]models.BusinessHoursData, error) {
+	log.Printf("Replacing business hours with %d entries\n", len(hours))
+
+	// トランザクションの開始
+	tx, err := supabase.Pool.Begin(supabase.Ctx)
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+
+	// トランザクションが成功または失敗した場合にコミットまたはロールバックを行う
+	defer func() {
+		if err != nil {
+			log.Println("Rolling back transaction...")
+			if rollbackErr := tx.Rollback(supabase.Ctx); rollbackErr != nil {
+				log.Printf("Failed to rollback transaction: %v", rollbackErr)
+			}
+			return
+		}
+
+		log.Println("Committing transaction...")
+		if commitErr := tx.Commit(supabase.Ctx); commitErr != nil {
+			log.Printf("Failed to commit transaction: %v", commitErr)
+		}
+	}()
+
+	_, err = tx.Exec(supabase.Ctx, `DELETE FROM business_hours`)
+	if err != nil {
+		log.Printf("Failed to delete business hours: %v", err)
+		return nil, err
+	}
+
+	query := `
+        INSERT INTO business_hours (weekday, open_time, close_time)
+        VALUES ($1, $2::time, $3::time)
+        RETURNING id
+    `
+
+	replaced := make([]models.BusinessHoursData, 0, len(hours))
+	for _, h := range hours {
+		err = tx.QueryRow(supabase.Ctx, query, h.Weekday, h.OpenTime, h.CloseTime).Scan(&h.ID)
+		if err != nil {
+			log.Printf("Failed to create business hours: %v", err)
+			return nil, err
+		}
+		replaced = append(replaced, h)
+	}
+
+	log.Println("Business hours replaced successfully")
+	return replaced, nil
+}
+
+// 指定した期間の、通常と異なる営業時間の設定を取得する。
+// fromとtoはその日を含み、空の場合は期間を限定しない。
+func (r *BusinessCalendarRepositoryImpl) FetchHoursOverrides(from, to string) ([]models.HoursOverrideData, error) {
+	log.Printf("Fetching hours overrides from %q to %q\n", from, to)
+
+	query := `
+        SELECT id, to_char(date, 'YYYY-MM-DD'), to_char(open_time, 'HH24:MI'), to_char(close_time, 'HH24:MI'), reason, created_at
+        FROM business_hours_overrides
+        WHERE date >= COALESCE(NULLIF($1, '')::date, '-infinity'::date)
+          AND date <= COALESCE(NULLIF($2, '')::date, 'infinity'::date)
+        ORDER BY date, open_time
+    `
+
+	// Supabaseからクエリを実行し、条件に一致する設定を取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query, from, to)
+	if err != nil {
+		log.Printf("Failed to fetch hours overrides: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	overrides := []models.HoursOverrideData{}
+	for rows.Next() {
+		var o models.HoursOverrideData
+		if err := rows.Scan(&o.ID, &o.Date, &o.OpenTime, &o.CloseTime, &o.Reason, &o.CreatedAt); err != nil {
+			log.Printf("Failed to scan hours override: %v", err)
+			return nil, err
+		}
+		overrides = append(overrides, o)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch hours overrides: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d hours overrides", len(overrides))
+	return overrides, nil
+}
+
+// 通常と異なる営業時間の設定を追加し、追加した設定を返す。
+func (r *BusinessCalendarRepositoryImpl) CreateHoursOverride(date, openTime, closeTime, reason string) (*models.HoursOverrideData, error) {
+	log.Printf("Creating hours override for %s\n", date)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if date == "" || openTime == "" || closeTime == "" {
+		log.Printf("Date, open time and close time are required")
+		return nil, errors.New("date, open time and close time are required")
+	}
+
+	query := `
+        INSERT INTO business_hours_overrides (date, open_time, close_time, reason, created_at)
+        VALUES ($1::date, $2::time, $3::time, $4, NOW())
+        RETURNING id, created_at
+    `
+
+	override := models.HoursOverrideData{
+		Date:      date,
+		OpenTime:  openTime,
+		CloseTime: closeTime,
+		Reason:    reason,
+	}
+
+	// 設定を挿入
+	err := supabase.Pool.QueryRow(supabase.Ctx, query, date, openTime, closeTime, reason).Scan(&override.ID, &override.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create hours override: %v", err)
+		return nil, err
+	}
+
+	log.Println("Hours override created successfully")
+	return &override, nil
+}
+
+// 指定されたIDの、通常と異なる営業時間の設定を削除する。
+func (r *BusinessCalendarRepositoryImpl) DeleteHoursOverride(id string) error {
+	log.Printf("Deleting hours override: %s\n", id)
+
+	// バリデーション: IDが空でないか確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	result, err := supabase.Pool.Exec(supabase.Ctx, `DELETE FROM business_hours_overrides WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("Failed to delete hours override: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Hours override not found: %s", id)
+		return errors.New("hours override not found")
+	}
+
+	log.Println("Hours override deleted successfully")
+	return nil
+}
+
+// 指定した期間と重なる休業期間を取得する。
+// fromとtoはその日を含み、空の場合は期間を限定しない。
+func (r *BusinessCalendarRepositoryImpl) FetchClosures(from, to string) ([]models.ClosureData, error) {
+	log.Printf("Fetching closures from %q to %q\n", from, to)
+
+	query := `
+        SELECT id, to_char(start_date, 'YYYY-MM-DD'), to_char(end_date, 'YYYY-MM-DD'), reason, created_at
+        FROM business_closures
+        WHERE end_date >= COALESCE(NULLIF($1, '')::date, '-infinity'::date)
+          AND start_date <= COALESCE(NULLIF($2, '')::date, 'infinity'::date)
+        ORDER BY start_date, end_date
+    `
+
+	// Supabaseからクエリを実行し、条件に一致する休業期間を取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query, from, to)
+	if err != nil {
+		log.Printf("Failed to fetch closures: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	closures := []models.ClosureData{}
+	for rows.Next() {
+		var c models.ClosureData
+		if err := rows.Scan(&c.ID, &c.StartDate, &c.EndDate, &c.Reason, &c.CreatedAt); err != nil {
+			log.Printf("Failed to scan closure: %v", err)
+			return nil, err
+		}
+		closures = append(closures, c)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch closures: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d closures", len(closures))
+	return closures, nil
+}
+
+// 休業期間を追加し、追加した休業期間を返す。
+func (r *BusinessCalendarRepositoryImpl) CreateClosure(startDate, endDate, reason string) (*models.ClosureData, error) {
+	log.Printf("Creating closure from %s to %s\n", startDate, endDate)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if startDate == "" || endDate == "" {
+		log.Printf("Start date and end date are required")
+		return nil, errors.New("start date and end date are required")
+	}
+
+	query := `
+        INSERT INTO business_closures (start_date, end_date, reason, created_at)
+        VALUES ($1::date, $2::date, $3, NOW())
+        RETURNING id, created_at
+    `
+
+	closure := models.ClosureData{
+		StartDate: startDate,
+		EndDate:   endDate,
+		Reason:    reason,
+	}
+
+	// 休業期間を挿入
+	err := supabase.Pool.QueryRow(supabase.Ctx, query, startDate, endDate, reason).Scan(&closure.ID, &closure.CreatedAt)
+	if err != nil {
+		log.Printf("Failed to create closure: %v", err)
+		return nil, err
+	}
+
+	log.Println("Closure created successfully")
+	return &closure, nil
+}
+
+// 指定されたIDの休業期間を削除する。
+func (r *BusinessCalendarRepositoryImpl) DeleteClosure(id string) error {
+	log.Printf("Deleting closure: %s\n", id)
+
+	// バリデーション: IDが空でないか確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	result, err := supabase.Pool.Exec(supabase.Ctx, `DELETE FROM business_closures WHERE id = $1`, id)
+	if err != nil {
+		log.Printf("Failed to delete closure: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Closure not found: %s", id)
+		return errors.New("closure not found")
+	}
+
+	log.Println("Closure deleted successfully")
+	return nil
+}
+
+// 指定した期間の営業日の判定に必要な設定をまとめて取得する。
+// fromとtoはその日を含む。
+func (r *BusinessCalendarRepositoryImpl) FetchCalendar(from, to string) (*models.BusinessCalendar, error) {
+	// バリデーション: 期間が空でないか確認
+	if from == "" || to == "" {
+		log.Printf("From and to are required")
+		return nil, errors.New("from and to are required")
+	}
+
+	hours, err := r.FetchBusinessHours()
+	if err != nil {
+		return nil, err
+	}
+	overrides, err := r.FetchHoursOverrides(from, to)
+	if err != nil {
+		return nil, err
+	}
+	closures, err := r.FetchClosures(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.BusinessCalendar{Hours: hours, Overrides: overrides, Closures: closures}, nil
+}
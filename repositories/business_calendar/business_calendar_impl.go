@@ -0,0 +1,24 @@
+package repositories_business_calendar
+
+import "backend/models"
+
+// BusinessCalendarRepositoryインターフェース
+// 日付はYYYY-MM-DD、時刻はHH:MM形式の文字列で扱う。
+type BusinessCalendarRepository interface {
+	FetchBusinessHours() ([]models.BusinessHoursData, error)
+	ReplaceBusinessHours(hours []models.BusinessHoursData) ([]models.BusinessHoursData, error)
+	FetchHoursOverrides(from, to string) ([]models.HoursOverrideData, error)
+	CreateHoursOverride(date, openTime, closeTime, reason string) (*models.HoursOverrideData, error)
+	DeleteHoursOverride(id string) error
+	FetchClosures(from, to string) ([]models.ClosureData, error)
+	CreateClosure(startDate, endDate, reason string) (*models.ClosureData, error)
+	DeleteClosure(id string) error
+	FetchCalendar(from, to string) (*models.BusinessCalendar, error)
+}
+
+// BusinessCalendarRepositoryImplはBusinessCalendarRepositoryインターフェースを実装する
+type BusinessCalendarRepositoryImpl struct{}
+
+func NewBusinessCalendarRepository() BusinessCalendarRepository {
+	return &BusinessCalendarRepositoryImpl{}
+}
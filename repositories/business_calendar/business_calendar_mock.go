@@ -0,0 +1,78 @@
+package repositories_business_calendar
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBusinessCalendarRepository is a mock implementation of BusinessCalendarRepository
+type MockBusinessCalendarRepository struct {
+	mock.Mock
+}
+
+func (m *MockBusinessCalendarRepository) FetchBusinessHours() ([]models.BusinessHoursData, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.BusinessHoursData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarRepository) ReplaceBusinessHours(hours []models.BusinessHoursData) ([]models.BusinessHoursData, error) {
+	args := m.Called(hours)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.BusinessHoursData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarRepository) FetchHoursOverrides(from, to string) ([]models.HoursOverrideData, error) {
+	args := m.Called(from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.HoursOverrideData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarRepository) CreateHoursOverride(date, openTime, closeTime, reason string) (*models.HoursOverrideData, error) {
+	args := m.Called(date, openTime, closeTime, reason)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.HoursOverrideData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarRepository) DeleteHoursOverride(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockBusinessCalendarRepository) FetchClosures(from, to string) ([]models.ClosureData, error) {
+	args := m.Called(from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.ClosureData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarRepository) CreateClosure(startDate, endDate, reason string) (*models.ClosureData, error) {
+	args := m.Called(startDate, endDate, reason)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.ClosureData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarRepository) DeleteClosure(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockBusinessCalendarRepository) FetchCalendar(from, to string) (*models.BusinessCalendar, error) {
+	args := m.Called(from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.BusinessCalendar), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,94 @@
+package repositories_business_calendar
+
+import (
+	"backend/supabase"
+	"log"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSupabase() {
+	// 環境変数の読み込み
+	err := godotenv.Load("../../.env.test")
+	if err != nil {
+		log.Println("No ../../.env.test file found")
+	}
+
+	// テストの前にSupabaseクライアントの初期化
+	err = supabase.InitSupabase()
+	if err != nil {
+		log.Fatalf("Supabase initialization failed: %v", err)
+	}
+}
+
+func TestRepository_CreateHoursOverride_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewBusinessCalendarRepository()
+
+	// メソッドを実行
+	_, err := repo.CreateHoursOverride("", "11:00", "", "Holiday")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_DeleteHoursOverride_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewBusinessCalendarRepository()
+
+	// メソッドを実行
+	err := repo.DeleteHoursOverride("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_CreateClosure_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewBusinessCalendarRepository()
+
+	// メソッドを実行
+	_, err := repo.CreateClosure("2024-12-31", "", "New Year")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_DeleteClosure_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewBusinessCalendarRepository()
+
+	// メソッドを実行
+	err := repo.DeleteClosure("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_FetchCalendar_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewBusinessCalendarRepository()
+
+	// メソッドを実行
+	_, err := repo.FetchCalendar("", "2024-12-31")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+package repositories_identities
+
+import (
+	"backend/models"
+	"backend/supabase"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// 指定されたプロバイダーとsubjectに紐付くアカウント情報を取得する。
+// 紐付けがない場合、エラーを返す。
+func (r *IdentityRepositoryImpl) FetchIdentity(provider, subject string) (*models.UserIdentityData, error) {
+	log.Printf("Fetching identity for provider: %s\n", provider)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if provider == "" || subject == "" {
+		log.Printf("Provider and subject are required")
+		return nil, errors.New("provider and subject are required")
+	}
+
+	query := `
+        SELECT id, user_id, provider, subject, email, created_at
+        FROM user_identities
+        WHERE provider = $1 AND subject = $2
+        LIMIT 1
+    `
+
+	// Supabaseからクエリを実行し、条件に一致する紐付けを取得
+	row := supabase.Pool.QueryRow(supabase.Ctx, query, provider, subject)
+
+	var identity models.UserIdentityData
+	err := row.Scan(
+		&identity.ID,
+		&identity.UserId,
+		&identity.Provider,
+		&identity.Subject,
+		&identity.Email,
+		&identity.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Identity not found for provider: %s", provider)
+			return nil, errors.New("identity not found")
+		}
+		log.Printf("Failed to fetch identity: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Identity found for userId: %s", identity.UserId)
+	return &identity, nil
+}
+
+// 外部のアカウントをユーザーに紐付ける。
+// 同じアカウントが既に紐付けられている場合はエラーを返す。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *IdentityRepositoryImpl) CreateIdentity(userId, provider, subject, email string) error {
+	log.Printf("Linking identity for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || provider == "" || subject == "" || email == "" {
+		log.Printf("UserID, provider, subject and email are required")
+		return errors.New("userID, provider, subject and email are required")
+	}
+
+	query := `
+        INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+        ON CONFLICT (provider, subject) DO NOTHING
+    `
+
+	// 紐付けを挿入
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, userId, provider, subject, email)
+	if err != nil {
+		log.Printf("Failed to create identity: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Identity already linked for provider: %s", provider)
+		return errors.New("identity already linked")
+	}
+
+	log.Println("Identity linked successfully")
+	return nil
+}
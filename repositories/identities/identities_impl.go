@@ -0,0 +1,16 @@
+package repositories_identities
+
+import "backend/models"
+
+// IdentityRepositoryインターフェース
+type IdentityRepository interface {
+	FetchIdentity(provider, subject string) (*models.UserIdentityData, error)
+	CreateIdentity(userId, provider, subject, email string) error
+}
+
+// IdentityRepositoryImplはIdentityRepositoryインターフェースを実装する
+type IdentityRepositoryImpl struct{}
+
+func NewIdentityRepository() IdentityRepository {
+	return &IdentityRepositoryImpl{}
+}
@@ -0,0 +1,25 @@
+package repositories_identities
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdentityRepository is a mock implementation of IdentityRepository
+type MockIdentityRepository struct {
+	mock.Mock
+}
+
+func (m *MockIdentityRepository) FetchIdentity(provider, subject string) (*models.UserIdentityData, error) {
+	args := m.Called(provider, subject)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.UserIdentityData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockIdentityRepository) CreateIdentity(userId, provider, subject, email string) error {
+	args := m.Called(userId, provider, subject, email)
+	return args.Error(0)
+}
@@ -0,0 +1,52 @@
+package repositories_identities
+
+import (
+	"backend/supabase"
+	"log"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSupabase() {
+	// 環境変数の読み込み
+	err := godotenv.Load("../../.env.test")
+	if err != nil {
+		log.Println("No ../../.env.test file found")
+	}
+
+	// テストの前にSupabaseクライアントの初期化
+	err = supabase.InitSupabase()
+	if err != nil {
+		log.Fatalf("Supabase initialization failed: %v", err)
+	}
+}
+
+func TestRepository_FetchIdentity_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewIdentityRepository()
+
+	// メソッドを実行
+	_, err := repo.FetchIdentity("google", "")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_CreateIdentity_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewIdentityRepository()
+
+	// メソッドを実行
+	err := repo.CreateIdentity("", "google", "subject", "test@example.com")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
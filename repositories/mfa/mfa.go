@@ -0,0 +1,248 @@
+package repositories_mfa
+
+import (
+	"backend/models"
+	"backend/supabase"
+	"errors"
+	"log"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// 指定されたユーザーの二要素認証の設定を取得する。
+// 設定がない場合、エラーを返す。
+func (r *MFARepositoryImpl) FetchMFAByUserId(userId string) (*models.UserMFAData, error) {
+	log.Printf("Fetching MFA settings for userId: %s\n", userId)
+
+	// バリデーション: ユーザーIDが空でないか確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return nil, errors.New("userID is required")
+	}
+
+	query := `
+        SELECT user_id, secret, confirmed_at, last_used_step, created_at, updated_at
+        FROM user_mfa
+        WHERE user_id = $1
+        LIMIT 1
+    `
+
+	// Supabaseからクエリを実行し、条件に一致する設定を取得
+	row := supabase.Pool.QueryRow(supabase.Ctx, query, userId)
+
+	var mfa models.UserMFAData
+	err := row.Scan(
+		&mfa.UserId,
+		&mfa.Secret,
+		&mfa.ConfirmedAt,
+		&mfa.LastUsedStep,
+		&mfa.CreatedAt,
+		&mfa.UpdatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("MFA settings not found for userId: %s", userId)
+			return nil, errors.New("mfa not found")
+		}
+		log.Printf("Failed to fetch MFA settings: %v", err)
+		return nil, err
+	}
+
+	log.Printf("MFA settings found for userId: %s", mfa.UserId)
+	return &mfa, nil
+}
+
+// 登録途中のシークレットを保存する。
+// 既に登録済みの場合は上書きせず、エラーを返す。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *MFARepositoryImpl) SaveMFASecret(userId, secret string) error {
+	log.Printf("Saving MFA secret for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || secret == "" {
+		log.Printf("UserID and secret are required")
+		return errors.New("userID and secret are required")
+	}
+
+	query := `
+        INSERT INTO user_mfa (user_id, secret, confirmed_at, last_used_step, created_at, updated_at)
+        VALUES ($1, $2, NULL, 0, NOW(), NOW())
+        ON CONFLICT (user_id) DO UPDATE
+        SET secret = EXCLUDED.secret, last_used_step = 0, updated_at = NOW()
+        WHERE user_mfa.confirmed_at IS NULL
+    `
+
+	// シークレットを保存
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, userId, secret)
+	if err != nil {
+		log.Printf("Failed to save MFA secret: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("MFA already enabled for userId: %s", userId)
+		return errors.New("mfa already enabled")
+	}
+
+	log.Println("MFA secret saved successfully")
+	return nil
+}
+
+// 二要素認証の登録を完了し、リカバリーコードを保存する。
+// 既存のリカバリーコードは削除する。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *MFARepositoryImpl) ConfirmMFA(userId string, step int64, recoveryCodeHashes []string) error {
+	log.Printf("Confirming MFA for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || len(recoveryCodeHashes) == 0 {
+		log.Printf("UserID and recovery codes are required")
+		return errors.New("userID and recovery codes are required")
+	}
+
+	// トランザクションの開始
+	tx, err := supabase.Pool.Begin(supabase.Ctx)
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return err
+	}
+
+	// トランザクションが成功または失敗した場合にコミットまたはロールバックを行う
+	defer func() {
+		if err != nil {
+			log.Println("Rolling back transaction...")
+			if rollbackErr := tx.Rollback(supabase.Ctx); rollbackErr != nil {
+				log.Printf("Failed to rollback transaction: %v", rollbackErr)
+			}
+			return
+		}
+
+		log.Println("Committing transaction...")
+		if commitErr := tx.Commit(supabase.Ctx); commitErr != nil {
+			log.Printf("Failed to commit transaction: %v", commitErr)
+		}
+	}()
+
+	// 登録途中の設定のみを有効にする
+	confirmQuery := `
+        UPDATE user_mfa
+        SET confirmed_at = NOW(), last_used_step = $2, updated_at = NOW()
+        WHERE user_id = $1 AND confirmed_at IS NULL
+    `
+	result, err := tx.Exec(supabase.Ctx, confirmQuery, userId, step)
+	if err != nil {
+		log.Printf("Failed to confirm MFA: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Pending MFA enrollment not found for userId: %s", userId)
+		err = errors.New("mfa not found")
+		return err
+	}
+
+	// 既存のリカバリーコードを削除
+	_, err = tx.Exec(supabase.Ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userId)
+	if err != nil {
+		log.Printf("Failed to delete recovery codes: %v", err)
+		return err
+	}
+
+	// 新しいリカバリーコードを挿入
+	insertQuery := `
+        INSERT INTO mfa_recovery_codes (user_id, code_hash, created_at)
+        VALUES ($1, $2, NOW())
+    `
+	for _, codeHash := range recoveryCodeHashes {
+		_, err = tx.Exec(supabase.Ctx, insertQuery, userId, codeHash)
+		if err != nil {
+			log.Printf("Failed to create recovery code: %v", err)
+			return err
+		}
+	}
+
+	log.Println("MFA confirmed successfully")
+	return nil
+}
+
+// 最後に使用したTOTPの時間ステップを更新する。
+// 同じまたはより古い時間ステップのコードは再利用とみなし、エラーを返す。
+func (r *MFARepositoryImpl) UpdateLastUsedStep(userId string, step int64) error {
+	log.Printf("Updating last used TOTP step for userId: %s\n", userId)
+
+	// バリデーション: ユーザーIDが空でないか確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return errors.New("userID is required")
+	}
+
+	// 同時に同じコードが提示された場合でも成功するのは1回のみ
+	query := `
+        UPDATE user_mfa
+        SET last_used_step = $2, updated_at = NOW()
+        WHERE user_id = $1 AND last_used_step < $2
+    `
+
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, userId, step)
+	if err != nil {
+		log.Printf("Failed to update last used TOTP step: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("TOTP code already used for userId: %s", userId)
+		return errors.New("code already used")
+	}
+
+	log.Println("Last used TOTP step updated successfully")
+	return nil
+}
+
+// 指定されたハッシュ値のリカバリーコードを使用済みにする。
+// 未使用のコードのみが対象で、該当するコードがない場合はエラーを返す。
+func (r *MFARepositoryImpl) ConsumeRecoveryCode(userId, codeHash string) error {
+	log.Printf("Consuming recovery code for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || codeHash == "" {
+		log.Printf("UserID and code hash are required")
+		return errors.New("userID and code hash are required")
+	}
+
+	query := `
+        UPDATE mfa_recovery_codes
+        SET used_at = NOW()
+        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+    `
+
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, userId, codeHash)
+	if err != nil {
+		log.Printf("Failed to consume recovery code: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Recovery code not found or already used")
+		return errors.New("recovery code not found")
+	}
+
+	log.Println("Recovery code consumed successfully")
+	return nil
+}
+
+// 指定されたユーザーの二要素認証の設定を削除する。
+// リカバリーコードも合わせて削除される。
+func (r *MFARepositoryImpl) DeleteMFA(userId string) error {
+	log.Printf("Deleting MFA settings for userId: %s\n", userId)
+
+	// バリデーション: ユーザーIDが空でないか確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return errors.New("userID is required")
+	}
+
+	_, err := supabase.Pool.Exec(supabase.Ctx, `DELETE FROM user_mfa WHERE user_id = $1`, userId)
+	if err != nil {
+		log.Printf("Failed to delete MFA settings: %v", err)
+		return err
+	}
+
+	log.Println("MFA settings deleted successfully")
+	return nil
+}
@@ -0,0 +1,20 @@
+package repositories_mfa
+
+import "backend/models"
+
+// MFARepositoryインターフェース
+type MFARepository interface {
+	FetchMFAByUserId(userId string) (*models.UserMFAData, error)
+	SaveMFASecret(userId, secret string) error
+	ConfirmMFA(userId string, step int64, recoveryCodeHashes []string) error
+	UpdateLastUsedStep(userId string, step int64) error
+	ConsumeRecoveryCode(userId, codeHash string) error
+	DeleteMFA(userId string) error
+}
+
+// MFARepositoryImplはMFARepositoryインターフェースを実装する
+type MFARepositoryImpl struct{}
+
+func NewMFARepository() MFARepository {
+	return &MFARepositoryImpl{}
+}
@@ -0,0 +1,45 @@
+package repositories_mfa
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMFARepository is a mock implementation of MFARepository
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) FetchMFAByUserId(userId string) (*models.UserMFAData, error) {
+	args := m.Called(userId)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.UserMFAData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFARepository) SaveMFASecret(userId, secret string) error {
+	args := m.Called(userId, secret)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConfirmMFA(userId string, step int64, recoveryCodeHashes []string) error {
+	args := m.Called(userId, step, recoveryCodeHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UpdateLastUsedStep(userId string, step int64) error {
+	args := m.Called(userId, step)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConsumeRecoveryCode(userId, codeHash string) error {
+	args := m.Called(userId, codeHash)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) DeleteMFA(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
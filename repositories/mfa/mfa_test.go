@@ -0,0 +1,80 @@
+package repositories_mfa
+
+import (
+	"backend/supabase"
+	"log"
+	"testing"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSupabase() {
+	// 環境変数の読み込み
+	err := godotenv.Load("../../.env.test")
+	if err != nil {
+		log.Println("No ../../.env.test file found")
+	}
+
+	// テストの前にSupabaseクライアントの初期化
+	err = supabase.InitSupabase()
+	if err != nil {
+		log.Fatalf("Supabase initialization failed: %v", err)
+	}
+}
+
+func TestRepository_FetchMFAByUserId_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewMFARepository()
+
+	// メソッドを実行
+	_, err := repo.FetchMFAByUserId("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_SaveMFASecret_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewMFARepository()
+
+	// メソッドを実行
+	err := repo.SaveMFASecret("", "")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_ConfirmMFA_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewMFARepository()
+
+	// メソッドを実行
+	err := repo.ConfirmMFA("", 0, nil)
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_ConsumeRecoveryCode_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewMFARepository()
+
+	// メソッドを実行
+	err := repo.ConsumeRecoveryCode("", "")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
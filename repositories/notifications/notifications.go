@@ -56,6 +56,54 @@ func (r *NotificationRepositoryImpl) FetchNotifications() ([]models.Notification
 	return notifications, nil
 }
 
+// 指定されたユーザーIDに対応する通知情報をすべて取得し、通知情報リストを返す。
+// 失敗した場合はエラーを返す。
+func (r *NotificationRepositoryImpl) FetchNotificationsByUserId(userId string) ([]models.NotificationData, error) {
+	log.Printf("Fetching notifications for userId: %s\n", userId)
+
+	query := `
+        SELECT id, user_id, reservation_id, message, created_at
+        FROM notifications
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+    `
+
+	// Supabaseからクエリを実行し、条件に一致する通知情報を取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query, userId)
+	if err != nil {
+		log.Printf("Failed to fetch notifications: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.NotificationData{}
+
+	// 結果をスキャンして通知情報をリストに追加
+	for rows.Next() {
+		var notification models.NotificationData
+		err := rows.Scan(
+			&notification.ID,
+			&notification.UserId,
+			&notification.ReservationId,
+			&notification.Message,
+			&notification.CreatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan notification: %v", err)
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch notifications: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d notifications", len(notifications))
+	return notifications, nil
+}
+
 // 新しい通知をデータベースに追加する。
 // 成功した場合はnilを返し、失敗した場合はエラーを返す。
 func (r *NotificationRepositoryImpl) CreateNotification(userId, reservationId, message string) error {
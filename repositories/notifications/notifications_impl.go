@@ -5,6 +5,7 @@ import "backend/models"
 // NotificationRepositoryインターフェース
 type NotificationRepository interface {
 	FetchNotifications() ([]models.NotificationData, error)
+	FetchNotificationsByUserId(userId string) ([]models.NotificationData, error)
 	CreateNotification(userId, reservationId, message string) error
 }
 
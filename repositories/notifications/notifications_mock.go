@@ -16,6 +16,14 @@ func (m *MockNotificationRepository) FetchNotifications() ([]models.Notification
 	return args.Get(0).([]models.NotificationData), args.Error(1)
 }
 
+func (m *MockNotificationRepository) FetchNotificationsByUserId(userId string) ([]models.NotificationData, error) {
+	args := m.Called(userId)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.NotificationData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *MockNotificationRepository) CreateNotification(userId, reservationId, message string) error {
 	args := m.Called(userId, reservationId, message)
 	return args.Error(0)
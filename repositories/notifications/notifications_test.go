@@ -3,6 +3,7 @@ package repositories_notifications
 import (
 	"backend/supabase"
 	"log"
+	"os"
 	"testing"
 
 	"github.com/joho/godotenv"
@@ -41,6 +42,21 @@ func TestRepository_FetchNotifications(t *testing.T) {
 	assert.GreaterOrEqual(t, len(notifications), 0)
 }
 
+func TestRepository_FetchNotificationsByUserId(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewNotificationRepository()
+
+	// メソッドを実行
+	notifications, err := repo.FetchNotificationsByUserId(os.Getenv("TEST_USER_ID"))
+
+	// エラーチェックとデータ確認
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, len(notifications), 0)
+}
+
 func TestRepository_CreateNotification_ErrorCases(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
@@ -0,0 +1,117 @@
+package repositories_password_resets
+
+import (
+	"backend/models"
+	"backend/supabase"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// 新しいパスワードリセットトークンをデータベースに追加する。
+// 同じユーザーの未使用のトークンは無効にし、最後に発行したトークンのみ使用できるようにする。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *PasswordResetRepositoryImpl) CreatePasswordResetToken(userId, tokenHash string, expiresAt time.Time) error {
+	log.Printf("Creating password reset token for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || tokenHash == "" {
+		log.Printf("UserID and token hash are required")
+		return errors.New("userID and token hash are required")
+	}
+
+	// トランザクションの開始
+	tx, err := supabase.Pool.Begin(supabase.Ctx)
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return err
+	}
+
+	// トランザクションが成功または失敗した場合にコミットまたはロールバックを行う
+	defer func() {
+		if err != nil {
+			log.Println("Rolling back transaction...")
+			if rollbackErr := tx.Rollback(supabase.Ctx); rollbackErr != nil {
+				log.Printf("Failed to rollback transaction: %v", rollbackErr)
+			}
+			return
+		}
+
+		log.Println("Committing transaction...")
+		if commitErr := tx.Commit(supabase.Ctx); commitErr != nil {
+			log.Printf("Failed to commit transaction: %v", commitErr)
+		}
+	}()
+
+	// 未使用のトークンを無効にする
+	invalidateQuery := `
+        UPDATE password_reset_tokens
+        SET used_at = NOW()
+        WHERE user_id = $1 AND used_at IS NULL
+    `
+	_, err = tx.Exec(supabase.Ctx, invalidateQuery, userId)
+	if err != nil {
+		log.Printf("Failed to invalidate password reset tokens: %v", err)
+		return err
+	}
+
+	// 新しいトークンを挿入
+	insertQuery := `
+        INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+        VALUES ($1, $2, $3, NOW())
+    `
+	_, err = tx.Exec(supabase.Ctx, insertQuery, userId, tokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Failed to create password reset token: %v", err)
+		return err
+	}
+
+	log.Println("Password reset token created successfully")
+	return nil
+}
+
+// 指定されたハッシュ値のパスワードリセットトークンを使用済みにし、その情報を返す。
+// 未使用かつ有効期限内のトークンのみが対象で、同時に同じトークンが提示された場合でも成功するのは1回のみ。
+// 該当するトークンがない場合、エラーを返す。
+func (r *PasswordResetRepositoryImpl) ConsumePasswordResetToken(tokenHash string) (*models.PasswordResetTokenData, error) {
+	log.Println("Consuming password reset token...")
+
+	// バリデーション: ハッシュ値が空でないか確認
+	if tokenHash == "" {
+		log.Printf("Token hash is required")
+		return nil, errors.New("token hash is required")
+	}
+
+	query := `
+        UPDATE password_reset_tokens
+        SET used_at = NOW()
+        WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+        RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+    `
+
+	// トークンを使用済みにして、更新後の値を取得
+	row := supabase.Pool.QueryRow(supabase.Ctx, query, tokenHash)
+
+	var token models.PasswordResetTokenData
+	err := row.Scan(
+		&token.ID,
+		&token.UserId,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Password reset token not found, already used or expired")
+			return nil, errors.New("password reset token not found")
+		}
+		log.Printf("Failed to consume password reset token: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Password reset token consumed: %s", token.ID)
+	return &token, nil
+}
@@ -0,0 +1,19 @@
+package repositories_password_resets
+
+import (
+	"backend/models"
+	"time"
+)
+
+// PasswordResetRepositoryインターフェース
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(userId, tokenHash string, expiresAt time.Time) error
+	ConsumePasswordResetToken(tokenHash string) (*models.PasswordResetTokenData, error)
+}
+
+// PasswordResetRepositoryImplはPasswordResetRepositoryインターフェースを実装する
+type PasswordResetRepositoryImpl struct{}
+
+func NewPasswordResetRepository() PasswordResetRepository {
+	return &PasswordResetRepositoryImpl{}
+}
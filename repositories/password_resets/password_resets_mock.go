@@ -0,0 +1,26 @@
+package repositories_password_resets
+
+import (
+	"backend/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasswordResetRepository is a mock implementation of PasswordResetRepository
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) CreatePasswordResetToken(userId, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(userId, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) ConsumePasswordResetToken(tokenHash string) (*models.PasswordResetTokenData, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.PasswordResetTokenData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,57 @@
+package repositories_password_resets
+
+import (
+	"backend/supabase"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSupabase() {
+	// 環境変数の読み込み
+	err := godotenv.Load("../../.env.test")
+	if err != nil {
+		log.Println("No ../../.env.test file found")
+	}
+
+	// テストの前にSupabaseクライアントの初期化
+	err = supabase.InitSupabase()
+	if err != nil {
+		log.Fatalf("Supabase initialization failed: %v", err)
+	}
+}
+
+func TestRepository_CreatePasswordResetToken_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewPasswordResetRepository()
+
+	// メソッドを実行
+	err := repo.CreatePasswordResetToken("", "", time.Now())
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_ConsumePasswordResetToken_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewPasswordResetRepository()
+
+	// 1. ハッシュ値が空の場合
+	token, err := repo.ConsumePasswordResetToken("")
+	assert.Error(t, err)
+	assert.Nil(t, token)
+
+	// 2. 存在しないトークンの場合
+	token, err = repo.ConsumePasswordResetToken("unknown-hash")
+	assert.Error(t, err)
+	assert.Nil(t, token)
+}
@@ -0,0 +1,194 @@
+package repositories_refresh_tokens
+
+import (
+	"backend/models"
+	"backend/supabase"
+	"errors"
+	"log"
+	"time"
+)
+
+// 指定されたハッシュ値に対応するリフレッシュトークンを取得する。
+// トークンが見つからない場合、エラーを返す。
+func (r *RefreshTokenRepositoryImpl) FetchRefreshTokenByHash(tokenHash string) (*models.RefreshTokenData, error) {
+	log.Println("Fetching refresh token from Supabase...")
+
+	query := `
+        SELECT id, user_id, family_id, token_hash, expires_at, used_at, revoked_at, created_at
+        FROM refresh_tokens
+        WHERE token_hash = $1
+        LIMIT 1
+    `
+
+	// Supabaseからクエリを実行し、条件に一致するトークンを取得
+	row := supabase.Pool.QueryRow(supabase.Ctx, query, tokenHash)
+
+	// 取得した結果をスキャン
+	var token models.RefreshTokenData
+	err := row.Scan(
+		&token.ID,
+		&token.UserId,
+		&token.FamilyId,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		log.Printf("Refresh token not found or failed to fetch refresh token: %v", err)
+		return nil, err
+	}
+
+	log.Printf("Refresh token found: %s", token.ID)
+	return &token, nil
+}
+
+// 新しいリフレッシュトークンをデータベースに追加する。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *RefreshTokenRepositoryImpl) CreateRefreshToken(userId, familyId, tokenHash string, expiresAt time.Time) error {
+	log.Printf("Creating new refresh token for userId: %s\n", userId)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if userId == "" || familyId == "" || tokenHash == "" {
+		log.Printf("UserID, familyID and token hash are required")
+		return errors.New("userID, familyID and token hash are required")
+	}
+
+	query := `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+    `
+
+	// リフレッシュトークンを挿入
+	_, err := supabase.Pool.Exec(supabase.Ctx, query, userId, familyId, tokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Failed to create refresh token: %v", err)
+		return err
+	}
+
+	log.Println("Refresh token created successfully")
+	return nil
+}
+
+// リフレッシュトークンを使用済みにし、同じ系列の新しいトークンを追加する。
+// 既に使用済みまたは失効済みの場合は何も変更せずfalseを返す。
+// 同時に同じトークンが提示された場合でも、ローテーションに成功するのは1回のみ。
+func (r *RefreshTokenRepositoryImpl) RotateRefreshToken(id, userId, familyId, newTokenHash string, expiresAt time.Time) (bool, error) {
+	log.Printf("Rotating refresh token: %s\n", id)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if id == "" || userId == "" || familyId == "" || newTokenHash == "" {
+		log.Printf("ID, userID, familyID and token hash are required")
+		return false, errors.New("id, userID, familyID and token hash are required")
+	}
+
+	// トランザクションの開始
+	tx, err := supabase.Pool.Begin(supabase.Ctx)
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return false, err
+	}
+
+	// トランザクションが成功または失敗した場合にコミットまたはロールバックを行う
+	defer func() {
+		if err != nil {
+			log.Println("Rolling back transaction...")
+			if rollbackErr := tx.Rollback(supabase.Ctx); rollbackErr != nil {
+				log.Printf("Failed to rollback transaction: %v", rollbackErr)
+			}
+			return
+		}
+
+		log.Println("Committing transaction...")
+		if commitErr := tx.Commit(supabase.Ctx); commitErr != nil {
+			log.Printf("Failed to commit transaction: %v", commitErr)
+		}
+	}()
+
+	// 未使用かつ未失効の場合のみ使用済みにする
+	updateQuery := `
+        UPDATE refresh_tokens
+        SET used_at = NOW()
+        WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL
+    `
+	result, err := tx.Exec(supabase.Ctx, updateQuery, id)
+	if err != nil {
+		log.Printf("Failed to mark refresh token as used: %v", err)
+		return false, err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Refresh token already used or revoked: %s", id)
+		return false, nil
+	}
+
+	// 同じ系列の新しいトークンを挿入
+	insertQuery := `
+        INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+        VALUES ($1, $2, $3, $4, NOW())
+    `
+	_, err = tx.Exec(supabase.Ctx, insertQuery, userId, familyId, newTokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Failed to create rotated refresh token: %v", err)
+		return false, err
+	}
+
+	log.Println("Refresh token rotated successfully")
+	return true, nil
+}
+
+// 指定された系列のリフレッシュトークンをすべて失効させる。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *RefreshTokenRepositoryImpl) RevokeRefreshTokenFamily(familyId string) error {
+	log.Printf("Revoking refresh token family: %s\n", familyId)
+
+	// バリデーション: familyIdが空でないか確認
+	if familyId == "" {
+		log.Printf("FamilyID is required")
+		return errors.New("familyID is required")
+	}
+
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE family_id = $1 AND revoked_at IS NULL
+    `
+
+	// 系列のトークンを失効
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, familyId)
+	if err != nil {
+		log.Printf("Failed to revoke refresh token family: %v", err)
+		return err
+	}
+
+	log.Printf("Revoked %d refresh tokens", result.RowsAffected())
+	return nil
+}
+
+// 指定されたユーザーのリフレッシュトークンをすべて失効させる。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *RefreshTokenRepositoryImpl) RevokeRefreshTokensByUserId(userId string) error {
+	log.Printf("Revoking all refresh tokens for userId: %s\n", userId)
+
+	// バリデーション: userIdが空でないか確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return errors.New("userID is required")
+	}
+
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL
+    `
+
+	// ユーザーのトークンを失効
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, userId)
+	if err != nil {
+		log.Printf("Failed to revoke refresh tokens: %v", err)
+		return err
+	}
+
+	log.Printf("Revoked %d refresh tokens", result.RowsAffected())
+	return nil
+}
@@ -0,0 +1,22 @@
+package repositories_refresh_tokens
+
+import (
+	"backend/models"
+	"time"
+)
+
+// RefreshTokenRepositoryインターフェース
+type RefreshTokenRepository interface {
+	FetchRefreshTokenByHash(tokenHash string) (*models.RefreshTokenData, error)
+	CreateRefreshToken(userId, familyId, tokenHash string, expiresAt time.Time) error
+	RotateRefreshToken(id, userId, familyId, newTokenHash string, expiresAt time.Time) (bool, error)
+	RevokeRefreshTokenFamily(familyId string) error
+	RevokeRefreshTokensByUserId(userId string) error
+}
+
+// RefreshTokenRepositoryImplはRefreshTokenRepositoryインターフェースを実装する
+type RefreshTokenRepositoryImpl struct{}
+
+func NewRefreshTokenRepository() RefreshTokenRepository {
+	return &RefreshTokenRepositoryImpl{}
+}
@@ -0,0 +1,41 @@
+package repositories_refresh_tokens
+
+import (
+	"backend/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRefreshTokenRepository is a mock implementation of RefreshTokenRepository
+type MockRefreshTokenRepository struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenRepository) FetchRefreshTokenByHash(tokenHash string) (*models.RefreshTokenData, error) {
+	args := m.Called(tokenHash)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.RefreshTokenData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) CreateRefreshToken(userId, familyId, tokenHash string, expiresAt time.Time) error {
+	args := m.Called(userId, familyId, tokenHash, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RotateRefreshToken(id, userId, familyId, newTokenHash string, expiresAt time.Time) (bool, error) {
+	args := m.Called(id, userId, familyId, newTokenHash, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokenFamily(familyId string) error {
+	args := m.Called(familyId)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenRepository) RevokeRefreshTokensByUserId(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
@@ -0,0 +1,97 @@
+package repositories_refresh_tokens
+
+import (
+	"backend/supabase"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupSupabase() {
+	// 環境変数の読み込み
+	err := godotenv.Load("../../.env.test")
+	if err != nil {
+		log.Println("No ../../.env.test file found")
+	}
+
+	// テストの前にSupabaseクライアントの初期化
+	err = supabase.InitSupabase()
+	if err != nil {
+		log.Fatalf("Supabase initialization failed: %v", err)
+	}
+}
+
+func TestRepository_FetchRefreshTokenByHash_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewRefreshTokenRepository()
+
+	// メソッドを実行
+	token, err := repo.FetchRefreshTokenByHash("unknown-hash")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+	assert.Nil(t, token)
+}
+
+func TestRepository_CreateRefreshToken_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewRefreshTokenRepository()
+
+	// メソッドを実行
+	err := repo.CreateRefreshToken("", "", "", time.Now())
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_RotateRefreshToken_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewRefreshTokenRepository()
+
+	// メソッドを実行
+	rotated, err := repo.RotateRefreshToken("", "", "", "", time.Now())
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+	assert.False(t, rotated)
+}
+
+func TestRepository_RevokeRefreshTokenFamily_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewRefreshTokenRepository()
+
+	// メソッドを実行
+	err := repo.RevokeRefreshTokenFamily("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_RevokeRefreshTokensByUserId_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewRefreshTokenRepository()
+
+	// メソッドを実行
+	err := repo.RevokeRefreshTokensByUserId("")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
@@ -5,6 +5,11 @@ import (
 	"backend/supabase"
 	"errors"
 	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
 )
 
 // Supabaseから全予約情報を取得し、予約情報リストを返す。
@@ -13,7 +18,7 @@ func (r *ReservationRepositoryImpl) FetchReservations() ([]models.ReservationDat
 	log.Println("Fetching reservations from Supabase...")
 
 	query := `
-        SELECT id, user_id, reservation_date, num_people, special_request, status, created_at, updated_at
+        SELECT id, user_id, reservation_date, time_zone, num_people, special_request, status, created_at, updated_at
         FROM reservations
         ORDER BY created_at DESC
     `
@@ -36,6 +41,59 @@ func (r *ReservationRepositoryImpl) FetchReservations() ([]models.ReservationDat
 			&reservation.ID,
 			&reservation.UserId,
 			&reservation.ReservationDate,
+			&reservation.TimeZone,
+			&reservation.NumPeople,
+			&reservation.SpecialRequest,
+			&reservation.Status,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan reservation: %v", err)
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch reservations: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d reservations", len(reservations))
+	return reservations, nil
+}
+
+// 指定されたユーザーIDに対応する予約情報をすべて取得し、予約情報リストを返す。
+// 失敗した場合はエラーを返す。
+func (r *ReservationRepositoryImpl) FetchReservationsByUserId(userId string) ([]models.ReservationData, error) {
+	log.Printf("Fetching reservations for userId: %s\n", userId)
+
+	query := `
+        SELECT id, user_id, reservation_date, time_zone, num_people, special_request, status, created_at, updated_at
+        FROM reservations
+        WHERE user_id = $1
+        ORDER BY created_at DESC
+    `
+
+	// Supabaseからクエリを実行し、条件に一致する予約情報を取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query, userId)
+	if err != nil {
+		log.Printf("Failed to fetch reservations: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	reservations := []models.ReservationData{}
+
+	// 結果をスキャンして予約情報をリストに追加
+	for rows.Next() {
+		var reservation models.ReservationData
+		err := rows.Scan(
+			&reservation.ID,
+			&reservation.UserId,
+			&reservation.ReservationDate,
+			&reservation.TimeZone,
 			&reservation.NumPeople,
 			&reservation.SpecialRequest,
 			&reservation.Status,
@@ -64,7 +122,7 @@ func (r *ReservationRepositoryImpl) FetchReservationById(id string) (*models.Res
 	log.Printf("Checking if reservation exists with id: %s\n", id)
 
 	query := `
-        SELECT id, user_id, reservation_date, num_people, special_request, status, created_at, updated_at
+        SELECT id, user_id, reservation_date, time_zone, num_people, special_request, status, created_at, updated_at
         FROM reservations
         WHERE id = $1
     `
@@ -74,7 +132,7 @@ func (r *ReservationRepositoryImpl) FetchReservationById(id string) (*models.Res
 
 	// 取得した結果をスキャン
 	var reservation models.ReservationData
-	err := row.Scan(&reservation.ID, &reservation.UserId, &reservation.ReservationDate, &reservation.NumPeople, &reservation.SpecialRequest, &reservation.Status, &reservation.CreatedAt, &reservation.UpdatedAt)
+	err := row.Scan(&reservation.ID, &reservation.UserId, &reservation.ReservationDate, &reservation.TimeZone, &reservation.NumPeople, &reservation.SpecialRequest, &reservation.Status, &reservation.CreatedAt, &reservation.UpdatedAt)
 	if err != nil {
 		log.Printf("Reservation not found or error fetching reservation: %v", err)
 		return nil, err
@@ -84,39 +142,102 @@ func (r *ReservationRepositoryImpl) FetchReservationById(id string) (*models.Res
 	return &reservation, nil
 }
 
-// 指定されたユーザーIDに対応する予約情報を取得する。
-// 予約情報が見つからない場合、エラーを返す。
-func (r *ReservationRepositoryImpl) FetchReservationByUserId(userId string) (*models.ReservationData, error) {
-	log.Printf("Checking if reservation exists with userId: %s\n", userId)
+// 条件に一致するユーザーの予約情報を、予約日時とIDの順に取得する。
+// カーソルを指定した場合は、その予約より後の予約を返す。
+// 失敗した場合はエラーを返す。
+func (r *ReservationRepositoryImpl) FetchReservationPage(query models.ReservationQuery) ([]models.ReservationData, error) {
+	log.Printf("Fetching reservation page for userId: %s\n", query.UserId)
 
-	query := `
-        SELECT id, user_id, reservation_date, num_people, special_request, status, created_at, updated_at
+	// バリデーション: ユーザーIDと件数を確認
+	if query.UserId == "" || query.Limit <= 0 {
+		log.Printf("UserId and limit are required")
+		return nil, errors.New("userId and limit are required")
+	}
+
+	// 条件に応じてWHERE句を組み立てる
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{query.UserId}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if len(query.Statuses) > 0 {
+		conditions = append(conditions, "status = ANY("+addArg(query.Statuses)+")")
+	}
+	if query.From != nil {
+		conditions = append(conditions, "reservation_date >= "+addArg(*query.From))
+	}
+	if query.To != nil {
+		conditions = append(conditions, "reservation_date < "+addArg(*query.To))
+	}
+
+	order, comparison := "ASC", ">"
+	if query.Descending {
+		order, comparison = "DESC", "<"
+	}
+	if query.After != nil {
+		// 予約日時が同じ予約はIDの順に並べ、ページの境界で重複や漏れが起きないようにする
+		conditions = append(conditions, "(reservation_date, id) "+comparison+" ("+addArg(query.After.ReservationDate)+", "+addArg(query.After.ID)+")")
+	}
+
+	sql := `
+        SELECT id, user_id, reservation_date, time_zone, num_people, special_request, status, created_at, updated_at
         FROM reservations
-        WHERE user_id = $1
-    `
+        WHERE ` + strings.Join(conditions, " AND ") + `
+        ORDER BY reservation_date ` + order + `, id ` + order + `
+        LIMIT ` + addArg(query.Limit)
 
 	// Supabaseからクエリを実行し、条件に一致する予約情報を取得
-	row := supabase.Pool.QueryRow(supabase.Ctx, query, userId)
-
-	// 取得した結果をスキャン
-	var reservation models.ReservationData
-	err := row.Scan(&reservation.ID, &reservation.UserId, &reservation.ReservationDate, &reservation.NumPeople, &reservation.SpecialRequest, &reservation.Status, &reservation.CreatedAt, &reservation.UpdatedAt)
+	rows, err := supabase.Pool.Query(supabase.Ctx, sql, args...)
 	if err != nil {
-		log.Printf("Reservation not found or error fetching reservation: %v", err)
+		log.Printf("Failed to fetch reservations: %v", err)
 		return nil, err
 	}
+	defer rows.Close()
 
-	log.Printf("Reservation found: %v", reservation)
-	return &reservation, nil
+	reservations := []models.ReservationData{}
+
+	// 結果をスキャンして予約情報をリストに追加
+	for rows.Next() {
+		var reservation models.ReservationData
+		err := rows.Scan(
+			&reservation.ID,
+			&reservation.UserId,
+			&reservation.ReservationDate,
+			&reservation.TimeZone,
+			&reservation.NumPeople,
+			&reservation.SpecialRequest,
+			&reservation.Status,
+			&reservation.CreatedAt,
+			&reservation.UpdatedAt,
+		)
+		if err != nil {
+			log.Printf("Failed to scan reservation: %v", err)
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch reservations: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	log.Printf("Fetched %d reservations", len(reservations))
+	return reservations, nil
 }
 
 // 新しい予約情報をデータベースに追加する。
+// 同じ枠への予約と同時に実行されても席数を超えないよう、枠ごとのアドバイザリーロックを取得してから残席を確認する。
+// 残席が足りない場合はエラーを返す。
+// 予約日時はUTCで保存し、timeZoneには表示に使用する店舗のタイムゾーンを指定する。
 // 成功した場合はnilを返し、失敗した場合はエラーを返す。
-func (r *ReservationRepositoryImpl) CreateReservation(userId, reservationDate string, numPeople int, specialRequest, status string) (string, error) {
+func (r *ReservationRepositoryImpl) CreateReservation(userId string, reservationDate time.Time, numPeople int, specialRequest, status, timeZone string, seatsPerSlot int) (string, error) {
 	log.Printf("Creating new reservation for userId: %s\n", userId)
 
 	// バリデーション: 必須フィールドが空でないか確認
-	if reservationDate == "" || numPeople <= 0 || userId == "" || status == "" || specialRequest == "" {
+	if reservationDate.IsZero() || numPeople <= 0 || userId == "" || status == "" || specialRequest == "" || timeZone == "" {
 		log.Printf("UserID, reservation date, and num_people are required")
 		return "", errors.New("userID, reservation date, and num_people are required")
 	}
@@ -144,15 +265,21 @@ func (r *ReservationRepositoryImpl) CreateReservation(userId, reservationDate st
 		}
 	}()
 
+	// 枠の残席を確認
+	err = checkSlotCapacity(tx, reservationDate, numPeople, seatsPerSlot, "")
+	if err != nil {
+		return "", err
+	}
+
 	var reservationId string
 	query := `
-        INSERT INTO reservations (user_id, reservation_date, num_people, special_request, status, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+        INSERT INTO reservations (user_id, reservation_date, time_zone, num_people, special_request, status, created_at, updated_at)
+        VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
         RETURNING id
     `
 
 	// 予約情報を挿入し、IDを取得
-	err = tx.QueryRow(supabase.Ctx, query, userId, reservationDate, numPeople, specialRequest, status).Scan(&reservationId)
+	err = tx.QueryRow(supabase.Ctx, query, userId, reservationDate.UTC(), timeZone, numPeople, specialRequest, status).Scan(&reservationId)
 	if err != nil {
 		log.Printf("Failed to create reservation: %v", err)
 		return "", err
@@ -161,3 +288,168 @@ func (r *ReservationRepositoryImpl) CreateReservation(userId, reservationDate st
 	log.Printf("Reservation created successfully with ID: %s", reservationId)
 	return reservationId, nil
 }
+
+// 指定されたIDの予約の日時、人数、リクエストを更新する。
+// 作成時と同様に、変更後の枠の残席を確認してから更新する。
+// 予約情報が見つからない場合、エラーを返す。
+func (r *ReservationRepositoryImpl) UpdateReservation(id string, reservationDate time.Time, numPeople int, specialRequest string, seatsPerSlot int) error {
+	log.Printf("Updating reservation: %s\n", id)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if id == "" || reservationDate.IsZero() || numPeople <= 0 {
+		log.Printf("ID, reservation date, and num_people are required")
+		return errors.New("id, reservation date, and num_people are required")
+	}
+
+	// トランザクションの開始
+	tx, err := supabase.Pool.Begin(supabase.Ctx)
+	if err != nil {
+		log.Printf("Failed to begin transaction: %v", err)
+		return err
+	}
+
+	// トランザクションが成功または失敗した場合にコミットまたはロールバックを行う
+	defer func() {
+		if err != nil {
+			log.Println("Rolling back transaction...")
+			if rollbackErr := tx.Rollback(supabase.Ctx); rollbackErr != nil {
+				log.Printf("Failed to rollback transaction: %v", rollbackErr)
+			}
+			return
+		}
+
+		log.Println("Committing transaction...")
+		if commitErr := tx.Commit(supabase.Ctx); commitErr != nil {
+			log.Printf("Failed to commit transaction: %v", commitErr)
+		}
+	}()
+
+	// 変更する予約自身を除いて、枠の残席を確認
+	err = checkSlotCapacity(tx, reservationDate, numPeople, seatsPerSlot, id)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        UPDATE reservations
+        SET reservation_date = $2, num_people = $3, special_request = $4, updated_at = NOW()
+        WHERE id = $1
+    `
+
+	// 予約情報を更新
+	result, err := tx.Exec(supabase.Ctx, query, id, reservationDate.UTC(), numPeople, specialRequest)
+	if err != nil {
+		log.Printf("Failed to update reservation: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Reservation not found: %s", id)
+		err = errors.New("reservation not found")
+		return err
+	}
+
+	log.Println("Reservation updated successfully")
+	return nil
+}
+
+// 枠のアドバイザリーロックを取得し、予約を追加できる残席があるかを確認する。
+// ロックはトランザクションの終了時に解放されるため、同じ枠への予約の確認と追加は直列に実行される。
+// excludeIdを指定した場合は、その予約を予約済みの席数に含めない。
+// ロックのキーは枠の開始日時のUNIX時間から求めるため、入力のタイムゾーンによらず同じ枠には同じロックを使用する。
+func checkSlotCapacity(tx pgx.Tx, reservationDate time.Time, numPeople, seatsPerSlot int, excludeId string) error {
+	_, err := tx.Exec(supabase.Ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, "reservation_slot:"+strconv.FormatInt(reservationDate.Unix(), 10))
+	if err != nil {
+		log.Printf("Failed to lock reservation slot: %v", err)
+		return err
+	}
+
+	query := `
+        SELECT COALESCE(SUM(num_people), 0)
+        FROM reservations
+        WHERE reservation_date = $1 AND status = ANY($2) AND id::text <> $3
+    `
+
+	var reservedSeats int
+	err = tx.QueryRow(supabase.Ctx, query, reservationDate.UTC(), models.ReservationSeatHoldingStatuses, excludeId).Scan(&reservedSeats)
+	if err != nil {
+		log.Printf("Failed to count reserved seats: %v", err)
+		return err
+	}
+
+	if reservedSeats+numPeople > seatsPerSlot {
+		log.Printf("Slot is full: %s (%d reserved, %d requested, %d seats)", reservationDate, reservedSeats, numPeople, seatsPerSlot)
+		return errors.New("slot is full")
+	}
+	return nil
+}
+
+// 指定した期間の予約枠ごとに、予約済みの席数を取得する。
+// 予約のない枠は含まない。
+func (r *ReservationRepositoryImpl) FetchSlotUsage(from, to time.Time) ([]models.SlotUsage, error) {
+	log.Printf("Fetching slot usage from %s to %s\n", from, to)
+
+	query := `
+        SELECT reservation_date, SUM(num_people)
+        FROM reservations
+        WHERE reservation_date >= $1 AND reservation_date < $2 AND status = ANY($3)
+        GROUP BY reservation_date
+        ORDER BY reservation_date
+    `
+
+	// Supabaseからクエリを実行し、枠ごとの予約済みの席数を取得
+	rows, err := supabase.Pool.Query(supabase.Ctx, query, from.UTC(), to.UTC(), models.ReservationSeatHoldingStatuses)
+	if err != nil {
+		log.Printf("Failed to fetch slot usage: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	usages := []models.SlotUsage{}
+	for rows.Next() {
+		var usage models.SlotUsage
+		if err := rows.Scan(&usage.Start, &usage.Seats); err != nil {
+			log.Printf("Failed to scan slot usage: %v", err)
+			return nil, err
+		}
+		usages = append(usages, usage)
+	}
+
+	if rows.Err() != nil {
+		log.Printf("Failed to fetch slot usage: %v", rows.Err())
+		return nil, rows.Err()
+	}
+
+	return usages, nil
+}
+
+// 指定されたIDの予約のステータスを更新する。
+// 現在のステータスがfromと一致する場合のみ更新し、他の操作で先に変更されていた場合はエラーを返す。
+func (r *ReservationRepositoryImpl) UpdateReservationStatus(id, from, to string) error {
+	log.Printf("Updating reservation status: %s (%s -> %s)\n", id, from, to)
+
+	// バリデーション: 必須フィールドが空でないか確認
+	if id == "" || from == "" || to == "" {
+		log.Printf("ID and status are required")
+		return errors.New("id and status are required")
+	}
+
+	query := `
+        UPDATE reservations
+        SET status = $3, updated_at = NOW()
+        WHERE id = $1 AND status = $2
+    `
+
+	// ステータスを更新
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, id, from, to)
+	if err != nil {
+		log.Printf("Failed to update reservation status: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("Reservation status has been changed: %s", id)
+		return errors.New("reservation status conflict")
+	}
+
+	log.Println("Reservation status updated successfully")
+	return nil
+}
@@ -1,10 +1,12 @@
 package repositories_reservations
 
 import (
+	"backend/models"
 	"backend/supabase"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -75,7 +77,7 @@ func TestRepository_FetchReservationById_NoData(t *testing.T) {
 	assert.Nil(t, reservation)
 }
 
-func TestRepository_FetchReservationByUserId(t *testing.T) {
+func TestRepository_FetchReservationPage(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -86,14 +88,41 @@ func TestRepository_FetchReservationByUserId(t *testing.T) {
 	testID := os.Getenv("TEST_USER_ID")
 
 	// メソッドを実行
-	reservation, err := repo.FetchReservationByUserId(testID)
+	from := time.Now().Add(-365 * 24 * time.Hour)
+	reservations, err := repo.FetchReservationPage(models.ReservationQuery{
+		UserId:   testID,
+		Statuses: []string{models.ReservationStatusPending, models.ReservationStatusConfirmed},
+		From:     &from,
+		Limit:    2,
+	})
 
 	// エラーチェックとデータ確認
 	assert.NoError(t, err)
-	assert.NotNil(t, reservation)
+	assert.LessOrEqual(t, len(reservations), 2)
+	for i, reservation := range reservations {
+		assert.Equal(t, testID, reservation.UserId)
+		if i > 0 {
+			assert.False(t, reservation.ReservationDate.Before(reservations[i-1].ReservationDate))
+		}
+	}
+	if len(reservations) == 0 {
+		return
+	}
+
+	// カーソルより後の予約のみを取得
+	last := reservations[len(reservations)-1]
+	next, err := repo.FetchReservationPage(models.ReservationQuery{
+		UserId: testID,
+		After:  &models.ReservationCursor{ReservationDate: last.ReservationDate, ID: last.ID},
+		Limit:  2,
+	})
+	assert.NoError(t, err)
+	for _, reservation := range next {
+		assert.NotEqual(t, last.ID, reservation.ID)
+	}
 }
 
-func TestRepository_FetchReservationByUserId_NoData(t *testing.T) {
+func TestRepository_FetchReservationPage_ValidationError(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -101,11 +130,31 @@ func TestRepository_FetchReservationByUserId_NoData(t *testing.T) {
 	repo := NewReservationRepository()
 
 	// メソッドを実行
-	reservation, err := repo.FetchReservationByUserId("99")
+	reservations, err := repo.FetchReservationPage(models.ReservationQuery{UserId: "99"})
 
 	// エラーチェックとデータ確認
-	assert.Error(t, err)
-	assert.Nil(t, reservation)
+	assert.EqualError(t, err, "userId and limit are required")
+	assert.Nil(t, reservations)
+}
+
+func TestRepository_FetchReservationsByUserId(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewReservationRepository()
+
+	// 環境変数
+	testID := os.Getenv("TEST_USER_ID")
+
+	// メソッドを実行
+	reservations, err := repo.FetchReservationsByUserId(testID)
+
+	// エラーチェックとデータ確認
+	assert.NoError(t, err)
+	for _, reservation := range reservations {
+		assert.Equal(t, testID, reservation.UserId)
+	}
 }
 
 func TestRepository_CreateReservation(t *testing.T) {
@@ -116,9 +165,37 @@ func TestRepository_CreateReservation(t *testing.T) {
 	repo := NewReservationRepository()
 
 	// メソッドを実行
-	reservationId, err := repo.CreateReservation("", "", 0, "", "")
+	reservationId, err := repo.CreateReservation("", time.Time{}, 0, "", "", "", 0)
 
 	// エラーチェックとデータ確認
 	assert.Error(t, err)
 	assert.Empty(t, reservationId)
 }
+
+func TestRepository_UpdateReservation(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewReservationRepository()
+
+	// メソッドを実行
+	err := repo.UpdateReservation("", time.Time{}, 0, "", 0)
+
+	// エラーチェック
+	assert.EqualError(t, err, "id, reservation date, and num_people are required")
+}
+
+func TestRepository_UpdateReservationStatus(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewReservationRepository()
+
+	// メソッドを実行
+	err := repo.UpdateReservationStatus("", "pending", "")
+
+	// エラーチェック
+	assert.EqualError(t, err, "id and status are required")
+}
@@ -1,13 +1,20 @@
 package repositories_reservations
 
-import "backend/models"
+import (
+	"backend/models"
+	"time"
+)
 
 // ReservationRepositoryインターフェース
 type ReservationRepository interface {
 	FetchReservations() ([]models.ReservationData, error)
 	FetchReservationById(id string) (*models.ReservationData, error)
-	FetchReservationByUserId(userId string) (*models.ReservationData, error)
-	CreateReservation(userId, reservationDate string, numPeople int, specialRequest, status string) (string, error)
+	FetchReservationsByUserId(userId string) ([]models.ReservationData, error)
+	FetchReservationPage(query models.ReservationQuery) ([]models.ReservationData, error)
+	CreateReservation(userId string, reservationDate time.Time, numPeople int, specialRequest, status, timeZone string, seatsPerSlot int) (string, error)
+	UpdateReservation(id string, reservationDate time.Time, numPeople int, specialRequest string, seatsPerSlot int) error
+	FetchSlotUsage(from, to time.Time) ([]models.SlotUsage, error)
+	UpdateReservationStatus(id, from, to string) error
 }
 
 // ReservationRepositoryImplはReservationRepositoryインターフェースを実装する
@@ -2,6 +2,7 @@ package repositories_reservations
 
 import (
 	"backend/models"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -27,15 +28,41 @@ func (m *MockReservationRepository) FetchReservationById(id string) (*models.Res
 	return nil, args.Error(1)
 }
 
-func (m *MockReservationRepository) FetchReservationByUserId(userId string) (*models.ReservationData, error) {
+func (m *MockReservationRepository) FetchReservationsByUserId(userId string) ([]models.ReservationData, error) {
 	args := m.Called(userId)
 	if args.Get(0) != nil {
-		return args.Get(0).(*models.ReservationData), args.Error(1)
+		return args.Get(0).([]models.ReservationData), args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *MockReservationRepository) CreateReservation(userId, reservationDate string, numPeople int, specialRequest, status string) (string, error) {
-	args := m.Called(userId, reservationDate, numPeople, specialRequest, status)
+func (m *MockReservationRepository) FetchReservationPage(query models.ReservationQuery) ([]models.ReservationData, error) {
+	args := m.Called(query)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.ReservationData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReservationRepository) CreateReservation(userId string, reservationDate time.Time, numPeople int, specialRequest, status, timeZone string, seatsPerSlot int) (string, error) {
+	args := m.Called(userId, reservationDate, numPeople, specialRequest, status, timeZone, seatsPerSlot)
 	return args.String(0), args.Error(1)
 }
+
+func (m *MockReservationRepository) UpdateReservation(id string, reservationDate time.Time, numPeople int, specialRequest string, seatsPerSlot int) error {
+	args := m.Called(id, reservationDate, numPeople, specialRequest, seatsPerSlot)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) UpdateReservationStatus(id, from, to string) error {
+	args := m.Called(id, from, to)
+	return args.Error(0)
+}
+
+func (m *MockReservationRepository) FetchSlotUsage(from, to time.Time) ([]models.SlotUsage, error) {
+	args := m.Called(from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.SlotUsage), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
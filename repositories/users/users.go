@@ -13,7 +13,7 @@ func (r *UserRepositoryImpl) FetchUsers() ([]models.UserData, error) {
 	log.Println("Fetching users from Supabase...")
 
 	query := `
-        SELECT id, name, email, created_at, updated_at
+        SELECT id, name, email, role, created_at, updated_at, email_verified_at
         FROM users
         ORDER BY created_at DESC
     `
@@ -36,8 +36,10 @@ func (r *UserRepositoryImpl) FetchUsers() ([]models.UserData, error) {
 			&user.ID,
 			&user.Name,
 			&user.Email,
+			&user.Role,
 			&user.CreatedAt,
 			&user.UpdatedAt,
+			&user.EmailVerifiedAt,
 		)
 		if err != nil {
 			log.Printf("Failed to scan user: %v", err)
@@ -55,46 +57,14 @@ func (r *UserRepositoryImpl) FetchUsers() ([]models.UserData, error) {
 	return users, nil
 }
 
-// 指定されたメールアドレスとパスワードでユーザーを取得する。
-// ユーザーが見つからない場合、エラーを返す。
-func (r *UserRepositoryImpl) FetchUserByEmailAndPassword(email, password string) (*models.UserData, error) {
-	log.Printf("Fetching user from Supabase by email: %s\n", email)
-
-	query := `
-        SELECT id, name, email, created_at, updated_at
-        FROM users
-        WHERE email = $1 AND password = $2
-        LIMIT 1
-    `
-
-	// Supabaseからクエリを実行し、条件に一致するユーザーを取得
-	row := supabase.Pool.QueryRow(supabase.Ctx, query, email, password)
-
-	// 取得した結果をスキャン
-	var user models.UserData
-	err := row.Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
-	if err != nil {
-		log.Printf("User not found or failed to fetch user: %v", err)
-		return nil, err
-	}
-
-	log.Printf("Fetched user successfully: %v", user)
-	return &user, nil
-}
-
 // 指定されたIDに対応するユーザーを取得する。
+// パスワード照合のため、保存されているパスワードハッシュも取得する。
 // ユーザーが見つからない場合、エラーを返す。
 func (r *UserRepositoryImpl) FetchUserById(id string) (*models.UserData, error) {
 	log.Printf("Checking if user exists with id: %s\n", id)
 
 	query := `
-        SELECT id, name, email, created_at, updated_at
+        SELECT id, name, email, password, role, created_at, updated_at, email_verified_at
         FROM users
         WHERE id = $1
         LIMIT 1
@@ -105,23 +75,24 @@ func (r *UserRepositoryImpl) FetchUserById(id string) (*models.UserData, error)
 
 	// ユーザーをスキャン
 	var user models.UserData
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.EmailVerifiedAt)
 	if err != nil {
 		log.Printf("User not found or error fetching user: %v", err)
 		return nil, err
 	}
 
-	log.Printf("User found: %v", user)
+	log.Printf("User found: %s", user.ID)
 	return &user, nil
 }
 
 // 指定されたメールアドレスに対応するユーザーを取得する。
+// パスワード照合のため、保存されているパスワードハッシュも取得する。
 // ユーザーが見つからない場合、エラーを返す。
 func (r *UserRepositoryImpl) FetchUserByEmail(email string) (*models.UserData, error) {
 	log.Printf("Checking if user exists with email: %s\n", email)
 
 	query := `
-        SELECT id, name, email, created_at, updated_at
+        SELECT id, name, email, password, role, created_at, updated_at, email_verified_at
         FROM users
         WHERE email = $1
         LIMIT 1
@@ -132,17 +103,18 @@ func (r *UserRepositoryImpl) FetchUserByEmail(email string) (*models.UserData, e
 
 	// ユーザーをスキャン
 	var user models.UserData
-	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+	err := row.Scan(&user.ID, &user.Name, &user.Email, &user.Password, &user.Role, &user.CreatedAt, &user.UpdatedAt, &user.EmailVerifiedAt)
 	if err != nil {
 		log.Printf("User not found or error fetching user: %v", err)
 		return nil, err
 	}
 
-	log.Printf("User found: %v", user)
+	log.Printf("User found: %s", user.ID)
 	return &user, nil
 }
 
 // 新しいユーザーをデータベースに追加する。
+// passwordにはハッシュ化済みのパスワードを渡す。
 // 成功した場合はnilを返し、失敗した場合はエラーを返す。
 func (r *UserRepositoryImpl) CreateUser(name, email, password string) error {
 	log.Printf("Creating new user with email: %s\n", email)
@@ -191,3 +163,100 @@ func (r *UserRepositoryImpl) CreateUser(name, email, password string) error {
 	log.Println("User created successfully")
 	return nil
 }
+
+// 指定されたユーザーのパスワードハッシュを更新する。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *UserRepositoryImpl) UpdatePassword(id, password string) error {
+	log.Printf("Updating password for user: %s\n", id)
+
+	// バリデーション: IDとパスワードが空でないかを確認
+	if id == "" || password == "" {
+		log.Printf("ID and password are required")
+		return errors.New("id and password are required")
+	}
+
+	query := `
+        UPDATE users
+        SET password = $2, updated_at = NOW()
+        WHERE id = $1
+    `
+
+	// パスワードを更新
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, id, password)
+	if err != nil {
+		log.Printf("Failed to update password: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("User not found: %s", id)
+		return errors.New("user not found")
+	}
+
+	log.Println("Password updated successfully")
+	return nil
+}
+
+// 指定されたユーザーのロールを更新する。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (r *UserRepositoryImpl) UpdateUserRole(id, role string) error {
+	log.Printf("Updating role for user: %s\n", id)
+
+	// バリデーション: IDとロールが空でないかを確認
+	if id == "" || role == "" {
+		log.Printf("ID and role are required")
+		return errors.New("id and role are required")
+	}
+
+	query := `
+        UPDATE users
+        SET role = $2, updated_at = NOW()
+        WHERE id = $1
+    `
+
+	// ロールを更新
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, id, role)
+	if err != nil {
+		log.Printf("Failed to update role: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("User not found: %s", id)
+		return errors.New("user not found")
+	}
+
+	log.Println("Role updated successfully")
+	return nil
+}
+
+// 指定されたユーザーのメールアドレスを確認済みにする。
+// 確認後にメールアドレスが変更されていた場合は更新しない。
+// 既に確認済みの場合は確認日時を変更せずにnilを返す。
+func (r *UserRepositoryImpl) MarkEmailVerified(id, email string) error {
+	log.Printf("Marking email as verified for user: %s\n", id)
+
+	// バリデーション: IDとEmailが空でないかを確認
+	if id == "" || email == "" {
+		log.Printf("ID and email are required")
+		return errors.New("id and email are required")
+	}
+
+	query := `
+        UPDATE users
+        SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+        WHERE id = $1 AND email = $2
+    `
+
+	// 確認日時を更新
+	result, err := supabase.Pool.Exec(supabase.Ctx, query, id, email)
+	if err != nil {
+		log.Printf("Failed to mark email as verified: %v", err)
+		return err
+	}
+	if result.RowsAffected() == 0 {
+		log.Printf("User not found: %s", id)
+		return errors.New("user not found")
+	}
+
+	log.Println("Email marked as verified successfully")
+	return nil
+}
@@ -42,7 +42,7 @@ func TestRepository_FetchUsers(t *testing.T) {
 	assert.GreaterOrEqual(t, len(users), 0)
 }
 
-func TestRepository_FetchUserByEmailAndPassword(t *testing.T) {
+func TestRepository_FetchUserById(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -50,12 +50,12 @@ func TestRepository_FetchUserByEmailAndPassword(t *testing.T) {
 	repo := NewUserRepository()
 
 	// テスト用の環境変数を取得
+	testUserId := os.Getenv("TEST_USER_ID")
 	testName := os.Getenv("TEST_USER_NAME")
 	testEmail := os.Getenv("TEST_USER_EMAIL")
-	testPasswd := os.Getenv("TEST_USER_PASSWD")
 
 	// メソッドを実行
-	user, err := repo.FetchUserByEmailAndPassword(testEmail, testPasswd)
+	user, err := repo.FetchUserById(testUserId)
 	if err != nil {
 		t.Fatalf("Failed to fetch user: %v", err)
 	}
@@ -66,7 +66,7 @@ func TestRepository_FetchUserByEmailAndPassword(t *testing.T) {
 	assert.Equal(t, testEmail, user.Email)
 }
 
-func TestRepository_FetchUserByEmailAndPassword_ErrorCases(t *testing.T) {
+func TestRepository_FetchUserById_ErrorCases(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -74,14 +74,14 @@ func TestRepository_FetchUserByEmailAndPassword_ErrorCases(t *testing.T) {
 	repo := NewUserRepository()
 
 	// メソッドを実行
-	user, err := repo.FetchUserByEmailAndPassword("", "")
+	user, err := repo.FetchUserById("")
 
 	// エラーチェックとデータ確認
 	assert.Error(t, err)
 	assert.Nil(t, user)
 }
 
-func TestRepository_FetchUserById(t *testing.T) {
+func TestRepository_FetchUserByEmail(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -89,12 +89,11 @@ func TestRepository_FetchUserById(t *testing.T) {
 	repo := NewUserRepository()
 
 	// テスト用の環境変数を取得
-	testUserId := os.Getenv("TEST_USER_ID")
 	testName := os.Getenv("TEST_USER_NAME")
 	testEmail := os.Getenv("TEST_USER_EMAIL")
 
 	// メソッドを実行
-	user, err := repo.FetchUserById(testUserId)
+	user, err := repo.FetchUserByEmail(testEmail)
 	if err != nil {
 		t.Fatalf("Failed to fetch user: %v", err)
 	}
@@ -103,9 +102,10 @@ func TestRepository_FetchUserById(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, testName, user.Name)
 	assert.Equal(t, testEmail, user.Email)
+	assert.NotEmpty(t, user.Password)
 }
 
-func TestRepository_FetchUserById_ErrorCases(t *testing.T) {
+func TestRepository_FetchUserByEmail_ErrorCases(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -113,37 +113,42 @@ func TestRepository_FetchUserById_ErrorCases(t *testing.T) {
 	repo := NewUserRepository()
 
 	// メソッドを実行
-	user, err := repo.FetchUserById("")
+	user, err := repo.FetchUserByEmail("")
 
 	// エラーチェックとデータ確認
 	assert.Error(t, err)
 	assert.Nil(t, user)
 }
 
-func TestRepository_FetchUserByEmail(t *testing.T) {
+func TestRepository_CreateUser_ErrorCases(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
 	// リポジトリのインスタンスを作成
 	repo := NewUserRepository()
 
-	// テスト用の環境変数を取得
-	testName := os.Getenv("TEST_USER_NAME")
-	testEmail := os.Getenv("TEST_USER_EMAIL")
+	// メソッドを実行
+	err := repo.CreateUser("", "", "")
+
+	// エラーチェックとデータ確認
+	assert.Error(t, err)
+}
+
+func TestRepository_UpdatePassword_ErrorCases(t *testing.T) {
+	// Supabaseクライアントの初期化
+	setupSupabase()
+
+	// リポジトリのインスタンスを作成
+	repo := NewUserRepository()
 
 	// メソッドを実行
-	user, err := repo.FetchUserByEmail(testEmail)
-	if err != nil {
-		t.Fatalf("Failed to fetch user: %v", err)
-	}
+	err := repo.UpdatePassword("", "")
 
 	// エラーチェックとデータ確認
-	assert.NoError(t, err)
-	assert.Equal(t, testName, user.Name)
-	assert.Equal(t, testEmail, user.Email)
+	assert.Error(t, err)
 }
 
-func TestRepository_FetchUserByEmail_ErrorCases(t *testing.T) {
+func TestRepository_UpdateUserRole_ErrorCases(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -151,14 +156,13 @@ func TestRepository_FetchUserByEmail_ErrorCases(t *testing.T) {
 	repo := NewUserRepository()
 
 	// メソッドを実行
-	user, err := repo.FetchUserByEmail("")
+	err := repo.UpdateUserRole("", "")
 
 	// エラーチェックとデータ確認
 	assert.Error(t, err)
-	assert.Nil(t, user)
 }
 
-func TestRepository_CreateUser_ErrorCases(t *testing.T) {
+func TestRepository_MarkEmailVerified_ErrorCases(t *testing.T) {
 	// Supabaseクライアントの初期化
 	setupSupabase()
 
@@ -166,7 +170,7 @@ func TestRepository_CreateUser_ErrorCases(t *testing.T) {
 	repo := NewUserRepository()
 
 	// メソッドを実行
-	err := repo.CreateUser("", "", "")
+	err := repo.MarkEmailVerified("", "")
 
 	// エラーチェックとデータ確認
 	assert.Error(t, err)
@@ -5,10 +5,12 @@ import "backend/models"
 // UserRepositoryインターフェース
 type UserRepository interface {
 	FetchUsers() ([]models.UserData, error)
-	FetchUserByEmailAndPassword(email, password string) (*models.UserData, error)
 	FetchUserById(id string) (*models.UserData, error)
 	FetchUserByEmail(email string) (*models.UserData, error)
 	CreateUser(name, email, password string) error
+	UpdatePassword(id, password string) error
+	UpdateUserRole(id, role string) error
+	MarkEmailVerified(id, email string) error
 }
 
 // UserRepositoryImplはUserRepositoryインターフェースを実装する
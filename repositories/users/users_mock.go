@@ -27,14 +27,6 @@ func (m *MockUserRepository) FetchUserById(id string) (*models.UserData, error)
 	return nil, args.Error(1)
 }
 
-func (m *MockUserRepository) FetchUserByEmailAndPassword(email, password string) (*models.UserData, error) {
-	args := m.Called(email, password)
-	if args.Get(0) != nil {
-		return args.Get(0).(*models.UserData), args.Error(1)
-	}
-	return nil, args.Error(1)
-}
-
 func (m *MockUserRepository) FetchUserByEmail(email string) (*models.UserData, error) {
 	args := m.Called(email)
 	if args.Get(0) != nil {
@@ -47,3 +39,18 @@ func (m *MockUserRepository) CreateUser(name, email, password string) error {
 	args := m.Called(name, email, password)
 	return args.Error(0)
 }
+
+func (m *MockUserRepository) UpdatePassword(id, password string) error {
+	args := m.Called(id, password)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUserRole(id, role string) error {
+	args := m.Called(id, role)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(id, email string) error {
+	args := m.Called(id, email)
+	return args.Error(0)
+}
@@ -0,0 +1,156 @@
+package services_api_keys
+
+import (
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+const (
+	// APIキーの接頭辞
+	// ログやリポジトリに誤って含まれた場合に検出しやすくする。
+	apiKeyPrefix = "bnk_"
+)
+
+// APIキーを発行する。
+// スコープは所有するユーザーのロールが持つ権限の範囲内でのみ指定できる。
+// キー本体は戻り値でのみ返し、データベースにはハッシュ値のみを保存する。
+func (s *APIKeyServiceImpl) CreateAPIKey(userId, name string, scopes []string, expiresAt *time.Time) (*models.IssuedAPIKey, error) {
+	// バリデーション：ユーザーIDと名前が空でないことを確認
+	name = strings.TrimSpace(name)
+	if userId == "" || name == "" {
+		log.Printf("UserID and name are required")
+		return nil, errors.New("userId and name are required")
+	}
+	// バリデーション：スコープが定義済みの権限であることを確認
+	scopes = uniqueScopes(scopes)
+	if len(scopes) == 0 {
+		log.Printf("Scopes are required")
+		return nil, errors.New("scopes are required")
+	}
+	for _, scope := range scopes {
+		if !models.IsValidPermission(scope) {
+			log.Printf("Invalid scope: %s", scope)
+			return nil, errors.New("invalid scope")
+		}
+	}
+	// バリデーション：有効期限が未来の日時であることを確認
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		log.Printf("Invalid expiration: %v", expiresAt)
+		return nil, errors.New("invalid expiration")
+	}
+
+	user, err := s.UserRepository.FetchUserById(userId)
+	if err != nil || user == nil {
+		log.Printf("User not found: %s", userId)
+		return nil, errors.New("user not found")
+	}
+	for _, scope := range scopes {
+		if !models.HasPermission(user.Role, scope) {
+			log.Printf("Scope %s is not allowed for role %s", scope, user.Role)
+			return nil, errors.New("scope not allowed for user role")
+		}
+	}
+
+	prefix, err := utils.GenerateRandomString(6)
+	if err != nil {
+		log.Printf("Failed to generate API key prefix: %v", err)
+		return nil, errors.New("failed to create api key")
+	}
+	secret, err := utils.GenerateRandomString(32)
+	if err != nil {
+		log.Printf("Failed to generate API key: %v", err)
+		return nil, errors.New("failed to create api key")
+	}
+	key := apiKeyPrefix + prefix + "." + secret
+
+	apiKey, err := s.APIKeyRepository.CreateAPIKey(userId, name, apiKeyPrefix+prefix, utils.HashToken(key), scopes, expiresAt)
+	if err != nil {
+		log.Printf("Error creating API key: %v", err)
+		return nil, errors.New("failed to create api key")
+	}
+
+	log.Printf("API key created for user: %s", userId)
+	return &models.IssuedAPIKey{Key: key, APIKey: *apiKey}, nil
+}
+
+// APIキーの一覧を取得する。
+// userIdが空の場合は全ユーザーのキーを返す。
+func (s *APIKeyServiceImpl) FetchAPIKeys(userId string) ([]models.APIKeyData, error) {
+	return s.APIKeyRepository.FetchAPIKeys(userId)
+}
+
+// APIキーを失効させる。
+func (s *APIKeyServiceImpl) RevokeAPIKey(id string) error {
+	// バリデーション：IDが空でないことを確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	err := s.APIKeyRepository.RevokeAPIKey(id)
+	if err != nil {
+		if err.Error() == "api key not found" {
+			return err
+		}
+		log.Printf("Error revoking API key: %v", err)
+		return errors.New("failed to revoke api key")
+	}
+
+	log.Printf("API key revoked: %s", id)
+	return nil
+}
+
+// APIキーを検証し、キーと所有するユーザーを返す。
+// 失効済み、有効期限切れ、所有するユーザーが存在しない場合は同じエラーを返す。
+func (s *APIKeyServiceImpl) Authenticate(key string) (*models.APIKeyData, *models.UserData, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	apiKey, err := s.APIKeyRepository.FetchAPIKeyByHash(utils.HashToken(key))
+	if err != nil {
+		if err.Error() == "api key not found" {
+			log.Printf("API key not found")
+			return nil, nil, errors.New("invalid api key")
+		}
+		log.Printf("Error fetching API key: %v", err)
+		return nil, nil, errors.New("failed to authenticate api key")
+	}
+	if !apiKey.IsActive(time.Now()) {
+		log.Printf("API key is revoked or expired: %s", apiKey.ID)
+		return nil, nil, errors.New("invalid api key")
+	}
+
+	user, err := s.UserRepository.FetchUserById(apiKey.UserId)
+	if err != nil || user == nil {
+		log.Printf("Owner of API key not found: %s", apiKey.ID)
+		return nil, nil, errors.New("invalid api key")
+	}
+	user.Password = ""
+
+	// 最終使用日時の更新に失敗しても認証は継続する
+	if err := s.APIKeyRepository.UpdateLastUsed(apiKey.ID); err != nil {
+		log.Printf("Failed to update API key last used: %v", err)
+	}
+
+	return apiKey, user, nil
+}
+
+// 空のスコープと重複を取り除く
+func uniqueScopes(scopes []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, scope := range scopes {
+		scope = strings.TrimSpace(scope)
+		if scope == "" || seen[scope] {
+			continue
+		}
+		seen[scope] = true
+		result = append(result, scope)
+	}
+	return result
+}
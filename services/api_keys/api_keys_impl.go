@@ -0,0 +1,32 @@
+package services_api_keys
+
+import (
+	"backend/models"
+	repositories_api_keys "backend/repositories/api_keys"
+	repositories_users "backend/repositories/users"
+	"time"
+)
+
+// APIKeyServiceインターフェース
+type APIKeyService interface {
+	CreateAPIKey(userId, name string, scopes []string, expiresAt *time.Time) (*models.IssuedAPIKey, error)
+	FetchAPIKeys(userId string) ([]models.APIKeyData, error)
+	RevokeAPIKey(id string) error
+	Authenticate(key string) (*models.APIKeyData, *models.UserData, error)
+}
+
+// APIKeyServiceImplはAPIKeyServiceインターフェースを実装する
+type APIKeyServiceImpl struct {
+	UserRepository   repositories_users.UserRepository
+	APIKeyRepository repositories_api_keys.APIKeyRepository
+}
+
+func NewAPIKeyService(
+	userRepository repositories_users.UserRepository,
+	apiKeyRepository repositories_api_keys.APIKeyRepository,
+) APIKeyService {
+	return &APIKeyServiceImpl{
+		UserRepository:   userRepository,
+		APIKeyRepository: apiKeyRepository,
+	}
+}
@@ -0,0 +1,47 @@
+package services_api_keys
+
+import (
+	"backend/models"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockAPIKeyService is the mock implementation for APIKeyService
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) CreateAPIKey(userId, name string, scopes []string, expiresAt *time.Time) (*models.IssuedAPIKey, error) {
+	args := m.Called(userId, name, scopes, expiresAt)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.IssuedAPIKey), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyService) FetchAPIKeys(userId string) ([]models.APIKeyData, error) {
+	args := m.Called(userId)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.APIKeyData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyService) RevokeAPIKey(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) Authenticate(key string) (*models.APIKeyData, *models.UserData, error) {
+	args := m.Called(key)
+	var apiKey *models.APIKeyData
+	if args.Get(0) != nil {
+		apiKey = args.Get(0).(*models.APIKeyData)
+	}
+	var user *models.UserData
+	if args.Get(1) != nil {
+		user = args.Get(1).(*models.UserData)
+	}
+	return apiKey, user, args.Error(2)
+}
@@ -0,0 +1,158 @@
+package services_api_keys
+
+import (
+	"backend/models"
+	repositories_api_keys "backend/repositories/api_keys"
+	repositories_users "backend/repositories/users"
+	"backend/utils"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_CreateAPIKey(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	apiKeyRepository := new(repositories_api_keys.MockAPIKeyRepository)
+	service := NewAPIKeyService(userRepository, apiKeyRepository)
+	scopes := []string{models.PermissionReservationsWrite, models.PermissionNotificationsRead}
+
+	// モックの挙動を設定
+	userRepository.On("FetchUserById", "kiosk1").Return(&models.UserData{ID: "kiosk1", Role: models.RoleStaff}, nil)
+
+	var savedHash string
+	apiKeyRepository.On("CreateAPIKey", "kiosk1", "Store tablet", mock.AnythingOfType("string"), mock.AnythingOfType("string"), scopes, (*time.Time)(nil)).
+		Run(func(args mock.Arguments) { savedHash = args.String(3) }).
+		Return(&models.APIKeyData{ID: "key1", UserId: "kiosk1", Name: "Store tablet", Scopes: scopes}, nil)
+
+	// サービス層メソッドの実行
+	issued, err := service.CreateAPIKey("kiosk1", "Store tablet", scopes, nil)
+	assert.NoError(t, err)
+
+	// キー本体はレスポンスでのみ返し、データベースにはハッシュ値のみが保存される
+	assert.True(t, strings.HasPrefix(issued.Key, apiKeyPrefix))
+	assert.Equal(t, utils.HashToken(issued.Key), savedHash)
+	assert.NotContains(t, savedHash, issued.Key)
+	assert.Equal(t, "key1", issued.APIKey.ID)
+
+	userRepository.AssertExpectations(t)
+	apiKeyRepository.AssertExpectations(t)
+}
+
+func TestService_CreateAPIKey_ErrorCases(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name        string
+		userId      string
+		scopes      []string
+		expiresAt   *time.Time
+		role        string
+		expectedErr string
+	}{
+		{"missing user", "", []string{models.PermissionReservationsRead}, nil, models.RoleStaff, "userId and name are required"},
+		{"missing scopes", "kiosk1", nil, nil, models.RoleStaff, "scopes are required"},
+		{"unknown scope", "kiosk1", []string{"reservations:delete"}, nil, models.RoleStaff, "invalid scope"},
+		{"expired", "kiosk1", []string{models.PermissionReservationsRead}, &past, models.RoleStaff, "invalid expiration"},
+		{"scope beyond role", "kiosk1", []string{models.PermissionUsersManage}, nil, models.RoleStaff, "scope not allowed for user role"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			apiKeyRepository := new(repositories_api_keys.MockAPIKeyRepository)
+			service := NewAPIKeyService(userRepository, apiKeyRepository)
+
+			// モックの挙動を設定
+			userRepository.On("FetchUserById", tc.userId).Return(&models.UserData{ID: tc.userId, Role: tc.role}, nil)
+
+			// サービス層メソッドの実行
+			_, err := service.CreateAPIKey(tc.userId, "Store tablet", tc.scopes, tc.expiresAt)
+			assert.EqualError(t, err, tc.expectedErr)
+
+			apiKeyRepository.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_Authenticate(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	apiKeyRepository := new(repositories_api_keys.MockAPIKeyRepository)
+	service := NewAPIKeyService(userRepository, apiKeyRepository)
+	key := apiKeyPrefix + "abcdefgh.secret"
+
+	// モックの挙動を設定
+	apiKeyRepository.On("FetchAPIKeyByHash", utils.HashToken(key)).Return(&models.APIKeyData{ID: "key1", UserId: "kiosk1", Scopes: []string{models.PermissionReservationsWrite}}, nil)
+	apiKeyRepository.On("UpdateLastUsed", "key1").Return(nil)
+	userRepository.On("FetchUserById", "kiosk1").Return(&models.UserData{ID: "kiosk1", Role: models.RoleStaff, Password: "hashed"}, nil)
+
+	// サービス層メソッドの実行
+	apiKey, user, err := service.Authenticate(key)
+	assert.NoError(t, err)
+	assert.Equal(t, "key1", apiKey.ID)
+	assert.Equal(t, "kiosk1", user.ID)
+	assert.Empty(t, user.Password)
+
+	userRepository.AssertExpectations(t)
+	apiKeyRepository.AssertExpectations(t)
+}
+
+func TestService_Authenticate_InvalidCases(t *testing.T) {
+	key := apiKeyPrefix + "abcdefgh.secret"
+	past := time.Now().Add(-time.Minute)
+
+	testCases := []struct {
+		name   string
+		key    string
+		apiKey *models.APIKeyData
+		repErr error
+	}{
+		{"wrong prefix", "other-key", nil, nil},
+		{"not found", key, nil, errors.New("api key not found")},
+		{"revoked", key, &models.APIKeyData{ID: "key1", UserId: "kiosk1", RevokedAt: &past}, nil},
+		{"expired", key, &models.APIKeyData{ID: "key1", UserId: "kiosk1", ExpiresAt: &past}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			apiKeyRepository := new(repositories_api_keys.MockAPIKeyRepository)
+			service := NewAPIKeyService(userRepository, apiKeyRepository)
+
+			// モックの挙動を設定
+			apiKeyRepository.On("FetchAPIKeyByHash", utils.HashToken(tc.key)).Return(tc.apiKey, tc.repErr)
+
+			// サービス層メソッドの実行
+			_, _, err := service.Authenticate(tc.key)
+			assert.EqualError(t, err, "invalid api key")
+
+			userRepository.AssertNotCalled(t, "FetchUserById", mock.Anything)
+			apiKeyRepository.AssertNotCalled(t, "UpdateLastUsed", mock.Anything)
+		})
+	}
+}
+
+func TestService_RevokeAPIKey(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	apiKeyRepository := new(repositories_api_keys.MockAPIKeyRepository)
+	service := NewAPIKeyService(userRepository, apiKeyRepository)
+
+	// モックの挙動を設定
+	apiKeyRepository.On("RevokeAPIKey", "key1").Return(nil)
+	apiKeyRepository.On("RevokeAPIKey", "missing").Return(errors.New("api key not found"))
+
+	// サービス層メソッドの実行
+	assert.NoError(t, service.RevokeAPIKey("key1"))
+	assert.EqualError(t, service.RevokeAPIKey("missing"), "api key not found")
+	assert.EqualError(t, service.RevokeAPIKey(""), "id is required")
+
+	apiKeyRepository.AssertExpectations(t)
+}
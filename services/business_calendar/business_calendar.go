@@ -0,0 +1,238 @@
+package services_business_calendar
+
+import (
+	"backend/models"
+	"errors"
+	"log"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 曜日ごとの営業時間をすべて取得する。
+func (s *BusinessCalendarServiceImpl) FetchBusinessHours() ([]models.BusinessHoursData, error) {
+	return s.BusinessCalendarRepository.FetchBusinessHours()
+}
+
+// 曜日ごとの営業時間を、指定した内容にすべて置き換える。
+// 指定しなかった曜日は休業日となる。
+// 空の場合は営業時間が未設定の状態(終日営業)に戻ってしまうため、エラーを返す。
+func (s *BusinessCalendarServiceImpl) UpdateBusinessHours(hours []models.BusinessHoursData) ([]models.BusinessHoursData, error) {
+	if len(hours) == 0 {
+		log.Println("Business hours are empty")
+		return nil, errors.New("business hours are required")
+	}
+
+	// バリデーション：曜日と時刻が正しく、同じ曜日の時間帯が重ならないことを確認
+	byWeekday := map[int][]timeRange{}
+	for _, h := range hours {
+		if h.Weekday < 0 || h.Weekday > 6 {
+			log.Printf("Invalid weekday: %d", h.Weekday)
+			return nil, errors.New("invalid weekday")
+		}
+		r, err := parseTimeRange(h.OpenTime, h.CloseTime)
+		if err != nil {
+			return nil, err
+		}
+		byWeekday[h.Weekday] = append(byWeekday[h.Weekday], r)
+	}
+	for weekday, ranges := range byWeekday {
+		if overlaps(ranges) {
+			log.Printf("Overlapping business hours on weekday %d", weekday)
+			return nil, errors.New("overlapping business hours")
+		}
+	}
+
+	replaced, err := s.BusinessCalendarRepository.ReplaceBusinessHours(hours)
+	if err != nil {
+		log.Printf("Error replacing business hours: %v", err)
+		return nil, errors.New("failed to update business hours")
+	}
+
+	log.Printf("Business hours updated with %d entries", len(replaced))
+	return replaced, nil
+}
+
+// 指定した期間の、通常と異なる営業時間の設定を取得する。
+// fromとtoはその日を含み、空の場合は期間を限定しない。
+func (s *BusinessCalendarServiceImpl) FetchHoursOverrides(from, to string) ([]models.HoursOverrideData, error) {
+	if err := validateDateRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.BusinessCalendarRepository.FetchHoursOverrides(from, to)
+}
+
+// 祝日などで通常と異なる営業時間とする日を追加する。
+// 同じ日の他の設定と時間帯が重なる場合はエラーを返す。
+func (s *BusinessCalendarServiceImpl) CreateHoursOverride(date, openTime, closeTime, reason string) (*models.HoursOverrideData, error) {
+	// バリデーション：日付と時刻が正しいことを確認
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		log.Printf("Invalid date format: %v", err)
+		return nil, errors.New("invalid date format. Use 'YYYY-MM-DD'")
+	}
+	r, err := parseTimeRange(openTime, closeTime)
+	if err != nil {
+		return nil, err
+	}
+
+	// 同じ日の設定と時間帯が重ならないことを確認
+	existing, err := s.BusinessCalendarRepository.FetchHoursOverrides(date, date)
+	if err != nil {
+		log.Printf("Error fetching hours overrides: %v", err)
+		return nil, errors.New("failed to create hours override")
+	}
+	ranges := []timeRange{r}
+	for _, o := range existing {
+		if existingRange, err := parseTimeRange(o.OpenTime, o.CloseTime); err == nil {
+			ranges = append(ranges, existingRange)
+		}
+	}
+	if overlaps(ranges) {
+		log.Printf("Overlapping hours override on %s", date)
+		return nil, errors.New("overlapping business hours")
+	}
+
+	override, err := s.BusinessCalendarRepository.CreateHoursOverride(date, openTime, closeTime, strings.TrimSpace(reason))
+	if err != nil {
+		log.Printf("Error creating hours override: %v", err)
+		return nil, errors.New("failed to create hours override")
+	}
+
+	log.Printf("Hours override created for %s", date)
+	return override, nil
+}
+
+// 通常と異なる営業時間の設定を削除する。
+func (s *BusinessCalendarServiceImpl) DeleteHoursOverride(id string) error {
+	// バリデーション：IDが空でないことを確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	err := s.BusinessCalendarRepository.DeleteHoursOverride(id)
+	if err != nil {
+		if err.Error() == "hours override not found" {
+			return err
+		}
+		log.Printf("Error deleting hours override: %v", err)
+		return errors.New("failed to delete hours override")
+	}
+
+	log.Printf("Hours override deleted: %s", id)
+	return nil
+}
+
+// 指定した期間と重なる休業期間を取得する。
+// fromとtoはその日を含み、空の場合は期間を限定しない。
+func (s *BusinessCalendarServiceImpl) FetchClosures(from, to string) ([]models.ClosureData, error) {
+	if err := validateDateRange(from, to); err != nil {
+		return nil, err
+	}
+	return s.BusinessCalendarRepository.FetchClosures(from, to)
+}
+
+// 臨時休業や予約を受け付けない期間を追加する。
+// 1日のみの場合は開始日と終了日に同じ日付を指定する。
+func (s *BusinessCalendarServiceImpl) CreateClosure(startDate, endDate, reason string) (*models.ClosureData, error) {
+	// バリデーション：開始日と終了日が正しいことを確認
+	if startDate == "" || endDate == "" {
+		log.Printf("Start date and end date are required")
+		return nil, errors.New("start date and end date are required")
+	}
+	if err := validateDateRange(startDate, endDate); err != nil {
+		return nil, err
+	}
+
+	closure, err := s.BusinessCalendarRepository.CreateClosure(startDate, endDate, strings.TrimSpace(reason))
+	if err != nil {
+		log.Printf("Error creating closure: %v", err)
+		return nil, errors.New("failed to create closure")
+	}
+
+	log.Printf("Closure created from %s to %s", startDate, endDate)
+	return closure, nil
+}
+
+// 休業期間を削除する。
+func (s *BusinessCalendarServiceImpl) DeleteClosure(id string) error {
+	// バリデーション：IDが空でないことを確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+
+	err := s.BusinessCalendarRepository.DeleteClosure(id)
+	if err != nil {
+		if err.Error() == "closure not found" {
+			return err
+		}
+		log.Printf("Error deleting closure: %v", err)
+		return errors.New("failed to delete closure")
+	}
+
+	log.Printf("Closure deleted: %s", id)
+	return nil
+}
+
+// 0時からの経過時間で表した時間帯
+type timeRange struct {
+	open  time.Duration
+	close time.Duration
+}
+
+// 開店時刻と閉店時刻を検証し、時間帯に変換する
+func parseTimeRange(openTime, closeTime string) (timeRange, error) {
+	openAt, ok := models.ParseClockTime(openTime)
+	if !ok {
+		log.Printf("Invalid open time: %s", openTime)
+		return timeRange{}, errors.New("invalid time format. Use 'HH:MM'")
+	}
+	closeAt, ok := models.ParseClockTime(closeTime)
+	if !ok {
+		log.Printf("Invalid close time: %s", closeTime)
+		return timeRange{}, errors.New("invalid time format. Use 'HH:MM'")
+	}
+	if closeAt <= openAt {
+		log.Printf("Open time %s is not before close time %s", openTime, closeTime)
+		return timeRange{}, errors.New("open time must be before close time")
+	}
+	return timeRange{open: openAt, close: closeAt}, nil
+}
+
+// 時間帯のいずれかが重なるかどうかを判定する
+// 閉店時刻と次の時間帯の開店時刻が同じ場合は重ならないとみなす。
+func overlaps(ranges []timeRange) bool {
+	sorted := append([]timeRange{}, ranges...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].open < sorted[j].open })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].open < sorted[i-1].close {
+			return true
+		}
+	}
+	return false
+}
+
+// 日付の範囲を検証する
+// 空の日付は範囲を限定しないものとして扱う。
+func validateDateRange(from, to string) error {
+	var fromDate, toDate time.Time
+	var err error
+	if from != "" {
+		if fromDate, err = time.Parse("2006-01-02", from); err != nil {
+			log.Printf("Invalid from date: %v", err)
+			return errors.New("invalid date format. Use 'YYYY-MM-DD'")
+		}
+	}
+	if to != "" {
+		if toDate, err = time.Parse("2006-01-02", to); err != nil {
+			log.Printf("Invalid to date: %v", err)
+			return errors.New("invalid date format. Use 'YYYY-MM-DD'")
+		}
+	}
+	if from != "" && to != "" && toDate.Before(fromDate) {
+		log.Printf("Invalid date range: %s - %s", from, to)
+		return errors.New("invalid date range")
+	}
+	return nil
+}
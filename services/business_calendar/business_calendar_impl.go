@@ -0,0 +1,31 @@
+package services_business_calendar
+
+import (
+	"backend/models"
+	repositories_business_calendar "backend/repositories/business_calendar"
+)
+
+// BusinessCalendarServiceインターフェース
+type BusinessCalendarService interface {
+	FetchBusinessHours() ([]models.BusinessHoursData, error)
+	UpdateBusinessHours(hours []models.BusinessHoursData) ([]models.BusinessHoursData, error)
+	FetchHoursOverrides(from, to string) ([]models.HoursOverrideData, error)
+	CreateHoursOverride(date, openTime, closeTime, reason string) (*models.HoursOverrideData, error)
+	DeleteHoursOverride(id string) error
+	FetchClosures(from, to string) ([]models.ClosureData, error)
+	CreateClosure(startDate, endDate, reason string) (*models.ClosureData, error)
+	DeleteClosure(id string) error
+}
+
+// BusinessCalendarServiceImplはBusinessCalendarServiceインターフェースを実装する
+type BusinessCalendarServiceImpl struct {
+	BusinessCalendarRepository repositories_business_calendar.BusinessCalendarRepository
+}
+
+func NewBusinessCalendarService(
+	businessCalendarRepository repositories_business_calendar.BusinessCalendarRepository,
+) BusinessCalendarService {
+	return &BusinessCalendarServiceImpl{
+		BusinessCalendarRepository: businessCalendarRepository,
+	}
+}
@@ -0,0 +1,70 @@
+package services_business_calendar
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockBusinessCalendarService is the mock implementation for BusinessCalendarService
+type MockBusinessCalendarService struct {
+	mock.Mock
+}
+
+func (m *MockBusinessCalendarService) FetchBusinessHours() ([]models.BusinessHoursData, error) {
+	args := m.Called()
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.BusinessHoursData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarService) UpdateBusinessHours(hours []models.BusinessHoursData) ([]models.BusinessHoursData, error) {
+	args := m.Called(hours)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.BusinessHoursData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarService) FetchHoursOverrides(from, to string) ([]models.HoursOverrideData, error) {
+	args := m.Called(from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.HoursOverrideData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarService) CreateHoursOverride(date, openTime, closeTime, reason string) (*models.HoursOverrideData, error) {
+	args := m.Called(date, openTime, closeTime, reason)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.HoursOverrideData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarService) DeleteHoursOverride(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
+
+func (m *MockBusinessCalendarService) FetchClosures(from, to string) ([]models.ClosureData, error) {
+	args := m.Called(from, to)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.ClosureData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarService) CreateClosure(startDate, endDate, reason string) (*models.ClosureData, error) {
+	args := m.Called(startDate, endDate, reason)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.ClosureData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockBusinessCalendarService) DeleteClosure(id string) error {
+	args := m.Called(id)
+	return args.Error(0)
+}
@@ -0,0 +1,213 @@
+package services_business_calendar
+
+import (
+	"backend/models"
+	repositories_business_calendar "backend/repositories/business_calendar"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_UpdateBusinessHours(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	service := NewBusinessCalendarService(repository)
+	hours := []models.BusinessHoursData{
+		{Weekday: 1, OpenTime: "11:00", CloseTime: "14:00"},
+		{Weekday: 1, OpenTime: "17:00", CloseTime: "22:00"},
+		{Weekday: 6, OpenTime: "11:00", CloseTime: "24:00"},
+	}
+
+	// モックの挙動を設定
+	repository.On("ReplaceBusinessHours", hours).Return(hours, nil)
+
+	// サービス層メソッドの実行
+	updated, err := service.UpdateBusinessHours(hours)
+	assert.NoError(t, err)
+	assert.Len(t, updated, 3)
+
+	repository.AssertExpectations(t)
+}
+
+func TestService_UpdateBusinessHours_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name        string
+		hours       []models.BusinessHoursData
+		expectedErr string
+	}{
+		{"empty", []models.BusinessHoursData{}, "business hours are required"},
+		{"missing", nil, "business hours are required"},
+		{"invalid weekday", []models.BusinessHoursData{{Weekday: 7, OpenTime: "11:00", CloseTime: "14:00"}}, "invalid weekday"},
+		{"invalid time", []models.BusinessHoursData{{Weekday: 1, OpenTime: "11am", CloseTime: "14:00"}}, "invalid time format. Use 'HH:MM'"},
+		{"after midnight", []models.BusinessHoursData{{Weekday: 1, OpenTime: "11:00", CloseTime: "24:30"}}, "invalid time format. Use 'HH:MM'"},
+		{"close before open", []models.BusinessHoursData{{Weekday: 1, OpenTime: "14:00", CloseTime: "11:00"}}, "open time must be before close time"},
+		{"overlapping", []models.BusinessHoursData{
+			{Weekday: 1, OpenTime: "11:00", CloseTime: "15:00"},
+			{Weekday: 1, OpenTime: "14:00", CloseTime: "22:00"},
+		}, "overlapping business hours"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+			service := NewBusinessCalendarService(repository)
+
+			// サービス層メソッドの実行
+			_, err := service.UpdateBusinessHours(tc.hours)
+			assert.EqualError(t, err, tc.expectedErr)
+
+			repository.AssertNotCalled(t, "ReplaceBusinessHours", mock.Anything)
+		})
+	}
+}
+
+func TestService_UpdateBusinessHours_RepositoryError(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	service := NewBusinessCalendarService(repository)
+
+	// モックの挙動を設定
+	repository.On("ReplaceBusinessHours", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	// サービス層メソッドの実行
+	_, err := service.UpdateBusinessHours([]models.BusinessHoursData{{Weekday: 1, OpenTime: "11:00", CloseTime: "22:00"}})
+	assert.EqualError(t, err, "failed to update business hours")
+}
+
+func TestService_CreateHoursOverride(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	service := NewBusinessCalendarService(repository)
+
+	// モックの挙動を設定
+	// 同じ日のランチの後にディナーの時間帯を追加する
+	repository.On("FetchHoursOverrides", "2024-12-24", "2024-12-24").Return([]models.HoursOverrideData{
+		{ID: "override1", Date: "2024-12-24", OpenTime: "11:00", CloseTime: "14:00"},
+	}, nil)
+	repository.On("CreateHoursOverride", "2024-12-24", "14:00", "23:00", "Christmas Eve").Return(&models.HoursOverrideData{ID: "override2", Date: "2024-12-24", OpenTime: "14:00", CloseTime: "23:00", Reason: "Christmas Eve"}, nil)
+
+	// サービス層メソッドの実行
+	override, err := service.CreateHoursOverride("2024-12-24", "14:00", "23:00", " Christmas Eve ")
+	assert.NoError(t, err)
+	assert.Equal(t, "override2", override.ID)
+
+	repository.AssertExpectations(t)
+}
+
+func TestService_CreateHoursOverride_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name        string
+		date        string
+		openTime    string
+		closeTime   string
+		expectedErr string
+	}{
+		{"invalid date", "2024/12/24", "11:00", "14:00", "invalid date format. Use 'YYYY-MM-DD'"},
+		{"invalid time", "2024-12-24", "11:00", "", "invalid time format. Use 'HH:MM'"},
+		{"close before open", "2024-12-24", "14:00", "14:00", "open time must be before close time"},
+		{"overlapping", "2024-12-24", "13:00", "15:00", "overlapping business hours"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+			service := NewBusinessCalendarService(repository)
+
+			// モックの挙動を設定
+			repository.On("FetchHoursOverrides", "2024-12-24", "2024-12-24").Return([]models.HoursOverrideData{
+				{ID: "override1", Date: "2024-12-24", OpenTime: "11:00", CloseTime: "14:00"},
+			}, nil)
+
+			// サービス層メソッドの実行
+			_, err := service.CreateHoursOverride(tc.date, tc.openTime, tc.closeTime, "")
+			assert.EqualError(t, err, tc.expectedErr)
+
+			repository.AssertNotCalled(t, "CreateHoursOverride", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_DeleteHoursOverride(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	service := NewBusinessCalendarService(repository)
+
+	// モックの挙動を設定
+	repository.On("DeleteHoursOverride", "override1").Return(nil)
+	repository.On("DeleteHoursOverride", "missing").Return(errors.New("hours override not found"))
+	repository.On("DeleteHoursOverride", "broken").Return(errors.New("connection refused"))
+
+	// サービス層メソッドの実行
+	assert.NoError(t, service.DeleteHoursOverride("override1"))
+	assert.EqualError(t, service.DeleteHoursOverride(""), "id is required")
+	assert.EqualError(t, service.DeleteHoursOverride("missing"), "hours override not found")
+	assert.EqualError(t, service.DeleteHoursOverride("broken"), "failed to delete hours override")
+}
+
+func TestService_CreateClosure(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	service := NewBusinessCalendarService(repository)
+
+	// モックの挙動を設定
+	repository.On("CreateClosure", "2024-12-31", "2025-01-03", "New Year holidays").Return(&models.ClosureData{ID: "closure1", StartDate: "2024-12-31", EndDate: "2025-01-03", Reason: "New Year holidays", CreatedAt: time.Now()}, nil)
+
+	// サービス層メソッドの実行
+	closure, err := service.CreateClosure("2024-12-31", "2025-01-03", "New Year holidays")
+	assert.NoError(t, err)
+	assert.Equal(t, "closure1", closure.ID)
+
+	repository.AssertExpectations(t)
+}
+
+func TestService_CreateClosure_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name        string
+		startDate   string
+		endDate     string
+		expectedErr string
+	}{
+		{"missing dates", "2024-12-31", "", "start date and end date are required"},
+		{"invalid date", "2024-12-31", "2025/01/03", "invalid date format. Use 'YYYY-MM-DD'"},
+		{"end before start", "2025-01-03", "2024-12-31", "invalid date range"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+			service := NewBusinessCalendarService(repository)
+
+			// サービス層メソッドの実行
+			_, err := service.CreateClosure(tc.startDate, tc.endDate, "")
+			assert.EqualError(t, err, tc.expectedErr)
+
+			repository.AssertNotCalled(t, "CreateClosure", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_FetchClosures(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	repository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	service := NewBusinessCalendarService(repository)
+
+	// モックの挙動を設定
+	repository.On("FetchClosures", "2024-12-01", "").Return([]models.ClosureData{{ID: "closure1"}}, nil)
+
+	// サービス層メソッドの実行
+	closures, err := service.FetchClosures("2024-12-01", "")
+	assert.NoError(t, err)
+	assert.Len(t, closures, 1)
+
+	// 不正な期間
+	_, err = service.FetchClosures("2024-12-31", "2024-12-01")
+	assert.EqualError(t, err, "invalid date range")
+	_, err = service.FetchHoursOverrides("december", "")
+	assert.EqualError(t, err, "invalid date format. Use 'YYYY-MM-DD'")
+}
@@ -0,0 +1,169 @@
+package services_email_verifications
+
+import (
+	"backend/mailer"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// 確認トークンに含める情報
+// メールアドレスを含めることで、変更前のアドレス宛てのリンクでは確認できないようにする。
+type verificationPayload struct {
+	UserId    string `json:"uid"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// 指定されたメールアドレス宛てに確認リンクを送信する。
+// 未確認のユーザーが存在する場合のみ送信し、
+// メールアドレスの登録有無が分からないよう、入力値の検証以外のエラーは返さない。
+func (s *EmailVerificationServiceImpl) SendVerification(email string) error {
+	// バリデーション：emailが空でないことを確認
+	if email == "" {
+		log.Printf("Email is required")
+		return errors.New("email is required")
+	}
+	// バリデーション：emailが有効な形式であることを確認
+	if _, err := mail.ParseAddress(email); err != nil {
+		log.Printf("Invalid email format: %v", err)
+		return errors.New("invalid email format")
+	}
+
+	user, err := s.UserRepository.FetchUserByEmail(email)
+	if err != nil || user == nil {
+		log.Printf("Email verification requested for unknown email")
+		return nil
+	}
+	if user.IsEmailVerified() {
+		log.Printf("Email already verified for user: %s", user.ID)
+		return nil
+	}
+
+	token, err := s.signToken(verificationPayload{
+		UserId:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().Add(s.TTL).Unix(),
+	})
+	if err != nil {
+		log.Printf("Failed to generate verification token: %v", err)
+		return nil
+	}
+
+	err = s.MailSender.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Thanks for signing up.\n\nOpen the link below within %s to verify your email address:\n%s\n\nIf you did not create an account, you can ignore this email.",
+			s.TTL, s.verifyLink(token),
+		),
+	})
+	if err != nil {
+		log.Printf("Error sending verification mail: %v", err)
+		return nil
+	}
+
+	log.Println("Verification mail sent successfully")
+	return nil
+}
+
+// 確認トークンを検証し、メールアドレスを確認済みにする。
+// 成功した場合はユーザーIDを返す。
+func (s *EmailVerificationServiceImpl) ConfirmEmail(token string) (string, error) {
+	// バリデーション：トークンが空でないことを確認
+	if token == "" {
+		log.Printf("Token is required")
+		return "", errors.New("token is required")
+	}
+
+	payload, err := s.verifyToken(token)
+	if err != nil {
+		log.Printf("Invalid verification token: %v", err)
+		return "", errors.New("invalid or expired verification token")
+	}
+
+	err = s.UserRepository.MarkEmailVerified(payload.UserId, payload.Email)
+	if err != nil {
+		if err.Error() == "user not found" {
+			// ユーザーが削除されたか、メールアドレスが変更されている
+			log.Printf("Verification token no longer matches user: %s", payload.UserId)
+			return "", errors.New("invalid or expired verification token")
+		}
+		log.Printf("Error marking email as verified: %v", err)
+		return "", errors.New("failed to verify email")
+	}
+
+	log.Printf("Email verified for user: %s", payload.UserId)
+	return payload.UserId, nil
+}
+
+// ペイロードに署名してトークンを生成する
+// 形式は base64url(ペイロード) + "." + base64url(HMAC-SHA256)
+func (s *EmailVerificationServiceImpl) signToken(payload verificationPayload) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(encoded)), nil
+}
+
+// トークンの署名と有効期限を検証し、ペイロードを返す
+func (s *EmailVerificationServiceImpl) verifyToken(token string) (*verificationPayload, error) {
+	encoded, signature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, errors.New("malformed token")
+	}
+
+	decodedSignature, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(decodedSignature, s.sign(encoded)) {
+		return nil, errors.New("invalid signature")
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload verificationPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if payload.UserId == "" || payload.Email == "" {
+		return nil, errors.New("incomplete payload")
+	}
+	if time.Now().Unix() > payload.ExpiresAt {
+		return nil, errors.New("token expired")
+	}
+
+	return &payload, nil
+}
+
+// HMAC-SHA256で署名する
+func (s *EmailVerificationServiceImpl) sign(value string) []byte {
+	mac := hmac.New(sha256.New, s.Secret)
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// 確認エンドポイントのURLにトークンを付与する
+func (s *EmailVerificationServiceImpl) verifyLink(token string) string {
+	link, err := url.Parse(s.VerifyURL)
+	if err != nil {
+		return s.VerifyURL + "?token=" + url.QueryEscape(token)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,38 @@
+package services_email_verifications
+
+import (
+	"backend/mailer"
+	repositories_users "backend/repositories/users"
+	"time"
+)
+
+// EmailVerificationServiceインターフェース
+type EmailVerificationService interface {
+	SendVerification(email string) error
+	ConfirmEmail(token string) (string, error)
+}
+
+// EmailVerificationServiceImplはEmailVerificationServiceインターフェースを実装する
+type EmailVerificationServiceImpl struct {
+	UserRepository repositories_users.UserRepository
+	MailSender     mailer.Sender
+	Secret         []byte        // 確認トークンの署名鍵
+	TTL            time.Duration // 確認リンクの有効期間
+	VerifyURL      string        // 確認エンドポイントのURL。トークンをクエリパラメータとして付与する。
+}
+
+func NewEmailVerificationService(
+	userRepository repositories_users.UserRepository,
+	mailSender mailer.Sender,
+	secret []byte,
+	ttl time.Duration,
+	verifyURL string,
+) EmailVerificationService {
+	return &EmailVerificationServiceImpl{
+		UserRepository: userRepository,
+		MailSender:     mailSender,
+		Secret:         secret,
+		TTL:            ttl,
+		VerifyURL:      verifyURL,
+	}
+}
@@ -0,0 +1,20 @@
+package services_email_verifications
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// MockEmailVerificationService is a mock implementation of EmailVerificationService
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) SendVerification(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationService) ConfirmEmail(token string) (string, error) {
+	args := m.Called(token)
+	return args.String(0), args.Error(1)
+}
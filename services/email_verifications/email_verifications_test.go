@@ -0,0 +1,175 @@
+package services_email_verifications
+
+import (
+	"backend/mailer"
+	"backend/models"
+	repositories_users "backend/repositories/users"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 送信されたメールから確認トークンを取り出す
+func tokenFromMessage(t *testing.T, message mailer.Message) string {
+	for _, line := range strings.Split(message.Body, "\n") {
+		if strings.HasPrefix(line, "https://example.com/api/email/verify?") {
+			link, err := url.Parse(line)
+			if assert.NoError(t, err) {
+				return link.Query().Get("token")
+			}
+		}
+	}
+	t.Fatal("verification link not found in mail body")
+	return ""
+}
+
+func TestService_SendVerification(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewEmailVerificationService(userRepository, mailSender, []byte("test-secret"), time.Hour, "https://example.com/api/email/verify").(*EmailVerificationServiceImpl)
+
+	// モックの挙動を設定
+	userRepository.On("FetchUserByEmail", "test@example.com").Return(&models.UserData{ID: "user1", Email: "test@example.com"}, nil)
+
+	var sent mailer.Message
+	mailSender.On("Send", mock.AnythingOfType("mailer.Message")).
+		Run(func(args mock.Arguments) { sent = args.Get(0).(mailer.Message) }).
+		Return(nil)
+
+	// サービス層メソッドの実行
+	err := service.SendVerification("test@example.com")
+	assert.NoError(t, err)
+
+	// 送信されたトークンで確認できることを確認
+	assert.Equal(t, "test@example.com", sent.To)
+	token := tokenFromMessage(t, sent)
+	userRepository.On("MarkEmailVerified", "user1", "test@example.com").Return(nil)
+
+	userId, err := service.ConfirmEmail(token)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", userId)
+
+	// モックが期待通りに呼び出されたかを確認
+	userRepository.AssertExpectations(t)
+	mailSender.AssertExpectations(t)
+}
+
+func TestService_SendVerification_SkipsWithoutLeaking(t *testing.T) {
+	verifiedAt := time.Now()
+	cases := []struct {
+		name string
+		user *models.UserData
+		err  error
+	}{
+		{"unknown email", nil, errors.New("no rows in result set")},
+		{"already verified", &models.UserData{ID: "user1", Email: "test@example.com", EmailVerifiedAt: &verifiedAt}, nil},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			mailSender := new(mailer.MockSender)
+			service := NewEmailVerificationService(userRepository, mailSender, []byte("test-secret"), time.Hour, "https://example.com/api/email/verify").(*EmailVerificationServiceImpl)
+
+			// モックの挙動を設定
+			userRepository.On("FetchUserByEmail", "test@example.com").Return(tc.user, tc.err)
+
+			// サービス層メソッドの実行
+			err := service.SendVerification("test@example.com")
+
+			// エラーを返さず、メールも送信しないことを確認
+			assert.NoError(t, err)
+			mailSender.AssertNotCalled(t, "Send", mock.Anything)
+			userRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_SendVerification_ValidationError(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewEmailVerificationService(userRepository, mailSender, []byte("test-secret"), time.Hour, "https://example.com/api/email/verify").(*EmailVerificationServiceImpl)
+
+	err := service.SendVerification("")
+	assert.EqualError(t, err, "email is required")
+
+	err = service.SendVerification("invalid-email")
+	assert.EqualError(t, err, "invalid email format")
+}
+
+func TestService_ConfirmEmail_InvalidToken(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewEmailVerificationService(userRepository, mailSender, []byte("test-secret"), time.Hour, "https://example.com/api/email/verify").(*EmailVerificationServiceImpl)
+
+	validToken, err := service.signToken(verificationPayload{UserId: "user1", Email: "test@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+	expiredToken, err := service.signToken(verificationPayload{UserId: "user1", Email: "test@example.com", ExpiresAt: time.Now().Add(-time.Minute).Unix()})
+	assert.NoError(t, err)
+
+	// 別の鍵で署名されたトークン
+	otherService := NewEmailVerificationService(userRepository, mailSender, []byte("other-secret"), time.Hour, "https://example.com/api/email/verify").(*EmailVerificationServiceImpl)
+	forgedToken, err := otherService.signToken(verificationPayload{UserId: "user1", Email: "test@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+	assert.NoError(t, err)
+
+	cases := []struct {
+		name  string
+		token string
+	}{
+		{"malformed", "not-a-token"},
+		{"tampered", validToken + "x"},
+		{"expired", expiredToken},
+		{"wrong secret", forgedToken},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := service.ConfirmEmail(tc.token)
+			assert.EqualError(t, err, "invalid or expired verification token")
+		})
+	}
+
+	// 検証に失敗したトークンではリポジトリが呼び出されないことを確認
+	userRepository.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+
+	_, err = service.ConfirmEmail("")
+	assert.EqualError(t, err, "token is required")
+}
+
+func TestService_ConfirmEmail_RepositoryErrors(t *testing.T) {
+	cases := []struct {
+		name    string
+		repoErr error
+		wantErr string
+	}{
+		{"email changed", errors.New("user not found"), "invalid or expired verification token"},
+		{"database error", errors.New("db error"), "failed to verify email"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			mailSender := new(mailer.MockSender)
+			service := NewEmailVerificationService(userRepository, mailSender, []byte("test-secret"), time.Hour, "https://example.com/api/email/verify").(*EmailVerificationServiceImpl)
+			token, err := service.signToken(verificationPayload{UserId: "user1", Email: "test@example.com", ExpiresAt: time.Now().Add(time.Hour).Unix()})
+			assert.NoError(t, err)
+
+			// モックの挙動を設定
+			userRepository.On("MarkEmailVerified", "user1", "test@example.com").Return(tc.repoErr)
+
+			_, err = service.ConfirmEmail(token)
+			assert.EqualError(t, err, tc.wantErr)
+			userRepository.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package services_identities
+
+import (
+	"backend/models"
+	"backend/utils"
+	"database/sql"
+	"errors"
+	"log"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// 外部のアカウントに対応するユーザーを返す。
+// 紐付けがない場合は、プロバイダーが確認済みのメールアドレスで既存のユーザーに紐付け、
+// 該当するユーザーがいない場合は新しいユーザーを作成して紐付ける。
+func (s *IdentityServiceImpl) ResolveUser(identity *models.ExternalIdentity) (*models.UserData, error) {
+	// バリデーション：プロバイダーとsubjectが空でないことを確認
+	if identity == nil || identity.Provider == "" || identity.Subject == "" {
+		log.Printf("Provider and subject are required")
+		return nil, errors.New("provider and subject are required")
+	}
+
+	// 紐付け済みのアカウント
+	user, err := s.fetchLinkedUser(identity)
+	if err == nil {
+		return user, nil
+	}
+	if err.Error() != "identity not found" {
+		return nil, err
+	}
+
+	// 未確認のメールアドレスでは、他人のアカウントに紐付けられるおそれがあるため拒否する
+	if !identity.EmailVerified || identity.Email == "" {
+		log.Printf("Email not verified by provider: %s", identity.Provider)
+		return nil, errors.New("email not verified")
+	}
+	if _, err := mail.ParseAddress(identity.Email); err != nil {
+		log.Printf("Invalid email format: %v", err)
+		return nil, errors.New("invalid email format")
+	}
+
+	user, err = s.UserRepository.FetchUserByEmail(identity.Email)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Error fetching user: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+
+	if err != nil || user == nil {
+		user, err = s.createUser(identity)
+		if err != nil {
+			return nil, err
+		}
+	} else if !user.IsEmailVerified() {
+		// メールアドレスの所有を確認していないアカウントは、第三者が先に登録した可能性があるため紐付けない
+		log.Printf("Existing user has not verified email: %s", user.ID)
+		return nil, errors.New("account email not verified")
+	}
+
+	err = s.IdentityRepository.CreateIdentity(user.ID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		if err.Error() == "identity already linked" {
+			// 同時に同じアカウントでログインした場合は、先に紐付けられたユーザーを返す
+			return s.fetchLinkedUser(identity)
+		}
+		log.Printf("Error linking identity: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+
+	log.Printf("Identity linked for user: %s", user.ID)
+	return user, nil
+}
+
+// 紐付け済みのユーザーを取得する
+func (s *IdentityServiceImpl) fetchLinkedUser(identity *models.ExternalIdentity) (*models.UserData, error) {
+	linked, err := s.IdentityRepository.FetchIdentity(identity.Provider, identity.Subject)
+	if err != nil {
+		if err.Error() == "identity not found" {
+			return nil, err
+		}
+		log.Printf("Error fetching identity: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+
+	user, err := s.UserRepository.FetchUserById(linked.UserId)
+	if err != nil || user == nil {
+		log.Printf("Error fetching linked user: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+	return user, nil
+}
+
+// 外部のアカウントの情報から新しいユーザーを作成する
+// パスワードは推測できないランダムな値とし、パスワードでのログインにはリセットを必要とする。
+func (s *IdentityServiceImpl) createUser(identity *models.ExternalIdentity) (*models.UserData, error) {
+	name := strings.TrimSpace(identity.Name)
+	if name == "" {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+
+	randomPassword, err := utils.GenerateRandomString(32)
+	if err != nil {
+		log.Printf("Failed to generate password: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+	hashedPassword, err := s.PasswordHasher.Hash(randomPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+
+	err = s.UserRepository.CreateUser(name, identity.Email, hashedPassword)
+	if err != nil {
+		log.Printf("Error creating user: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+
+	user, err := s.UserRepository.FetchUserByEmail(identity.Email)
+	if err != nil || user == nil {
+		log.Printf("Error fetching created user: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+
+	// プロバイダーが確認済みのメールアドレスのため、確認メールは送信しない
+	err = s.UserRepository.MarkEmailVerified(user.ID, user.Email)
+	if err != nil {
+		log.Printf("Error marking email as verified: %v", err)
+		return nil, errors.New("failed to resolve identity")
+	}
+	verifiedAt := time.Now()
+	user.EmailVerifiedAt = &verifiedAt
+
+	log.Printf("User created from identity: %s", user.ID)
+	return user, nil
+}
@@ -0,0 +1,32 @@
+package services_identities
+
+import (
+	"backend/models"
+	"backend/passwords"
+	repositories_identities "backend/repositories/identities"
+	repositories_users "backend/repositories/users"
+)
+
+// IdentityServiceインターフェース
+type IdentityService interface {
+	ResolveUser(identity *models.ExternalIdentity) (*models.UserData, error)
+}
+
+// IdentityServiceImplはIdentityServiceインターフェースを実装する
+type IdentityServiceImpl struct {
+	UserRepository     repositories_users.UserRepository
+	IdentityRepository repositories_identities.IdentityRepository
+	PasswordHasher     passwords.Hasher
+}
+
+func NewIdentityService(
+	userRepository repositories_users.UserRepository,
+	identityRepository repositories_identities.IdentityRepository,
+	passwordHasher passwords.Hasher,
+) IdentityService {
+	return &IdentityServiceImpl{
+		UserRepository:     userRepository,
+		IdentityRepository: identityRepository,
+		PasswordHasher:     passwordHasher,
+	}
+}
@@ -0,0 +1,20 @@
+package services_identities
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockIdentityService is the mock implementation for IdentityService
+type MockIdentityService struct {
+	mock.Mock
+}
+
+func (m *MockIdentityService) ResolveUser(identity *models.ExternalIdentity) (*models.UserData, error) {
+	args := m.Called(identity)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.UserData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,141 @@
+package services_identities
+
+import (
+	"backend/models"
+	"backend/passwords"
+	repositories_identities "backend/repositories/identities"
+	repositories_users "backend/repositories/users"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// テスト用に計算コストを下げたパスワードハッシャー
+var testPasswordHasher = passwords.NewHasher(passwords.Config{
+	Algorithm:  passwords.AlgorithmBcrypt,
+	BcryptCost: bcrypt.MinCost,
+})
+
+// テスト用の外部アカウント
+func newIdentity() *models.ExternalIdentity {
+	return &models.ExternalIdentity{Provider: "google", Subject: "sub-1", Email: "test@example.com", EmailVerified: true, Name: "Test User"}
+}
+
+func TestService_ResolveUser_Linked(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	identityRepository := new(repositories_identities.MockIdentityRepository)
+	service := NewIdentityService(userRepository, identityRepository, testPasswordHasher)
+
+	// モックの挙動を設定
+	identityRepository.On("FetchIdentity", "google", "sub-1").Return(&models.UserIdentityData{UserId: "user1", Provider: "google", Subject: "sub-1"}, nil)
+	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Email: "test@example.com"}, nil)
+
+	// サービス層メソッドの実行
+	user, err := service.ResolveUser(newIdentity())
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", user.ID)
+
+	identityRepository.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	userRepository.AssertExpectations(t)
+	identityRepository.AssertExpectations(t)
+}
+
+func TestService_ResolveUser_LinksExistingUser(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	identityRepository := new(repositories_identities.MockIdentityRepository)
+	service := NewIdentityService(userRepository, identityRepository, testPasswordHasher)
+	verifiedAt := time.Now()
+
+	// モックの挙動を設定
+	identityRepository.On("FetchIdentity", "google", "sub-1").Return(nil, errors.New("identity not found"))
+	userRepository.On("FetchUserByEmail", "test@example.com").Return(&models.UserData{ID: "user1", Email: "test@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+	identityRepository.On("CreateIdentity", "user1", "google", "sub-1", "test@example.com").Return(nil)
+
+	// サービス層メソッドの実行
+	user, err := service.ResolveUser(newIdentity())
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", user.ID)
+
+	userRepository.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+	userRepository.AssertExpectations(t)
+	identityRepository.AssertExpectations(t)
+}
+
+func TestService_ResolveUser_CreatesUser(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	identityRepository := new(repositories_identities.MockIdentityRepository)
+	service := NewIdentityService(userRepository, identityRepository, testPasswordHasher)
+
+	// モックの挙動を設定
+	identityRepository.On("FetchIdentity", "google", "sub-1").Return(nil, errors.New("identity not found"))
+	userRepository.On("FetchUserByEmail", "test@example.com").Return(nil, pgx.ErrNoRows).Once()
+	userRepository.On("CreateUser", "Test User", "test@example.com", mock.AnythingOfType("string")).Return(nil)
+	userRepository.On("FetchUserByEmail", "test@example.com").Return(&models.UserData{ID: "user1", Email: "test@example.com"}, nil).Once()
+	userRepository.On("MarkEmailVerified", "user1", "test@example.com").Return(nil)
+	identityRepository.On("CreateIdentity", "user1", "google", "sub-1", "test@example.com").Return(nil)
+
+	// サービス層メソッドの実行
+	user, err := service.ResolveUser(newIdentity())
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", user.ID)
+	assert.True(t, user.IsEmailVerified())
+
+	userRepository.AssertExpectations(t)
+	identityRepository.AssertExpectations(t)
+}
+
+func TestService_ResolveUser_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name        string
+		identity    *models.ExternalIdentity
+		existing    *models.UserData
+		expectedErr string
+	}{
+		{
+			name:        "missing subject",
+			identity:    &models.ExternalIdentity{Provider: "google", Email: "test@example.com", EmailVerified: true},
+			expectedErr: "provider and subject are required",
+		},
+		{
+			name:        "email not verified by provider",
+			identity:    &models.ExternalIdentity{Provider: "google", Subject: "sub-1", Email: "test@example.com"},
+			expectedErr: "email not verified",
+		},
+		{
+			name:        "existing user has not verified email",
+			identity:    newIdentity(),
+			existing:    &models.UserData{ID: "user1", Email: "test@example.com"},
+			expectedErr: "account email not verified",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			identityRepository := new(repositories_identities.MockIdentityRepository)
+			service := NewIdentityService(userRepository, identityRepository, testPasswordHasher)
+
+			// モックの挙動を設定
+			identityRepository.On("FetchIdentity", tc.identity.Provider, tc.identity.Subject).Return(nil, errors.New("identity not found"))
+			if tc.existing != nil {
+				userRepository.On("FetchUserByEmail", tc.identity.Email).Return(tc.existing, nil)
+			}
+
+			// サービス層メソッドの実行
+			_, err := service.ResolveUser(tc.identity)
+			assert.EqualError(t, err, tc.expectedErr)
+
+			identityRepository.AssertNotCalled(t, "CreateIdentity", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			userRepository.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
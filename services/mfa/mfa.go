@@ -0,0 +1,356 @@
+package services_mfa
+
+import (
+	"backend/models"
+	"backend/utils"
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"image/png"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	totpPeriod          = 30                       // TOTPの時間ステップ(秒)
+	totpSkew            = 1                        // 前後に許容する時間ステップ数
+	recoveryCodeCount   = 10                       // 発行するリカバリーコードの数
+	encryptedPrefix     = "enc:"                   // 暗号化済みのシークレットの接頭辞
+	qrCodeSize          = 200                      // QRコードの画像サイズ(px)
+	qrCodeDataURIPrefix = "data:image/png;base64," // QRコードのData URIの接頭辞
+)
+
+// 二要素認証が有効かどうかを返す。
+// 登録途中の場合は無効として扱う。
+func (s *MFAServiceImpl) IsEnabled(userId string) (bool, error) {
+	mfa, err := s.MFARepository.FetchMFAByUserId(userId)
+	if err != nil {
+		if err.Error() == "mfa not found" {
+			return false, nil
+		}
+		log.Printf("Error fetching MFA settings: %v", err)
+		return false, err
+	}
+	return mfa.IsEnabled(), nil
+}
+
+// TOTPのシークレットを発行し、認証アプリに登録するための情報を返す。
+// Confirmで認証アプリが生成したコードを確認するまでは有効にならない。
+func (s *MFAServiceImpl) Enroll(userId, accountName string) (*models.MFAEnrollment, error) {
+	// バリデーション：ユーザーIDが空でないことを確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return nil, errors.New("userId is required")
+	}
+
+	// 既に有効な場合は登録し直せない
+	enabled, err := s.IsEnabled(userId)
+	if err != nil {
+		return nil, errors.New("failed to enroll mfa")
+	}
+	if enabled {
+		log.Printf("MFA already enabled for user: %s", userId)
+		return nil, errors.New("mfa already enabled")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      s.Issuer,
+		AccountName: accountName,
+		Period:      totpPeriod,
+		Digits:      otp.DigitsSix,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		log.Printf("Failed to generate TOTP key: %v", err)
+		return nil, errors.New("failed to enroll mfa")
+	}
+
+	encryptedSecret, err := s.encryptSecret(key.Secret())
+	if err != nil {
+		log.Printf("Failed to encrypt TOTP secret: %v", err)
+		return nil, errors.New("failed to enroll mfa")
+	}
+
+	err = s.MFARepository.SaveMFASecret(userId, encryptedSecret)
+	if err != nil {
+		if err.Error() == "mfa already enabled" {
+			return nil, err
+		}
+		log.Printf("Error saving MFA secret: %v", err)
+		return nil, errors.New("failed to enroll mfa")
+	}
+
+	qrCode, err := qrCodeDataURI(key)
+	if err != nil {
+		log.Printf("Failed to generate QR code: %v", err)
+		return nil, errors.New("failed to enroll mfa")
+	}
+
+	log.Printf("MFA enrollment started for user: %s", userId)
+	return &models.MFAEnrollment{
+		Secret:          key.Secret(),
+		ProvisioningURI: key.URL(),
+		QRCode:          qrCode,
+	}, nil
+}
+
+// 認証アプリが生成したコードを確認して二要素認証を有効にし、リカバリーコードを返す。
+// リカバリーコードはこの時点でのみ返し、以降は取得できない。
+func (s *MFAServiceImpl) Confirm(userId, code string) ([]string, error) {
+	// バリデーション：ユーザーIDとコードが空でないことを確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return nil, errors.New("userId is required")
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		log.Printf("Code is required")
+		return nil, errors.New("code is required")
+	}
+
+	mfa, err := s.MFARepository.FetchMFAByUserId(userId)
+	if err != nil {
+		if err.Error() == "mfa not found" {
+			return nil, errors.New("mfa not enrolled")
+		}
+		log.Printf("Error fetching MFA settings: %v", err)
+		return nil, errors.New("failed to confirm mfa")
+	}
+	if mfa.IsEnabled() {
+		log.Printf("MFA already enabled for user: %s", userId)
+		return nil, errors.New("mfa already enabled")
+	}
+
+	secret, err := s.decryptSecret(mfa.Secret)
+	if err != nil {
+		log.Printf("Failed to decrypt TOTP secret: %v", err)
+		return nil, errors.New("failed to confirm mfa")
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok {
+		log.Printf("Invalid TOTP code for user: %s", userId)
+		return nil, errors.New("invalid code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		log.Printf("Failed to generate recovery codes: %v", err)
+		return nil, errors.New("failed to confirm mfa")
+	}
+
+	err = s.MFARepository.ConfirmMFA(userId, step, hashes)
+	if err != nil {
+		log.Printf("Error confirming MFA: %v", err)
+		return nil, errors.New("failed to confirm mfa")
+	}
+
+	log.Printf("MFA enabled for user: %s", userId)
+	return codes, nil
+}
+
+// TOTPのコードまたはリカバリーコードを検証する。
+// TOTPのコードは同じ時間ステップのものを再利用できず、リカバリーコードは一度しか使用できない。
+func (s *MFAServiceImpl) Verify(userId, code string) error {
+	// バリデーション：ユーザーIDとコードが空でないことを確認
+	if userId == "" {
+		log.Printf("UserID is required")
+		return errors.New("userId is required")
+	}
+	code = strings.TrimSpace(code)
+	if code == "" {
+		log.Printf("Code is required")
+		return errors.New("code is required")
+	}
+
+	mfa, err := s.MFARepository.FetchMFAByUserId(userId)
+	if err != nil {
+		if err.Error() == "mfa not found" {
+			return errors.New("mfa not enabled")
+		}
+		log.Printf("Error fetching MFA settings: %v", err)
+		return errors.New("failed to verify mfa")
+	}
+	if !mfa.IsEnabled() {
+		log.Printf("MFA not enabled for user: %s", userId)
+		return errors.New("mfa not enabled")
+	}
+
+	// 6桁の数字はTOTPのコード、それ以外はリカバリーコードとして扱う
+	if isTOTPCode(code) {
+		return s.verifyTOTP(mfa, code)
+	}
+	return s.verifyRecoveryCode(userId, code)
+}
+
+// コードを確認した上で二要素認証を無効にする。
+func (s *MFAServiceImpl) Disable(userId, code string) error {
+	if err := s.Verify(userId, code); err != nil {
+		return err
+	}
+
+	err := s.MFARepository.DeleteMFA(userId)
+	if err != nil {
+		log.Printf("Error deleting MFA settings: %v", err)
+		return errors.New("failed to disable mfa")
+	}
+
+	log.Printf("MFA disabled for user: %s", userId)
+	return nil
+}
+
+// TOTPのコードを検証し、使用した時間ステップを記録する
+func (s *MFAServiceImpl) verifyTOTP(mfa *models.UserMFAData, code string) error {
+	secret, err := s.decryptSecret(mfa.Secret)
+	if err != nil {
+		log.Printf("Failed to decrypt TOTP secret: %v", err)
+		return errors.New("failed to verify mfa")
+	}
+
+	step, ok := validateTOTP(secret, code, time.Now())
+	if !ok || step <= mfa.LastUsedStep {
+		log.Printf("Invalid or reused TOTP code for user: %s", mfa.UserId)
+		return errors.New("invalid code")
+	}
+
+	err = s.MFARepository.UpdateLastUsedStep(mfa.UserId, step)
+	if err != nil {
+		if err.Error() == "code already used" {
+			// 同時に同じコードが提示された場合
+			return errors.New("invalid code")
+		}
+		log.Printf("Error updating last used TOTP step: %v", err)
+		return errors.New("failed to verify mfa")
+	}
+
+	return nil
+}
+
+// リカバリーコードを検証し、使用済みにする
+func (s *MFAServiceImpl) verifyRecoveryCode(userId, code string) error {
+	err := s.MFARepository.ConsumeRecoveryCode(userId, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		if err.Error() == "recovery code not found" {
+			log.Printf("Invalid recovery code for user: %s", userId)
+			return errors.New("invalid code")
+		}
+		log.Printf("Error consuming recovery code: %v", err)
+		return errors.New("failed to verify mfa")
+	}
+
+	log.Printf("Recovery code used for user: %s", userId)
+	return nil
+}
+
+// シークレットを暗号化する
+// 鍵が設定されていない場合はそのまま返す。
+func (s *MFAServiceImpl) encryptSecret(secret string) (string, error) {
+	if s.EncryptionKey == nil {
+		return secret, nil
+	}
+
+	encrypted, err := utils.Encrypt(s.EncryptionKey, secret)
+	if err != nil {
+		return "", err
+	}
+	return encryptedPrefix + encrypted, nil
+}
+
+// 保存されているシークレットを復号する
+// 暗号化されていない値は、鍵を設定する前に登録されたものとしてそのまま返す。
+func (s *MFAServiceImpl) decryptSecret(stored string) (string, error) {
+	if !strings.HasPrefix(stored, encryptedPrefix) {
+		return stored, nil
+	}
+	if s.EncryptionKey == nil {
+		return "", errors.New("encryption key is not configured")
+	}
+	return utils.Decrypt(s.EncryptionKey, strings.TrimPrefix(stored, encryptedPrefix))
+}
+
+// TOTPのコードを検証し、一致した時間ステップを返す
+// 時刻のずれを考慮して前後の時間ステップも許容する。
+func validateTOTP(secret, code string, now time.Time) (int64, bool) {
+	if !isTOTPCode(code) {
+		return 0, false
+	}
+
+	current := now.Unix() / totpPeriod
+	for offset := int64(-totpSkew); offset <= totpSkew; offset++ {
+		step := current + offset
+		expected, err := totp.GenerateCodeCustom(secret, time.Unix(step*totpPeriod, 0), totp.ValidateOpts{
+			Period:    totpPeriod,
+			Digits:    otp.DigitsSix,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// 6桁の数字かどうかを返す
+func isTOTPCode(code string) bool {
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// リカバリーコードを生成し、コードとハッシュ値を返す
+// コードは読みやすさのため xxxxx-xxxxx の形式とする。
+func generateRecoveryCodes(count int) ([]string, []string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		buf := make([]byte, 7)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(encoding.EncodeToString(buf))[:10]
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, utils.HashToken(raw))
+	}
+
+	return codes, hashes, nil
+}
+
+// 入力されたリカバリーコードを正規化する
+// 区切り文字や大文字小文字の違いを無視する。
+func normalizeRecoveryCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+// provisioning URIのQRコードをPNGのData URIとして返す
+func qrCodeDataURI(key *otp.Key) (string, error) {
+	image, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image); err != nil {
+		return "", err
+	}
+	return qrCodeDataURIPrefix + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
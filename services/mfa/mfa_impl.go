@@ -0,0 +1,34 @@
+package services_mfa
+
+import (
+	"backend/models"
+	repositories_mfa "backend/repositories/mfa"
+)
+
+// MFAServiceインターフェース
+type MFAService interface {
+	IsEnabled(userId string) (bool, error)
+	Enroll(userId, accountName string) (*models.MFAEnrollment, error)
+	Confirm(userId, code string) ([]string, error)
+	Verify(userId, code string) error
+	Disable(userId, code string) error
+}
+
+// MFAServiceImplはMFAServiceインターフェースを実装する
+type MFAServiceImpl struct {
+	MFARepository repositories_mfa.MFARepository
+	Issuer        string // 認証アプリに表示する発行者名
+	EncryptionKey []byte // シークレットの暗号化に使用する鍵。nilの場合は暗号化しない。
+}
+
+func NewMFAService(
+	mfaRepository repositories_mfa.MFARepository,
+	issuer string,
+	encryptionKey []byte,
+) MFAService {
+	return &MFAServiceImpl{
+		MFARepository: mfaRepository,
+		Issuer:        issuer,
+		EncryptionKey: encryptionKey,
+	}
+}
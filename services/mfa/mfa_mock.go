@@ -0,0 +1,43 @@
+package services_mfa
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockMFAService is a mock implementation of MFAService
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) IsEnabled(userId string) (bool, error) {
+	args := m.Called(userId)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) Enroll(userId, accountName string) (*models.MFAEnrollment, error) {
+	args := m.Called(userId, accountName)
+	if args.Get(0) != nil {
+		return args.Get(0).(*models.MFAEnrollment), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) Confirm(userId, code string) ([]string, error) {
+	args := m.Called(userId, code)
+	if args.Get(0) != nil {
+		return args.Get(0).([]string), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) Verify(userId, code string) error {
+	args := m.Called(userId, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) Disable(userId, code string) error {
+	args := m.Called(userId, code)
+	return args.Error(0)
+}
@@ -0,0 +1,300 @@
+package services_mfa
+
+import (
+	"backend/models"
+	repositories_mfa "backend/repositories/mfa"
+	"backend/utils"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// テスト用の暗号化鍵
+var testEncryptionKey = utils.DeriveKey("test-mfa-key")
+
+// 有効な二要素認証の設定を生成
+func enabledMFA(t *testing.T, secret string, lastUsedStep int64) *models.UserMFAData {
+	encrypted, err := utils.Encrypt(testEncryptionKey, secret)
+	assert.NoError(t, err)
+	confirmedAt := time.Now()
+	return &models.UserMFAData{UserId: "user1", Secret: encryptedPrefix + encrypted, ConfirmedAt: &confirmedAt, LastUsedStep: lastUsedStep}
+}
+
+// テスト用のシークレットを生成
+func newSecret(t *testing.T) string {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: "Test App", AccountName: "test@example.com"})
+	assert.NoError(t, err)
+	return key.Secret()
+}
+
+func TestService_Enroll(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(nil, errors.New("mfa not found"))
+
+	var savedSecret string
+	mfaRepository.On("SaveMFASecret", "user1", mock.AnythingOfType("string")).
+		Run(func(args mock.Arguments) { savedSecret = args.String(1) }).
+		Return(nil)
+
+	// サービス層メソッドの実行
+	enrollment, err := service.Enroll("user1", "test@example.com")
+	assert.NoError(t, err)
+
+	// 認証アプリに登録するための情報を確認
+	link, err := url.Parse(enrollment.ProvisioningURI)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "otpauth", link.Scheme)
+		assert.Equal(t, "totp", link.Host)
+		assert.Equal(t, enrollment.Secret, link.Query().Get("secret"))
+		assert.Equal(t, "Test App", link.Query().Get("issuer"))
+		assert.Contains(t, link.Path, "test@example.com")
+	}
+	assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+	// シークレットは暗号化して保存される
+	assert.True(t, strings.HasPrefix(savedSecret, encryptedPrefix))
+	assert.NotContains(t, savedSecret, enrollment.Secret)
+	decrypted, err := utils.Decrypt(testEncryptionKey, strings.TrimPrefix(savedSecret, encryptedPrefix))
+	assert.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, decrypted)
+
+	// モックが期待通りに呼び出されたかを確認
+	mfaRepository.AssertExpectations(t)
+}
+
+func TestService_Enroll_AlreadyEnabled(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(enabledMFA(t, newSecret(t), 0), nil)
+
+	// サービス層メソッドの実行
+	_, err := service.Enroll("user1", "test@example.com")
+	assert.EqualError(t, err, "mfa already enabled")
+
+	// シークレットが上書きされていないことを確認
+	mfaRepository.AssertNotCalled(t, "SaveMFASecret", mock.Anything, mock.Anything)
+}
+
+func TestService_Confirm(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+	secret := newSecret(t)
+	pending := enabledMFA(t, secret, 0)
+	pending.ConfirmedAt = nil
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(pending, nil)
+
+	var savedHashes []string
+	mfaRepository.On("ConfirmMFA", "user1", mock.AnythingOfType("int64"), mock.AnythingOfType("[]string")).
+		Run(func(args mock.Arguments) { savedHashes = args.Get(2).([]string) }).
+		Return(nil)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	// サービス層メソッドの実行
+	codes, err := service.Confirm("user1", code)
+	assert.NoError(t, err)
+
+	// リカバリーコードは重複せず、ハッシュ値のみが保存される
+	assert.Len(t, codes, recoveryCodeCount)
+	assert.Len(t, savedHashes, recoveryCodeCount)
+	unique := map[string]bool{}
+	for i, recoveryCode := range codes {
+		assert.Regexp(t, `^[a-z2-7]{5}-[a-z2-7]{5}$`, recoveryCode)
+		assert.Equal(t, utils.HashToken(normalizeRecoveryCode(recoveryCode)), savedHashes[i])
+		unique[recoveryCode] = true
+	}
+	assert.Len(t, unique, recoveryCodeCount)
+
+	// モックが期待通りに呼び出されたかを確認
+	mfaRepository.AssertExpectations(t)
+}
+
+func TestService_Confirm_ErrorCases(t *testing.T) {
+	secret := newSecret(t)
+	pending := enabledMFA(t, secret, 0)
+	pending.ConfirmedAt = nil
+
+	cases := []struct {
+		name    string
+		code    string
+		mfa     *models.UserMFAData
+		repoErr error
+		wantErr string
+	}{
+		{"missing code", "", nil, nil, "code is required"},
+		{"not enrolled", "123456", nil, errors.New("mfa not found"), "mfa not enrolled"},
+		{"already enabled", "123456", enabledMFA(t, secret, 0), nil, "mfa already enabled"},
+		{"invalid code", "000000", pending, nil, "invalid code"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			mfaRepository := new(repositories_mfa.MockMFARepository)
+			service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+
+			// モックの挙動を設定
+			mfaRepository.On("FetchMFAByUserId", "user1").Return(tc.mfa, tc.repoErr).Maybe()
+
+			// サービス層メソッドの実行
+			_, err := service.Confirm("user1", tc.code)
+			assert.EqualError(t, err, tc.wantErr)
+			mfaRepository.AssertNotCalled(t, "ConfirmMFA", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_Verify_TOTP(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+	secret := newSecret(t)
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(enabledMFA(t, secret, 0), nil)
+	mfaRepository.On("UpdateLastUsedStep", "user1", mock.AnythingOfType("int64")).Return(nil)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	// サービス層メソッドの実行
+	err = service.Verify("user1", code)
+	assert.NoError(t, err)
+
+	// モックが期待通りに呼び出されたかを確認
+	mfaRepository.AssertExpectations(t)
+}
+
+func TestService_Verify_RejectsReusedTOTP(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+	secret := newSecret(t)
+
+	// 現在の時間ステップのコードは既に使用済み
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(enabledMFA(t, secret, time.Now().Unix()/totpPeriod+totpSkew), nil)
+
+	code, err := totp.GenerateCode(secret, time.Now())
+	assert.NoError(t, err)
+
+	// サービス層メソッドの実行
+	err = service.Verify("user1", code)
+	assert.EqualError(t, err, "invalid code")
+	mfaRepository.AssertNotCalled(t, "UpdateLastUsedStep", mock.Anything, mock.Anything)
+}
+
+func TestService_Verify_RecoveryCode(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(enabledMFA(t, newSecret(t), 0), nil)
+	mfaRepository.On("ConsumeRecoveryCode", "user1", utils.HashToken("abcdefghij")).Return(nil).Once()
+	mfaRepository.On("ConsumeRecoveryCode", "user1", utils.HashToken("abcdefghij")).Return(errors.New("recovery code not found"))
+
+	// 区切り文字や大文字小文字に関わらず使用できる
+	err := service.Verify("user1", "ABCDE-fghij")
+	assert.NoError(t, err)
+
+	// 使用済みのリカバリーコードは再利用できない
+	err = service.Verify("user1", "abcde-fghij")
+	assert.EqualError(t, err, "invalid code")
+
+	// モックが期待通りに呼び出されたかを確認
+	mfaRepository.AssertExpectations(t)
+}
+
+func TestService_Verify_NotEnabled(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(nil, errors.New("mfa not found"))
+
+	// サービス層メソッドの実行
+	err := service.Verify("user1", "123456")
+	assert.EqualError(t, err, "mfa not enabled")
+}
+
+func TestService_IsEnabled(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+	pending := enabledMFA(t, newSecret(t), 0)
+	pending.ConfirmedAt = nil
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "enabled").Return(enabledMFA(t, newSecret(t), 0), nil)
+	mfaRepository.On("FetchMFAByUserId", "pending").Return(pending, nil)
+	mfaRepository.On("FetchMFAByUserId", "none").Return(nil, errors.New("mfa not found"))
+	mfaRepository.On("FetchMFAByUserId", "error").Return(nil, errors.New("db error"))
+
+	enabled, err := service.IsEnabled("enabled")
+	assert.NoError(t, err)
+	assert.True(t, enabled)
+
+	// 登録途中の場合は無効として扱う
+	enabled, err = service.IsEnabled("pending")
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	enabled, err = service.IsEnabled("none")
+	assert.NoError(t, err)
+	assert.False(t, enabled)
+
+	_, err = service.IsEnabled("error")
+	assert.Error(t, err)
+}
+
+func TestService_Disable(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mfaRepository := new(repositories_mfa.MockMFARepository)
+	service := NewMFAService(mfaRepository, "Test App", testEncryptionKey)
+
+	// モックの挙動を設定
+	mfaRepository.On("FetchMFAByUserId", "user1").Return(enabledMFA(t, newSecret(t), 0), nil)
+	mfaRepository.On("ConsumeRecoveryCode", "user1", utils.HashToken("abcdefghij")).Return(nil)
+	mfaRepository.On("DeleteMFA", "user1").Return(nil)
+
+	// サービス層メソッドの実行
+	err := service.Disable("user1", "abcde-fghij")
+	assert.NoError(t, err)
+
+	// モックが期待通りに呼び出されたかを確認
+	mfaRepository.AssertExpectations(t)
+}
+
+func TestService_DecryptSecret_Plaintext(t *testing.T) {
+	// 鍵を設定する前に保存されたシークレットはそのまま使用する
+	service := NewMFAService(nil, "Test App", testEncryptionKey).(*MFAServiceImpl)
+	secret, err := service.decryptSecret("PLAINSECRET")
+	assert.NoError(t, err)
+	assert.Equal(t, "PLAINSECRET", secret)
+
+	// 鍵が設定されていない場合は暗号化しない
+	service.EncryptionKey = nil
+	stored, err := service.encryptSecret("PLAINSECRET")
+	assert.NoError(t, err)
+	assert.Equal(t, "PLAINSECRET", stored)
+	_, err = service.decryptSecret(encryptedPrefix + "data")
+	assert.Error(t, err)
+}
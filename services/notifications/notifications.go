@@ -12,6 +12,18 @@ func (s *NotificationServiceImpl) FetchNotifications() ([]models.NotificationDat
 	return s.NotificationRepository.FetchNotifications()
 }
 
+// 指定されたユーザーIDに対応する通知情報をすべて取得する。
+// 失敗した場合はエラーを返す。
+func (s *NotificationServiceImpl) FetchNotificationsByUserId(userId string) ([]models.NotificationData, error) {
+	// バリデーション：userIdが空でないことを確認
+	if userId == "" {
+		log.Printf("userId is required")
+		return nil, errors.New("userId is required")
+	}
+
+	return s.NotificationRepository.FetchNotificationsByUserId(userId)
+}
+
 // 新しい通知をデータベースに追加する。
 // 成功した場合はnilを返し、失敗した場合はエラーを返す。
 func (s *NotificationServiceImpl) CreateNotification(userId, reservationId, message string) error {
@@ -10,6 +10,7 @@ import (
 // NotificationServiceインターフェース
 type NotificationService interface {
 	FetchNotifications() ([]models.NotificationData, error)
+	FetchNotificationsByUserId(userId string) ([]models.NotificationData, error)
 	CreateNotification(userId, reservationId, message string) error
 }
 
@@ -19,6 +19,14 @@ func (m *MockNotificationService) FetchNotifications() ([]models.NotificationDat
 	return nil, args.Error(1)
 }
 
+func (m *MockNotificationService) FetchNotificationsByUserId(userId string) ([]models.NotificationData, error) {
+	args := m.Called(userId)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.NotificationData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
 func (m *MockNotificationService) CreateNotification(userID, reservationID, message string) error {
 	args := m.Called(userID, reservationID, message)
 	return args.Error(0)
@@ -173,3 +173,31 @@ func TestService_CreateNotification_CreateError(t *testing.T) {
 	reservationRepository.AssertCalled(t, "FetchReservationById", "reservation1")
 	notificationRepository.AssertCalled(t, "CreateNotification", "user1", "reservation1", "New reservation confirmed")
 }
+
+func TestService_FetchNotificationsByUserId(t *testing.T) {
+	// モックをインスタンス化
+	notificationRepository := new(repositories_notifications.MockNotificationRepository)
+	notificationService := NewNotificationService(nil, nil, notificationRepository)
+
+	// モックの挙動を設定
+	mockNotifications := []models.NotificationData{
+		{ID: "1", UserId: "user1", Message: "New reservation confirmed"},
+	}
+	notificationRepository.On("FetchNotificationsByUserId", "user1").Return(mockNotifications, nil)
+
+	// サービス層メソッドの実行
+	notifications, err := notificationService.FetchNotificationsByUserId("user1")
+
+	// エラーチェック
+	assert.NoError(t, err)
+	assert.Len(t, notifications, 1)
+	assert.Equal(t, "user1", notifications[0].UserId)
+
+	// ユーザーIDが空の場合
+	_, err = notificationService.FetchNotificationsByUserId("")
+	assert.Error(t, err)
+	assert.Equal(t, "userId is required", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	notificationRepository.AssertExpectations(t)
+}
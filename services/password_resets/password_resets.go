@@ -0,0 +1,107 @@
+package services_password_resets
+
+import (
+	"backend/mailer"
+	"backend/utils"
+	"errors"
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"time"
+)
+
+// パスワードリセットを要求する。
+// ユーザーが存在する場合のみリセットトークンを発行してメールで送信する。
+// メールアドレスの登録有無が分からないよう、入力値の検証以外のエラーは返さない。
+func (s *PasswordResetServiceImpl) RequestPasswordReset(email string) error {
+	// バリデーション：emailが空でないことを確認
+	if email == "" {
+		log.Printf("Email is required")
+		return errors.New("email is required")
+	}
+	// バリデーション：emailが有効な形式であることを確認
+	if _, err := mail.ParseAddress(email); err != nil {
+		log.Printf("Invalid email format: %v", err)
+		return errors.New("invalid email format")
+	}
+
+	user, err := s.UserRepository.FetchUserByEmail(email)
+	if err != nil || user == nil {
+		log.Printf("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := utils.GenerateRandomString(32)
+	if err != nil {
+		log.Printf("Failed to generate password reset token: %v", err)
+		return nil
+	}
+
+	expiresAt := time.Now().Add(s.TTL)
+	err = s.PasswordResetRepository.CreatePasswordResetToken(user.ID, utils.HashToken(token), expiresAt)
+	if err != nil {
+		log.Printf("Error creating password reset token: %v", err)
+		return nil
+	}
+
+	err = s.MailSender.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"We received a request to reset your password.\n\nOpen the link below within %s to choose a new password:\n%s\n\nIf you did not request this, you can ignore this email.",
+			s.TTL, s.resetLink(token),
+		),
+	})
+	if err != nil {
+		log.Printf("Error sending password reset mail: %v", err)
+		return nil
+	}
+
+	log.Println("Password reset mail sent successfully")
+	return nil
+}
+
+// リセットトークンを検証し、パスワードを変更する。
+// トークンは一度しか使用できない。成功した場合はユーザーIDを返す。
+func (s *PasswordResetServiceImpl) ResetPassword(token, newPassword string) (string, error) {
+	// バリデーション：トークンと新しいパスワードが空でないことを確認
+	if token == "" || newPassword == "" {
+		log.Printf("Token and new password are required")
+		return "", errors.New("token and new password are required")
+	}
+
+	resetToken, err := s.PasswordResetRepository.ConsumePasswordResetToken(utils.HashToken(token))
+	if err != nil || resetToken == nil {
+		log.Printf("Invalid password reset token: %v", err)
+		return "", errors.New("invalid or expired reset token")
+	}
+
+	hashedPassword, err := s.PasswordHasher.Hash(newPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return "", errors.New("failed to reset password")
+	}
+
+	err = s.UserRepository.UpdatePassword(resetToken.UserId, hashedPassword)
+	if err != nil {
+		log.Printf("Error updating password: %v", err)
+		return "", errors.New("failed to reset password")
+	}
+
+	log.Println("Password reset successfully")
+	return resetToken.UserId, nil
+}
+
+// リセット画面のURLにトークンを付与したリンクを返す
+func (s *PasswordResetServiceImpl) resetLink(token string) string {
+	link, err := url.Parse(s.ResetURL)
+	if err != nil {
+		return s.ResetURL + "?token=" + url.QueryEscape(token)
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,43 @@
+package services_password_resets
+
+import (
+	"backend/mailer"
+	"backend/passwords"
+	repositories_password_resets "backend/repositories/password_resets"
+	repositories_users "backend/repositories/users"
+	"time"
+)
+
+// PasswordResetServiceインターフェース
+type PasswordResetService interface {
+	RequestPasswordReset(email string) error
+	ResetPassword(token, newPassword string) (string, error)
+}
+
+// PasswordResetServiceImplはPasswordResetServiceインターフェースを実装する
+type PasswordResetServiceImpl struct {
+	UserRepository          repositories_users.UserRepository
+	PasswordResetRepository repositories_password_resets.PasswordResetRepository
+	PasswordHasher          passwords.Hasher
+	MailSender              mailer.Sender
+	TTL                     time.Duration // リセットトークンの有効期間
+	ResetURL                string        // リセット画面のURL。トークンをクエリパラメータとして付与する。
+}
+
+func NewPasswordResetService(
+	userRepository repositories_users.UserRepository,
+	passwordResetRepository repositories_password_resets.PasswordResetRepository,
+	passwordHasher passwords.Hasher,
+	mailSender mailer.Sender,
+	ttl time.Duration,
+	resetURL string,
+) PasswordResetService {
+	return &PasswordResetServiceImpl{
+		UserRepository:          userRepository,
+		PasswordResetRepository: passwordResetRepository,
+		PasswordHasher:          passwordHasher,
+		MailSender:              mailSender,
+		TTL:                     ttl,
+		ResetURL:                resetURL,
+	}
+}
@@ -0,0 +1,20 @@
+package services_password_resets
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPasswordResetService is the mock implementation for PasswordResetService
+type MockPasswordResetService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetService) RequestPasswordReset(email string) error {
+	args := m.Called(email)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetService) ResetPassword(token, newPassword string) (string, error) {
+	args := m.Called(token, newPassword)
+	return args.String(0), args.Error(1)
+}
@@ -0,0 +1,166 @@
+package services_password_resets
+
+import (
+	"backend/mailer"
+	"backend/models"
+	"backend/passwords"
+	repositories_password_resets "backend/repositories/password_resets"
+	repositories_users "backend/repositories/users"
+	"backend/utils"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// テスト用に計算コストを下げたパスワードハッシャー
+var testPasswordHasher = passwords.NewHasher(passwords.Config{
+	Algorithm:  passwords.AlgorithmBcrypt,
+	BcryptCost: bcrypt.MinCost,
+})
+
+func TestService_RequestPasswordReset(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	passwordResetRepository := new(repositories_password_resets.MockPasswordResetRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewPasswordResetService(userRepository, passwordResetRepository, testPasswordHasher, mailSender, 30*time.Minute, "https://example.com/password/reset")
+
+	// モックの挙動を設定
+	userRepository.On("FetchUserByEmail", "test@example.com").Return(&models.UserData{ID: "user1", Email: "test@example.com"}, nil)
+
+	var savedHash string
+	passwordResetRepository.On("CreatePasswordResetToken", "user1", mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+		Run(func(args mock.Arguments) { savedHash = args.String(1) }).
+		Return(nil)
+
+	var sent mailer.Message
+	mailSender.On("Send", mock.AnythingOfType("mailer.Message")).
+		Run(func(args mock.Arguments) { sent = args.Get(0).(mailer.Message) }).
+		Return(nil)
+
+	// サービス層メソッドの実行
+	err := service.RequestPasswordReset("test@example.com")
+	assert.NoError(t, err)
+
+	// メールにはトークン本体を含むリンクが記載され、データベースにはハッシュ値のみが保存される
+	assert.Equal(t, "test@example.com", sent.To)
+	var link string
+	for _, line := range strings.Split(sent.Body, "\n") {
+		if strings.HasPrefix(line, "https://example.com/password/reset?") {
+			link = line
+		}
+	}
+	if assert.NotEmpty(t, link) {
+		parsed, _ := url.Parse(link)
+		token := parsed.Query().Get("token")
+		assert.NotEmpty(t, token)
+		assert.Equal(t, utils.HashToken(token), savedHash)
+		assert.NotContains(t, savedHash, token)
+	}
+
+	// モックが期待通りに呼び出されたかを確認
+	userRepository.AssertExpectations(t)
+	passwordResetRepository.AssertExpectations(t)
+	mailSender.AssertExpectations(t)
+}
+
+func TestService_RequestPasswordReset_UnknownEmail(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	passwordResetRepository := new(repositories_password_resets.MockPasswordResetRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewPasswordResetService(userRepository, passwordResetRepository, testPasswordHasher, mailSender, 30*time.Minute, "https://example.com/password/reset")
+
+	// モックの挙動を設定
+	userRepository.On("FetchUserByEmail", "unknown@example.com").Return(nil, errors.New("no rows in result set"))
+
+	// 登録されていないメールアドレスでもエラーを返さない
+	err := service.RequestPasswordReset("unknown@example.com")
+	assert.NoError(t, err)
+
+	// トークンの発行とメール送信は行わない
+	passwordResetRepository.AssertNotCalled(t, "CreatePasswordResetToken", mock.Anything, mock.Anything, mock.Anything)
+	mailSender.AssertNotCalled(t, "Send", mock.Anything)
+}
+
+func TestService_RequestPasswordReset_InvalidCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	passwordResetRepository := new(repositories_password_resets.MockPasswordResetRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewPasswordResetService(userRepository, passwordResetRepository, testPasswordHasher, mailSender, 30*time.Minute, "https://example.com/password/reset")
+
+	// 1. メールアドレスが空の場合
+	err := service.RequestPasswordReset("")
+	assert.Error(t, err)
+	assert.Equal(t, "email is required", err.Error())
+
+	// 2. メールアドレスの形式が不正な場合
+	err = service.RequestPasswordReset("invalid-email")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid email format", err.Error())
+}
+
+func TestService_ResetPassword(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	passwordResetRepository := new(repositories_password_resets.MockPasswordResetRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewPasswordResetService(userRepository, passwordResetRepository, testPasswordHasher, mailSender, 30*time.Minute, "https://example.com/password/reset")
+
+	// モックの挙動を設定
+	passwordResetRepository.On("ConsumePasswordResetToken", utils.HashToken("reset-token")).Return(&models.PasswordResetTokenData{ID: "1", UserId: "user1"}, nil)
+	userRepository.On("UpdatePassword", "user1", mock.MatchedBy(func(hashed string) bool {
+		match, _, err := testPasswordHasher.Verify(hashed, "new-password")
+		return err == nil && match
+	})).Return(nil)
+
+	// サービス層メソッドの実行
+	userId, err := service.ResetPassword("reset-token", "new-password")
+
+	// エラーチェック
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", userId)
+
+	// モックが期待通りに呼び出されたかを確認
+	passwordResetRepository.AssertExpectations(t)
+	userRepository.AssertExpectations(t)
+}
+
+func TestService_ResetPassword_InvalidCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	passwordResetRepository := new(repositories_password_resets.MockPasswordResetRepository)
+	mailSender := new(mailer.MockSender)
+	service := NewPasswordResetService(userRepository, passwordResetRepository, testPasswordHasher, mailSender, 30*time.Minute, "https://example.com/password/reset")
+
+	// 1. 入力が空の場合
+	_, err := service.ResetPassword("", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "token and new password are required", err.Error())
+
+	// 2. 使用済み・期限切れ・存在しないトークンの場合
+	passwordResetRepository.On("ConsumePasswordResetToken", utils.HashToken("used-token")).Return(nil, errors.New("password reset token not found"))
+
+	_, err = service.ResetPassword("used-token", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid or expired reset token", err.Error())
+
+	// 3. パスワードの更新に失敗した場合
+	passwordResetRepository.On("ConsumePasswordResetToken", utils.HashToken("reset-token")).Return(&models.PasswordResetTokenData{ID: "1", UserId: "user1"}, nil)
+	userRepository.On("UpdatePassword", "user1", mock.Anything).Return(errors.New("update failed"))
+
+	_, err = service.ResetPassword("reset-token", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "failed to reset password", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	passwordResetRepository.AssertExpectations(t)
+	userRepository.AssertExpectations(t)
+}
@@ -0,0 +1,167 @@
+package services_refresh_tokens
+
+import (
+	"backend/models"
+	"backend/utils"
+	"errors"
+	"log"
+	"time"
+)
+
+// 新しい系列のリフレッシュトークンを発行する。
+// 発行したトークン本体を返し、データベースにはハッシュ値のみを保存する。
+func (s *RefreshTokenServiceImpl) IssueRefreshToken(userId string) (*models.IssuedRefreshToken, error) {
+	// バリデーション: userIdが空でないか確認
+	if userId == "" {
+		log.Printf("userId is required")
+		return nil, errors.New("userId is required")
+	}
+
+	token, tokenHash, err := generateRefreshToken()
+	if err != nil {
+		log.Printf("Failed to generate refresh token: %v", err)
+		return nil, errors.New("failed to issue refresh token")
+	}
+
+	familyId, err := utils.GenerateRandomString(16)
+	if err != nil {
+		log.Printf("Failed to generate family ID: %v", err)
+		return nil, errors.New("failed to issue refresh token")
+	}
+
+	expiresAt := time.Now().Add(s.TTL)
+	err = s.RefreshTokenRepository.CreateRefreshToken(userId, familyId, tokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Error creating refresh token: %v", err)
+		return nil, errors.New("failed to issue refresh token")
+	}
+
+	log.Println("Refresh token issued successfully")
+	return &models.IssuedRefreshToken{
+		Token:     token,
+		UserId:    userId,
+		FamilyId:  familyId,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// リフレッシュトークンをローテーションし、同じ系列の新しいトークンを返す。
+// 使用済みのトークンが再度提示された場合は盗用とみなし、系列全体を失効させる。
+func (s *RefreshTokenServiceImpl) RotateRefreshToken(token string) (*models.IssuedRefreshToken, error) {
+	// バリデーション: トークンが空でないか確認
+	if token == "" {
+		log.Printf("Refresh token is required")
+		return nil, errors.New("refresh token is required")
+	}
+
+	current, err := s.RefreshTokenRepository.FetchRefreshTokenByHash(utils.HashToken(token))
+	if err != nil || current == nil {
+		log.Printf("Refresh token not found: %v", err)
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// 失効済みの系列
+	if current.RevokedAt != nil {
+		log.Printf("Refresh token family already revoked: %s", current.FamilyId)
+		return nil, errors.New("invalid refresh token")
+	}
+
+	// 使用済みのトークンの再利用
+	if current.UsedAt != nil {
+		s.revokeFamily(current.FamilyId)
+		return nil, errors.New("refresh token reused")
+	}
+
+	// 有効期限切れ
+	if time.Now().After(current.ExpiresAt) {
+		log.Printf("Refresh token expired: %s", current.ID)
+		return nil, errors.New("refresh token expired")
+	}
+
+	newToken, newTokenHash, err := generateRefreshToken()
+	if err != nil {
+		log.Printf("Failed to generate refresh token: %v", err)
+		return nil, errors.New("failed to rotate refresh token")
+	}
+
+	expiresAt := time.Now().Add(s.TTL)
+	rotated, err := s.RefreshTokenRepository.RotateRefreshToken(current.ID, current.UserId, current.FamilyId, newTokenHash, expiresAt)
+	if err != nil {
+		log.Printf("Error rotating refresh token: %v", err)
+		return nil, errors.New("failed to rotate refresh token")
+	}
+
+	// 取得後に別のリクエストでローテーションされた場合も再利用とみなす
+	if !rotated {
+		s.revokeFamily(current.FamilyId)
+		return nil, errors.New("refresh token reused")
+	}
+
+	log.Println("Refresh token rotated successfully")
+	return &models.IssuedRefreshToken{
+		Token:     newToken,
+		UserId:    current.UserId,
+		FamilyId:  current.FamilyId,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// リフレッシュトークンの系列を失効させる。
+// トークンが存在しない場合は何もしない。
+func (s *RefreshTokenServiceImpl) RevokeRefreshToken(token string) error {
+	if token == "" {
+		return nil
+	}
+
+	current, err := s.RefreshTokenRepository.FetchRefreshTokenByHash(utils.HashToken(token))
+	if err != nil || current == nil {
+		log.Printf("Refresh token not found, nothing to revoke")
+		return nil
+	}
+
+	err = s.RefreshTokenRepository.RevokeRefreshTokenFamily(current.FamilyId)
+	if err != nil {
+		log.Printf("Error revoking refresh token family: %v", err)
+		return errors.New("failed to revoke refresh token")
+	}
+
+	log.Println("Refresh token revoked successfully")
+	return nil
+}
+
+// 指定されたユーザーのリフレッシュトークンをすべて失効させる。
+// 全端末からのログアウトやパスワード変更時に使用する。
+func (s *RefreshTokenServiceImpl) RevokeAllRefreshTokens(userId string) error {
+	// バリデーション: userIdが空でないか確認
+	if userId == "" {
+		log.Printf("userId is required")
+		return errors.New("userId is required")
+	}
+
+	err := s.RefreshTokenRepository.RevokeRefreshTokensByUserId(userId)
+	if err != nil {
+		log.Printf("Error revoking refresh tokens: %v", err)
+		return errors.New("failed to revoke refresh token")
+	}
+
+	log.Println("All refresh tokens revoked successfully")
+	return nil
+}
+
+// 再利用を検知した系列を失効させる
+func (s *RefreshTokenServiceImpl) revokeFamily(familyId string) {
+	log.Printf("Refresh token reuse detected, revoking family: %s", familyId)
+
+	if err := s.RefreshTokenRepository.RevokeRefreshTokenFamily(familyId); err != nil {
+		log.Printf("Failed to revoke refresh token family: %v", err)
+	}
+}
+
+// リフレッシュトークンを生成し、トークン本体とハッシュ値を返す
+func generateRefreshToken() (string, string, error) {
+	token, err := utils.GenerateRandomString(32)
+	if err != nil {
+		return "", "", err
+	}
+	return token, utils.HashToken(token), nil
+}
@@ -0,0 +1,31 @@
+package services_refresh_tokens
+
+import (
+	"backend/models"
+	repositories_refresh_tokens "backend/repositories/refresh_tokens"
+	"time"
+)
+
+// RefreshTokenServiceインターフェース
+type RefreshTokenService interface {
+	IssueRefreshToken(userId string) (*models.IssuedRefreshToken, error)
+	RotateRefreshToken(token string) (*models.IssuedRefreshToken, error)
+	RevokeRefreshToken(token string) error
+	RevokeAllRefreshTokens(userId string) error
+}
+
+// RefreshTokenServiceImplはRefreshTokenServiceインターフェースを実装する
+type RefreshTokenServiceImpl struct {
+	RefreshTokenRepository repositories_refresh_tokens.RefreshTokenRepository
+	TTL                    time.Duration // リフレッシュトークンの有効期間
+}
+
+func NewRefreshTokenService(
+	refreshTokenRepository repositories_refresh_tokens.RefreshTokenRepository,
+	ttl time.Duration,
+) RefreshTokenService {
+	return &RefreshTokenServiceImpl{
+		RefreshTokenRepository: refreshTokenRepository,
+		TTL:                    ttl,
+	}
+}
@@ -0,0 +1,38 @@
+package services_refresh_tokens
+
+import (
+	"backend/models"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRefreshTokenService is the mock implementation for RefreshTokenService
+type MockRefreshTokenService struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenService) IssueRefreshToken(userId string) (*models.IssuedRefreshToken, error) {
+	args := m.Called(userId)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IssuedRefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenService) RotateRefreshToken(token string) (*models.IssuedRefreshToken, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.IssuedRefreshToken), args.Error(1)
+}
+
+func (m *MockRefreshTokenService) RevokeRefreshToken(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenService) RevokeAllRefreshTokens(userId string) error {
+	args := m.Called(userId)
+	return args.Error(0)
+}
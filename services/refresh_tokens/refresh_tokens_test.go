@@ -0,0 +1,225 @@
+package services_refresh_tokens
+
+import (
+	"backend/models"
+	repositories_refresh_tokens "backend/repositories/refresh_tokens"
+	"backend/utils"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_IssueRefreshToken(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// モックの挙動を設定
+	var savedHash string
+	mockRepository.On("CreateRefreshToken", "user1", mock.AnythingOfType("string"), mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).
+		Run(func(args mock.Arguments) {
+			savedHash = args.String(2)
+		}).Return(nil)
+
+	// サービス層メソッドの実行
+	issued, err := service.IssueRefreshToken("user1")
+
+	// エラーチェック
+	assert.NoError(t, err)
+	assert.NotEmpty(t, issued.Token)
+	assert.Equal(t, "user1", issued.UserId)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), issued.ExpiresAt, time.Minute)
+
+	// トークン本体ではなくハッシュ値が保存されることを確認
+	assert.NotEqual(t, issued.Token, savedHash)
+	assert.Equal(t, utils.HashToken(issued.Token), savedHash)
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_IssueRefreshToken_InvalidCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// 1. ユーザーIDが空の場合
+	_, err := service.IssueRefreshToken("")
+	assert.Error(t, err)
+	assert.Equal(t, "userId is required", err.Error())
+
+	// 2. 保存に失敗した場合
+	mockRepository.On("CreateRefreshToken", "user1", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("insert failed"))
+
+	_, err = service.IssueRefreshToken("user1")
+	assert.Error(t, err)
+	assert.Equal(t, "failed to issue refresh token", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RotateRefreshToken(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// モックの挙動を設定
+	current := &models.RefreshTokenData{
+		ID:        "token1",
+		UserId:    "user1",
+		FamilyId:  "family1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("old-token")).Return(current, nil)
+	mockRepository.On("RotateRefreshToken", "token1", "user1", "family1", mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(true, nil)
+
+	// サービス層メソッドの実行
+	issued, err := service.RotateRefreshToken("old-token")
+
+	// エラーチェック
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", issued.UserId)
+	assert.Equal(t, "family1", issued.FamilyId)
+	assert.NotEmpty(t, issued.Token)
+	assert.NotEqual(t, "old-token", issued.Token)
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RotateRefreshToken_ReuseDetection(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// 1. 使用済みのトークンが提示された場合は系列全体を失効させる
+	usedAt := time.Now().Add(-time.Minute)
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("used-token")).Return(&models.RefreshTokenData{
+		ID:        "token1",
+		UserId:    "user1",
+		FamilyId:  "family1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}, nil)
+	mockRepository.On("RevokeRefreshTokenFamily", "family1").Return(nil).Once()
+
+	_, err := service.RotateRefreshToken("used-token")
+	assert.Error(t, err)
+	assert.Equal(t, "refresh token reused", err.Error())
+
+	// 2. 同時にローテーションされた場合も系列全体を失効させる
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("raced-token")).Return(&models.RefreshTokenData{
+		ID:        "token2",
+		UserId:    "user1",
+		FamilyId:  "family2",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockRepository.On("RotateRefreshToken", "token2", "user1", "family2", mock.Anything, mock.Anything).Return(false, nil)
+	mockRepository.On("RevokeRefreshTokenFamily", "family2").Return(nil).Once()
+
+	_, err = service.RotateRefreshToken("raced-token")
+	assert.Error(t, err)
+	assert.Equal(t, "refresh token reused", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RotateRefreshToken_InvalidCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// 1. トークンが空の場合
+	_, err := service.RotateRefreshToken("")
+	assert.Error(t, err)
+	assert.Equal(t, "refresh token is required", err.Error())
+
+	// 2. トークンが存在しない場合
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("unknown-token")).Return(nil, errors.New("no rows in result set"))
+
+	_, err = service.RotateRefreshToken("unknown-token")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid refresh token", err.Error())
+
+	// 3. 系列が失効済みの場合
+	revokedAt := time.Now().Add(-time.Minute)
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("revoked-token")).Return(&models.RefreshTokenData{
+		ID:        "token1",
+		FamilyId:  "family1",
+		ExpiresAt: time.Now().Add(time.Hour),
+		RevokedAt: &revokedAt,
+	}, nil)
+
+	_, err = service.RotateRefreshToken("revoked-token")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid refresh token", err.Error())
+
+	// 4. 有効期限切れの場合
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("expired-token")).Return(&models.RefreshTokenData{
+		ID:        "token2",
+		FamilyId:  "family2",
+		ExpiresAt: time.Now().Add(-time.Minute),
+	}, nil)
+
+	_, err = service.RotateRefreshToken("expired-token")
+	assert.Error(t, err)
+	assert.Equal(t, "refresh token expired", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+	mockRepository.AssertNotCalled(t, "RotateRefreshToken", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_RevokeRefreshToken(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// モックの挙動を設定
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("token")).Return(&models.RefreshTokenData{
+		ID:       "token1",
+		FamilyId: "family1",
+	}, nil)
+	mockRepository.On("RevokeRefreshTokenFamily", "family1").Return(nil)
+	mockRepository.On("FetchRefreshTokenByHash", utils.HashToken("unknown-token")).Return(nil, errors.New("no rows in result set"))
+
+	// 存在するトークン
+	assert.NoError(t, service.RevokeRefreshToken("token"))
+
+	// 存在しないトークンや空のトークンはエラーにしない
+	assert.NoError(t, service.RevokeRefreshToken("unknown-token"))
+	assert.NoError(t, service.RevokeRefreshToken(""))
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+}
+
+func TestService_RevokeAllRefreshTokens(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockRepository := new(repositories_refresh_tokens.MockRefreshTokenRepository)
+	service := NewRefreshTokenService(mockRepository, time.Hour)
+
+	// モックの挙動を設定
+	mockRepository.On("RevokeRefreshTokensByUserId", "user1").Return(nil)
+	mockRepository.On("RevokeRefreshTokensByUserId", "user2").Return(errors.New("update failed"))
+
+	// 正常系
+	assert.NoError(t, service.RevokeAllRefreshTokens("user1"))
+
+	// 異常系
+	err := service.RevokeAllRefreshTokens("")
+	assert.Error(t, err)
+	assert.Equal(t, "userId is required", err.Error())
+
+	err = service.RevokeAllRefreshTokens("user2")
+	assert.Error(t, err)
+	assert.Equal(t, "failed to revoke refresh token", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockRepository.AssertExpectations(t)
+}
@@ -2,9 +2,14 @@ package services_reservations
 
 import (
 	"backend/models"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"log"
 	"time"
+
+	"github.com/jackc/pgx/v4"
 )
 
 // Supabaseから全予約情報を取得し、予約情報リストを返す。
@@ -16,32 +21,140 @@ func (s *ReservationServiceImpl) FetchReservations() ([]models.ReservationData,
 // 指定されたIDに対応する予約情報を取得する。
 // 予約情報が見つからない場合、エラーを返す。
 func (s *ReservationServiceImpl) FetchReservationById(id string) (*models.ReservationData, error) {
-	return s.ReservationRepository.FetchReservationById(id)
+	reservation, err := s.ReservationRepository.FetchReservationById(id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
+			log.Printf("Reservation not found: %s", id)
+			return nil, errors.New("reservation not found")
+		}
+		return nil, err
+	}
+	return reservation, nil
 }
 
-// 指定されたユーザーIDに対応する予約情報を取得する。
-// 予約情報が見つからない場合、エラーを返す。
-func (s *ReservationServiceImpl) FetchReservationByUserId(userId string) (*models.ReservationData, error) {
+// 指定されたユーザーIDに対応する予約情報をすべて取得する。
+// 失敗した場合はエラーを返す。
+func (s *ReservationServiceImpl) FetchReservationsByUserId(userId string) ([]models.ReservationData, error) {
+	// バリデーション：userIdが空でないことを確認
+	if userId == "" {
+		log.Printf("userId is required")
+		return nil, errors.New("userId is required")
+	}
+
+	return s.ReservationRepository.FetchReservationsByUserId(userId)
+}
+
+// 予約一覧の1ページの件数
+const (
+	defaultReservationPageSize = 20
+	maxReservationPageSize     = 100
+)
+
+// 指定されたユーザーの予約情報を、条件で絞り込んで予約日時の順に取得する。
+// これからの予約は古い順に、過去の予約は新しい順に返す。
+// 続きがある場合は、次のページを取得するためのカーソルを返す。
+func (s *ReservationServiceImpl) SearchReservationsByUserId(userId string, params models.ReservationSearchParams) (*models.ReservationPage, error) {
 	// バリデーション：userIdが空でないことを確認
 	if userId == "" {
 		log.Printf("userId is required")
 		return nil, errors.New("userId is required")
 	}
-	log.Println("userId is valid")
 
-	// サービス層からユーザーデータを取得
-	reservation, err := s.ReservationRepository.FetchReservationByUserId(userId)
+	query := models.ReservationQuery{UserId: userId, Limit: params.Limit}
+	if query.Limit == 0 {
+		query.Limit = defaultReservationPageSize
+	}
+	if query.Limit < 0 || query.Limit > maxReservationPageSize {
+		log.Printf("Invalid limit: %d", params.Limit)
+		return nil, errors.New("invalid limit")
+	}
+
+	for _, status := range params.Statuses {
+		if !models.IsValidReservationStatus(status) {
+			log.Printf("Invalid reservation status: %s", status)
+			return nil, errors.New("invalid reservation status")
+		}
+	}
+	query.Statuses = params.Statuses
+
+	// 日付の範囲は店舗のタイムゾーンの日付とし、終了日の当日を含める
+	if params.From != "" {
+		from, err := time.ParseInLocation("2006-01-02", params.From, s.Location)
+		if err != nil {
+			log.Printf("Invalid from date: %v", err)
+			return nil, errors.New("invalid date range")
+		}
+		query.From = &from
+	}
+	if params.To != "" {
+		to, err := time.ParseInLocation("2006-01-02", params.To, s.Location)
+		if err != nil {
+			log.Printf("Invalid to date: %v", err)
+			return nil, errors.New("invalid date range")
+		}
+		to = to.AddDate(0, 0, 1)
+		query.To = &to
+	}
+	if query.From != nil && query.To != nil && !query.From.Before(*query.To) {
+		log.Printf("Invalid date range: %s - %s", params.From, params.To)
+		return nil, errors.New("invalid date range")
+	}
+
+	// 現在時刻を基準に期間を絞り込む
+	now := time.Now()
+	switch params.Period {
+	case "":
+	case models.ReservationPeriodUpcoming:
+		if query.From == nil || query.From.Before(now) {
+			query.From = &now
+		}
+	case models.ReservationPeriodPast:
+		if query.To == nil || query.To.After(now) {
+			query.To = &now
+		}
+		query.Descending = true
+	default:
+		log.Printf("Invalid period: %s", params.Period)
+		return nil, errors.New("invalid period")
+	}
+
+	if params.Cursor != "" {
+		cursor, err := decodeReservationCursor(params.Cursor)
+		if err != nil {
+			log.Printf("Invalid cursor: %v", err)
+			return nil, errors.New("invalid cursor")
+		}
+		query.After = cursor
+	}
+
+	// 次のページの有無を判定するため、1件多く取得する
+	limit := query.Limit
+	query.Limit = limit + 1
+	reservations, err := s.ReservationRepository.FetchReservationPage(query)
 	if err != nil {
-		log.Printf("Error fetching reservation: %v", err)
-		return nil, errors.New("reservation not found")
+		log.Printf("Error fetching reservations: %v", err)
+		return nil, errors.New("failed to fetch reservations")
 	}
 
-	return reservation, nil
+	page := &models.ReservationPage{Items: reservations}
+	if page.Items == nil {
+		page.Items = []models.ReservationData{}
+	}
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		cursor := encodeReservationCursor(&models.ReservationCursor{ReservationDate: last.ReservationDate, ID: last.ID})
+		page.NextCursor = &cursor
+	}
+
+	log.Printf("Fetched %d reservations for userId: %s", len(page.Items), userId)
+	return page, nil
 }
 
 // 新しい予約情報をデータベースに追加する。
+// 予約はpendingのステータスで作成し、以降のステータスは店舗が変更する。
 // 成功した場合はnilを返し、失敗した場合はエラーを返す。
-func (s *ReservationServiceImpl) CreateReservation(userId, reservationDate string, numPeople int, specialRequest, status string) (string, error) {
+func (s *ReservationServiceImpl) CreateReservation(userId, reservationDate string, numPeople int, specialRequest string) (string, error) {
 	// バリデーション: 必須フィールドが空でないか確認
 	if reservationDate == "" || numPeople <= 0 {
 		log.Printf("UserID, reservation date, and num_people are required")
@@ -49,10 +162,15 @@ func (s *ReservationServiceImpl) CreateReservation(userId, reservationDate strin
 	}
 
 	// 予約日が正しいフォーマットか確認
-	_, err := time.Parse("2006-01-02 15:04:05", reservationDate)
+	date, err := s.parseReservationDate(reservationDate)
 	if err != nil {
 		log.Printf("Invalid reservation date format: %v", err)
-		return "", errors.New("invalid reservation date format. Use 'YYYY-MM-DD HH:MM:SS'")
+		return "", errors.New("invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'")
+	}
+
+	// 人数と日時が予約枠の設定に合っているか確認
+	if err := s.validateSlot(date, numPeople); err != nil {
+		return "", err
 	}
 
 	// ユーザーが存在するか確認
@@ -62,19 +180,306 @@ func (s *ReservationServiceImpl) CreateReservation(userId, reservationDate strin
 		return "", errors.New("user not found")
 	}
 
-	// ステータスが指定されていない場合、デフォルトで"pending"とする
-	if status == "" {
-		status = "pending"
+	// 設定により、メールアドレスが未確認のユーザーの予約を拒否する
+	if s.RequireVerifiedEmail && !existingUser.IsEmailVerified() {
+		log.Printf("Email not verified for user: %s", userId)
+		return "", errors.New("email not verified")
+	}
+
+	// 営業時間内の枠であることを確認
+	if err := s.checkBusinessHours(date); err != nil {
+		return "", err
 	}
 
 	log.Println("Request body is valid")
 
 	// 予約を作成する
-	reservationId, err := s.ReservationRepository.CreateReservation(userId, reservationDate, numPeople, specialRequest, status)
+	reservationId, err := s.ReservationRepository.CreateReservation(userId, date, numPeople, specialRequest, models.ReservationStatusPending, s.Location.String(), s.Capacity.SeatsPerSlot)
 	if err != nil {
+		if err.Error() == "slot is full" {
+			return "", err
+		}
 		log.Printf("Error creating reservation: %v", err)
 		return "", errors.New("failed to create reservation")
 	}
 
 	return reservationId, nil
 }
+
+// 指定されたIDの予約の日時、人数、リクエストを変更し、変更後の予約情報を返す。
+// nilの項目は変更せず、現在の予約の値を使用する。来店後や終了状態の予約は変更できない。
+func (s *ReservationServiceImpl) UpdateReservation(id string, reservationDate *string, numPeople *int, specialRequest *string) (*models.ReservationData, error) {
+	// バリデーション: IDと、変更する日時・人数が空でないか確認
+	if id == "" || (reservationDate != nil && *reservationDate == "") || (numPeople != nil && *numPeople <= 0) {
+		log.Printf("ID, reservation date, and num_people are required")
+		return nil, errors.New("id, reservation date, and num_people are required")
+	}
+
+	// 予約日が正しいフォーマットか確認
+	var date time.Time
+	if reservationDate != nil {
+		parsed, err := s.parseReservationDate(*reservationDate)
+		if err != nil {
+			log.Printf("Invalid reservation date format: %v", err)
+			return nil, errors.New("invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'")
+		}
+		date = parsed
+	}
+
+	reservation, err := s.FetchReservationById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.IsReservationModifiable(reservation.Status) {
+		log.Printf("Reservation cannot be modified in status %s: %s", reservation.Status, id)
+		return nil, errors.New("reservation cannot be modified")
+	}
+
+	// 指定されなかった項目は現在の予約の値を使用する
+	if reservationDate == nil {
+		date = reservation.ReservationDate
+	}
+	people := reservation.NumPeople
+	if numPeople != nil {
+		people = *numPeople
+	}
+	request := reservation.SpecialRequest
+	if specialRequest != nil {
+		request = *specialRequest
+	}
+
+	// 日時を変更する場合のみ、変更後の枠が予約枠の設定と営業時間に合っているか確認する
+	// 日時を変えない場合は、設定の変更前に受け付けた予約でも人数やリクエストを変更できる。
+	if !date.Equal(reservation.ReservationDate) {
+		if err := s.validateSlot(date, people); err != nil {
+			return nil, err
+		}
+		if err := s.checkBusinessHours(date); err != nil {
+			return nil, err
+		}
+	} else if people != reservation.NumPeople {
+		if err := s.validatePartySize(people); err != nil {
+			return nil, err
+		}
+	}
+
+	// 予約を更新する
+	err = s.ReservationRepository.UpdateReservation(id, date, people, request, s.Capacity.SeatsPerSlot)
+	if err != nil {
+		if err.Error() == "reservation not found" || err.Error() == "slot is full" {
+			return nil, err
+		}
+		log.Printf("Error updating reservation: %v", err)
+		return nil, errors.New("failed to update reservation")
+	}
+
+	reservation.ReservationDate = date.UTC()
+	reservation.NumPeople = people
+	reservation.SpecialRequest = request
+	reservation.UpdatedAt = time.Now()
+	return reservation, nil
+}
+
+// 指定されたIDの予約のステータスを遷移させ、変更後の予約情報を返す。
+// 許可されていない遷移や、他の操作で先にステータスが変更された場合はエラーを返す。
+func (s *ReservationServiceImpl) ChangeReservationStatus(id, status string) (*models.ReservationData, error) {
+	// バリデーション: 必須フィールドが空でないか確認
+	if id == "" || status == "" {
+		log.Printf("ID and status are required")
+		return nil, errors.New("id and status are required")
+	}
+	if !models.IsValidReservationStatus(status) {
+		log.Printf("Invalid reservation status: %s", status)
+		return nil, errors.New("invalid reservation status")
+	}
+
+	reservation, err := s.FetchReservationById(id)
+	if err != nil {
+		return nil, err
+	}
+	if !models.CanTransitionReservationStatus(reservation.Status, status) {
+		log.Printf("Invalid status transition for reservation %s: %s -> %s", id, reservation.Status, status)
+		return nil, errors.New("invalid status transition")
+	}
+
+	// 取得時のステータスから変更されていない場合のみ更新する
+	err = s.ReservationRepository.UpdateReservationStatus(id, reservation.Status, status)
+	if err != nil {
+		if err.Error() == "reservation status conflict" {
+			return nil, err
+		}
+		log.Printf("Error updating reservation status: %v", err)
+		return nil, errors.New("failed to update reservation status")
+	}
+
+	reservation.Status = status
+	reservation.UpdatedAt = time.Now()
+	return reservation, nil
+}
+
+// 指定した日の予約枠のうち、指定した人数で予約できる枠を返す。
+// 営業時間外の枠、開始時刻を過ぎた枠、残席が人数に満たない枠は含まない。
+// 休業日の場合は枠を返さず、closedをtrueにする。
+// 日付と枠の開始時刻は店舗のタイムゾーンで扱う。
+func (s *ReservationServiceImpl) FetchAvailability(date string, partySize int) (*models.Availability, error) {
+	day, err := time.ParseInLocation("2006-01-02", date, s.Location)
+	if err != nil {
+		log.Printf("Invalid date format: %v", err)
+		return nil, errors.New("invalid date format. Use 'YYYY-MM-DD'")
+	}
+	if partySize <= 0 {
+		log.Printf("Invalid party size: %d", partySize)
+		return nil, errors.New("invalid party size")
+	}
+	if partySize > s.Capacity.MaxPartySize {
+		log.Printf("Party size exceeds maximum: %d", partySize)
+		return nil, errors.New("party size exceeds maximum")
+	}
+
+	availability := &models.Availability{Date: date, TimeZone: s.Location.String(), PartySize: partySize, Slots: []models.AvailabilitySlot{}}
+
+	// 営業時間帯を取得
+	calendar, err := s.CalendarRepository.FetchCalendar(date, date)
+	if err != nil {
+		log.Printf("Error fetching business calendar: %v", err)
+		return nil, errors.New("failed to fetch availability")
+	}
+	if len(calendar.OpeningPeriods(day)) == 0 {
+		log.Printf("Closed on %s", date)
+		availability.Closed = true
+		return availability, nil
+	}
+
+	usages, err := s.ReservationRepository.FetchSlotUsage(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		log.Printf("Error fetching slot usage: %v", err)
+		return nil, errors.New("failed to fetch availability")
+	}
+
+	reserved := make(map[time.Time]int, len(usages))
+	for _, usage := range usages {
+		reserved[usage.Start.UTC()] += usage.Seats
+	}
+
+	// 営業時間帯に収まる枠のみを対象とする
+	now := time.Now()
+	for _, start := range s.slotStarts(day) {
+		if start.Before(now) || !calendar.IsOpen(start, start.Add(s.Capacity.SlotDuration)) {
+			continue
+		}
+		available := s.Capacity.SeatsPerSlot - reserved[start.UTC()]
+		if available < partySize {
+			continue
+		}
+		availability.Slots = append(availability.Slots, models.AvailabilitySlot{Start: start.UTC(), StartLocal: start, AvailableSeats: available})
+	}
+
+	return availability, nil
+}
+
+// 予約枠が営業日の営業時間内にあるかを確認する
+// 枠の終了時刻までが営業時間帯に収まる必要がある。
+func (s *ReservationServiceImpl) checkBusinessHours(start time.Time) error {
+	start = start.In(s.Location)
+	date := start.Format("2006-01-02")
+	calendar, err := s.CalendarRepository.FetchCalendar(date, date)
+	if err != nil {
+		log.Printf("Error fetching business calendar: %v", err)
+		return errors.New("failed to fetch business calendar")
+	}
+	if len(calendar.OpeningPeriods(start)) == 0 {
+		log.Printf("Closed on reservation date: %s", date)
+		return errors.New("closed on reservation date")
+	}
+	if !calendar.IsOpen(start, start.Add(s.Capacity.SlotDuration)) {
+		log.Printf("Reservation is outside business hours: %s", start)
+		return errors.New("outside business hours")
+	}
+	return nil
+}
+
+// 予約の人数が上限以下で、日時が予約枠の開始時刻に合っているかを確認する
+// 枠は店舗のタイムゾーンの0時から区切る。
+func (s *ReservationServiceImpl) validateSlot(date time.Time, numPeople int) error {
+	if err := s.validatePartySize(numPeople); err != nil {
+		return err
+	}
+	local := date.In(s.Location)
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second + time.Duration(local.Nanosecond())
+	if clock%s.Capacity.SlotDuration != 0 {
+		log.Printf("Reservation date is not on a slot boundary: %s", date)
+		return errors.New("reservation date is not on a slot boundary")
+	}
+	return nil
+}
+
+// 予約の人数が上限以下であるかを確認する
+func (s *ReservationServiceImpl) validatePartySize(numPeople int) error {
+	if numPeople > s.Capacity.MaxPartySize {
+		log.Printf("Party size exceeds maximum: %d", numPeople)
+		return errors.New("party size exceeds maximum")
+	}
+	return nil
+}
+
+// 指定した日の予約枠の開始日時を、店舗のタイムゾーンの時計の時刻で返す
+// 夏時間の切り替えで存在しない時刻の枠は含まない。
+func (s *ReservationServiceImpl) slotStarts(day time.Time) []time.Time {
+	starts := []time.Time{}
+	for clock := time.Duration(0); clock < 24*time.Hour; clock += s.Capacity.SlotDuration {
+		hour, minute := int(clock/time.Hour), int(clock%time.Hour/time.Minute)
+		start := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, s.Location)
+		if start.Hour() != hour || start.Minute() != minute {
+			continue
+		}
+		starts = append(starts, start)
+	}
+	return starts
+}
+
+// 予約日時を解析する。
+// オフセット付きのRFC 3339形式と、店舗のタイムゾーンの時刻として扱う従来の'YYYY-MM-DD HH:MM:SS'形式を受け付ける。
+func (s *ReservationServiceImpl) parseReservationDate(value string) (time.Time, error) {
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date, nil
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", value, s.Location)
+}
+
+// カーソルをJSONで表した形式
+type reservationCursorPayload struct {
+	ReservationDate string `json:"d"`
+	ID              string `json:"id"`
+}
+
+// 予約一覧のページ位置を、クライアントに渡すカーソル文字列に変換する
+func encodeReservationCursor(cursor *models.ReservationCursor) string {
+	payload, _ := json.Marshal(reservationCursorPayload{
+		ReservationDate: cursor.ReservationDate.Format(time.RFC3339Nano),
+		ID:              cursor.ID,
+	})
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+// クライアントから受け取ったカーソル文字列を、予約一覧のページ位置に変換する
+func decodeReservationCursor(value string) (*models.ReservationCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload reservationCursorPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return nil, err
+	}
+	if payload.ID == "" {
+		return nil, errors.New("cursor id is required")
+	}
+
+	reservationDate, err := time.Parse(time.RFC3339Nano, payload.ReservationDate)
+	if err != nil {
+		return nil, err
+	}
+	return &models.ReservationCursor{ReservationDate: reservationDate, ID: payload.ID}, nil
+}
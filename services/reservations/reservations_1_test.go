@@ -6,15 +6,17 @@ import (
 	repositories_users "backend/repositories/users"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestService_FetchReservations(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// モックの挙動を設定
 	mockReservations := []models.ReservationData{
@@ -42,7 +44,7 @@ func TestService_FetchReservations_EmptyList(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// モックの挙動を設定
 	reservationRepository.On("FetchReservations").Return([]models.ReservationData{}, nil)
@@ -65,7 +67,7 @@ func TestService_FetchReservationById(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// モックの挙動を設定
 	mockReservation := &models.ReservationData{
@@ -94,7 +96,7 @@ func TestService_FetchReservationById_EmptyData(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// モックの挙動を設定
 	reservationRepository.On("FetchReservationById", "1").Return(nil, errors.New("record not found"))
@@ -111,72 +113,161 @@ func TestService_FetchReservationById_EmptyData(t *testing.T) {
 	reservationRepository.AssertExpectations(t)
 }
 
-func TestService_FetchReservationByUserId(t *testing.T) {
+func TestService_SearchReservationsByUserId(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// モックの挙動を設定
-	mockReservation := &models.ReservationData{
-		ID:             "1",
-		UserId:         "1",
-		NumPeople:      2,
-		SpecialRequest: "No smoking",
-		Status:         "reserved",
+	// 1件多く取得できた場合は次のページがある
+	date := time.Date(2024, 10, 1, 18, 0, 0, 0, time.UTC)
+	mockReservations := []models.ReservationData{
+		{ID: "1", UserId: "1", ReservationDate: date, Status: models.ReservationStatusConfirmed},
+		{ID: "2", UserId: "1", ReservationDate: date.Add(time.Hour), Status: models.ReservationStatusConfirmed},
+		{ID: "3", UserId: "1", ReservationDate: date.Add(2 * time.Hour), Status: models.ReservationStatusConfirmed},
 	}
-	reservationRepository.On("FetchReservationByUserId", "1").Return(mockReservation, nil)
+	from := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	reservationRepository.On("FetchReservationPage", models.ReservationQuery{
+		UserId:   "1",
+		Statuses: []string{models.ReservationStatusConfirmed},
+		From:     &from,
+		To:       &to,
+		Limit:    3,
+	}).Return(mockReservations, nil)
 
 	// サービス層メソッドの実行
-	reservation, err := reserationService.FetchReservationByUserId("1")
+	page, err := reserationService.SearchReservationsByUserId("1", models.ReservationSearchParams{
+		Statuses: []string{models.ReservationStatusConfirmed},
+		From:     "2024-10-01",
+		To:       "2024-10-31",
+		Limit:    2,
+	})
 
 	// エラーチェック
 	assert.NoError(t, err)
 
 	// データが期待通りか確認
-	assert.NotNil(t, reservation)
-	assert.Equal(t, "No smoking", reservation.SpecialRequest)
+	assert.Len(t, page.Items, 2)
+	if assert.NotNil(t, page.NextCursor) {
+		// 次のページのカーソルは、そのページの最後の予約を指す
+		cursor, err := decodeReservationCursor(*page.NextCursor)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.ReservationCursor{ReservationDate: date.Add(time.Hour), ID: "2"}, cursor)
+	}
 
 	// モックが期待通りに呼び出されたかを確認
 	reservationRepository.AssertExpectations(t)
 }
 
-func TestService_FetchReservationByUserId_ValidationUserI(t *testing.T) {
+func TestService_SearchReservationsByUserId_Period(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
-	// サービス層メソッドの実行
-	reservation, err := reserationService.FetchReservationByUserId("")
-
-	// エラーチェック
-	assert.Error(t, err)
-	// データが期待通りか確認
-	assert.Nil(t, reservation)
-	assert.Equal(t, "userId is required", err.Error())
+	// これからの予約は現在時刻以降を古い順に取得する
+	reservationRepository.On("FetchReservationPage", mock.MatchedBy(func(query models.ReservationQuery) bool {
+		return query.From != nil && time.Since(*query.From) < time.Minute && query.To == nil && !query.Descending && query.Limit == defaultReservationPageSize+1
+	})).Return([]models.ReservationData{}, nil).Once()
+	page, err := reserationService.SearchReservationsByUserId("1", models.ReservationSearchParams{Period: models.ReservationPeriodUpcoming})
+	assert.NoError(t, err)
+	assert.Empty(t, page.Items)
+	assert.Nil(t, page.NextCursor)
+
+	// 過去の予約は現在時刻より前を新しい順に、カーソルより後から取得する
+	cursor := encodeReservationCursor(&models.ReservationCursor{ReservationDate: time.Date(2024, 10, 1, 18, 0, 0, 0, time.UTC), ID: "2"})
+	reservationRepository.On("FetchReservationPage", mock.MatchedBy(func(query models.ReservationQuery) bool {
+		return query.From == nil && query.To != nil && time.Since(*query.To) < time.Minute && query.Descending &&
+			query.After != nil && query.After.ID == "2"
+	})).Return(nil, nil).Once()
+	page, err = reserationService.SearchReservationsByUserId("1", models.ReservationSearchParams{Period: models.ReservationPeriodPast, Cursor: cursor})
+	assert.NoError(t, err)
+	assert.NotNil(t, page.Items)
 
 	// モックが期待通りに呼び出されたかを確認
 	reservationRepository.AssertExpectations(t)
 }
 
-func TestService_FetchReservationByUserId_EmptyData(t *testing.T) {
+func TestService_SearchReservationsByUserId_ValidationError(t *testing.T) {
+	testCases := []struct {
+		name        string
+		userId      string
+		params      models.ReservationSearchParams
+		expectedErr string
+	}{
+		{"missing user", "", models.ReservationSearchParams{}, "userId is required"},
+		{"negative limit", "1", models.ReservationSearchParams{Limit: -1}, "invalid limit"},
+		{"too large limit", "1", models.ReservationSearchParams{Limit: maxReservationPageSize + 1}, "invalid limit"},
+		{"unknown status", "1", models.ReservationSearchParams{Statuses: []string{"reserved"}}, "invalid reservation status"},
+		{"invalid from", "1", models.ReservationSearchParams{From: "2024/10/01"}, "invalid date range"},
+		{"reversed range", "1", models.ReservationSearchParams{From: "2024-10-02", To: "2024-10-01"}, "invalid date range"},
+		{"unknown period", "1", models.ReservationSearchParams{Period: "today"}, "invalid period"},
+		{"invalid cursor", "1", models.ReservationSearchParams{Cursor: "not-a-cursor"}, "invalid cursor"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// サービス層メソッドの実行
+			page, err := reserationService.SearchReservationsByUserId(tc.userId, tc.params)
+
+			// エラーチェック
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.Nil(t, page)
+
+			// リポジトリが呼び出されていないことを確認
+			reservationRepository.AssertNotCalled(t, "FetchReservationPage", mock.Anything)
+		})
+	}
+}
+
+func TestService_SearchReservationsByUserId_RepositoryError(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchReservationPage", mock.Anything).Return(nil, errors.New("connection refused"))
+
+	// サービス層メソッドの実行
+	page, err := reserationService.SearchReservationsByUserId("1", models.ReservationSearchParams{})
+
+	// エラーチェック
+	assert.EqualError(t, err, "failed to fetch reservations")
+	assert.Nil(t, page)
+}
+
+func TestService_FetchReservationsByUserId(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// モックの挙動を設定
-	reservationRepository.On("FetchReservationByUserId", "1").Return(nil, errors.New("reservation not found"))
+	mockReservations := []models.ReservationData{
+		{ID: "1", UserId: "user1", NumPeople: 2, Status: "pending"},
+		{ID: "2", UserId: "user1", NumPeople: 4, Status: "confirmed"},
+	}
+	reservationRepository.On("FetchReservationsByUserId", "user1").Return(mockReservations, nil)
 
 	// サービス層メソッドの実行
-	reservation, err := reserationService.FetchReservationByUserId("1")
+	reservations, err := reserationService.FetchReservationsByUserId("user1")
 
 	// エラーチェック
+	assert.NoError(t, err)
+	assert.Len(t, reservations, 2)
+
+	// ユーザーIDが空の場合
+	_, err = reserationService.FetchReservationsByUserId("")
 	assert.Error(t, err)
-	// データが期待通りか確認
-	assert.Nil(t, reservation)
-	assert.Equal(t, "reservation not found", err.Error())
+	assert.Equal(t, "userId is required", err.Error())
 
 	// モックが期待通りに呼び出されたかを確認
 	reservationRepository.AssertExpectations(t)
@@ -3,6 +3,7 @@ package services_reservations
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"backend/models"
 	repositories_reservations "backend/repositories/reservations"
@@ -15,16 +16,16 @@ func TestService_CreateReservation_Success(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// ユーザーが存在する場合のモックの挙動を設定
 	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Name: "John Doe", Email: "john@example.com"}, nil)
 
 	// 予約作成のモックの挙動を設定
-	reservationRepository.On("CreateReservation", "user1", "2024-10-10 12:00:00", 4, "Special request", "pending").Return("reservation1", nil)
+	reservationRepository.On("CreateReservation", "user1", time.Date(2024, 10, 10, 12, 0, 0, 0, time.UTC), 4, "Special request", "pending", "UTC", 40).Return("reservation1", nil)
 
 	// サービス層メソッドの実行
-	reservationId, err := reserationService.CreateReservation("user1", "2024-10-10 12:00:00", 4, "Special request", "")
+	reservationId, err := reserationService.CreateReservation("user1", "2024-10-10 12:00:00", 4, "Special request")
 
 	// エラーチェックと結果の確認
 	assert.NoError(t, err)
@@ -39,10 +40,10 @@ func TestService_CreateReservation_ValidationError(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// バリデーションエラーを確認するため、ユーザー取得などは不要
-	_, err := reserationService.CreateReservation("user1", "", 0, "Special request", "")
+	_, err := reserationService.CreateReservation("user1", "", 0, "Special request")
 
 	// エラーチェック
 	assert.Error(t, err)
@@ -57,17 +58,17 @@ func TestService_CreateReservation_InvalidDate(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// ユーザーが存在する場合のモックの挙動を設定
 	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Name: "John Doe", Email: "john@example.com"}, nil)
 
 	// 不正な日付フォーマットを渡す
-	_, err := reserationService.CreateReservation("user1", "invalid-date", 4, "Special request", "")
+	_, err := reserationService.CreateReservation("user1", "invalid-date", 4, "Special request")
 
 	// エラーチェック
 	assert.Error(t, err)
-	assert.Equal(t, "invalid reservation date format. Use 'YYYY-MM-DD HH:MM:SS'", err.Error())
+	assert.Equal(t, "invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'", err.Error())
 
 	// モックが呼び出されていないか確認
 	reservationRepository.AssertNotCalled(t, "CreateReservation")
@@ -77,13 +78,13 @@ func TestService_CreateReservation_UserNotFound(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	userRepository := new(repositories_users.MockUserRepository)
 	reservationRepository := new(repositories_reservations.MockReservationRepository)
-	reserationService := NewReservationService(userRepository, reservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
 
 	// ユーザーが存在しない場合のモックの挙動を設定
 	userRepository.On("FetchUserById", "user1").Return(nil, errors.New("user not found"))
 
 	// サービス層メソッドの実行
-	_, err := reserationService.CreateReservation("user1", "2024-10-10 12:00:00", 4, "Special request", "")
+	_, err := reserationService.CreateReservation("user1", "2024-10-10 12:00:00", 4, "Special request")
 
 	// エラーチェック
 	assert.Error(t, err)
@@ -93,3 +94,47 @@ func TestService_CreateReservation_UserNotFound(t *testing.T) {
 	userRepository.AssertCalled(t, "FetchUserById", "user1")
 	reservationRepository.AssertNotCalled(t, "CreateReservation")
 }
+
+func TestService_CreateReservation_EmailNotVerified(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), true, DefaultCapacityConfig(), time.UTC)
+
+	// メールアドレスが未確認のユーザーのモックの挙動を設定
+	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Name: "John Doe", Email: "john@example.com"}, nil)
+
+	// サービス層メソッドの実行
+	_, err := reserationService.CreateReservation("user1", "2024-10-10 12:00:00", 4, "Special request")
+
+	// エラーチェック
+	assert.Error(t, err)
+	assert.Equal(t, "email not verified", err.Error())
+
+	// 予約が作成されていないことを確認
+	userRepository.AssertExpectations(t)
+	reservationRepository.AssertNotCalled(t, "CreateReservation")
+}
+
+func TestService_CreateReservation_EmailVerified(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), true, DefaultCapacityConfig(), time.UTC)
+
+	// メールアドレスが確認済みのユーザーのモックの挙動を設定
+	verifiedAt := time.Now()
+	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1", Name: "John Doe", Email: "john@example.com", EmailVerifiedAt: &verifiedAt}, nil)
+	reservationRepository.On("CreateReservation", "user1", time.Date(2024, 10, 10, 12, 0, 0, 0, time.UTC), 4, "Special request", "pending", "UTC", 40).Return("reservation1", nil)
+
+	// サービス層メソッドの実行
+	reservationId, err := reserationService.CreateReservation("user1", "2024-10-10 12:00:00", 4, "Special request")
+
+	// エラーチェックと結果の確認
+	assert.NoError(t, err)
+	assert.Equal(t, "reservation1", reservationId)
+
+	// モックが期待通りに呼び出されたかを確認
+	userRepository.AssertExpectations(t)
+	reservationRepository.AssertExpectations(t)
+}
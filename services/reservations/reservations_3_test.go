@@ -0,0 +1,253 @@
+package services_reservations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend/models"
+	repositories_business_calendar "backend/repositories/business_calendar"
+	repositories_reservations "backend/repositories/reservations"
+	repositories_users "backend/repositories/users"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 毎日終日営業するカレンダーのモックを生成
+func newOpenCalendarRepository() *repositories_business_calendar.MockBusinessCalendarRepository {
+	calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	hours := []models.BusinessHoursData{}
+	for weekday := 0; weekday < 7; weekday++ {
+		hours = append(hours, models.BusinessHoursData{Weekday: weekday, OpenTime: "00:00", CloseTime: "24:00"})
+	}
+	calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(&models.BusinessCalendar{Hours: hours}, nil)
+	return calendarRepository
+}
+
+// テスト用に値へのポインタを返す
+func ptr[T any](value T) *T {
+	return &value
+}
+
+func TestService_FetchReservationById_NotFound(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchReservationById", "1").Return(nil, pgx.ErrNoRows)
+
+	// サービス層メソッドの実行
+	_, err := reserationService.FetchReservationById("1")
+	assert.EqualError(t, err, "reservation not found")
+}
+
+func TestService_UpdateReservation(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", NumPeople: 2, Status: models.ReservationStatusConfirmed}, nil)
+	reservationRepository.On("UpdateReservation", "reservation1", time.Date(2024, 10, 10, 19, 0, 0, 0, time.UTC), 4, "Window seat", 40).Return(nil)
+
+	// サービス層メソッドの実行
+	reservation, err := reserationService.UpdateReservation("reservation1", ptr("2024-10-10 19:00:00"), ptr(4), ptr("Window seat"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, reservation.NumPeople)
+	assert.Equal(t, "Window seat", reservation.SpecialRequest)
+	assert.Equal(t, time.Date(2024, 10, 10, 19, 0, 0, 0, time.UTC), reservation.ReservationDate)
+	assert.Equal(t, models.ReservationStatusConfirmed, reservation.Status)
+
+	reservationRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateReservation_PartialUpdate(t *testing.T) {
+	current := &models.ReservationData{
+		ID:              "reservation1",
+		UserId:          "user1",
+		ReservationDate: time.Date(2024, 10, 10, 18, 0, 0, 0, time.UTC),
+		NumPeople:       2,
+		SpecialRequest:  "Window seat",
+		Status:          models.ReservationStatusConfirmed,
+	}
+
+	testCases := []struct {
+		name            string
+		reservationDate *string
+		numPeople       *int
+		specialRequest  *string
+		expectedDate    time.Time
+		expectedPeople  int
+		expectedRequest string
+	}{
+		// 日時と人数のみ変更した場合は、リクエストを維持する
+		{"date and party size only", ptr("2024-10-10 19:00:00"), ptr(4), nil, time.Date(2024, 10, 10, 19, 0, 0, 0, time.UTC), 4, "Window seat"},
+		{"date only", ptr("2024-10-10 19:00:00"), nil, nil, time.Date(2024, 10, 10, 19, 0, 0, 0, time.UTC), 2, "Window seat"},
+		// リクエストのみ変更した場合は、日時と人数を維持する
+		{"special request only", nil, nil, ptr("Birthday cake"), current.ReservationDate, 2, "Birthday cake"},
+		// 空のリクエストを指定した場合は、リクエストを削除する
+		{"clear special request", nil, nil, ptr(""), current.ReservationDate, 2, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			fetched := *current
+			reservationRepository.On("FetchReservationById", "reservation1").Return(&fetched, nil)
+			reservationRepository.On("UpdateReservation", "reservation1", tc.expectedDate, tc.expectedPeople, tc.expectedRequest, 40).Return(nil)
+
+			// サービス層メソッドの実行
+			reservation, err := reserationService.UpdateReservation("reservation1", tc.reservationDate, tc.numPeople, tc.specialRequest)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expectedDate, reservation.ReservationDate)
+			assert.Equal(t, tc.expectedPeople, reservation.NumPeople)
+			assert.Equal(t, tc.expectedRequest, reservation.SpecialRequest)
+
+			reservationRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_UpdateReservation_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name        string
+		date        *string
+		numPeople   *int
+		status      string
+		fetchErr    error
+		expectedErr string
+	}{
+		{"empty date", ptr(""), ptr(2), models.ReservationStatusPending, nil, "id, reservation date, and num_people are required"},
+		{"zero party size", nil, ptr(0), models.ReservationStatusPending, nil, "id, reservation date, and num_people are required"},
+		{"invalid date", ptr("2024/10/10"), ptr(2), models.ReservationStatusPending, nil, "invalid reservation date format. Use RFC 3339 or 'YYYY-MM-DD HH:MM:SS'"},
+		{"not found", ptr("2024-10-10 19:00:00"), ptr(2), "", pgx.ErrNoRows, "reservation not found"},
+		{"seated", ptr("2024-10-10 19:00:00"), ptr(2), models.ReservationStatusSeated, nil, "reservation cannot be modified"},
+		{"cancelled", ptr("2024-10-10 19:00:00"), ptr(2), models.ReservationStatusCancelled, nil, "reservation cannot be modified"},
+		// 人数のみ変更した場合も、変更後の人数で確認する
+		{"party too large", nil, ptr(9), models.ReservationStatusPending, nil, "party size exceeds maximum"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			if tc.fetchErr != nil {
+				reservationRepository.On("FetchReservationById", "reservation1").Return(nil, tc.fetchErr)
+			} else {
+				reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", Status: tc.status}, nil)
+			}
+
+			// サービス層メソッドの実行
+			_, err := reserationService.UpdateReservation("reservation1", tc.date, tc.numPeople, nil)
+			assert.EqualError(t, err, tc.expectedErr)
+
+			reservationRepository.AssertNotCalled(t, "UpdateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_ChangeReservationStatus(t *testing.T) {
+	testCases := []struct {
+		from string
+		to   string
+	}{
+		{models.ReservationStatusPending, models.ReservationStatusConfirmed},
+		{models.ReservationStatusPending, models.ReservationStatusCancelled},
+		{models.ReservationStatusConfirmed, models.ReservationStatusSeated},
+		{models.ReservationStatusConfirmed, models.ReservationStatusCancelled},
+		{models.ReservationStatusConfirmed, models.ReservationStatusNoShow},
+		{models.ReservationStatusSeated, models.ReservationStatusCompleted},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.from+" to "+tc.to, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", UserId: "user1", Status: tc.from}, nil)
+			reservationRepository.On("UpdateReservationStatus", "reservation1", tc.from, tc.to).Return(nil)
+
+			// サービス層メソッドの実行
+			reservation, err := reserationService.ChangeReservationStatus("reservation1", tc.to)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.to, reservation.Status)
+
+			reservationRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_ChangeReservationStatus_InvalidTransitions(t *testing.T) {
+	testCases := []struct {
+		from string
+		to   string
+	}{
+		{models.ReservationStatusPending, models.ReservationStatusSeated},
+		{models.ReservationStatusPending, models.ReservationStatusNoShow},
+		{models.ReservationStatusPending, models.ReservationStatusPending},
+		{models.ReservationStatusConfirmed, models.ReservationStatusCompleted},
+		{models.ReservationStatusSeated, models.ReservationStatusCancelled},
+		{models.ReservationStatusCompleted, models.ReservationStatusCancelled},
+		{models.ReservationStatusCancelled, models.ReservationStatusConfirmed},
+		{models.ReservationStatusNoShow, models.ReservationStatusSeated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.from+" to "+tc.to, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", Status: tc.from}, nil)
+
+			// サービス層メソッドの実行
+			_, err := reserationService.ChangeReservationStatus("reservation1", tc.to)
+			assert.EqualError(t, err, "invalid status transition")
+
+			reservationRepository.AssertNotCalled(t, "UpdateReservationStatus", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_ChangeReservationStatus_ErrorCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// 未定義のステータス
+	_, err := reserationService.ChangeReservationStatus("reservation1", "arrived")
+	assert.EqualError(t, err, "invalid reservation status")
+	_, err = reserationService.ChangeReservationStatus("reservation1", "")
+	assert.EqualError(t, err, "id and status are required")
+
+	// 取得後に他の操作でステータスが変更された場合
+	reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", Status: models.ReservationStatusPending}, nil)
+	reservationRepository.On("UpdateReservationStatus", "reservation1", models.ReservationStatusPending, models.ReservationStatusConfirmed).Return(errors.New("reservation status conflict")).Once()
+	_, err = reserationService.ChangeReservationStatus("reservation1", models.ReservationStatusConfirmed)
+	assert.EqualError(t, err, "reservation status conflict")
+
+	// データベースのエラー
+	reservationRepository.On("UpdateReservationStatus", "reservation1", models.ReservationStatusPending, models.ReservationStatusConfirmed).Return(errors.New("connection refused")).Once()
+	_, err = reserationService.ChangeReservationStatus("reservation1", models.ReservationStatusConfirmed)
+	assert.EqualError(t, err, "failed to update reservation status")
+}
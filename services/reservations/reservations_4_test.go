@@ -0,0 +1,211 @@
+package services_reservations
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"backend/models"
+	repositories_business_calendar "backend/repositories/business_calendar"
+	repositories_reservations "backend/repositories/reservations"
+	repositories_users "backend/repositories/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_CreateReservation_CapacityErrors(t *testing.T) {
+	testCases := []struct {
+		name        string
+		date        string
+		numPeople   int
+		expectedErr string
+	}{
+		{"party too large", "2099-10-10 12:00:00", 9, "party size exceeds maximum"},
+		{"not on slot boundary", "2099-10-10 12:15:00", 2, "reservation date is not on a slot boundary"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// サービス層メソッドの実行
+			_, err := reserationService.CreateReservation("user1", tc.date, tc.numPeople, "Special request")
+			assert.EqualError(t, err, tc.expectedErr)
+
+			reservationRepository.AssertNotCalled(t, "CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateReservation_SlotFull(t *testing.T) {
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1"}, nil)
+	reservationRepository.On("CreateReservation", "user1", time.Date(2099, 10, 10, 12, 0, 0, 0, time.UTC), 4, "Special request", "pending", "UTC", 40).Return("", errors.New("slot is full"))
+
+	// サービス層メソッドの実行
+	_, err := reserationService.CreateReservation("user1", "2099-10-10 12:00:00", 4, "Special request")
+	assert.EqualError(t, err, "slot is full")
+}
+
+func TestService_UpdateReservation_SlotFull(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", Status: models.ReservationStatusPending}, nil)
+	reservationRepository.On("UpdateReservation", "reservation1", time.Date(2099, 10, 10, 19, 0, 0, 0, time.UTC), 6, "", 40).Return(errors.New("slot is full"))
+
+	// サービス層メソッドの実行
+	_, err := reserationService.UpdateReservation("reservation1", ptr("2099-10-10 19:00:00"), ptr(6), nil)
+	assert.EqualError(t, err, "slot is full")
+}
+
+func TestService_UpdateReservation_UnchangedDate(t *testing.T) {
+	// 枠の長さや営業時間を変更する前に受け付けた、現在の設定では受け付けない日時の予約
+	current := &models.ReservationData{
+		ID:              "reservation1",
+		ReservationDate: time.Date(2099, 10, 10, 18, 10, 0, 0, time.UTC),
+		NumPeople:       2,
+		Status:          models.ReservationStatusConfirmed,
+	}
+
+	testCases := []struct {
+		name            string
+		reservationDate *string
+		numPeople       *int
+		specialRequest  *string
+		expectedErr     string
+	}{
+		// 日時を変更しない場合は、枠と営業時間を確認しない
+		{"special request only", nil, nil, ptr("Birthday cake"), ""},
+		{"party size only", nil, ptr(4), nil, ""},
+		{"same date", ptr("2099-10-10 18:10:00"), ptr(4), nil, ""},
+		// 人数の上限は確認する
+		{"party too large", nil, ptr(12), nil, "party size exceeds maximum"},
+		// 日時を変更する場合は、変更後の日時を確認する
+		{"new date off boundary", ptr("2099-10-10 19:10:00"), nil, nil, "reservation date is not on a slot boundary"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			fetched := *current
+			reservationRepository.On("FetchReservationById", "reservation1").Return(&fetched, nil)
+			reservationRepository.On("UpdateReservation", "reservation1", mock.Anything, mock.Anything, mock.Anything, 40).Return(nil)
+
+			// サービス層メソッドの実行
+			_, err := reserationService.UpdateReservation("reservation1", tc.reservationDate, tc.numPeople, tc.specialRequest)
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				reservationRepository.AssertCalled(t, "UpdateReservation", "reservation1", current.ReservationDate, mock.Anything, mock.Anything, 40)
+			} else {
+				assert.EqualError(t, err, tc.expectedErr)
+				reservationRepository.AssertNotCalled(t, "UpdateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			// 日時を変更しない場合は、営業時間を取得しない
+			calendarRepository.AssertNotCalled(t, "FetchCalendar", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_FetchAvailability(t *testing.T) {
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, CapacityConfig{
+		SeatsPerSlot: 10,
+		SlotDuration: 6 * time.Hour,
+		MaxPartySize: 6,
+	}, time.UTC)
+
+	day := time.Date(2099, 10, 10, 0, 0, 0, 0, time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchSlotUsage", day, day.AddDate(0, 0, 1)).Return([]models.SlotUsage{
+		{Start: day.Add(6 * time.Hour), Seats: 8},
+		{Start: day.Add(12 * time.Hour), Seats: 4},
+	}, nil)
+
+	// サービス層メソッドの実行
+	availability, err := reserationService.FetchAvailability("2099-10-10", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, "2099-10-10", availability.Date)
+	assert.Equal(t, 4, availability.PartySize)
+	assert.Equal(t, []models.AvailabilitySlot{
+		{Start: day, StartLocal: day, AvailableSeats: 10},
+		{Start: day.Add(12 * time.Hour), StartLocal: day.Add(12 * time.Hour), AvailableSeats: 6},
+		{Start: day.Add(18 * time.Hour), StartLocal: day.Add(18 * time.Hour), AvailableSeats: 10},
+	}, availability.Slots)
+}
+
+func TestService_FetchAvailability_SkipsPastSlots(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchSlotUsage", mock.Anything, mock.Anything).Return([]models.SlotUsage{}, nil)
+
+	// サービス層メソッドの実行
+	availability, err := reserationService.FetchAvailability("2000-01-01", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, availability.Slots)
+}
+
+func TestService_FetchAvailability_ErrorCases(t *testing.T) {
+	testCases := []struct {
+		name        string
+		date        string
+		partySize   int
+		expectedErr string
+	}{
+		{"invalid date", "2099/10/10", 2, "invalid date format. Use 'YYYY-MM-DD'"},
+		{"invalid party size", "2099-10-10", 0, "invalid party size"},
+		{"party too large", "2099-10-10", 9, "party size exceeds maximum"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), time.UTC)
+
+			// サービス層メソッドの実行
+			_, err := reserationService.FetchAvailability(tc.date, tc.partySize)
+			assert.EqualError(t, err, tc.expectedErr)
+
+			reservationRepository.AssertNotCalled(t, "FetchSlotUsage", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestLoadCapacityConfig(t *testing.T) {
+	t.Setenv("RESERVATION_SEATS_PER_SLOT", "20")
+	t.Setenv("RESERVATION_SLOT_DURATION", "15m")
+	t.Setenv("RESERVATION_MAX_PARTY_SIZE", "6")
+	assert.Equal(t, CapacityConfig{SeatsPerSlot: 20, SlotDuration: 15 * time.Minute, MaxPartySize: 6}, LoadCapacityConfig())
+
+	// 不正な値はデフォルト値を使用する
+	t.Setenv("RESERVATION_SEATS_PER_SLOT", "0")
+	t.Setenv("RESERVATION_SLOT_DURATION", "7m")
+	t.Setenv("RESERVATION_MAX_PARTY_SIZE", "-1")
+	assert.Equal(t, DefaultCapacityConfig(), LoadCapacityConfig())
+}
@@ -0,0 +1,188 @@
+package services_reservations
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+	repositories_business_calendar "backend/repositories/business_calendar"
+	repositories_reservations "backend/repositories/reservations"
+	repositories_users "backend/repositories/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 2099-10-10は土曜日
+// 土曜日はランチとディナーの2つの時間帯で営業し、年末年始は休業する。
+var testCalendar = &models.BusinessCalendar{
+	Hours: []models.BusinessHoursData{
+		{Weekday: 6, OpenTime: "11:00", CloseTime: "14:00"},
+		{Weekday: 6, OpenTime: "17:00", CloseTime: "22:00"},
+	},
+	Overrides: []models.HoursOverrideData{
+		{Date: "2099-10-17", OpenTime: "17:00", CloseTime: "19:00", Reason: "Private event"},
+	},
+	Closures: []models.ClosureData{
+		{StartDate: "2099-12-31", EndDate: "2100-01-03", Reason: "New Year holidays"},
+	},
+}
+
+func TestService_CreateReservation_BusinessHours(t *testing.T) {
+	testCases := []struct {
+		name        string
+		date        string
+		expectedErr string
+	}{
+		{"lunch", "2099-10-10 11:00:00", ""},
+		{"last dinner slot", "2099-10-10 21:30:00", ""},
+		{"before opening", "2099-10-10 10:30:00", "outside business hours"},
+		{"between periods", "2099-10-10 15:00:00", "outside business hours"},
+		{"slot ends after closing", "2099-10-10 22:00:00", "outside business hours"},
+		{"no hours on weekday", "2099-10-11 12:00:00", "closed on reservation date"},
+		{"override", "2099-10-17 18:00:00", ""},
+		{"outside override", "2099-10-17 12:00:00", "outside business hours"},
+		{"closure", "2100-01-01 12:00:00", "closed on reservation date"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+			calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(testCalendar, nil)
+			reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1"}, nil)
+			reservationRepository.On("CreateReservation", "user1", mock.AnythingOfType("time.Time"), 2, "Special request", "pending", "UTC", 40).Return("reservation1", nil)
+
+			// サービス層メソッドの実行
+			_, err := reserationService.CreateReservation("user1", tc.date, 2, "Special request")
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedErr)
+			reservationRepository.AssertNotCalled(t, "CreateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestService_CreateReservation_NoBusinessHours(t *testing.T) {
+	// 曜日ごとの営業時間を設定していない場合は営業時間を制限しないが、休業期間は適用する
+	emptyCalendar := &models.BusinessCalendar{Closures: testCalendar.Closures}
+
+	testCases := []struct {
+		name        string
+		date        string
+		expectedErr string
+	}{
+		{"any time", "2099-10-11 03:00:00", ""},
+		{"closure", "2100-01-01 12:00:00", "closed on reservation date"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// モックリポジトリをインスタンス化
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+			calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(emptyCalendar, nil)
+			reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+			// モックの挙動を設定
+			userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1"}, nil)
+			reservationRepository.On("CreateReservation", "user1", mock.AnythingOfType("time.Time"), 2, "Special request", "pending", "UTC", 40).Return("reservation1", nil)
+
+			// サービス層メソッドの実行
+			_, err := reserationService.CreateReservation("user1", tc.date, 2, "Special request")
+			if tc.expectedErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.EqualError(t, err, tc.expectedErr)
+		})
+	}
+}
+
+func TestService_FetchAvailability_NoBusinessHours(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(&models.BusinessCalendar{}, nil)
+	reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchSlotUsage", mock.Anything, mock.Anything).Return([]models.SlotUsage{}, nil)
+
+	// サービス層メソッドの実行
+	availability, err := reserationService.FetchAvailability("2099-10-11", 2)
+	assert.NoError(t, err)
+	assert.False(t, availability.Closed)
+	assert.Len(t, availability.Slots, 48)
+}
+
+func TestService_UpdateReservation_BusinessHours(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(testCalendar, nil)
+	reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", Status: models.ReservationStatusConfirmed}, nil)
+
+	// サービス層メソッドの実行
+	_, err := reserationService.UpdateReservation("reservation1", ptr("2099-10-10 03:00:00"), ptr(2), nil)
+	assert.EqualError(t, err, "outside business hours")
+
+	reservationRepository.AssertNotCalled(t, "UpdateReservation", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestService_FetchAvailability_BusinessHours(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(testCalendar, nil)
+	reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+	day := time.Date(2099, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchSlotUsage", day, day.AddDate(0, 0, 1)).Return([]models.SlotUsage{
+		{Start: day.Add(18 * time.Hour), Seats: 38},
+	}, nil)
+
+	// サービス層メソッドの実行
+	// 祝日の設定により17:00から19:00のみ営業する
+	availability, err := reserationService.FetchAvailability("2099-10-17", 4)
+	assert.NoError(t, err)
+	assert.False(t, availability.Closed)
+	assert.Equal(t, []models.AvailabilitySlot{
+		{Start: day.Add(17 * time.Hour), StartLocal: day.Add(17 * time.Hour), AvailableSeats: 40},
+		{Start: day.Add(17*time.Hour + 30*time.Minute), StartLocal: day.Add(17*time.Hour + 30*time.Minute), AvailableSeats: 40},
+		{Start: day.Add(18*time.Hour + 30*time.Minute), StartLocal: day.Add(18*time.Hour + 30*time.Minute), AvailableSeats: 40},
+	}, availability.Slots)
+}
+
+func TestService_FetchAvailability_Closed(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	calendarRepository := new(repositories_business_calendar.MockBusinessCalendarRepository)
+	calendarRepository.On("FetchCalendar", mock.Anything, mock.Anything).Return(testCalendar, nil)
+	reserationService := NewReservationService(userRepository, reservationRepository, calendarRepository, false, DefaultCapacityConfig(), time.UTC)
+
+	// サービス層メソッドの実行
+	availability, err := reserationService.FetchAvailability("2100-01-02", 2)
+	assert.NoError(t, err)
+	assert.True(t, availability.Closed)
+	assert.Empty(t, availability.Slots)
+
+	reservationRepository.AssertNotCalled(t, "FetchSlotUsage", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,145 @@
+package services_reservations
+
+import (
+	"testing"
+	"time"
+
+	"backend/models"
+	repositories_reservations "backend/repositories/reservations"
+	repositories_users "backend/repositories/users"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestService_CreateReservation_TimeZone(t *testing.T) {
+	testCases := []struct {
+		name     string
+		date     string
+		expected time.Time
+	}{
+		// 従来の形式は店舗のタイムゾーンの時刻として扱う
+		{"legacy format", "2099-10-10 12:00:00", time.Date(2099, 10, 10, 3, 0, 0, 0, time.UTC)},
+		{"rfc3339 with venue offset", "2099-10-10T12:00:00+09:00", time.Date(2099, 10, 10, 3, 0, 0, 0, time.UTC)},
+		{"rfc3339 in utc", "2099-10-10T03:00:00Z", time.Date(2099, 10, 10, 3, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			location, err := time.LoadLocation("Asia/Tokyo")
+			assert.NoError(t, err)
+			userRepository := new(repositories_users.MockUserRepository)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), location)
+
+			// モックの挙動を設定
+			userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1"}, nil)
+			reservationRepository.On("CreateReservation", "user1", mock.MatchedBy(tc.expected.Equal), 2, "", "pending", "Asia/Tokyo", 40).Return("reservation1", nil)
+
+			// サービス層メソッドの実行
+			id, err := reserationService.CreateReservation("user1", tc.date, 2, "")
+			assert.NoError(t, err)
+			assert.Equal(t, "reservation1", id)
+
+			reservationRepository.AssertExpectations(t)
+		})
+	}
+}
+
+func TestService_CreateReservation_TimeZoneSlotBoundary(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Kolkata")
+	assert.NoError(t, err)
+	userRepository := new(repositories_users.MockUserRepository)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(userRepository, reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), location)
+
+	// モックの挙動を設定
+	userRepository.On("FetchUserById", "user1").Return(&models.UserData{ID: "user1"}, nil)
+
+	// 店舗の時計で12:00はUTCでは06:30となるが、枠の開始時刻として扱う
+	reservationRepository.On("CreateReservation", "user1", mock.AnythingOfType("time.Time"), 2, "", "pending", "Asia/Kolkata", 40).Return("reservation1", nil)
+	_, err = reserationService.CreateReservation("user1", "2099-10-10T06:30:00Z", 2, "")
+	assert.NoError(t, err)
+
+	// 店舗の時計で12:15は枠の開始時刻ではない
+	_, err = reserationService.CreateReservation("user1", "2099-10-10T06:45:00Z", 2, "")
+	assert.EqualError(t, err, "reservation date is not on a slot boundary")
+}
+
+func TestService_UpdateReservation_TimeZone(t *testing.T) {
+	location, err := time.LoadLocation("Asia/Tokyo")
+	assert.NoError(t, err)
+	reservationRepository := new(repositories_reservations.MockReservationRepository)
+	reserationService := NewReservationService(new(repositories_users.MockUserRepository), reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), location)
+	expected := time.Date(2099, 10, 10, 10, 0, 0, 0, time.UTC)
+
+	// モックの挙動を設定
+	reservationRepository.On("FetchReservationById", "reservation1").Return(&models.ReservationData{ID: "reservation1", TimeZone: "Asia/Tokyo", Status: models.ReservationStatusPending}, nil)
+	reservationRepository.On("UpdateReservation", "reservation1", mock.MatchedBy(expected.Equal), 4, "", 40).Return(nil)
+
+	// サービス層メソッドの実行
+	reservation, err := reserationService.UpdateReservation("reservation1", ptr("2099-10-10 19:00:00"), ptr(4), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, reservation.ReservationDate)
+	assert.Equal(t, time.Date(2099, 10, 10, 19, 0, 0, 0, reservation.LocalReservationDate().Location()), reservation.LocalReservationDate())
+}
+
+func TestService_FetchAvailability_DaylightSaving(t *testing.T) {
+	testCases := []struct {
+		name          string
+		date          string
+		expectedSlots int
+	}{
+		// 2099-03-08は夏時間の開始日で、02:00から02:59の時刻は存在しない
+		{"spring forward", "2099-03-08", 46},
+		// 2099-11-01は夏時間の終了日で、01:00から01:59の時刻は2回ある
+		{"fall back", "2099-11-01", 48},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			location, err := time.LoadLocation("America/New_York")
+			assert.NoError(t, err)
+			reservationRepository := new(repositories_reservations.MockReservationRepository)
+			reserationService := NewReservationService(new(repositories_users.MockUserRepository), reservationRepository, newOpenCalendarRepository(), false, DefaultCapacityConfig(), location)
+
+			// モックの挙動を設定
+			reservationRepository.On("FetchSlotUsage", mock.Anything, mock.Anything).Return([]models.SlotUsage{}, nil)
+
+			// サービス層メソッドの実行
+			availability, err := reserationService.FetchAvailability(tc.date, 2)
+			assert.NoError(t, err)
+			assert.Equal(t, "America/New_York", availability.TimeZone)
+			assert.Len(t, availability.Slots, tc.expectedSlots)
+
+			// 枠の開始時刻は店舗の時計で30分ごとに並び、UTCの時刻と一致する
+			for i, slot := range availability.Slots {
+				assert.Equal(t, location, slot.StartLocal.Location())
+				assert.Equal(t, time.UTC, slot.Start.Location())
+				assert.True(t, slot.Start.Equal(slot.StartLocal))
+				assert.Zero(t, slot.StartLocal.Minute()%30)
+				if i > 0 {
+					assert.True(t, slot.Start.After(availability.Slots[i-1].Start))
+				}
+			}
+		})
+	}
+}
+
+func TestLoadVenueLocation(t *testing.T) {
+	// 未設定の場合はUTCを使用する
+	t.Setenv("VENUE_TIME_ZONE", "")
+	location, err := LoadVenueLocation()
+	assert.NoError(t, err)
+	assert.Equal(t, time.UTC, location)
+
+	t.Setenv("VENUE_TIME_ZONE", "Asia/Tokyo")
+	location, err = LoadVenueLocation()
+	assert.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", location.String())
+
+	// 存在しないタイムゾーンはエラーとする
+	t.Setenv("VENUE_TIME_ZONE", "Mars/Olympus_Mons")
+	_, err = LoadVenueLocation()
+	assert.Error(t, err)
+}
@@ -2,30 +2,106 @@ package services_reservations
 
 import (
 	"backend/models"
+	repositories_business_calendar "backend/repositories/business_calendar"
 	repositories_reservations "backend/repositories/reservations"
 	repositories_users "backend/repositories/users"
+	"backend/utils"
+	"fmt"
+	"os"
+	"time"
 )
 
 // ReservationServiceインターフェース
 type ReservationService interface {
 	FetchReservations() ([]models.ReservationData, error)
 	FetchReservationById(id string) (*models.ReservationData, error)
-	FetchReservationByUserId(userId string) (*models.ReservationData, error)
-	CreateReservation(userId, reservationDate string, numPeople int, specialRequest, status string) (string, error)
+	FetchReservationsByUserId(userId string) ([]models.ReservationData, error)
+	SearchReservationsByUserId(userId string, params models.ReservationSearchParams) (*models.ReservationPage, error)
+	CreateReservation(userId, reservationDate string, numPeople int, specialRequest string) (string, error)
+	UpdateReservation(id string, reservationDate *string, numPeople *int, specialRequest *string) (*models.ReservationData, error)
+	ChangeReservationStatus(id, status string) (*models.ReservationData, error)
+	FetchAvailability(date string, partySize int) (*models.Availability, error)
+}
+
+// 店舗の席数と予約枠の設定
+type CapacityConfig struct {
+	SeatsPerSlot int           // 1つの予約枠で受け付ける席数
+	SlotDuration time.Duration // 予約枠の長さ。予約日時は枠の開始時刻に合わせる
+	MaxPartySize int           // 1件の予約で受け付ける最大人数
+}
+
+// デフォルトの設定を返す。
+func DefaultCapacityConfig() CapacityConfig {
+	return CapacityConfig{
+		SeatsPerSlot: 40,
+		SlotDuration: 30 * time.Minute,
+		MaxPartySize: 8,
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定または不正な項目はデフォルト値を使用する。
+func LoadCapacityConfig() CapacityConfig {
+	defaults := DefaultCapacityConfig()
+	config := CapacityConfig{
+		SeatsPerSlot: utils.GetEnvInt("RESERVATION_SEATS_PER_SLOT", defaults.SeatsPerSlot),
+		SlotDuration: utils.GetEnvDuration("RESERVATION_SLOT_DURATION", defaults.SlotDuration),
+		MaxPartySize: utils.GetEnvInt("RESERVATION_MAX_PARTY_SIZE", defaults.MaxPartySize),
+	}
+	if config.SeatsPerSlot <= 0 {
+		config.SeatsPerSlot = defaults.SeatsPerSlot
+	}
+	// 1日を等分できない長さの枠は扱わない
+	if config.SlotDuration <= 0 || (24*time.Hour)%config.SlotDuration != 0 {
+		config.SlotDuration = defaults.SlotDuration
+	}
+	if config.MaxPartySize <= 0 || config.MaxPartySize > config.SeatsPerSlot {
+		config.MaxPartySize = defaults.MaxPartySize
+		if config.MaxPartySize > config.SeatsPerSlot {
+			config.MaxPartySize = config.SeatsPerSlot
+		}
+	}
+	return config
+}
+
+// 環境変数VENUE_TIME_ZONEから店舗のタイムゾーンを読み込む。
+// IANAのタイムゾーン名(Asia/Tokyoなど)を指定し、未設定の場合はUTCを使用する。
+func LoadVenueLocation() (*time.Location, error) {
+	name := os.Getenv("VENUE_TIME_ZONE")
+	if name == "" {
+		return time.UTC, nil
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VENUE_TIME_ZONE %q: %w", name, err)
+	}
+	return location, nil
 }
 
 // ReservationServiceImplはReservationServiceインターフェースを実装する
 type ReservationServiceImpl struct {
 	UserRepository        repositories_users.UserRepository
 	ReservationRepository repositories_reservations.ReservationRepository
+	CalendarRepository    repositories_business_calendar.BusinessCalendarRepository // 営業時間と休業日の判定に使用する
+	RequireVerifiedEmail  bool                                                      // trueの場合、メールアドレスが未確認のユーザーの予約を拒否する
+	Capacity              CapacityConfig
+	Location              *time.Location // 店舗のタイムゾーン。営業時間と予約枠はこのタイムゾーンの時計の時刻で扱う
 }
 
 func NewReservationService(
 	userRepository repositories_users.UserRepository,
 	reservationRepository repositories_reservations.ReservationRepository,
+	calendarRepository repositories_business_calendar.BusinessCalendarRepository,
+	requireVerifiedEmail bool,
+	capacity CapacityConfig,
+	location *time.Location,
 ) ReservationService {
 	return &ReservationServiceImpl{
 		UserRepository:        userRepository,
 		ReservationRepository: reservationRepository,
+		CalendarRepository:    calendarRepository,
+		RequireVerifiedEmail:  requireVerifiedEmail,
+		Capacity:              capacity,
+		Location:              location,
 	}
 }
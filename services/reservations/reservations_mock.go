@@ -27,15 +27,47 @@ func (m *MockReservationService) FetchReservationById(id string) (*models.Reserv
 	return args.Get(0).(*models.ReservationData), args.Error(1)
 }
 
-func (m *MockReservationService) FetchReservationByUserId(userId string) (*models.ReservationData, error) {
+func (m *MockReservationService) FetchReservationsByUserId(userId string) ([]models.ReservationData, error) {
 	args := m.Called(userId)
+	if args.Get(0) != nil {
+		return args.Get(0).([]models.ReservationData), args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockReservationService) SearchReservationsByUserId(userId string, params models.ReservationSearchParams) (*models.ReservationPage, error) {
+	args := m.Called(userId, params)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).(*models.ReservationData), args.Error(1)
+	return args.Get(0).(*models.ReservationPage), args.Error(1)
 }
 
-func (m *MockReservationService) CreateReservation(userId, reservationDate string, numPeople int, specialRequest, status string) (string, error) {
-	args := m.Called(userId, reservationDate, numPeople, specialRequest, status)
+func (m *MockReservationService) CreateReservation(userId, reservationDate string, numPeople int, specialRequest string) (string, error) {
+	args := m.Called(userId, reservationDate, numPeople, specialRequest)
 	return args.String(0), args.Error(1)
 }
+
+func (m *MockReservationService) UpdateReservation(id string, reservationDate *string, numPeople *int, specialRequest *string) (*models.ReservationData, error) {
+	args := m.Called(id, reservationDate, numPeople, specialRequest)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReservationData), args.Error(1)
+}
+
+func (m *MockReservationService) ChangeReservationStatus(id, status string) (*models.ReservationData, error) {
+	args := m.Called(id, status)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ReservationData), args.Error(1)
+}
+
+func (m *MockReservationService) FetchAvailability(date string, partySize int) (*models.Availability, error) {
+	args := m.Called(date, partySize)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Availability), args.Error(1)
+}
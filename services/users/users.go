@@ -6,6 +6,8 @@ import (
 	"errors"
 	"log"
 	"net/mail"
+
+	"github.com/jackc/pgx/v4"
 )
 
 // Supabaseから全ユーザーを取得し、ユーザーリストを返す。
@@ -29,18 +31,109 @@ func (s *UserServiceImpl) FetchUserByEmailAndPassword(email, password string) (*
 	}
 	log.Println("Email and password are valid")
 
-	user, err := s.UserRepository.FetchUserByEmailAndPassword(email, password)
+	user, err := s.UserRepository.FetchUserByEmail(email)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, pgx.ErrNoRows) {
 			log.Printf("User not found for email: %s", email)
+			// 応答時間からユーザーの存在有無が分からないよう、ダミーのハッシュで照合を行う
+			s.PasswordHasher.Verify(s.dummyPasswordHash(), password)
 			return nil, errors.New("user not found")
 		}
 		return nil, err
 	}
 
+	// パスワードハッシュとの照合
+	match, needsRehash, err := s.PasswordHasher.Verify(user.Password, password)
+	if err != nil {
+		log.Printf("Failed to verify password: %v", err)
+		return nil, err
+	}
+	if !match {
+		// ユーザーの存在有無が分からないように、見つからない場合と同じエラーを返す
+		log.Printf("Password mismatch for email: %s", email)
+		return nil, errors.New("user not found")
+	}
+
+	// 平文や古いコストで保存されている場合は現在の設定で再ハッシュする
+	// 失敗してもログインは継続し、次回のログインで再試行する
+	if needsRehash {
+		s.rehashPassword(user.ID, password)
+	}
+
+	user.Password = ""
 	return user, nil
 }
 
+// 現在のパスワードを確認した上で、新しいパスワードに変更する。
+// 成功した場合はnilを返し、失敗した場合はエラーを返す。
+func (s *UserServiceImpl) ChangePassword(id, currentPassword, newPassword string) error {
+	// バリデーション: 現在のパスワードと新しいパスワードが空でないかを確認
+	if id == "" || currentPassword == "" || newPassword == "" {
+		log.Printf("Current password and new password are required")
+		return errors.New("current password and new password are required")
+	}
+
+	user, err := s.UserRepository.FetchUserById(id)
+	if err != nil || user == nil {
+		log.Printf("User not found: %s", id)
+		return errors.New("user not found")
+	}
+
+	// 現在のパスワードを照合
+	match, _, err := s.PasswordHasher.Verify(user.Password, currentPassword)
+	if err != nil || !match {
+		log.Printf("Current password mismatch for user: %s", id)
+		return errors.New("invalid current password")
+	}
+
+	hashedPassword, err := s.PasswordHasher.Hash(newPassword)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return errors.New("failed to change password")
+	}
+
+	err = s.UserRepository.UpdatePassword(id, hashedPassword)
+	if err != nil {
+		log.Printf("Error updating password: %v", err)
+		return errors.New("failed to change password")
+	}
+
+	log.Println("Password changed successfully")
+	return nil
+}
+
+// 存在しないユーザーの照合に使用するダミーのパスワードハッシュを返す
+// 初回の呼び出し時に現在の設定でハッシュ化し、以降は同じ値を使用する。
+func (s *UserServiceImpl) dummyPasswordHash() string {
+	s.dummyHashOnce.Do(func() {
+		hashedPassword, err := s.PasswordHasher.Hash("dummy-password")
+		if err != nil {
+			log.Printf("Failed to create dummy password hash: %v", err)
+			return
+		}
+		s.dummyHash = hashedPassword
+	})
+	return s.dummyHash
+}
+
+// パスワードを現在の設定で再ハッシュして保存する
+func (s *UserServiceImpl) rehashPassword(id, password string) {
+	log.Printf("Rehashing password for user: %s", id)
+
+	hashedPassword, err := s.PasswordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Failed to rehash password: %v", err)
+		return
+	}
+
+	if err := s.UserRepository.UpdatePassword(id, hashedPassword); err != nil {
+		log.Printf("Failed to save rehashed password: %v", err)
+		return
+	}
+
+	log.Println("Password rehashed successfully")
+}
+
 // 指定されたIDに対応するユーザーを取得する。
 // ユーザーが見つからない場合、エラーを返す。
 func (s *UserServiceImpl) FetchUserById(id string) (*models.UserData, error) {
@@ -78,7 +171,14 @@ func (s *UserServiceImpl) CreateUser(name, email, password string) error {
 	}
 	log.Println("User does not exist")
 
-	err = s.UserRepository.CreateUser(name, email, password)
+	// パスワードをハッシュ化してから保存する
+	hashedPassword, err := s.PasswordHasher.Hash(password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		return errors.New("failed to create user")
+	}
+
+	err = s.UserRepository.CreateUser(name, email, hashedPassword)
 	if err != nil {
 		log.Printf("Error creating user: %v", err)
 		return errors.New("failed to create user")
@@ -86,3 +186,29 @@ func (s *UserServiceImpl) CreateUser(name, email, password string) error {
 
 	return nil
 }
+
+// 指定されたユーザーのロールを変更する。
+// 発行済みのアクセストークンには変更前のロールが含まれるため、呼び出し元でユーザーのセッションを失効させる。
+func (s *UserServiceImpl) UpdateUserRole(id, role string) error {
+	// バリデーション：IDが空でないことを確認
+	if id == "" {
+		log.Printf("ID is required")
+		return errors.New("id is required")
+	}
+	// バリデーション：定義済みのロールであることを確認
+	if !models.IsValidRole(role) {
+		log.Printf("Invalid role: %s", role)
+		return errors.New("invalid role")
+	}
+
+	err := s.UserRepository.UpdateUserRole(id, role)
+	if err != nil {
+		if err.Error() == "user not found" {
+			return err
+		}
+		log.Printf("Error updating role: %v", err)
+		return errors.New("failed to update role")
+	}
+
+	return nil
+}
@@ -2,17 +2,38 @@ package services_users
 
 import (
 	"backend/models"
+	"backend/passwords"
 	repositories_users "backend/repositories/users"
 	"database/sql"
+	"errors"
+	"strings"
 	"testing"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// テスト用に計算コストを下げたパスワードハッシャー
+var testPasswordHasher = passwords.NewHasher(passwords.Config{
+	Algorithm:  passwords.AlgorithmBcrypt,
+	BcryptCost: bcrypt.MinCost,
+})
+
+// テスト用のパスワードハッシュを生成
+func mustHash(t *testing.T, password string) string {
+	hashed, err := testPasswordHasher.Hash(password)
+	if err != nil {
+		t.Fatalf("Failed to hash password: %v", err)
+	}
+	return hashed
+}
+
 func TestService_FetchUsers(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// モックの挙動を設定
 	mockUsers := []models.UserData{
@@ -39,7 +60,7 @@ func TestService_FetchUsers(t *testing.T) {
 func TestService_FetchUsers_EmptyList(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 2. ユーザーが存在しない場合
 	mockUserRepository.On("FetchUsers").Return([]models.UserData{}, nil)
@@ -58,15 +79,16 @@ func TestService_FetchUsers_EmptyList(t *testing.T) {
 func TestService_FetchUserByEmailAndPassword(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// モックの挙動を設定
 	mockUser := &models.UserData{
-		ID:    "1",
-		Name:  "John Doe",
-		Email: "john@example.com",
+		ID:       "1",
+		Name:     "John Doe",
+		Email:    "john@example.com",
+		Password: mustHash(t, "password123"),
 	}
-	mockUserRepository.On("FetchUserByEmailAndPassword", "john@example.com", "password123").Return(mockUser, nil)
+	mockUserRepository.On("FetchUserByEmail", "john@example.com").Return(mockUser, nil)
 
 	// サービス層メソッドの実行
 	user, err := userService.FetchUserByEmailAndPassword("john@example.com", "password123")
@@ -77,6 +99,10 @@ func TestService_FetchUserByEmailAndPassword(t *testing.T) {
 	// データが期待通りか確認
 	assert.NotNil(t, user)                 // userがnilでないことを確認
 	assert.Equal(t, "John Doe", user.Name) // ユーザーの名前が期待通りかを確認
+	assert.Empty(t, user.Password)         // パスワードハッシュが返されないことを確認
+
+	// 再ハッシュが不要なため更新されないことを確認
+	mockUserRepository.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
 
 	// モックが期待通りに呼び出されたかを確認
 	mockUserRepository.AssertExpectations(t)
@@ -85,7 +111,7 @@ func TestService_FetchUserByEmailAndPassword(t *testing.T) {
 func TestService_FetchUserByEmailAndPassword_InvalidCases(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 1. メールアドレスとパスワードが空の場合
 	_, err := userService.FetchUserByEmailAndPassword("", "")
@@ -98,12 +124,67 @@ func TestService_FetchUserByEmailAndPassword_InvalidCases(t *testing.T) {
 	assert.Equal(t, "invalid email format", err.Error())
 
 	// 3. ユーザーが見つからない場合
-	mockUserRepository.On("FetchUserByEmailAndPassword", "john@example.com", "password123").Return(nil, sql.ErrNoRows)
+	mockUserRepository.On("FetchUserByEmail", "john@example.com").Return(nil, sql.ErrNoRows)
 
 	_, err = userService.FetchUserByEmailAndPassword("john@example.com", "password123")
 	assert.Error(t, err)
 	assert.Equal(t, "user not found", err.Error())
 
+	// 4. pgxでユーザーが見つからない場合も同じエラーを返す
+	mockUserRepository.On("FetchUserByEmail", "pgx@example.com").Return(nil, pgx.ErrNoRows)
+
+	_, err = userService.FetchUserByEmailAndPassword("pgx@example.com", "password123")
+	assert.Error(t, err)
+	assert.Equal(t, "user not found", err.Error())
+
+	// 5. パスワードが一致しない場合
+	mockUserRepository.On("FetchUserByEmail", "jane@example.com").Return(&models.UserData{
+		ID:       "2",
+		Email:    "jane@example.com",
+		Password: mustHash(t, "password123"),
+	}, nil)
+
+	_, err = userService.FetchUserByEmailAndPassword("jane@example.com", "wrong-password")
+	assert.Error(t, err)
+	assert.Equal(t, "user not found", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockUserRepository.AssertExpectations(t)
+}
+
+func TestService_FetchUserByEmailAndPassword_Rehash(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockUserRepository := new(repositories_users.MockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
+
+	// 1. 平文で保存されている旧データはログイン時にハッシュ化される
+	mockUserRepository.On("FetchUserByEmail", "john@example.com").Return(&models.UserData{
+		ID:       "1",
+		Name:     "John Doe",
+		Email:    "john@example.com",
+		Password: "password123",
+	}, nil)
+	mockUserRepository.On("UpdatePassword", "1", mock.MatchedBy(func(hashed string) bool {
+		return strings.HasPrefix(hashed, "$2a$")
+	})).Return(nil).Once()
+
+	user, err := userService.FetchUserByEmailAndPassword("john@example.com", "password123")
+	assert.NoError(t, err)
+	assert.Equal(t, "John Doe", user.Name)
+
+	// 2. 再ハッシュの保存に失敗してもログインは成功する
+	mockUserRepository.On("FetchUserByEmail", "jane@example.com").Return(&models.UserData{
+		ID:       "2",
+		Name:     "Jane Doe",
+		Email:    "jane@example.com",
+		Password: "password123",
+	}, nil)
+	mockUserRepository.On("UpdatePassword", "2", mock.Anything).Return(errors.New("update failed")).Once()
+
+	user, err = userService.FetchUserByEmailAndPassword("jane@example.com", "password123")
+	assert.NoError(t, err)
+	assert.Equal(t, "Jane Doe", user.Name)
+
 	// モックが期待通りに呼び出されたかを確認
 	mockUserRepository.AssertExpectations(t)
 }
@@ -111,7 +192,7 @@ func TestService_FetchUserByEmailAndPassword_InvalidCases(t *testing.T) {
 func TestService_FetchUserById(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 正常系: ユーザーが見つかる場合
 	expectedUser := &models.UserData{ID: "1", Name: "John Doe", Email: "john@example.com"}
@@ -130,7 +211,7 @@ func TestService_FetchUserById(t *testing.T) {
 func TestService_FetchUserById_InvalidCases(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 異常系: ユーザーが見つからない場合
 	mockUserRepository.On("FetchUserById", "2").Return(nil, sql.ErrNoRows)
@@ -148,7 +229,7 @@ func TestService_FetchUserById_InvalidCases(t *testing.T) {
 func TestService_FetchUserByEmail(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 正常系: ユーザーが見つかる場合
 	expectedUser := &models.UserData{ID: "1", Name: "John Doe", Email: "john@example.com"}
@@ -167,7 +248,7 @@ func TestService_FetchUserByEmail(t *testing.T) {
 func TestService_FetchUserByEmail_InvalidCases(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 異常系: ユーザーが見つからない場合
 	mockUserRepository.On("FetchUserByEmail", "unknown@example.com").Return(nil, sql.ErrNoRows)
@@ -7,16 +7,25 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
+// 指定したパスワードのハッシュであるかを判定するマッチャー
+func hashOf(password string) interface{} {
+	return mock.MatchedBy(func(hashed string) bool {
+		match, _, err := testPasswordHasher.Verify(hashed, password)
+		return err == nil && match && hashed != password
+	})
+}
+
 func TestService_CreateUser(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// モックの挙動を設定
 	mockUserRepository.On("FetchUserByEmail", "john@example.com").Return(nil, nil) // ユーザーが存在しない場合
-	mockUserRepository.On("CreateUser", "John Doe", "john@example.com", "password123").Return(nil)
+	mockUserRepository.On("CreateUser", "John Doe", "john@example.com", hashOf("password123")).Return(nil)
 
 	// サービス層メソッドの実行
 	err := userService.CreateUser("John Doe", "john@example.com", "password123")
@@ -31,7 +40,7 @@ func TestService_CreateUser(t *testing.T) {
 func TestService_CreateUser_InvalidCases(t *testing.T) {
 	// モックリポジトリをインスタンス化
 	mockUserRepository := new(repositories_users.MockUserRepository)
-	userService := NewUserService(mockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
 
 	// 1. 既にユーザーが存在する場合
 	existingUser := &models.UserData{ID: "1", Name: "John Doe", Email: "john@example.com"}
@@ -56,7 +65,7 @@ func TestService_CreateUser_InvalidCases(t *testing.T) {
 
 	// 3. ユーザー追加に失敗する場合
 	mockUserRepository.On("FetchUserByEmail", "newuser@example.com").Return(nil, nil)
-	mockUserRepository.On("CreateUser", "New User", "newuser@example.com", "password123").Return(errors.New("insert failed"))
+	mockUserRepository.On("CreateUser", "New User", "newuser@example.com", hashOf("password123")).Return(errors.New("insert failed"))
 
 	err = userService.CreateUser("New User", "newuser@example.com", "password123")
 	assert.Error(t, err)
@@ -65,3 +74,108 @@ func TestService_CreateUser_InvalidCases(t *testing.T) {
 	// モックが期待通りに呼び出されたかを確認
 	mockUserRepository.AssertExpectations(t)
 }
+
+func TestService_ChangePassword(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockUserRepository := new(repositories_users.MockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
+
+	// モックの挙動を設定
+	mockUserRepository.On("FetchUserById", "1").Return(&models.UserData{ID: "1", Password: mustHash(t, "old-password")}, nil)
+	mockUserRepository.On("UpdatePassword", "1", hashOf("new-password")).Return(nil)
+
+	// サービス層メソッドの実行
+	err := userService.ChangePassword("1", "old-password", "new-password")
+
+	// エラーチェック
+	assert.NoError(t, err)
+
+	// モックが期待通りに呼び出されたかを確認
+	mockUserRepository.AssertExpectations(t)
+}
+
+func TestService_ChangePassword_InvalidCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockUserRepository := new(repositories_users.MockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
+
+	// 1. 入力が空の場合
+	err := userService.ChangePassword("1", "", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "current password and new password are required", err.Error())
+
+	// 2. ユーザーが存在しない場合
+	mockUserRepository.On("FetchUserById", "2").Return(nil, errors.New("no rows in result set"))
+
+	err = userService.ChangePassword("2", "old-password", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "user not found", err.Error())
+
+	// 3. 現在のパスワードが一致しない場合
+	mockUserRepository.On("FetchUserById", "1").Return(&models.UserData{ID: "1", Password: mustHash(t, "old-password")}, nil)
+
+	err = userService.ChangePassword("1", "wrong-password", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid current password", err.Error())
+
+	// 4. 更新に失敗した場合
+	mockUserRepository.On("UpdatePassword", "1", mock.Anything).Return(errors.New("update failed"))
+
+	err = userService.ChangePassword("1", "old-password", "new-password")
+	assert.Error(t, err)
+	assert.Equal(t, "failed to change password", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockUserRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateUserRole(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockUserRepository := new(repositories_users.MockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
+
+	// モックの挙動を設定
+	mockUserRepository.On("UpdateUserRole", "1", models.RoleStaff).Return(nil)
+
+	// サービス層メソッドの実行
+	err := userService.UpdateUserRole("1", models.RoleStaff)
+
+	// エラーチェック
+	assert.NoError(t, err)
+
+	// モックが期待通りに呼び出されたかを確認
+	mockUserRepository.AssertExpectations(t)
+}
+
+func TestService_UpdateUserRole_InvalidCases(t *testing.T) {
+	// モックリポジトリをインスタンス化
+	mockUserRepository := new(repositories_users.MockUserRepository)
+	userService := NewUserService(mockUserRepository, testPasswordHasher)
+
+	// 1. IDが空の場合
+	err := userService.UpdateUserRole("", models.RoleStaff)
+	assert.Error(t, err)
+	assert.Equal(t, "id is required", err.Error())
+
+	// 2. 未定義のロールの場合
+	err = userService.UpdateUserRole("1", "owner")
+	assert.Error(t, err)
+	assert.Equal(t, "invalid role", err.Error())
+
+	// 3. ユーザーが存在しない場合
+	mockUserRepository.On("UpdateUserRole", "2", models.RoleAdmin).Return(errors.New("user not found"))
+
+	err = userService.UpdateUserRole("2", models.RoleAdmin)
+	assert.Error(t, err)
+	assert.Equal(t, "user not found", err.Error())
+
+	// 4. 更新に失敗した場合
+	mockUserRepository.On("UpdateUserRole", "1", models.RoleAdmin).Return(errors.New("database error"))
+
+	err = userService.UpdateUserRole("1", models.RoleAdmin)
+	assert.Error(t, err)
+	assert.Equal(t, "failed to update role", err.Error())
+
+	// モックが期待通りに呼び出されたかを確認
+	mockUserRepository.AssertExpectations(t)
+}
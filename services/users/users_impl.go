@@ -2,7 +2,9 @@ package services_users
 
 import (
 	"backend/models"
+	"backend/passwords"
 	repositories_users "backend/repositories/users"
+	"sync"
 )
 
 // UserServiceインターフェース
@@ -12,17 +14,25 @@ type UserService interface {
 	FetchUserById(id string) (*models.UserData, error)
 	FetchUserByEmail(email string) (*models.UserData, error)
 	CreateUser(name, email, password string) error
+	ChangePassword(id, currentPassword, newPassword string) error
+	UpdateUserRole(id, role string) error
 }
 
 // UserServiceImplはUserServiceインターフェースを実装する
 type UserServiceImpl struct {
 	UserRepository repositories_users.UserRepository
+	PasswordHasher passwords.Hasher
+
+	dummyHashOnce sync.Once
+	dummyHash     string // 存在しないユーザーの照合に使用するダミーのハッシュ
 }
 
 func NewUserService(
 	userRepository repositories_users.UserRepository,
+	passwordHasher passwords.Hasher,
 ) UserService {
 	return &UserServiceImpl{
 		UserRepository: userRepository,
+		PasswordHasher: passwordHasher,
 	}
 }
@@ -47,3 +47,13 @@ func (m *MockUserService) CreateUser(name, email, password string) error {
 	args := m.Called(name, email, password)
 	return args.Error(0)
 }
+
+func (m *MockUserService) ChangePassword(id, currentPassword, newPassword string) error {
+	args := m.Called(id, currentPassword, newPassword)
+	return args.Error(0)
+}
+
+func (m *MockUserService) UpdateUserRole(id, role string) error {
+	args := m.Called(id, role)
+	return args.Error(0)
+}
@@ -0,0 +1,10 @@
+-- ユーザーのロール
+-- 既存ユーザーと新規登録ユーザーはcustomerとして扱う。
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'customer';
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS users_role_check;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_role_check CHECK (role IN ('customer', 'staff', 'admin'));
@@ -0,0 +1,8 @@
+-- メールアドレスの確認日時
+-- NULLの場合は未確認。既存ユーザーは登録日時で確認済みとして扱う。
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ;
+
+UPDATE users
+    SET email_verified_at = created_at
+    WHERE email_verified_at IS NULL;
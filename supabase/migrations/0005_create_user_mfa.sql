@@ -0,0 +1,22 @@
+-- TOTPによる二要素認証の設定
+-- secretは暗号化して保存する。confirmed_atがNULLの場合は登録途中で、ログイン時には要求しない。
+-- last_used_stepには最後に使用したTOTPの時間ステップを保存し、同じコードの再利用を防ぐ。
+CREATE TABLE IF NOT EXISTS user_mfa (
+    user_id         UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    secret          TEXT NOT NULL,
+    confirmed_at    TIMESTAMPTZ,
+    last_used_step  BIGINT NOT NULL DEFAULT 0,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- 認証アプリを利用できない場合のリカバリーコード
+-- コード本体は保存せず、SHA-256ハッシュのみを保存する。各コードは一度しか使用できない。
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES user_mfa (user_id) ON DELETE CASCADE,
+    code_hash   TEXT NOT NULL,
+    used_at     TIMESTAMPTZ,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,13 @@
+-- 外部のIDプロバイダー(OIDC)のアカウントとユーザーの紐付け
+-- プロバイダー内で一意なsubjectで識別し、同じ外部アカウントは一人のユーザーにのみ紐付ける。
+CREATE TABLE IF NOT EXISTS user_identities (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id     UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    provider    TEXT NOT NULL,
+    subject     TEXT NOT NULL,
+    email       TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
@@ -0,0 +1,17 @@
+-- サーバー間連携や店舗端末向けのAPIキー
+-- キー本体は保存せず、SHA-256ハッシュのみを保存する。prefixは一覧で識別するための先頭部分。
+-- scopesで利用できる権限を限定し、所有するユーザーのロールの権限を超えることはない。
+CREATE TABLE IF NOT EXISTS api_keys (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id       UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    name          TEXT NOT NULL,
+    prefix        TEXT NOT NULL,
+    key_hash      TEXT NOT NULL UNIQUE,
+    scopes        TEXT[] NOT NULL DEFAULT '{}',
+    expires_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
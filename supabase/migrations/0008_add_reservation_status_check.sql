@@ -0,0 +1,40 @@
+-- 予約ステータス
+-- 任意の文字列を保存できないよう、定義済みのステータスに制限する。
+
+-- 以前は任意のステータスを保存できたため、対応の明らかなステータスを定義済みのステータスに変換する。
+-- reservedは予約を受け付けて確定した状態として扱う。
+UPDATE reservations
+    SET status = CASE lower(trim(status))
+        WHEN 'reserved' THEN 'confirmed'
+        WHEN 'canceled' THEN 'cancelled'
+        ELSE lower(trim(status))
+    END
+    WHERE status IS NOT NULL;
+
+-- 対応の分からないステータスの予約が残っている場合は、推測で変換せずにマイグレーションを中止する。
+-- 該当する予約のステータスを確認して修正した後に、再度実行する。
+DO $$
+DECLARE
+    unknown_statuses TEXT;
+BEGIN
+    SELECT string_agg(DISTINCT COALESCE(status, 'NULL'), ', ')
+        INTO unknown_statuses
+        FROM reservations
+        WHERE status IS NULL
+           OR status NOT IN ('pending', 'confirmed', 'seated', 'completed', 'cancelled', 'no_show');
+
+    IF unknown_statuses IS NOT NULL THEN
+        RAISE EXCEPTION 'reservations have unknown statuses: %', unknown_statuses;
+    END IF;
+END
+$$;
+
+ALTER TABLE reservations
+    ALTER COLUMN status SET DEFAULT 'pending',
+    ALTER COLUMN status SET NOT NULL;
+
+ALTER TABLE reservations
+    DROP CONSTRAINT IF EXISTS reservations_status_check;
+
+ALTER TABLE reservations
+    ADD CONSTRAINT reservations_status_check CHECK (status IN ('pending', 'confirmed', 'seated', 'completed', 'cancelled', 'no_show'));
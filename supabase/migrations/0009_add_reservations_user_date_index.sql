@@ -0,0 +1,4 @@
+-- ユーザーの予約一覧の取得に使用するインデックス
+-- 予約日時とIDの順に並べ、カーソルによるページングで使用する。
+CREATE INDEX IF NOT EXISTS reservations_user_id_reservation_date_idx
+    ON reservations (user_id, reservation_date, id);
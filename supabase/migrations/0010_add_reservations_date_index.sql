@@ -0,0 +1,5 @@
+-- 予約枠ごとの予約済みの席数の集計に使用するインデックス
+-- 予約の作成・変更時の残席の確認と、空き状況の取得で使用する。
+CREATE INDEX IF NOT EXISTS reservations_reservation_date_idx
+    ON reservations (reservation_date)
+    INCLUDE (num_people, status);
@@ -0,0 +1,39 @@
+-- 曜日ごとの通常の営業時間
+-- weekdayは0が日曜日。同じ曜日に複数の時間帯を設定できる。close_timeの24:00は日付の終わりを表す。
+-- 1件も設定されていない間は営業時間を制限しないため、導入直後も予約を受け付ける。
+CREATE TABLE IF NOT EXISTS business_hours (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    weekday     SMALLINT NOT NULL CHECK (weekday BETWEEN 0 AND 6),
+    open_time   TIME NOT NULL,
+    close_time  TIME NOT NULL,
+    CHECK (open_time < close_time)
+);
+
+CREATE INDEX IF NOT EXISTS business_hours_weekday_idx ON business_hours (weekday);
+
+-- 祝日などで通常と異なる営業時間とする日
+-- 同じ日に設定がある場合は、その日の曜日の営業時間を使用しない。
+CREATE TABLE IF NOT EXISTS business_hours_overrides (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    date        DATE NOT NULL,
+    open_time   TIME NOT NULL,
+    close_time  TIME NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (open_time < close_time)
+);
+
+CREATE INDEX IF NOT EXISTS business_hours_overrides_date_idx ON business_hours_overrides (date);
+
+-- 臨時休業や、予約を受け付けない期間
+-- end_dateの当日を含む。
+CREATE TABLE IF NOT EXISTS business_closures (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    start_date  DATE NOT NULL,
+    end_date    DATE NOT NULL,
+    reason      TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CHECK (start_date <= end_date)
+);
+
+CREATE INDEX IF NOT EXISTS business_closures_date_idx ON business_closures (start_date, end_date);
@@ -0,0 +1,47 @@
+-- 予約日時をタイムゾーン付きで保存する
+-- 既存の予約日時はタイムゾーンなしの店舗の時計の時刻として保存されているため、店舗のタイムゾーンとして変換する。
+-- 店舗のタイムゾーンはデータベースの設定 app.venue_time_zone から読み込むため、
+-- 予約が登録済みの場合は、VENUE_TIME_ZONE と同じ値を設定してから実行する。
+--   ALTER DATABASE postgres SET app.venue_time_zone = 'Asia/Tokyo';
+-- 予約が登録済みで設定がない場合は、推測で変換せずにマイグレーションを中止する。
+
+-- 予約を受け付けた店舗のタイムゾーン(IANAのタイムゾーン名)
+ALTER TABLE reservations
+    ADD COLUMN IF NOT EXISTS time_zone TEXT;
+
+DO $$
+DECLARE
+    venue_time_zone TEXT := NULLIF(current_setting('app.venue_time_zone', true), '');
+BEGIN
+    IF venue_time_zone IS NULL THEN
+        IF EXISTS (SELECT 1 FROM reservations WHERE time_zone IS NULL) THEN
+            RAISE EXCEPTION 'app.venue_time_zone must be set to the venue time zone to convert existing reservations';
+        END IF;
+        venue_time_zone := 'UTC';
+    END IF;
+
+    -- 存在しないタイムゾーン名の場合はここでエラーとなる
+    PERFORM now() AT TIME ZONE venue_time_zone;
+
+    IF EXISTS (
+        SELECT 1
+        FROM information_schema.columns
+        WHERE table_name = 'reservations'
+          AND column_name = 'reservation_date'
+          AND data_type = 'timestamp without time zone'
+    ) THEN
+        EXECUTE format(
+            'ALTER TABLE reservations ALTER COLUMN reservation_date TYPE TIMESTAMPTZ USING reservation_date AT TIME ZONE %L',
+            venue_time_zone
+        );
+    END IF;
+
+    -- 既存の予約は変換に使用した店舗のタイムゾーンで受け付けたものとして記録する
+    UPDATE reservations
+        SET time_zone = venue_time_zone
+        WHERE time_zone IS NULL;
+END
+$$;
+
+ALTER TABLE reservations
+    ALTER COLUMN time_zone SET NOT NULL;
@@ -59,6 +59,14 @@ resource "aws_ecs_task_definition" "ecs_task_def" {
         {
           name      = "JWT_SECRET_KEY",
           valueFrom = "${data.aws_secretsmanager_secret.echo_env.arn}:JWT_SECRET_KEY::"
+        },
+        {
+          name      = "EMAIL_VERIFICATION_SECRET",
+          valueFrom = "${data.aws_secretsmanager_secret.echo_env.arn}:EMAIL_VERIFICATION_SECRET::"
+        },
+        {
+          name      = "MFA_ENCRYPTION_KEY",
+          valueFrom = "${data.aws_secretsmanager_secret.echo_env.arn}:MFA_ENCRYPTION_KEY::"
         }
       ]
     }
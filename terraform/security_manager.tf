@@ -8,10 +8,12 @@ resource "aws_secretsmanager_secret" "echo_env" {
 resource "aws_secretsmanager_secret_version" "echo_env" {
   secret_id = aws_secretsmanager_secret.echo_env.id
   secret_string = jsonencode({
-    ALLOWED_ORIGINS = "${var.allowed_cors_address}",
-    PORT            = "${var.ecs_port}",
-    SUPABASE_URL    = "${var.supabase_url}",
-    JWT_SECRET_KEY  = "${var.jwt_secret_key}",
+    ALLOWED_ORIGINS           = "${var.allowed_cors_address}",
+    PORT                      = "${var.ecs_port}",
+    SUPABASE_URL              = "${var.supabase_url}",
+    JWT_SECRET_KEY            = "${var.jwt_secret_key}",
+    EMAIL_VERIFICATION_SECRET = "${var.email_verification_secret}",
+    MFA_ENCRYPTION_KEY        = "${var.mfa_encryption_key}",
   })
 
   depends_on = [aws_elasticache_replication_group.redis]
@@ -109,3 +109,11 @@ variable "jwt_secret_key" {
   type = string
 }
 
+variable "email_verification_secret" {
+  type = string
+}
+
+variable "mfa_encryption_key" {
+  type = string
+}
+
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeriveKey(t *testing.T) {
+	key := DeriveKey("secret")
+	assert.Len(t, key, 32)
+	assert.Equal(t, key, DeriveKey("secret"))
+	assert.NotEqual(t, key, DeriveKey("other-secret"))
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	key := DeriveKey("secret")
+
+	first, err := Encrypt(key, "plaintext")
+	assert.NoError(t, err)
+	second, err := Encrypt(key, "plaintext")
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second) // nonceが異なる
+	assert.NotContains(t, first, "plaintext")
+
+	plaintext, err := Decrypt(key, first)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext", plaintext)
+
+	// 鍵が異なる場合は復号できない
+	_, err = Decrypt(DeriveKey("other-secret"), first)
+	assert.Error(t, err)
+
+	// 改ざんされた値は復号できない
+	_, err = Decrypt(key, first[:len(first)-2]+"AA")
+	assert.Error(t, err)
+	_, err = Decrypt(key, "short")
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// 環境変数を時間として取得
+// 未設定または不正な値の場合はデフォルト値を返す。
+func GetEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Invalid %s: %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// 環境変数を整数として取得
+// 未設定または不正な値の場合はデフォルト値を返す。
+func GetEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s: %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+// 環境変数を真偽値として取得
+// 未設定または不正な値の場合はデフォルト値を返す。
+func GetEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid %s: %q, using %t", key, value, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetEnvDuration(t *testing.T) {
+	// 未設定の場合はデフォルト値
+	assert.Equal(t, time.Hour, GetEnvDuration("TEST_ENV_DURATION", time.Hour))
+
+	// 正しい値
+	t.Setenv("TEST_ENV_DURATION", "15m")
+	assert.Equal(t, 15*time.Minute, GetEnvDuration("TEST_ENV_DURATION", time.Hour))
+
+	// 不正な値
+	t.Setenv("TEST_ENV_DURATION", "abc")
+	assert.Equal(t, time.Hour, GetEnvDuration("TEST_ENV_DURATION", time.Hour))
+	t.Setenv("TEST_ENV_DURATION", "-1s")
+	assert.Equal(t, time.Hour, GetEnvDuration("TEST_ENV_DURATION", time.Hour))
+}
+
+func TestGetEnvInt(t *testing.T) {
+	assert.Equal(t, 5, GetEnvInt("TEST_ENV_INT", 5))
+
+	t.Setenv("TEST_ENV_INT", "10")
+	assert.Equal(t, 10, GetEnvInt("TEST_ENV_INT", 5))
+
+	t.Setenv("TEST_ENV_INT", "ten")
+	assert.Equal(t, 5, GetEnvInt("TEST_ENV_INT", 5))
+}
+
+func TestGetEnvBool(t *testing.T) {
+	assert.True(t, GetEnvBool("TEST_ENV_BOOL", true))
+
+	t.Setenv("TEST_ENV_BOOL", "false")
+	assert.False(t, GetEnvBool("TEST_ENV_BOOL", true))
+
+	t.Setenv("TEST_ENV_BOOL", "maybe")
+	assert.True(t, GetEnvBool("TEST_ENV_BOOL", true))
+}
@@ -30,3 +30,9 @@ func LogError(c echo.Context, message string) {
 func LogDebug(c echo.Context, message string) {
 	logWithLevel(c, "DEBUG", message)
 }
+
+// 監査ログ
+// アカウントのロックなど、セキュリティ上追跡が必要なイベントを記録する。
+func LogAudit(c echo.Context, message string) {
+	logWithLevel(c, "AUDIT", message)
+}
@@ -93,3 +93,31 @@ func TestLogDebug(t *testing.T) {
 		t.Error("ログ出力が不正です")
 	}
 }
+
+func TestLogAudit(t *testing.T) {
+	// Echoのセットアップ
+	e := echo.New()
+	req := httptest.NewRequest(echo.POST, "/test", nil)
+	req.Header.Set("User-Agent", "TestUserAgent")
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+
+	// ログ出力をキャプチャするためのバッファを作成
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+
+	// テスト実行
+	LogAudit(c, "This is an audit message")
+
+	// ログ出力の確認
+	output := buf.String()
+	// 日付と時刻を除去
+	startIndex := len("2024/10/04 01:00:37 ") // 日付と時刻の長さを取得
+	if len(output) > startIndex {
+		outputWithoutDate := output[startIndex:] // 日付と時刻を除去した出力
+		expected := "AUDIT: POST  TestUserAgent - This is an audit message\n"
+		assert.Equal(t, expected, outputWithoutDate, "ログにAUDITが含まれていません")
+	} else {
+		t.Error("ログ出力が不正です")
+	}
+}
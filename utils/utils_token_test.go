@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateRandomString(t *testing.T) {
+	first, err := GenerateRandomString(32)
+	assert.NoError(t, err)
+	assert.Len(t, first, 43) // 32バイトのBase64(パディングなし)
+
+	second, err := GenerateRandomString(32)
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
+
+func TestHashToken(t *testing.T) {
+	hash := HashToken("token")
+	assert.Len(t, hash, 64)
+	assert.Equal(t, hash, HashToken("token"))
+	assert.NotEqual(t, hash, HashToken("other-token"))
+}
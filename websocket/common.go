@@ -1,55 +1,97 @@
 package websocket
 
 import (
-	"context"
+	"backend/broker"
 	"encoding/json"
+	"errors"
 	"log"
+)
 
-	"github.com/gorilla/websocket"
+// ブローカーのチャンネル
+const (
+	ChannelReservationNotifications = "reservation-notifications" // 予約に関する通知
+	ChannelDebug                    = "debug-channel"             // 動作確認用のメッセージ
 )
 
-// Redisに通知をパブリッシュする関数
-func PublishToRedis(channel, message string) error {
-	log.Println("Publishing message to Redis")
+var (
+	errRecipientRequired = errors.New("recipient is required")
+	errUnknownChannel    = errors.New("unknown channel")
+	errBrokerRequired    = errors.New("broker is not configured")
+)
 
-	// Redisにメッセージをパブリッシュ
-	err := rdb.Publish(context.Background(), channel, message).Err()
-	if err != nil {
-		log.Printf("Failed to publish message to Redis: %v", err)
-		return err
-	}
+// インスタンス間で通知を中継するブローカー
+// クライアントから送信されたメッセージのパブリッシュと、配信するメッセージのサブスクライブに使用する。
+var messageBroker broker.Broker
 
-	log.Println("Published message to Redis successfully")
-	return nil
+// 通知の中継に使用するブローカーを設定する
+func SetBroker(b broker.Broker) {
+	messageBroker = b
+}
+
+// 通知をパブリッシュするインターフェース
+// ハンドラーにはブローカーを直接渡さず、このインターフェースを注入する。
+type Publisher interface {
+	Publish(channel string, message Message) error
 }
 
-// ブロードキャストメッセージ
-func broadcastMessage(messageType string, content string) {
-	log.Println("Broadcasting message to all clients")
+// ブローカーに通知をパブリッシュするPublisherの実装
+type brokerPublisher struct {
+	broker broker.Broker
+}
 
-	mutex.Lock()
-	defer mutex.Unlock()
+// コンストラクタ
+func NewPublisher(b broker.Broker) Publisher {
+	return &brokerPublisher{broker: b}
+}
 
-	// すべてのクライアントにメッセージを送信
-	for client := range clients {
-		log.Println("Sending message to client")
+// ブローカーに通知をパブリッシュする
+// すべてのインスタンスが受信し、宛先に該当する自身の接続に配信する。
+func (p *brokerPublisher) Publish(channel string, message Message) error {
+	log.Println("Publishing message to broker")
 
-		msg := map[string]string{
-			"type":    messageType,
-			"content": content,
-		}
+	if p.broker == nil {
+		log.Println("Broker is not configured")
+		return errBrokerRequired
+	}
+
+	// 宛先のないメッセージは全員に配信されることのないよう拒否する
+	if message.Recipient.IsEmpty() {
+		log.Println("Message recipient is required")
+		return errRecipientRequired
+	}
 
-		// メッセージをJSON形式に変換
-		messageJSON, _ := json.Marshal(msg)
-		// クライアントにメッセージを送信
-		err := client.WriteMessage(websocket.TextMessage, messageJSON)
+	// 予約の通知は再接続したクライアントに再送できるよう、履歴に追加してIDを採番する
+	// 履歴に追加できない場合も、接続中のクライアントへの配信は行う
+	if channel == ChannelReservationNotifications {
+		id, err := appendToStream(message)
 		if err != nil {
-			// エラーが発生した場合、クライアントをクローズし、クライアントリストから削除
-			log.Printf("WebSocket write error: %v", err)
-			client.Close()
-			delete(clients, client)
+			log.Printf("Failed to append message to stream: %v", err)
 		}
+		message.ID = id
 	}
 
-	log.Println("Broadcasted message to all clients")
+	payload, err := json.Marshal(message)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return err
+	}
+
+	// ブローカーにメッセージをパブリッシュ
+	err = p.broker.Publish(ctx, channel, payload)
+	if err != nil {
+		log.Printf("Failed to publish message to broker: %v", err)
+		return err
+	}
+
+	log.Println("Published message to broker successfully")
+	return nil
+}
+
+// 宛先に該当するクライアントにメッセージを送信
+func deliverMessage(message Message) {
+	log.Println("Delivering message to recipients")
+
+	count := hub.deliver(message)
+
+	log.Printf("Delivered message to %d clients", count)
 }
@@ -0,0 +1,354 @@
+package websocket
+
+import (
+	"backend/auth"
+	"backend/utils"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+// 送信バッファがあふれたクライアントを切断する際のクローズコード
+// 再接続して受信し直すよう促す。
+const CloseTryAgainLater = 1013
+
+// ハブの設定
+type HubConfig struct {
+	SendBufferSize int           // クライアントごとの送信バッファの件数
+	WriteWait      time.Duration // 1件の書き込みの待ち時間
+	PingInterval   time.Duration // Pingを送信する間隔。PongWaitより短くする。
+	PongWait       time.Duration // Pongを待つ時間。この間に応答のない接続は切断する。
+	MaxMessageSize int64         // クライアントから受信するメッセージの最大サイズ(バイト)
+}
+
+// デフォルトの設定を返す。
+// ALBのアイドルタイムアウト(デフォルト60秒)より短い間隔でPingを送信する。
+func DefaultHubConfig() HubConfig {
+	return HubConfig{
+		SendBufferSize: 64,
+		WriteWait:      10 * time.Second,
+		PingInterval:   30 * time.Second,
+		PongWait:       45 * time.Second,
+		MaxMessageSize: 4096,
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定の項目はデフォルト値を使用する。
+func LoadHubConfig() HubConfig {
+	config := DefaultHubConfig()
+	config.SendBufferSize = utils.GetEnvInt("WS_SEND_BUFFER_SIZE", config.SendBufferSize)
+	config.WriteWait = utils.GetEnvDuration("WS_WRITE_WAIT", config.WriteWait)
+	config.PingInterval = utils.GetEnvDuration("WS_PING_INTERVAL", config.PingInterval)
+	config.PongWait = utils.GetEnvDuration("WS_PONG_WAIT", config.PongWait)
+	config.MaxMessageSize = int64(utils.GetEnvInt("WS_MAX_MESSAGE_SIZE", int(config.MaxMessageSize)))
+	if config.SendBufferSize <= 0 {
+		config.SendBufferSize = DefaultHubConfig().SendBufferSize
+	}
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = DefaultHubConfig().MaxMessageSize
+	}
+	// Pongを待つ間に少なくとも一度はPingを送信する
+	if config.PingInterval >= config.PongWait {
+		log.Printf("WS_PING_INTERVAL must be shorter than WS_PONG_WAIT, using %s", config.PongWait*9/10)
+		config.PingInterval = config.PongWait * 9 / 10
+	}
+	return config
+}
+
+// 接続中のクライアント
+// 送信はクライアントごとの書き込みゴルーチンが行い、配信側はバッファに積むだけとする。
+// Server-Sent Eventsのクライアントの場合、Connはnilとなり、ハンドラーが送信バッファを読み出す。
+type Client struct {
+	Conn   *websocket.Conn
+	Claims *auth.Claims
+
+	send    chan outbound // 送信待ちのメッセージ
+	done    chan struct{} // 接続を閉じる指示
+	stopped chan struct{} // 書き込みゴルーチンの終了
+
+	closeOnce   sync.Once
+	closeCode   int
+	closeReason string
+
+	// 再接続時に再送した範囲の最後の通知のID。再送済みの通知を重複して送信しないために使用する。
+	// ライブ配信の通知はIDの順に届くとは限らないため、再送時にのみ更新する。
+	// 書き込みゴルーチンの開始後は、書き込みゴルーチンのみが参照する。
+	replayedUntil string
+}
+
+// 送信待ちのメッセージ
+type outbound struct {
+	id   string
+	data []byte
+}
+
+// クライアントに送信するメッセージの形式
+type outgoingMessage struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// クライアントに送信するメッセージを生成する
+func newOutbound(message Message) outbound {
+	// メッセージをJSON形式に変換
+	data, _ := json.Marshal(outgoingMessage{
+		ID:      message.ID,
+		Type:    message.Type,
+		Content: message.Content,
+	})
+	return outbound{id: message.ID, data: data}
+}
+
+func newClient(conn *websocket.Conn, claims *auth.Claims, bufferSize int) *Client {
+	return &Client{
+		Conn:    conn,
+		Claims:  claims,
+		send:    make(chan outbound, bufferSize),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+}
+
+// 接続しているユーザーのID
+func (c *Client) UserID() string {
+	return c.Claims.UserID
+}
+
+// 接続しているユーザーのロール
+func (c *Client) Role() string {
+	return c.Claims.Role
+}
+
+// クローズコードを送信して接続を閉じるよう、書き込みゴルーチンに指示する
+// 複数回呼び出した場合は最初の指示のみ有効。
+func (c *Client) close(code int, reason string) {
+	c.closeOnce.Do(func() {
+		c.closeCode = code
+		c.closeReason = reason
+		close(c.done)
+	})
+}
+
+// 接続を閉じる指示が出ているかどうか
+func (c *Client) isClosed() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// バッファのメッセージを順に送信する書き込みゴルーチン
+// 接続への書き込みはこのゴルーチンのみが行う。一定間隔でPingを送信し、その際にセッションの失効も確認する。
+func (c *Client) writePump(config HubConfig) {
+	ticker := time.NewTicker(config.PingInterval)
+	defer ticker.Stop()
+	defer close(c.stopped)
+	defer c.Conn.Close()
+
+	for {
+		select {
+		case message := <-c.send:
+			// 再送済みの通知は送信しない
+			if c.alreadyReplayed(message) {
+				continue
+			}
+			if err := c.write(message, config.WriteWait); err != nil {
+				log.Printf("WebSocket write error: %v", err)
+				return
+			}
+		case <-ticker.C:
+			// ログアウトなどで失効したセッションの接続を、メッセージの受信を待たずに閉じる
+			if err := auth.CheckWebSocketSession(revocationStore, c.Claims); err != nil {
+				log.Printf("WebSocket session is no longer valid: %v", err)
+				c.close(CloseUnauthorized, err.Error())
+				continue
+			}
+			c.Conn.SetWriteDeadline(time.Now().Add(config.WriteWait))
+			if err := c.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				log.Printf("WebSocket ping error: %v", err)
+				return
+			}
+		case <-c.done:
+			closeMessage := websocket.FormatCloseMessage(c.closeCode, c.closeReason)
+			if err := c.Conn.WriteControl(websocket.CloseMessage, closeMessage, time.Now().Add(closeWriteWait)); err != nil {
+				log.Printf("Failed to send close message: %v", err)
+			}
+			return
+		}
+	}
+}
+
+// メッセージを接続に書き込む
+// 書き込みゴルーチンの開始前、または書き込みゴルーチンからのみ呼び出す。
+func (c *Client) write(message outbound, writeWait time.Duration) error {
+	c.Conn.SetWriteDeadline(time.Now().Add(writeWait))
+	return c.Conn.WriteMessage(websocket.TextMessage, message.data)
+}
+
+// 再送済みの範囲に含まれる通知かどうか
+func (c *Client) alreadyReplayed(message outbound) bool {
+	return message.id != "" && c.replayedUntil != "" && compareStreamIDs(message.id, c.replayedUntil) <= 0
+}
+
+// 指定したIDまでを再送済みの範囲として記録する
+func (c *Client) markReplayed(id string) {
+	if c.replayedUntil == "" || compareStreamIDs(id, c.replayedUntil) > 0 {
+		c.replayedUntil = id
+	}
+}
+
+// 接続中のクライアントを管理し、メッセージを配信するハブ
+type Hub struct {
+	config   HubConfig
+	registry *registry
+
+	mutex   sync.Mutex
+	closing bool // シャットダウン中は新しい接続を登録しない
+
+	delivered atomic.Uint64 // バッファに積んだメッセージ数
+	dropped   atomic.Uint64 // バッファがあふれて破棄したメッセージ数
+	evicted   atomic.Uint64 // バッファがあふれて切断したクライアント数
+}
+
+func NewHub(config HubConfig) *Hub {
+	return &Hub{
+		config:   config,
+		registry: newRegistry(),
+	}
+}
+
+// アプリケーション全体で使用するハブ
+var hub = NewHub(DefaultHubConfig())
+
+// 設定を指定してアプリケーション全体で使用するハブを初期化する
+// 接続を受け付ける前に呼び出す。
+func InitHub(config HubConfig) {
+	hub = NewHub(config)
+}
+
+// クライアントを登録する
+// シャットダウン中の場合は登録せずにfalseを返す。
+func (h *Hub) register(client *Client) bool {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.closing {
+		return false
+	}
+	h.registry.add(client)
+	return true
+}
+
+// クライアントの登録を解除する
+func (h *Hub) unregister(client *Client) {
+	h.registry.remove(client)
+}
+
+// 宛先に該当するクライアントの送信バッファにメッセージを積む
+// 送信を待たないため、応答しないクライアントがいても他のクライアントへの配信は遅れない。
+func (h *Hub) deliver(message Message) int {
+	msg := newOutbound(message)
+
+	count := 0
+	for _, client := range h.registry.lookup(message.Recipient) {
+		if h.enqueue(client, msg) {
+			count++
+		}
+	}
+	return count
+}
+
+// クライアントの送信バッファにメッセージを積む
+// バッファがあふれた場合は、メッセージを破棄してクライアントを切断する。
+func (h *Hub) enqueue(client *Client, message outbound) bool {
+	if client.isClosed() {
+		return false
+	}
+
+	select {
+	case client.send <- message:
+		h.delivered.Add(1)
+		return true
+	default:
+		log.Printf("WebSocket send buffer overflow, evicting client for user: %s", client.UserID())
+		h.dropped.Add(1)
+		h.evicted.Add(1)
+		h.unregister(client)
+		client.close(CloseTryAgainLater, "send buffer overflow")
+		return false
+	}
+}
+
+// すべての接続に1001 Going Awayのクローズフレームを送信し、書き込みゴルーチンの終了を待つ
+// 以降の接続は受け付けない。ctxの期限までに終了しない場合はエラーを返す。
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.mutex.Lock()
+	h.closing = true
+	h.mutex.Unlock()
+
+	clients := h.registry.all()
+	log.Printf("Closing %d WebSocket connections", len(clients))
+	for _, client := range clients {
+		client.close(websocket.CloseGoingAway, "server shutting down")
+	}
+
+	for _, client := range clients {
+		select {
+		case <-client.stopped:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// アプリケーション全体で使用するハブをシャットダウンする
+func Shutdown(ctx context.Context) error {
+	return hub.Shutdown(ctx)
+}
+
+// ハブの統計情報
+type HubStats struct {
+	Clients           int    `json:"clients"`            // 接続中のクライアント数
+	QueueDepth        int    `json:"queue_depth"`        // 送信待ちのメッセージの合計
+	MaxQueueDepth     int    `json:"max_queue_depth"`    // クライアントごとの送信待ちの最大
+	SendBufferSize    int    `json:"send_buffer_size"`   // クライアントごとの送信バッファの件数
+	DeliveredMessages uint64 `json:"delivered_messages"` // バッファに積んだメッセージ数
+	DroppedMessages   uint64 `json:"dropped_messages"`   // 破棄したメッセージ数
+	EvictedClients    uint64 `json:"evicted_clients"`    // バッファがあふれて切断したクライアント数
+}
+
+// ハブの統計情報を返す
+func (h *Hub) Stats() HubStats {
+	stats := HubStats{
+		SendBufferSize:    h.config.SendBufferSize,
+		DeliveredMessages: h.delivered.Load(),
+		DroppedMessages:   h.dropped.Load(),
+		EvictedClients:    h.evicted.Load(),
+	}
+	for _, client := range h.registry.all() {
+		depth := len(client.send)
+		stats.Clients++
+		stats.QueueDepth += depth
+		if depth > stats.MaxQueueDepth {
+			stats.MaxQueueDepth = depth
+		}
+	}
+	return stats
+}
+
+// ハブの統計情報を返すハンドラー
+func HandleMetrics(c echo.Context) error {
+	return c.JSON(http.StatusOK, hub.Stats())
+}
@@ -0,0 +1,78 @@
+package websocket
+
+import (
+	"backend/auth"
+	"backend/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 書き込みゴルーチンを開始しない(応答しない)クライアントを登録する
+func registerStalledClient(h *Hub, userId, role string) *Client {
+	client := newClient(nil, &auth.Claims{UserID: userId, Role: role}, h.config.SendBufferSize)
+	h.register(client)
+	return client
+}
+
+func TestHub_EvictsSlowClient(t *testing.T) {
+	h := NewHub(HubConfig{SendBufferSize: 2})
+	slow := registerStalledClient(h, "customer1", models.RoleCustomer)
+	staff := registerStalledClient(h, "staff1", models.RoleStaff)
+
+	recipient := StaffRecipient()
+	recipient.UserID = "customer1"
+	assert.Equal(t, 2, h.deliver(Message{Content: "1", Recipient: recipient}))
+	assert.Equal(t, 2, h.deliver(Message{Content: "2", Recipient: recipient}))
+
+	// スタッフのバッファを空ける
+	<-staff.send
+	<-staff.send
+
+	// バッファがあふれたクライアントは切断され、他のクライアントへの配信は続く
+	assert.Equal(t, 1, h.deliver(Message{Content: "3", Recipient: recipient}))
+	assert.True(t, slow.isClosed())
+	assert.Equal(t, CloseTryAgainLater, slow.closeCode)
+	assert.False(t, staff.isClosed())
+	assert.Equal(t, 1, len(staff.send))
+
+	// 切断したクライアントには配信しない
+	assert.Equal(t, 1, h.deliver(Message{Content: "4", Recipient: recipient}))
+
+	stats := h.Stats()
+	assert.Equal(t, 1, stats.Clients)
+	assert.Equal(t, 2, stats.QueueDepth)
+	assert.Equal(t, 2, stats.MaxQueueDepth)
+	assert.Equal(t, uint64(6), stats.DeliveredMessages)
+	assert.Equal(t, uint64(1), stats.DroppedMessages)
+	assert.Equal(t, uint64(1), stats.EvictedClients)
+}
+
+func TestClient_CloseOnce(t *testing.T) {
+	client := newClient(nil, &auth.Claims{UserID: "user1"}, 1)
+
+	client.close(CloseUnauthorized, "session expired")
+	client.close(CloseForbidden, "forbidden")
+
+	assert.True(t, client.isClosed())
+	assert.Equal(t, CloseUnauthorized, client.closeCode)
+	assert.Equal(t, "session expired", client.closeReason)
+}
+
+func TestLoadHubConfig(t *testing.T) {
+	// 未設定の場合はデフォルト値
+	assert.Equal(t, DefaultHubConfig(), LoadHubConfig())
+
+	// Ping間隔はPongの待ち時間より短くする
+	t.Setenv("WS_PING_INTERVAL", "1m")
+	t.Setenv("WS_PONG_WAIT", "10s")
+	assert.Equal(t, 9*time.Second, LoadHubConfig().PingInterval)
+
+	t.Setenv("WS_SEND_BUFFER_SIZE", "8")
+	assert.Equal(t, 8, LoadHubConfig().SendBufferSize)
+
+	// 不正な値
+	t.Setenv("WS_SEND_BUFFER_SIZE", "0")
+	assert.Equal(t, DefaultHubConfig().SendBufferSize, LoadHubConfig().SendBufferSize)
+}
@@ -1,41 +1,60 @@
 package websocket
 
 import (
-	"encoding/json"
 	"log"
 )
 
 // 動作確認用のデバッグメッセージ処理
+// 店舗スタッフの接続にのみ配信する。
 func handleDebugMessage(messageData map[string]interface{}) {
 	log.Println("Handling debug message")
-	message := messageData["message"].(string)
+	message, ok := messageData["message"].(string)
+	if !ok {
+		log.Println("Debug message missing or invalid")
+		return
+	}
 	log.Printf("Debug message: %s", message)
 
-	// Redisにデバッグメッセージをパブリッシュ
-	err := rdb.Publish(ctx, "debug-channel", message).Err()
+	// ブローカーにデバッグメッセージをパブリッシュ
+	err := NewPublisher(messageBroker).Publish(ChannelDebug, Message{
+		Type:      "debug",
+		Content:   message,
+		Recipient: StaffRecipient(),
+	})
 	if err != nil {
-		log.Printf("Failed to publish debug message to Redis: %v", err)
+		log.Printf("Failed to publish debug message to broker: %v", err)
+		return
 	}
 
-	log.Println("Published debug message to Redis")
+	log.Println("Published debug message to broker")
 }
 
 // 予約通知メッセージ処理
+// user_idを指定した場合はそのユーザーに、指定しない場合は店舗スタッフに配信する。
 func handleReservationNotification(messageData map[string]interface{}) {
 	log.Println("Handling reservation notification")
 
-	// messageData を JSON に変換
-	messageJSON, err := json.Marshal(messageData)
-	if err != nil {
-		log.Printf("Failed to marshal reservation notification: %v", err)
+	message, ok := messageData["message"].(string)
+	if !ok {
+		log.Println("Reservation notification message missing or invalid")
 		return
 	}
 
-	// Redisに予約通知メッセージをパブリッシュ
-	err = rdb.Publish(ctx, "reservation-channel", messageJSON).Err()
+	recipient := StaffRecipient()
+	if userId, ok := messageData["user_id"].(string); ok && userId != "" {
+		recipient = UserRecipient(userId)
+	}
+
+	// ブローカーに予約通知メッセージをパブリッシュ
+	err := NewPublisher(messageBroker).Publish(ChannelReservationNotifications, Message{
+		Type:      "reservation_notification",
+		Content:   message,
+		Recipient: recipient,
+	})
 	if err != nil {
-		log.Printf("Failed to publish reservation notification to Redis: %v", err)
+		log.Printf("Failed to publish reservation notification to broker: %v", err)
+		return
 	}
 
-	log.Println("Published reservation notification to Redis")
+	log.Println("Published reservation notification to broker")
 }
@@ -0,0 +1,15 @@
+package websocket
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+// MockPublisher is the mock implementation for Publisher
+type MockPublisher struct {
+	mock.Mock
+}
+
+func (m *MockPublisher) Publish(channel string, message Message) error {
+	args := m.Called(channel, message)
+	return args.Error(0)
+}
@@ -0,0 +1,143 @@
+package websocket
+
+import (
+	"backend/models"
+	"sync"
+)
+
+// 通知の宛先
+// ユーザーIDとロールのいずれか、または両方を指定する。どちらも空の場合は誰にも配信しない。
+type Recipient struct {
+	UserID string   `json:"user_id,omitempty"` // 宛先のユーザー
+	Roles  []string `json:"roles,omitempty"`   // 宛先のロール
+}
+
+// 指定したユーザー宛ての宛先
+func UserRecipient(userId string) Recipient {
+	return Recipient{UserID: userId}
+}
+
+// 店舗スタッフ(スタッフと管理者)宛ての宛先
+func StaffRecipient() Recipient {
+	return Recipient{Roles: []string{models.RoleStaff, models.RoleAdmin}}
+}
+
+// 宛先が指定されているかどうか
+func (r Recipient) IsEmpty() bool {
+	return r.UserID == "" && len(r.Roles) == 0
+}
+
+// 指定したユーザーが宛先に含まれるかどうか
+func (r Recipient) Includes(userId, role string) bool {
+	if r.UserID != "" && r.UserID == userId {
+		return true
+	}
+	for _, recipientRole := range r.Roles {
+		if recipientRole == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Redisを経由して配信するメッセージ
+// 各インスタンスは宛先に該当する自身の接続にのみ配信する。
+type Message struct {
+	ID        string    `json:"id,omitempty"` // 通知の履歴(Redis Stream)のID。履歴に残さないメッセージは空。
+	Type      string    `json:"type"`
+	Content   string    `json:"content"`
+	Recipient Recipient `json:"recipient"`
+}
+
+// 接続中のクライアントをユーザーとロールで索引するレジストリ
+type registry struct {
+	mutex  sync.RWMutex
+	byUser map[string]map[*Client]bool
+	byRole map[string]map[*Client]bool
+}
+
+func newRegistry() *registry {
+	return &registry{
+		byUser: make(map[string]map[*Client]bool),
+		byRole: make(map[string]map[*Client]bool),
+	}
+}
+
+// クライアントを登録する
+func (r *registry) add(client *Client) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	addToIndex(r.byUser, client.UserID(), client)
+	addToIndex(r.byRole, client.Role(), client)
+}
+
+// クライアントの登録を解除する
+func (r *registry) remove(client *Client) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	removeFromIndex(r.byUser, client.UserID(), client)
+	removeFromIndex(r.byRole, client.Role(), client)
+}
+
+// 宛先に該当するクライアントを返す
+// ユーザーとロールの両方に該当するクライアントは一度だけ含める。
+func (r *registry) lookup(recipient Recipient) []*Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	seen := make(map[*Client]bool)
+	var result []*Client
+	collect := func(clients map[*Client]bool) {
+		for client := range clients {
+			if !seen[client] {
+				seen[client] = true
+				result = append(result, client)
+			}
+		}
+	}
+
+	if recipient.UserID != "" {
+		collect(r.byUser[recipient.UserID])
+	}
+	for _, role := range recipient.Roles {
+		collect(r.byRole[role])
+	}
+	return result
+}
+
+// 登録されているすべてのクライアントを返す
+func (r *registry) all() []*Client {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	var result []*Client
+	for _, clients := range r.byUser {
+		for client := range clients {
+			result = append(result, client)
+		}
+	}
+	return result
+}
+
+// 指定したユーザーの接続数を返す
+func (r *registry) countUser(userId string) int {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return len(r.byUser[userId])
+}
+
+func addToIndex(index map[string]map[*Client]bool, key string, client *Client) {
+	if index[key] == nil {
+		index[key] = make(map[*Client]bool)
+	}
+	index[key][client] = true
+}
+
+func removeFromIndex(index map[string]map[*Client]bool, key string, client *Client) {
+	delete(index[key], client)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
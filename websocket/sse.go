@@ -0,0 +1,172 @@
+package websocket
+
+import (
+	"backend/auth"
+	"backend/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// Server-Sent Eventsの再接続時に最後に受信したイベントのIDを送信するヘッダー
+	LastEventIDHeader = "Last-Event-ID"
+
+	// セッションが期限切れ・失効したことを通知するメッセージタイプ
+	// 受信したクライアントは再認証後に接続し直す。
+	MessageTypeUnauthorized = "unauthorized"
+
+	// ブラウザが再接続するまでの待ち時間
+	sseRetry = 3 * time.Second
+)
+
+// Server-Sent Eventsによる通知の配信ハンドラー
+// WebSocketに接続できない環境向けの代替手段。WebSocketと同じ認証、宛先の絞り込み、再送を行う。
+// 通知はevent名を指定せずに送信するため、クライアントはonmessageでWebSocketと同じ形式のJSONを受信する。
+func HandleSSE(c echo.Context) error {
+	log.Println("SSE connection requested")
+
+	// WebSocketと同じく、クッキーのアクセストークンまたはチケットで認証する
+	claims, err := auth.AuthenticateWebSocket(c, revocationStore)
+	if err != nil {
+		log.Printf("SSE authentication failed: %v", err)
+		return c.JSON(http.StatusUnauthorized, map[string]string{
+			"error": "Unauthorized",
+		})
+	}
+	if !claims.HasPermission(models.PermissionNotificationsRead) {
+		log.Printf("User %s is not allowed to read notifications", claims.UserID)
+		return c.JSON(http.StatusForbidden, map[string]string{
+			"error": "Forbidden",
+		})
+	}
+
+	// クライアントをハブに登録
+	h := hub
+	client := newClient(nil, claims, h.config.SendBufferSize)
+	if !h.register(client) {
+		log.Println("Server is shutting down, rejecting SSE connection")
+		return c.JSON(http.StatusServiceUnavailable, map[string]string{
+			"error": "Server is shutting down",
+		})
+	}
+	defer func() {
+		h.unregister(client)
+		close(client.stopped)
+		log.Println("SSE connection closed")
+	}()
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	// プロキシでのバッファリングを無効化する
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	writer := &sseWriter{
+		response:   res,
+		controller: http.NewResponseController(res),
+		writeWait:  h.config.WriteWait,
+	}
+	// キープアライブで再利用される接続に書き込みの期限を残さない
+	defer writer.controller.SetWriteDeadline(time.Time{})
+	if err := writer.write(fmt.Sprintf("retry: %d\n\n", sseRetry.Milliseconds())); err != nil {
+		return nil
+	}
+
+	log.Printf("SSE connection established for user: %s", client.UserID())
+
+	// 再接続の場合は、ブラウザが送信するLast-Event-IDより後の通知を再送する
+	lastEventID := c.Request().Header.Get(LastEventIDHeader)
+	if lastEventID == "" {
+		lastEventID = c.QueryParam(LastEventIDQueryParam)
+	}
+	if lastEventID != "" {
+		if err := replayMissedMessages(client, lastEventID, writer.writeEvent); err != nil {
+			log.Printf("Failed to replay messages: %v", err)
+			return nil
+		}
+	}
+
+	// 一定間隔でコメントを送信し、プロキシやロードバランサーに切断されないようにする
+	heartbeat := time.NewTicker(h.config.PingInterval)
+	defer heartbeat.Stop()
+
+	// セッションの有効期限で接続を閉じる
+	var expired <-chan time.Time
+	if claims.ExpiresAt > 0 {
+		expiryTimer := time.NewTimer(time.Until(time.Unix(claims.ExpiresAt, 0)))
+		defer expiryTimer.Stop()
+		expired = expiryTimer.C
+	}
+
+	for {
+		select {
+		case message := <-client.send:
+			// 再送済みの通知は送信しない
+			if client.alreadyReplayed(message) {
+				continue
+			}
+			if err := writer.writeEvent(message); err != nil {
+				log.Printf("SSE write error: %v", err)
+				return nil
+			}
+		case <-heartbeat.C:
+			// ログアウトなどで失効したセッションの接続を閉じる
+			if err := auth.CheckWebSocketSession(revocationStore, claims); err != nil {
+				log.Printf("SSE session is no longer valid: %v", err)
+				writer.writeEvent(newOutbound(Message{Type: MessageTypeUnauthorized, Content: err.Error()}))
+				return nil
+			}
+			if err := writer.write(": heartbeat\n\n"); err != nil {
+				log.Printf("SSE heartbeat error: %v", err)
+				return nil
+			}
+		case <-expired:
+			log.Printf("SSE session expired for user: %s", client.UserID())
+			writer.writeEvent(newOutbound(Message{Type: MessageTypeUnauthorized, Content: "session expired"}))
+			return nil
+		case <-client.done:
+			// 送信バッファがあふれた場合やシャットダウン時は、ブラウザの再接続に任せる
+			log.Printf("SSE connection closed by server: %s", client.closeReason)
+			return nil
+		case <-c.Request().Context().Done():
+			return nil
+		}
+	}
+}
+
+// Server-Sent Eventsの書き込み
+type sseWriter struct {
+	response   *echo.Response
+	controller *http.ResponseController
+	writeWait  time.Duration
+}
+
+// 書き込みの期限を設定して書き込み、すぐに送信する
+func (w *sseWriter) write(data string) error {
+	if err := w.controller.SetWriteDeadline(time.Now().Add(w.writeWait)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	if _, err := w.response.Write([]byte(data)); err != nil {
+		return err
+	}
+	w.response.Flush()
+	return nil
+}
+
+// メッセージをイベントとして書き込む
+// 履歴に残る通知はidを付与し、ブラウザが再接続時にLast-Event-IDとして送信する。
+func (w *sseWriter) writeEvent(message outbound) error {
+	event := ""
+	if message.id != "" {
+		event += "id: " + message.id + "\n"
+	}
+	event += "data: " + string(message.data) + "\n\n"
+	return w.write(event)
+}
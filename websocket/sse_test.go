@@ -0,0 +1,167 @@
+package websocket
+
+import (
+	"backend/models"
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のSSEサーバーを起動する
+func newTestSSEServer(t *testing.T) *httptest.Server {
+	e := echo.New()
+	e.GET("/api/notifications/stream", HandleSSE)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// SSEのイベント
+type sseEvent struct {
+	id      string
+	data    string
+	comment string
+}
+
+// SSEのストリームに接続し、受信したイベントを返すチャンネルを返す
+func openSSE(t *testing.T, server *httptest.Server, token, lastEventID string) (*http.Response, <-chan sseEvent) {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/notifications/stream", nil)
+	if token != "" {
+		req.AddCookie(&http.Cookie{Name: "token", Value: token})
+	}
+	if lastEventID != "" {
+		req.Header.Set(LastEventIDHeader, lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	events := make(chan sseEvent, 16)
+	go func() {
+		defer close(events)
+		scanner := bufio.NewScanner(res.Body)
+		var event sseEvent
+		for scanner.Scan() {
+			line := scanner.Text()
+			switch {
+			case line == "":
+				if event != (sseEvent{}) {
+					events <- event
+				}
+				event = sseEvent{}
+			case strings.HasPrefix(line, ":"):
+				event.comment = strings.TrimSpace(line[1:])
+			case strings.HasPrefix(line, "id: "):
+				event.id = line[len("id: "):]
+			case strings.HasPrefix(line, "data: "):
+				event.data = line[len("data: "):]
+			}
+		}
+	}()
+	return res, events
+}
+
+// 次のイベントを受信する(retryのみのイベントとハートビートは読み飛ばす)
+func nextEvent(t *testing.T, events <-chan sseEvent) (sseEvent, outgoingMessage) {
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				t.Fatal("Stream closed")
+			}
+			if event.data == "" {
+				continue
+			}
+			var msg outgoingMessage
+			assert.NoError(t, json.Unmarshal([]byte(event.data), &msg))
+			return event, msg
+		case <-timeout:
+			t.Fatal("Timed out waiting for event")
+		}
+	}
+}
+
+func TestHandleSSE_Unauthorized(t *testing.T) {
+	server := newTestSSEServer(t)
+
+	res, _ := openSSE(t, server, "", "")
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestHandleSSE_Delivery(t *testing.T) {
+	useTestHub(t, DefaultHubConfig())
+	server := newTestSSEServer(t)
+
+	res, events := openSSE(t, server, newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour)), "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get(echo.HeaderContentType))
+	assert.Eventually(t, func() bool { return isConnected("customer1") }, time.Second, 10*time.Millisecond)
+
+	// 他のユーザー宛ての通知は届かない
+	hub.deliver(Message{ID: "1-0", Type: "reservation_notification", Content: "other", Recipient: UserRecipient("customer2")})
+	hub.deliver(Message{Type: "debug", Content: "staff", Recipient: StaffRecipient()})
+	hub.deliver(Message{ID: "2-0", Type: "reservation_notification", Content: "mine", Recipient: UserRecipient("customer1")})
+
+	event, msg := nextEvent(t, events)
+	assert.Equal(t, "2-0", event.id)
+	assert.Equal(t, outgoingMessage{ID: "2-0", Type: "reservation_notification", Content: "mine"}, msg)
+}
+
+func TestHandleSSE_Heartbeat(t *testing.T) {
+	useTestHub(t, newTestHubConfig())
+	server := newTestSSEServer(t)
+
+	_, events := openSSE(t, server, newTestToken(t, time.Now().Add(time.Hour)), "")
+
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.comment == "heartbeat" {
+				return
+			}
+		case <-timeout:
+			t.Fatal("Timed out waiting for heartbeat")
+		}
+	}
+}
+
+func TestHandleSSE_LastEventID(t *testing.T) {
+	useTestRedis(t)
+	useTestHub(t, DefaultHubConfig())
+	server := newTestSSEServer(t)
+
+	lastID, _ := appendToStream(Message{Type: "reservation_notification", Content: "received", Recipient: UserRecipient("customer1")})
+	missedID, _ := appendToStream(Message{Type: "reservation_notification", Content: "missed", Recipient: UserRecipient("customer1")})
+
+	// ブラウザが再接続時に送信するLast-Event-IDより後の通知が再送される
+	_, events := openSSE(t, server, newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour)), lastID)
+
+	event, msg := nextEvent(t, events)
+	assert.Equal(t, missedID, event.id)
+	assert.Equal(t, "missed", msg.Content)
+}
+
+func TestHandleSSE_SessionExpired(t *testing.T) {
+	useTestHub(t, DefaultHubConfig())
+	server := newTestSSEServer(t)
+
+	_, events := openSSE(t, server, newTestToken(t, time.Now().Add(1500*time.Millisecond)), "")
+
+	_, msg := nextEvent(t, events)
+	assert.Equal(t, MessageTypeUnauthorized, msg.Type)
+}
@@ -0,0 +1,209 @@
+package websocket
+
+import (
+	"backend/utils"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// 再接続したクライアントに、取りこぼした通知の再取得を促すメッセージタイプ
+// 保持期間を過ぎた場合など、すべての通知を再送できない場合に送信する。
+const MessageTypeResyncRequired = "resync_required"
+
+// 再接続時に最後に受信したメッセージのIDを指定するクエリパラメータ
+const LastEventIDQueryParam = "last_event_id"
+
+// 通知の履歴を保持するRedis Streamの設定
+type StreamConfig struct {
+	Key       string        // Redis Streamのキー
+	Retention time.Duration // 再送のために通知を保持する期間
+	MaxReplay int64         // 再接続時に再送する最大件数
+}
+
+// デフォルトの設定を返す。
+func DefaultStreamConfig() StreamConfig {
+	return StreamConfig{
+		Key:       "notifications:stream",
+		Retention: 24 * time.Hour,
+		MaxReplay: 500,
+	}
+}
+
+// 環境変数から設定を読み込む。
+// 未設定の項目はデフォルト値を使用する。
+func LoadStreamConfig() StreamConfig {
+	config := DefaultStreamConfig()
+	config.Retention = utils.GetEnvDuration("WS_REPLAY_RETENTION", config.Retention)
+	config.MaxReplay = int64(utils.GetEnvInt("WS_MAX_REPLAY", int(config.MaxReplay)))
+	if config.MaxReplay <= 0 {
+		config.MaxReplay = DefaultStreamConfig().MaxReplay
+	}
+	return config
+}
+
+var (
+	// アプリケーション全体で使用する通知の履歴の設定
+	streamConfig = DefaultStreamConfig()
+	// 通知の履歴を保持するRedisクライアント。nilの場合は履歴を保持せず、再接続時は再取得を促す。
+	streamClient *redis.Client
+
+	errStreamDisabled = errors.New("notification stream is not configured")
+)
+
+// 通知の履歴の設定と、履歴を保持するRedisクライアントを変更する
+// ブローカーにRedisを使用しない場合も、履歴にはRedisを使用する。
+func InitStream(config StreamConfig, client *redis.Client) {
+	streamConfig = config
+	streamClient = client
+}
+
+// 通知をRedis Streamに追加し、採番されたIDを返す
+// 保持期間より古い通知は追加時に削除する。
+func appendToStream(message Message) (string, error) {
+	if streamClient == nil {
+		return "", errStreamDisabled
+	}
+
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+
+	minID := strconv.FormatInt(time.Now().Add(-streamConfig.Retention).UnixMilli(), 10)
+	return streamClient.XAdd(ctx, &redis.XAddArgs{
+		Stream: streamConfig.Key,
+		MinID:  minID,
+		Approx: true,
+		Values: map[string]interface{}{"payload": payload},
+	}).Result()
+}
+
+// 指定したIDより後に追加された通知を古い順に返す
+// 件数が上限を超える場合は、上限までの通知とtrueを返す。
+func readStreamSince(lastEventID string) ([]Message, bool, error) {
+	if streamClient == nil {
+		return nil, false, errStreamDisabled
+	}
+
+	entries, err := streamClient.XRangeN(ctx, streamConfig.Key, "("+lastEventID, "+", streamConfig.MaxReplay+1).Result()
+	if err != nil {
+		return nil, false, err
+	}
+
+	truncated := int64(len(entries)) > streamConfig.MaxReplay
+	if truncated {
+		entries = entries[:streamConfig.MaxReplay]
+	}
+
+	messages := make([]Message, 0, len(entries))
+	for _, entry := range entries {
+		payload, _ := entry.Values["payload"].(string)
+		var message Message
+		if err := json.Unmarshal([]byte(payload), &message); err != nil {
+			log.Printf("Skipping invalid stream entry %s: %v", entry.ID, err)
+			continue
+		}
+		message.ID = entry.ID
+		messages = append(messages, message)
+	}
+	return messages, truncated, nil
+}
+
+// Redis StreamのID(ミリ秒-連番)を解析する
+func parseStreamID(id string) (uint64, uint64, error) {
+	msPart, seqPart, found := strings.Cut(id, "-")
+	if !found {
+		return 0, 0, errors.New("invalid stream id")
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid stream id")
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, errors.New("invalid stream id")
+	}
+	return ms, seq, nil
+}
+
+// Redis StreamのIDを比較する
+// aがbより前なら負、同じなら0、後なら正の値を返す。不正なIDは最も前として扱う。
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq, _ := parseStreamID(a)
+	bMs, bSeq, _ := parseStreamID(b)
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// 指定したIDが保持期間内かどうか
+// 保持期間を過ぎたIDより後の通知は、一部が削除されている可能性がある。
+func isWithinRetention(id string) bool {
+	ms, _, err := parseStreamID(id)
+	if err != nil {
+		return false
+	}
+	return int64(ms) >= time.Now().Add(-streamConfig.Retention).UnixMilli()
+}
+
+// 再接続したクライアントに、指定したIDより後の宛先に該当する通知を再送する
+// すべてを再送できない場合は、再取得を促すメッセージを送信する。
+// 送信バッファの読み出しを開始する前に呼び出す。
+func replayMissedMessages(client *Client, lastEventID string, write func(outbound) error) error {
+	resync := newOutbound(Message{Type: MessageTypeResyncRequired})
+
+	if _, _, err := parseStreamID(lastEventID); err != nil {
+		log.Printf("Invalid last event id: %s", lastEventID)
+		return write(resync)
+	}
+	// クライアントが受信済みの通知は、ライブ配信でも送信しない
+	client.markReplayed(lastEventID)
+	if !isWithinRetention(lastEventID) {
+		log.Printf("Last event id is older than retention: %s", lastEventID)
+		if err := write(resync); err != nil {
+			return err
+		}
+	}
+
+	messages, truncated, err := readStreamSince(lastEventID)
+	if err != nil {
+		// 履歴を取得できない場合も、ライブ配信は継続する
+		log.Printf("Failed to read notification stream: %v", err)
+		return write(resync)
+	}
+
+	count := 0
+	for _, message := range messages {
+		msg := newOutbound(message)
+		if message.Recipient.Includes(client.UserID(), client.Role()) {
+			if err := write(msg); err != nil {
+				return err
+			}
+			count++
+		}
+		// 宛先に該当しない通知も、ライブ配信で重複しないよう再送済みとして扱う
+		client.markReplayed(message.ID)
+	}
+	log.Printf("Replayed %d messages for user: %s", count, client.UserID())
+
+	if truncated {
+		return write(resync)
+	}
+	return nil
+}
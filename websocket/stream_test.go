@@ -0,0 +1,169 @@
+package websocket
+
+import (
+	"backend/models"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のRedisに差し替える
+func useTestRedis(t *testing.T) *miniredis.Miniredis {
+	mr := miniredis.RunT(t)
+	original := streamClient
+	streamClient = redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() {
+		streamClient.Close()
+		streamClient = original
+	})
+	return mr
+}
+
+func TestCompareStreamIDs(t *testing.T) {
+	assert.Equal(t, 0, compareStreamIDs("100-1", "100-1"))
+	assert.Equal(t, -1, compareStreamIDs("100-1", "100-2"))
+	assert.Equal(t, -1, compareStreamIDs("99-5", "100-0"))
+	assert.Equal(t, 1, compareStreamIDs("1000-0", "999-9"))
+}
+
+func TestReadStreamSince(t *testing.T) {
+	useTestRedis(t)
+	defer InitStream(DefaultStreamConfig(), streamClient)
+	config := DefaultStreamConfig()
+	config.MaxReplay = 2
+	InitStream(config, streamClient)
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := appendToStream(Message{Type: "reservation_notification", Content: strconv.Itoa(i), Recipient: UserRecipient("user1")})
+		if !assert.NoError(t, err) {
+			return
+		}
+		ids = append(ids, id)
+	}
+	assert.Equal(t, -1, compareStreamIDs(ids[0], ids[1]))
+
+	// 指定したIDより後の通知のみを返す
+	messages, truncated, err := readStreamSince(ids[0])
+	if assert.NoError(t, err) {
+		assert.False(t, truncated)
+		if assert.Len(t, messages, 2) {
+			assert.Equal(t, ids[1], messages[0].ID)
+			assert.Equal(t, "1", messages[0].Content)
+			assert.Equal(t, "user1", messages[0].Recipient.UserID)
+		}
+	}
+
+	// 上限を超える場合
+	messages, truncated, err = readStreamSince("0-0")
+	if assert.NoError(t, err) {
+		assert.True(t, truncated)
+		assert.Len(t, messages, 2)
+	}
+}
+
+func TestIsWithinRetention(t *testing.T) {
+	now := time.Now().UnixMilli()
+	assert.True(t, isWithinRetention(strconv.FormatInt(now, 10)+"-0"))
+	assert.False(t, isWithinRetention(strconv.FormatInt(now-(25*time.Hour).Milliseconds(), 10)+"-0"))
+	assert.False(t, isWithinRetention("invalid"))
+}
+
+// 再接続用のパラメータを指定してWebSocketサーバーに接続する
+func dialWithLastEventID(t *testing.T, server string, token, lastEventID string) *websocket.Conn {
+	header := map[string][]string{
+		"Origin": {testOrigin},
+		"Cookie": {"token=" + token},
+	}
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+server[len("http"):]+"/ws?"+LastEventIDQueryParam+"="+lastEventID, header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// 指定した件数のメッセージを受信する
+func readMessages(t *testing.T, ws *websocket.Conn, count int) []outgoingMessage {
+	var messages []outgoingMessage
+	for i := 0; i <= count; i++ {
+		ws.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		var msg outgoingMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			break
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+func TestHandleWebSocket_Replay(t *testing.T) {
+	useTestRedis(t)
+	useTestHub(t, DefaultHubConfig())
+	server := newTestServer(t)
+	token := newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour))
+
+	// 切断中に送信された通知
+	lastID, _ := appendToStream(Message{Type: "reservation_notification", Content: "received", Recipient: UserRecipient("customer1")})
+	missedID, _ := appendToStream(Message{Type: "reservation_notification", Content: "missed", Recipient: UserRecipient("customer1")})
+	appendToStream(Message{Type: "reservation_notification", Content: "other user", Recipient: UserRecipient("customer2")})
+
+	ws := dialWithLastEventID(t, server.URL, token, lastID)
+	assert.Eventually(t, func() bool { return isConnected("customer1") }, time.Second, 10*time.Millisecond)
+
+	// 再送と重複するライブ配信は送信されず、新しい通知のみ続けて配信される
+	hub.deliver(Message{ID: missedID, Type: "reservation_notification", Content: "missed", Recipient: UserRecipient("customer1")})
+	liveID, _ := appendToStream(Message{Type: "reservation_notification", Content: "live", Recipient: UserRecipient("customer1")})
+	hub.deliver(Message{ID: liveID, Type: "reservation_notification", Content: "live", Recipient: UserRecipient("customer1")})
+
+	messages := readMessages(t, ws, 2)
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, outgoingMessage{ID: missedID, Type: "reservation_notification", Content: "missed"}, messages[0])
+		assert.Equal(t, outgoingMessage{ID: liveID, Type: "reservation_notification", Content: "live"}, messages[1])
+	}
+}
+
+func TestHandleWebSocket_LiveOutOfOrder(t *testing.T) {
+	useTestRedis(t)
+	useTestHub(t, DefaultHubConfig())
+	server := newTestServer(t)
+	token := newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour))
+
+	lastID, _ := appendToStream(Message{Type: "reservation_notification", Content: "received", Recipient: UserRecipient("customer1")})
+	ws := dialWithLastEventID(t, server.URL, token, lastID)
+	assert.Eventually(t, func() bool { return isConnected("customer1") }, time.Second, 10*time.Millisecond)
+
+	// 同時に発行された通知は、IDの逆順に配信されても両方送信される
+	firstID, _ := appendToStream(Message{Type: "reservation_notification", Content: "first", Recipient: UserRecipient("customer1")})
+	secondID, _ := appendToStream(Message{Type: "reservation_notification", Content: "second", Recipient: UserRecipient("customer1")})
+	hub.deliver(Message{ID: secondID, Type: "reservation_notification", Content: "second", Recipient: UserRecipient("customer1")})
+	hub.deliver(Message{ID: firstID, Type: "reservation_notification", Content: "first", Recipient: UserRecipient("customer1")})
+
+	messages := readMessages(t, ws, 2)
+	if assert.Len(t, messages, 2) {
+		assert.Equal(t, outgoingMessage{ID: secondID, Type: "reservation_notification", Content: "second"}, messages[0])
+		assert.Equal(t, outgoingMessage{ID: firstID, Type: "reservation_notification", Content: "first"}, messages[1])
+	}
+}
+
+func TestHandleWebSocket_ReplayResync(t *testing.T) {
+	useTestRedis(t)
+	useTestHub(t, DefaultHubConfig())
+	server := newTestServer(t)
+	token := newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour))
+
+	// 保持期間を過ぎたID、または不正なIDの場合は再取得を促す
+	expired := strconv.FormatInt(time.Now().Add(-48*time.Hour).UnixMilli(), 10) + "-0"
+	for _, lastEventID := range []string{expired, "invalid"} {
+		ws := dialWithLastEventID(t, server.URL, token, lastEventID)
+		messages := readMessages(t, ws, 1)
+		if assert.Len(t, messages, 1) {
+			assert.Equal(t, MessageTypeResyncRequired, messages[0].Type)
+		}
+	}
+}
@@ -1,42 +1,64 @@
 package websocket
 
 import (
+	"encoding/json"
 	"log"
 )
 
-// Redisからのメッセージをすべてのクライアントにブロードキャスト
-func HandleMessages() {
-	log.Println("Starting to broadcast messages from Redis")
+// ブローカーからのメッセージを宛先のクライアントに配信
+// ブローカーがクローズされると終了する。
+func HandleMessages() error {
+	log.Println("Starting to deliver messages from broker")
 
-	// Redis Pub/Sub をサブスクライブ。複数チャンネルを指定。
-	pubsub := rdb.Subscribe(ctx, "reservation-notifications", "debug-channel")
-	defer pubsub.Close()
+	if messageBroker == nil {
+		log.Println("Broker is not configured")
+		return errBrokerRequired
+	}
+
+	// 複数チャンネルを指定してサブスクライブ
+	subscription, err := messageBroker.Subscribe(ctx, ChannelReservationNotifications, ChannelDebug)
+	if err != nil {
+		log.Printf("Failed to subscribe to broker: %v", err)
+		return err
+	}
+	defer subscription.Close()
 
-	for {
-		log.Println("Waiting for messages from Redis")
+	for msg := range subscription.Messages() {
+		log.Printf("Received message from broker: %s", msg.Payload)
 
-		// Redisからのメッセージを受信
-		msg, err := pubsub.ReceiveMessage(ctx)
+		message, err := parseMessage(msg.Channel, string(msg.Payload))
 		if err != nil {
-			log.Printf("Failed to receive message from Redis: %v", err)
+			log.Printf("Discarding message from %s: %v", msg.Channel, err)
 			continue
 		}
-		log.Printf("Received message from Redis: %s", msg.Payload)
-
-		// チャンネルに応じて処理を分岐
-		switch msg.Channel {
-		case "reservation-notifications":
-			log.Printf("Broadcasting reservation notification: %s", msg.Payload)
-			// WebSocketクライアントにメッセージを送信
-			broadcastMessage("reservation_notification", msg.Payload)
-		case "debug-channel":
-			log.Printf("Broadcasting debug message: %s", msg.Payload)
-			// WebSocketクライアントにメッセージを送信
-			broadcastMessage("debug", msg.Payload)
-		default:
-			log.Printf("Unknown channel: %s", msg.Channel)
-		}
 
-		log.Println("Message broadcasted to all clients")
+		// WebSocketクライアントにメッセージを送信
+		deliverMessage(*message)
+	}
+
+	log.Println("Stopped delivering messages from broker")
+	return nil
+}
+
+// ブローカーのメッセージを解析する
+// 宛先のないメッセージは、全員に配信されることのないよう破棄する。
+func parseMessage(channel, payload string) (*Message, error) {
+	var message Message
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		return nil, err
+	}
+	if message.Recipient.IsEmpty() {
+		return nil, errRecipientRequired
+	}
+
+	// チャンネルに応じてメッセージタイプを決定
+	switch channel {
+	case ChannelReservationNotifications:
+		message.Type = "reservation_notification"
+	case ChannelDebug:
+		message.Type = "debug"
+	default:
+		return nil, errUnknownChannel
 	}
+	return &message, nil
 }
@@ -1,27 +1,23 @@
 package websocket
 
 import (
+	"backend/auth"
+	"backend/models"
 	"context"
 	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"strings"
-	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/labstack/echo/v4"
-	"github.com/redis/go-redis/v9"
 )
 
 var (
 	ctx = context.Background()
 
-	// Redisクライアントの設定
-	rdb = redis.NewClient(&redis.Options{
-		Addr: os.Getenv("REDIS_URL"), // Docker ComposeでRedisコンテナを利用
-	})
-
 	// 許可するオリジンを環境変数から取得
 	allowedOrigins = strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",")
 
@@ -39,15 +35,45 @@ var (
 		},
 	}
 
-	// クライアントを保持するためのマップとロック
-	clients = make(map[*websocket.Conn]bool)
-	mutex   = &sync.Mutex{}
+	// セッションの失効状態を確認するストア。nilの場合は確認しない。
+	revocationStore auth.RevocationStore
+)
+
+const (
+	// 認証されていない、またはセッションが期限切れ・失効した接続を閉じる際のクローズコード
+	// HTTPの401に対応させ、クライアントは再認証後に接続し直す。
+	CloseUnauthorized = 4401
+	// 許可されていない操作を行った接続を閉じる際のクローズコード
+	CloseForbidden = 4403
+
+	// クローズフレームの送信の待ち時間
+	closeWriteWait = time.Second
 )
 
+// セッションの失効状態を確認するストアを設定する
+func SetRevocationStore(store auth.RevocationStore) {
+	revocationStore = store
+}
+
+// クローズコードを送信して接続を閉じる
+// 書き込みゴルーチンを開始する前の接続に使用する。
+func closeWithCode(ws *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	if err := ws.WriteControl(websocket.CloseMessage, message, time.Now().Add(closeWriteWait)); err != nil {
+		log.Printf("Failed to send close message: %v", err)
+	}
+	ws.Close()
+}
+
 // WebSocketハンドラー
+// クッキーのアクセストークン、またはticketクエリパラメータのチケットで認証する。
+// 認証できない場合も接続をアップグレードし、ブラウザが理由を受け取れるようクローズコードで閉じる。
 func HandleWebSocket(c echo.Context) error {
 	log.Println("WebSocket connection requested")
 
+	// アップグレード前にリクエストを認証
+	claims, authErr := auth.AuthenticateWebSocket(c, revocationStore)
+
 	// WebSocket接続をアップグレード
 	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
@@ -55,23 +81,63 @@ func HandleWebSocket(c echo.Context) error {
 		return err
 	}
 	log.Println("WebSocket connection upgraded")
-	defer ws.Close()
 
-	// クライアントをマップに追加
-	mutex.Lock()
-	clients[ws] = true
-	mutex.Unlock()
+	if authErr != nil {
+		log.Printf("WebSocket authentication failed: %v", authErr)
+		closeWithCode(ws, CloseUnauthorized, "unauthorized")
+		return nil
+	}
 
-	log.Println("WebSocket connection established")
+	// クライアントをハブに登録し、書き込みゴルーチンを開始
+	h := hub
+	client := newClient(ws, claims, h.config.SendBufferSize)
+	if !h.register(client) {
+		log.Println("Server is shutting down, rejecting WebSocket connection")
+		closeWithCode(ws, websocket.CloseGoingAway, "server shutting down")
+		return nil
+	}
 
-	// クライアントが切断されたときに、マップから削除する
+	// 再接続の場合は、書き込みゴルーチンの開始前に取りこぼした通知を再送する
+	// 再送中に届いた通知は送信バッファに積まれ、再送済みのものは書き込みゴルーチンが除外する
+	if lastEventID := c.QueryParam(LastEventIDQueryParam); lastEventID != "" {
+		write := func(message outbound) error {
+			return client.write(message, h.config.WriteWait)
+		}
+		if err := replayMissedMessages(client, lastEventID, write); err != nil {
+			log.Printf("Failed to replay messages: %v", err)
+			h.unregister(client)
+			ws.Close()
+			return nil
+		}
+	}
+	go client.writePump(h.config)
+
+	// Pongを受信するたびに読み込みの期限を延長し、応答のない接続(ハーフオープン)を検出する
+	ws.SetReadLimit(h.config.MaxMessageSize)
+	ws.SetReadDeadline(time.Now().Add(h.config.PongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(h.config.PongWait))
+	})
+
+	log.Printf("WebSocket connection established for user: %s", client.UserID())
+
+	// クライアントが切断されたときに、ハブから削除して書き込みゴルーチンの終了を待つ
 	defer func() {
-		mutex.Lock()
-		delete(clients, ws)
-		mutex.Unlock()
+		h.unregister(client)
+		client.close(websocket.CloseNormalClosure, "")
+		<-client.stopped
 		log.Println("WebSocket connection closed")
 	}()
 
+	// セッションの有効期限で接続を閉じる
+	if claims.ExpiresAt > 0 {
+		expiryTimer := time.AfterFunc(time.Until(time.Unix(claims.ExpiresAt, 0)), func() {
+			log.Printf("WebSocket session expired for user: %s", client.UserID())
+			client.close(CloseUnauthorized, "session expired")
+		})
+		defer expiryTimer.Stop()
+	}
+
 	// クライアントからのメッセージを受信
 	for {
 		log.Println("Waiting for message...")
@@ -83,6 +149,13 @@ func HandleWebSocket(c echo.Context) error {
 		}
 		log.Println("Message received")
 
+		// ログアウトなどで失効したセッションからのメッセージを拒否する
+		if err := auth.CheckWebSocketSession(revocationStore, claims); err != nil {
+			log.Printf("WebSocket session is no longer valid: %v", err)
+			client.close(CloseUnauthorized, err.Error())
+			break
+		}
+
 		// メッセージのタイプで処理を分岐
 		var messageData map[string]interface{}
 		if err := json.Unmarshal(msg, &messageData); err != nil {
@@ -98,6 +171,14 @@ func HandleWebSocket(c echo.Context) error {
 		}
 
 		log.Println("Message type:", messageType)
+
+		// Redisへのパブリッシュは通知を管理できるユーザーのみ
+		if !claims.HasPermission(models.PermissionNotificationsManage) {
+			log.Printf("User %s is not allowed to publish messages", client.UserID())
+			client.close(CloseForbidden, "forbidden")
+			break
+		}
+
 		// メッセージタイプによって処理を分岐
 		switch messageType {
 		case "debug":
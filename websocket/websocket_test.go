@@ -0,0 +1,300 @@
+package websocket
+
+import (
+	"backend/auth"
+	"backend/broker"
+	"backend/models"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+// テスト用のオリジン
+const testOrigin = "http://localhost:3000"
+
+func TestMain(m *testing.M) {
+	// テスト用のキーリングを設定
+	testKeyring, err := auth.NewKeyring([]*auth.SigningKey{auth.NewHMACKey("test", []byte("test-secret"))}, "test")
+	if err != nil {
+		panic("Error creating test keyring: " + err.Error())
+	}
+	auth.SetKeyring(testKeyring)
+	allowedOrigins = []string{testOrigin}
+
+	// テストを実行
+	os.Exit(m.Run())
+}
+
+// テスト用のWebSocketサーバーを起動する
+func newTestServer(t *testing.T) *httptest.Server {
+	e := echo.New()
+	e.GET("/ws", HandleWebSocket)
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// テスト用のアクセストークンを生成
+func newTestToken(t *testing.T, expiresAt time.Time) string {
+	return newTestUserToken(t, "user1", models.RoleCustomer, expiresAt)
+}
+
+// ユーザーとロールを指定してテスト用のアクセストークンを生成
+func newTestUserToken(t *testing.T, userId, role string, expiresAt time.Time) string {
+	tokenString, err := auth.SignToken(&auth.Claims{
+		UserID: userId,
+		Role:   role,
+		StandardClaims: jwt.StandardClaims{
+			Id:        "session1",
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: expiresAt.Unix(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return tokenString
+}
+
+// WebSocketサーバーに接続する
+func dial(t *testing.T, server *httptest.Server, token string) *websocket.Conn {
+	header := http.Header{}
+	header.Set("Origin", testOrigin)
+	if token != "" {
+		header.Set("Cookie", "token="+token)
+	}
+
+	ws, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { ws.Close() })
+	return ws
+}
+
+// 接続が閉じられるまで待ち、クローズコードを返す
+func readCloseCode(t *testing.T, ws *websocket.Conn) int {
+	ws.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := ws.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if closeErr, ok := err.(*websocket.CloseError); ok {
+			return closeErr.Code
+		}
+		t.Fatalf("Unexpected read error: %v", err)
+		return 0
+	}
+}
+
+// 指定したユーザーの接続が登録されているかどうか
+func isConnected(userId string) bool {
+	return hub.registry.countUser(userId) > 0
+}
+
+func TestHandleWebSocket_Unauthorized(t *testing.T) {
+	server := newTestServer(t)
+
+	ws := dial(t, server, "")
+	assert.Equal(t, CloseUnauthorized, readCloseCode(t, ws))
+}
+
+func TestHandleWebSocket_Authenticated(t *testing.T) {
+	server := newTestServer(t)
+
+	ws := dial(t, server, newTestToken(t, time.Now().Add(time.Hour)))
+	assert.Eventually(t, func() bool { return isConnected("user1") }, time.Second, 10*time.Millisecond)
+
+	// 通知を管理する権限のないユーザーはメッセージをパブリッシュできない
+	assert.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"debug","message":"hello"}`)))
+	assert.Equal(t, CloseForbidden, readCloseCode(t, ws))
+	assert.Eventually(t, func() bool { return !isConnected("user1") }, time.Second, 10*time.Millisecond)
+}
+
+func TestHandleWebSocket_SessionExpired(t *testing.T) {
+	server := newTestServer(t)
+
+	// 接続中にセッションの有効期限が切れた場合
+	ws := dial(t, server, newTestToken(t, time.Now().Add(1500*time.Millisecond)))
+	assert.Equal(t, CloseUnauthorized, readCloseCode(t, ws))
+}
+
+func TestDeliverMessage(t *testing.T) {
+	server := newTestServer(t)
+
+	customer1 := dial(t, server, newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour)))
+	customer2 := dial(t, server, newTestUserToken(t, "customer2", models.RoleCustomer, time.Now().Add(time.Hour)))
+	staff := dial(t, server, newTestUserToken(t, "staff1", models.RoleStaff, time.Now().Add(time.Hour)))
+	for _, userId := range []string{"customer1", "customer2", "staff1"} {
+		userId := userId
+		assert.Eventually(t, func() bool { return isConnected(userId) }, time.Second, 10*time.Millisecond)
+	}
+
+	// 予約したユーザー本人と店舗スタッフ宛て
+	recipient := StaffRecipient()
+	recipient.UserID = "customer1"
+	deliverMessage(Message{Type: "reservation_notification", Content: "for customer1", Recipient: recipient})
+	// 店舗スタッフのみ宛て
+	deliverMessage(Message{Type: "debug", Content: "for staff", Recipient: StaffRecipient()})
+	// 別のユーザー宛て
+	deliverMessage(Message{Type: "reservation_notification", Content: "for customer2", Recipient: UserRecipient("customer2")})
+
+	assert.Equal(t, []string{"for customer1"}, readContents(t, customer1, 1))
+	assert.Equal(t, []string{"for customer2"}, readContents(t, customer2, 1))
+	assert.Equal(t, []string{"for customer1", "for staff"}, readContents(t, staff, 2))
+}
+
+func TestHandleMessages(t *testing.T) {
+	server := newTestServer(t)
+	useTestRedis(t)
+
+	// ブローカーを差し替え、パブリッシュした通知がサブスクライブ側から配信されることを確認する
+	memoryBroker := broker.NewMemoryBroker()
+	original := messageBroker
+	SetBroker(memoryBroker)
+	t.Cleanup(func() { SetBroker(original) })
+
+	done := make(chan error, 1)
+	go func() { done <- HandleMessages() }()
+
+	customer1 := dial(t, server, newTestUserToken(t, "customer1", models.RoleCustomer, time.Now().Add(time.Hour)))
+	assert.Eventually(t, func() bool { return isConnected("customer1") }, time.Second, 10*time.Millisecond)
+
+	publisher := NewPublisher(memoryBroker)
+	assert.Equal(t, errRecipientRequired, publisher.Publish(ChannelReservationNotifications, Message{Content: "no recipient"}))
+	assert.NoError(t, publisher.Publish(ChannelReservationNotifications, Message{Content: "for customer2", Recipient: UserRecipient("customer2")}))
+	assert.NoError(t, publisher.Publish(ChannelReservationNotifications, Message{Content: "for customer1", Recipient: UserRecipient("customer1")}))
+	assert.Equal(t, []string{"for customer1"}, readContents(t, customer1, 1))
+
+	// ブローカーがクローズされると配信を終了する
+	assert.NoError(t, memoryBroker.Close())
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("HandleMessages did not stop")
+	}
+}
+
+// 指定した件数のメッセージを受信し、内容を返す
+// 指定した件数より多く受信していないことも確認する。
+func readContents(t *testing.T, ws *websocket.Conn, count int) []string {
+	var contents []string
+	for i := 0; i <= count; i++ {
+		ws.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+		var msg map[string]string
+		if err := ws.ReadJSON(&msg); err != nil {
+			break
+		}
+		contents = append(contents, msg["content"])
+	}
+	return contents
+}
+
+func TestParseMessage(t *testing.T) {
+	message, err := parseMessage(ChannelReservationNotifications, `{"content":"hello","recipient":{"user_id":"user1"}}`)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "reservation_notification", message.Type)
+		assert.Equal(t, "user1", message.Recipient.UserID)
+	}
+
+	// 宛先のないメッセージ(従来の形式)は破棄する
+	_, err = parseMessage(ChannelReservationNotifications, `New reservation created for user user1`)
+	assert.Error(t, err)
+	_, err = parseMessage(ChannelReservationNotifications, `{"content":"hello"}`)
+	assert.Equal(t, errRecipientRequired, err)
+	_, err = parseMessage("other-channel", `{"content":"hello","recipient":{"roles":["staff"]}}`)
+	assert.Equal(t, errUnknownChannel, err)
+}
+
+// テスト用の設定でハブを差し替える
+func useTestHub(t *testing.T, config HubConfig) {
+	original := hub
+	hub = NewHub(config)
+	t.Cleanup(func() { hub = original })
+}
+
+// テスト用の短い間隔のハブの設定
+func newTestHubConfig() HubConfig {
+	config := DefaultHubConfig()
+	config.PingInterval = 50 * time.Millisecond
+	config.PongWait = 150 * time.Millisecond
+	config.MaxMessageSize = 64
+	return config
+}
+
+func TestHandleWebSocket_Heartbeat(t *testing.T) {
+	useTestHub(t, newTestHubConfig())
+	server := newTestServer(t)
+
+	// 読み込みを続けるクライアントはPingに自動で応答するため、接続が維持される
+	alive := dial(t, server, newTestUserToken(t, "alive", models.RoleCustomer, time.Now().Add(time.Hour)))
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// 読み込みを行わないクライアントはPongを返さないため、切断される
+	dial(t, server, newTestUserToken(t, "stalled", models.RoleCustomer, time.Now().Add(time.Hour)))
+
+	assert.Eventually(t, func() bool { return isConnected("alive") && isConnected("stalled") }, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool { return !isConnected("stalled") }, 2*time.Second, 10*time.Millisecond)
+	assert.True(t, isConnected("alive"))
+}
+
+func TestHandleWebSocket_SessionRevoked(t *testing.T) {
+	useTestHub(t, newTestHubConfig())
+	store := auth.NewMemoryRevocationStore()
+	SetRevocationStore(store)
+	t.Cleanup(func() { SetRevocationStore(nil) })
+	server := newTestServer(t)
+
+	ws := dial(t, server, newTestUserToken(t, "revoked", models.RoleCustomer, time.Now().Add(time.Hour)))
+	assert.Eventually(t, func() bool { return isConnected("revoked") }, time.Second, 10*time.Millisecond)
+
+	// メッセージを送信しない接続も、Pingの送信時に失効を検出して閉じる
+	assert.NoError(t, store.RevokeAllForUser("revoked", time.Now().Add(time.Second)))
+	assert.Equal(t, CloseUnauthorized, readCloseCode(t, ws))
+	assert.Eventually(t, func() bool { return !isConnected("revoked") }, time.Second, 10*time.Millisecond)
+}
+
+func TestHandleWebSocket_MaxMessageSize(t *testing.T) {
+	useTestHub(t, newTestHubConfig())
+	server := newTestServer(t)
+
+	ws := dial(t, server, newTestUserToken(t, "staff1", models.RoleStaff, time.Now().Add(time.Hour)))
+	assert.NoError(t, ws.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("a", 128))))
+	assert.Equal(t, websocket.CloseMessageTooBig, readCloseCode(t, ws))
+}
+
+func TestShutdown(t *testing.T) {
+	useTestHub(t, DefaultHubConfig())
+	server := newTestServer(t)
+
+	ws := dial(t, server, newTestToken(t, time.Now().Add(time.Hour)))
+	assert.Eventually(t, func() bool { return isConnected("user1") }, time.Second, 10*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, Shutdown(ctx))
+
+	// すべての接続に1001 Going Awayが送信される
+	assert.Equal(t, websocket.CloseGoingAway, readCloseCode(t, ws))
+
+	// シャットダウン後の接続は受け付けない
+	ws = dial(t, server, newTestToken(t, time.Now().Add(time.Hour)))
+	assert.Equal(t, websocket.CloseGoingAway, readCloseCode(t, ws))
+}